@@ -0,0 +1,151 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`sort`
+)
+
+// Picker item that stands for all the accounts together.
+const allAccounts = `All accounts`
+
+// Account is the brokerage account, ex. taxable or IRA, with its own cash
+// balance and holdings. The holdings of the profile itself belong to no
+// account and are only counted in the consolidated view.
+type Account struct {
+	Cash       float64            // Cash balance.
+	Holdings   map[string]Holding // Number of shares held and cost basis per ticker.
+	CostMethod string             // Cost basis method of the ledger: "fifo", "lifo", or "average"; blank for FIFO.
+}
+
+// AccountNames returns the names of the accounts in alphabetical order.
+func (profile *Profile) AccountNames() []string {
+	names := []string{}
+	for name := range profile.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SelectAccount chooses the account the portfolio is shown for, blank for
+// all the accounts together, and saves the profile.
+func (profile *Profile) SelectAccount(name string) error {
+	if _, ok := profile.Accounts[name]; name != `` && !ok {
+		return fmt.Errorf("No %q account in the profile", name)
+	}
+	profile.Account = name
+
+	return profile.Save()
+}
+
+// NewAccountPicker displays the picker to choose the account the portfolio
+// is shown for.
+func NewAccountPicker(screen *Screen, quotes *Quotes) *Picker {
+	profile := quotes.profile
+	return NewPicker(screen, `Account`, append([]string{allAccounts}, profile.AccountNames()...), func(name string, ok bool) {
+		if ok {
+			if name == allAccounts {
+				name = ``
+			}
+			profile.SelectAccount(name)
+		}
+		screen.Draw(quotes)
+	})
+}
+
+// Returns the names of the given account or, if the account is blank, of
+// all the accounts along with the blank name for the holdings outside of
+// them.
+//-----------------------------------------------------------------------------
+func (profile *Profile) scope(account string) []string {
+	if account != `` {
+		return []string{account}
+	}
+	return append([]string{``}, profile.AccountNames()...)
+}
+
+// Returns the holdings of the account with the given name, blank for the
+// holdings outside of the accounts.
+//-----------------------------------------------------------------------------
+func (profile *Profile) holdingsOf(name string) map[string]Holding {
+	if name == `` {
+		return profile.Holdings
+	}
+	return profile.Accounts[name].Holdings
+}
+
+// Returns the holdings in the given account, or in all of them if the
+// account is blank.
+//-----------------------------------------------------------------------------
+func (profile *Profile) holdings(account string) []map[string]Holding {
+	holdings := []map[string]Holding{}
+	for _, name := range profile.scope(account) {
+		holdings = append(holdings, profile.holdingsOf(name))
+	}
+
+	return holdings
+}
+
+// Returns the number of shares of the ticker and their cost basis in the
+// given account, or added up across all of them if the account is blank.
+// The cost basis of the ledger, as per the account's cost basis method,
+// takes precedence over the one set for the holding.
+//-----------------------------------------------------------------------------
+func (profile *Profile) position(account, ticker string) (shares, cost float64) {
+	for _, name := range profile.scope(account) {
+		holding := profile.holdingsOf(name)[ticker]
+		s, c := holding.Position()
+		if entry, ok := profile.ledgerBasis(name)[ticker]; ok {
+			c += entry.cost - holding.CostBasis // Keeps the reinvested dividends, if any.
+		}
+		shares, cost = shares+s, cost+c
+	}
+
+	return shares, cost
+}
+
+// Returns the profit or loss realized by the sales in the ledger of the
+// given account, or of all of them if the account is blank.
+//-----------------------------------------------------------------------------
+func (profile *Profile) realized(account string) float64 {
+	realized := 0.0
+	for _, name := range profile.scope(account) {
+		for _, entry := range profile.ledgerBasis(name) {
+			realized += entry.realized
+		}
+	}
+
+	return realized
+}
+
+// Returns the cash balance of the given account, or of all of them if the
+// account is blank.
+//-----------------------------------------------------------------------------
+func (profile *Profile) cash(account string) float64 {
+	cash := 0.0
+	for name, balance := range profile.Accounts {
+		if account == `` || account == name {
+			cash += balance.Cash
+		}
+	}
+
+	return cash
+}
+
+// Returns true if there are holdings in the account selected in the
+// profile.
+//-----------------------------------------------------------------------------
+func (profile *Profile) hasHoldings() bool {
+	for _, holdings := range profile.holdings(profile.Account) {
+		if len(holdings) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	profile := &Profile{
+		filename: filepath.Join(t.TempDir(), `.moprc`),
+		Holdings: map[string]Holding{`AAPL`: {Shares: 1, CostBasis: 150}},
+		Accounts: map[string]Account{
+			`taxable`: {Cash: 500, Holdings: map[string]Holding{`AAPL`: {Shares: 10, CostBasis: 1500}}},
+			`IRA`:     {Cash: 250, Holdings: map[string]Holding{`AAPL`: {Shares: 5, CostBasis: 900}, `IBM`: {Shares: 10, CostBasis: 1400}}},
+		},
+		Transactions: []Transaction{
+			{Date: `2019-01-02`, Ticker: `AAPL`, Shares: 10, Price: 150, Account: `taxable`},
+			{Date: `2019-01-02`, Ticker: `IBM`, Shares: 10, Price: 140, Account: `IRA`},
+		},
+	}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.00), Change: numberOf(2.00)}, {Ticker: `IBM`, LastTrade: numberOf(140.00), Change: numberOf(-1.00)}}}
+	assert.Equal(t, []string{`IRA`, `taxable`}, profile.AccountNames())
+
+	portfolio := NewPortfolio(quotes)
+	assert.Equal(t, ``, portfolio.Account, `consolidated`)
+	assert.InDelta(t, 16*200+1400.0, portfolio.Value, 1e-9)
+	assert.InDelta(t, 3950.0, portfolio.Cost, 1e-9)
+	assert.InDelta(t, 750.0, portfolio.Cash, 1e-9)
+	assert.Equal(t, 16.0, variables(quotes.stocks[0], profile)[`shares`])
+
+	require.NoError(t, profile.SelectAccount(`taxable`))
+	portfolio = NewPortfolio(quotes)
+	assert.Equal(t, `taxable`, portfolio.Account)
+	assert.InDelta(t, 2000.0, portfolio.Value, 1e-9)
+	assert.InDelta(t, 500.0, portfolio.Cash, 1e-9)
+	assert.Equal(t, `AAPL`, portfolio.Largest)
+	assert.Nil(t, portfolio.TWR, `value history is consolidated`)
+	assert.Len(t, profile.cashFlows(profile.Account), 1)
+	assert.Equal(t, 10.0, variables(quotes.stocks[0], profile)[`shares`])
+	assert.Equal(t, 0.0, variables(quotes.stocks[1], profile)[`shares`])
+
+	assert.Error(t, profile.SelectAccount(`401k`))
+	assert.Equal(t, `taxable`, profile.Account)
+	require.NoError(t, profile.SelectAccount(``))
+	assert.True(t, profile.hasHoldings())
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`regexp`
+	`strings`
+
+	`github.com/Knetic/govaluate`
+)
+
+// Matches the ticker the alert rule applies to, ex. `AAPL: last > 200`, or
+// `*: changePercent < -5` for the rule that applies to every stock.
+var alertTicker = regexp.MustCompile(`^\s*([A-Z0-9.^=-]+|\*)\s*:\s*(.+)$`)
+
+// alert is user-defined condition that gets evaluated after each refresh
+// of stock quotes. Rules prefixed with the ticker are evaluated against the
+// stock properties (same as the filter) while the rest are evaluated
+// against the portfolio aggregates.
+type alert struct {
+	rule       string                         // The rule as defined in the profile.
+	ticker     string                         // The ticker, `*` for every stock, or blank for portfolio rule.
+	expression *govaluate.EvaluableExpression // The condition to evaluate.
+}
+
+// Parses alert rule such as "AAPL: last > 200" or "dayPnlPct < -2".
+func newAlert(rule string) (alert, error) {
+	ticker, condition := ``, rule
+	if match := alertTicker.FindStringSubmatch(rule); match != nil {
+		ticker, condition = match[1], match[2]
+	}
+
+	expression, err := newExpression(condition)
+	if err != nil {
+		return alert{}, fmt.Errorf("Invalid alert `%s`: %s", rule, err)
+	}
+
+	return alert{strings.TrimSpace(rule), ticker, expression}, nil
+}
+
+// Alerts evaluates alert rules from the profile and returns the list of
+// the ones that are triggered, i.e. their condition is true. Rules that
+// apply to every stock are listed with the ticker they are triggered for.
+// Failure to send the digest or to export the quotes is reported along with
+// the alerts.
+func (quotes *Quotes) Alerts() []string {
+	triggered := []string{}
+	for _, failed := range []string{quotes.digestError, quotes.exportError} {
+		if failed != `` {
+			triggered = append(triggered, failed)
+		}
+	}
+	if len(quotes.profile.alerts) == 0 {
+		return triggered
+	}
+
+	portfolio := NewPortfolio(quotes).variables()
+	for _, alert := range quotes.profile.alerts {
+		if alert.ticker == `` {
+			if truthy(alert.expression, portfolio) {
+				triggered = append(triggered, alert.rule)
+			}
+			continue
+		}
+		for _, stock := range quotes.stocks {
+			if alert.ticker != `*` && alert.ticker != stock.Ticker {
+				continue
+			}
+			if truthy(alert.expression, variables(stock, quotes.profile)) {
+				if alert.ticker == `*` {
+					triggered = append(triggered, stock.Ticker+`: `+alertTicker.FindStringSubmatch(alert.rule)[2])
+				} else {
+					triggered = append(triggered, alert.rule)
+				}
+			}
+		}
+	}
+
+	return triggered
+}
+
+// AddAlert adds the alert rule, ex. "AAPL: last > 200", to the profile and
+// saves it. The rule that doesn't parse is not added.
+func (profile *Profile) AddAlert(rule string) error {
+	rule = strings.TrimSpace(rule)
+	if rule == `` {
+		return nil
+	}
+	if _, err := newAlert(rule); err != nil {
+		return err
+	}
+	profile.SetAlerts(append(append([]string{}, profile.Alerts...), rule))
+
+	return profile.Save()
+}
+
+// Returns true if the expression evaluates to true; evaluation errors and
+// non-boolean results count as false.
+func truthy(expression *govaluate.EvaluableExpression, values map[string]interface{}) bool {
+	result, err := expression.Evaluate(values)
+	if err != nil {
+		return false
+	}
+	truthy, ok := result.(bool)
+
+	return ok && truthy
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlerts(t *testing.T) {
+	profile := &Profile{Holdings: map[string]Holding{
+		`AAPL`: {Shares: 10, CostBasis: 1500},
+		`IBM`:  {Shares: 5, CostBasis: 800},
+	}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200.00), Change: numberOf(-10.00), ChangePct: numberOf(-4.76)},
+		{Ticker: `IBM`, LastTrade: numberOf(140.00), Change: numberOf(-2.00), ChangePct: numberOf(-1.41)},
+		{Ticker: `KO`, LastTrade: numberOf(46.00), Change: numberOf(0.50), ChangePct: numberOf(1.10)},
+	}}
+
+	portfolio := NewPortfolio(quotes)
+	assert.InDelta(t, 2700.0, portfolio.Value, 0.01)
+	assert.InDelta(t, 2300.0, portfolio.Cost, 0.01)
+	assert.InDelta(t, -110.0, portfolio.DayChange, 0.01)
+	assert.Equal(t, `AAPL`, portfolio.Largest)
+	assert.InDelta(t, 74.07, portfolio.MaxWeight, 0.01)
+
+	_, err := newAlert(`AAPL: last >`)
+	assert.Error(t, err)
+
+	require.NoError(t, profile.SetAlerts([]string{
+		`AAPL: last > 190`,
+		`IBM: last > 190`,
+		`*: changePercent < -1`,
+		`dayPnlPct < -2`,
+		`maxWeight > 80`,
+		`value > 2500 ? true : false`,
+	}))
+	assert.Equal(t, []string{
+		`AAPL: last > 190`,
+		`AAPL: changePercent < -1`,
+		`IBM: changePercent < -1`,
+		`dayPnlPct < -2`,
+		`value > 2500 ? true : false`,
+	}, quotes.Alerts())
+}
+
+func TestAddAlert(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `profile`)
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"Alerts": ["dayPnlPct < -2"]}`), 0644))
+	profile := NewProfile(filename)
+
+	require.NoError(t, profile.AddAlert(` AAPL: last > 200 `))
+	assert.Error(t, profile.AddAlert(`AAPL: last >`))
+	assert.NoError(t, profile.AddAlert(``))
+	assert.Equal(t, []string{`dayPnlPct < -2`, `AAPL: last > 200`}, profile.Alerts)
+	assert.Len(t, profile.alerts, 2)
+	assert.Equal(t, profile.Alerts, NewProfile(filename).Alerts, `saved`)
+}
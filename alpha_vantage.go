@@ -0,0 +1,137 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/url`
+	`strconv`
+	`strings`
+)
+
+const alphaVantageURL = `https://www.alphavantage.co/query?function=GLOBAL_QUOTE&symbol=%s&apikey=%s`
+
+// Number of requests per minute allowed by Alpha Vantage free API key.
+const alphaVantageLimit = 5
+
+// alphaVantage fetches stock quotes using Alpha Vantage API. The API returns
+// one quote per request and limits the number of requests per minute, so
+// each fetch requests as many tickers as the limit allows, picking up where
+// the previous fetch left off.
+type alphaVantage struct {
+	rateLimiter
+	url  string // Quote URL, with placeholders for the ticker and the API key.
+	key  string // Alpha Vantage API key.
+	next int    // Index of the ticker to request next.
+}
+
+// Returns new Alpha Vantage provider for the given API key and the number
+// of requests per minute (0 for the free API key limit).
+func newAlphaVantage(key string, limit int) *alphaVantage {
+	if limit <= 0 {
+		limit = alphaVantageLimit
+	}
+	return &alphaVantage{rateLimiter: newRateLimiter(limit), url: alphaVantageURL, key: key}
+}
+
+// Fetch requests quotes of as many tickers as the rate limit allows.
+func (vantage *alphaVantage) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if vantage.key == `` {
+		return nil, errors.New(`Alpha Vantage API key is not set in the profile`)
+	}
+
+	stocks := []Stock{}
+	for count := vantage.budget(); count > 0 && len(tickers) > 0; count-- {
+		if vantage.next >= len(tickers) {
+			vantage.next = 0
+		}
+		stock, err := vantage.quote(ctx, tickers[vantage.next])
+		if err != nil {
+			return stocks, err
+		}
+		stocks = append(stocks, stock)
+		vantage.next++
+		if len(stocks) == len(tickers) {
+			break
+		}
+	}
+
+	return stocks, nil
+}
+
+//-----------------------------------------------------------------------------
+func (vantage *alphaVantage) quote(ctx context.Context, ticker string) (Stock, error) {
+	vantage.record()
+	response, err := get(ctx, fmt.Sprintf(vantage.url, url.QueryEscape(ticker), url.QueryEscape(vantage.key)))
+	if err != nil {
+		return Stock{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return Stock{}, err
+	}
+
+	return parseAlphaVantage(body)
+}
+
+// Parses Alpha Vantage global quote, ex.
+//
+//   { "Global Quote": { "01. symbol": "IBM", "05. price": "140.0000", ... } }
+//
+// Rate limit and other errors are reported in the "Note", "Information" or
+// "Error Message" fields instead.
+//-----------------------------------------------------------------------------
+func parseAlphaVantage(body []byte) (Stock, error) {
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return Stock{}, err
+	}
+	for _, key := range []string{`Error Message`, `Note`, `Information`} {
+		if message, ok := data[key].(string); ok {
+			return Stock{}, errors.New(message)
+		}
+	}
+
+	quote := map[string]string{}
+	if raw, ok := data[`Global Quote`].(map[string]interface{}); ok {
+		for key, value := range raw {
+			if i := strings.Index(key, `. `); i >= 0 { // Drop the numbering, ex. "05. price" => "price".
+				key = key[i+2:]
+			}
+			quote[key], _ = value.(string)
+		}
+	}
+	if quote[`symbol`] == `` {
+		return Stock{}, errors.New(`Unexpected Alpha Vantage response`)
+	}
+
+	number := func(key string) Number {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(quote[key], `%`), 64)
+		if err != nil {
+			return Number{}
+		}
+		return numberOf(value)
+	}
+	stock := Stock{
+		Ticker:    quote[`symbol`],
+		LastTrade: number(`price`),
+		Change:    number(`change`),
+		ChangePct: number(`change percent`),
+		Open:      number(`open`),
+		Low:       number(`low`),
+		High:      number(`high`),
+		Volume:    number(`volume`),
+		PrevClose: number(`previous close`),
+	}
+	stock.Advancing = stock.Change.Value() >= 0
+
+	return stock, nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlphaVantage(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get(`symbol`)
+		requested = append(requested, symbol)
+		fmt.Fprintf(w, `{"Global Quote": {"01. symbol": "%s", "02. open": "139.0000", "05. price": "140.0000",
+			"06. volume": "3456789", "08. previous close": "141.5000", "09. change": "-1.5000", "10. change percent": "-1.0601%%"}}`, symbol)
+	}))
+	defer server.Close()
+
+	now := time.Date(2019, 6, 28, 10, 0, 0, 0, time.UTC)
+	vantage := newAlphaVantage(`demo`, 2)
+	vantage.url, vantage.now = server.URL+`?symbol=%s&apikey=%s`, func() time.Time { return now }
+
+	stocks, err := vantage.Fetch(context.Background(), []string{`AAPL`, `IBM`, `MSFT`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 2, `rate limit allows 2 requests per minute`)
+	assert.Equal(t, `IBM`, stocks[1].Ticker)
+	assert.Equal(t, `140.000`, stocks[1].LastTrade.String())
+	assert.Equal(t, `-1.060`, stocks[1].ChangePct.String())
+	assert.Equal(t, `3.457M`, stocks[1].Volume.String())
+	assert.False(t, stocks[1].Advancing)
+
+	stocks, _ = vantage.Fetch(context.Background(), []string{`AAPL`, `IBM`, `MSFT`})
+	assert.Empty(t, stocks, `no budget left`)
+
+	now = now.Add(time.Minute)
+	stocks, _ = vantage.Fetch(context.Background(), []string{`AAPL`, `IBM`, `MSFT`})
+	require.Len(t, stocks, 2)
+	assert.Equal(t, []string{`AAPL`, `IBM`, `MSFT`, `AAPL`}, requested, `picks up where it left off`)
+
+	_, err = parseAlphaVantage([]byte(`{"Note": "Thank you for using Alpha Vantage!"}`))
+	assert.EqualError(t, err, `Thank you for using Alpha Vantage!`)
+
+	_, err = newAlphaVantage(``, 0).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`crypto/subtle`
+	`net/http`
+	`strings`
+)
+
+// Scopes of the server access tokens: read-only tokens could see the data
+// mop is tracking, while read-write tokens could also change the watchlist.
+const (
+	ScopeRead  = `read`
+	ScopeWrite = `write`
+)
+
+// ServerSettings defines who could access the server and how.
+type ServerSettings struct {
+	Tokens      map[string]string // Access tokens and their scopes, ex. "s3cr3t": "read".
+	Users       map[string]string // Access tokens of the other users and their profiles, ex. "alice-token": "/home/alice/.moprc".
+	Certificate string            // Path to TLS certificate file, blank to serve plain HTTP.
+	Key         string            // Path to TLS private key file.
+}
+
+// Wraps the handler so it's only called for the requests that come with the
+// token that has the given scope. The token is expected either in the
+// "Authorization: Bearer <token>" header or, for the websocket connections
+// only, in the "token" query parameter (browsers can't set the header for
+// them). Without any
+// tokens set up the data is open for everyone to read but nobody could
+// change it.
+//-----------------------------------------------------------------------------
+func (server *Server) authorize(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !server.allowed(token(r), scope) {
+			w.Header().Set(`WWW-Authenticate`, `Bearer realm="mop"`)
+			http.Error(w, `Unauthorized`, http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Returns true if the token grants the scope; read-write tokens grant
+// read-only access too. The other users could see and change their own
+// profiles, and only those.
+//-----------------------------------------------------------------------------
+func (server *Server) allowed(token, scope string) bool {
+	if server.userOf(token) != `` {
+		return true
+	}
+	if server.settings == nil || len(server.settings.Tokens) == 0 {
+		return scope == ScopeRead
+	}
+
+	granted := ``
+	for known, scope := range server.settings.Tokens { // Compare all to keep the timing the same.
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			granted = scope
+		}
+	}
+
+	return token != `` && (granted == ScopeWrite || granted == scope)
+}
+
+// Returns the other user the token belongs to, or blank if none. The
+// tokens are compared in constant time, same as the ones in the settings.
+//-----------------------------------------------------------------------------
+func (server *Server) userOf(token string) string {
+	user := ``
+	for known := range server.users { // Compare all to keep the timing the same.
+		if token != `` && subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			user = known
+		}
+	}
+
+	return user
+}
+
+// Returns the access token from the Authorization header or, if there is
+// none and the request is for the websocket, from the token query parameter,
+// so that the token doesn't show up in the URLs of the other requests.
+//-----------------------------------------------------------------------------
+func token(r *http.Request) string {
+	if header := r.Header.Get(`Authorization`); strings.HasPrefix(header, `Bearer `) {
+		return strings.TrimSpace(strings.TrimPrefix(header, `Bearer `))
+	}
+	if strings.EqualFold(r.Header.Get(`Upgrade`), `websocket`) {
+		return r.URL.Query().Get(`token`)
+	}
+	return ``
+}
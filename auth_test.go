@@ -0,0 +1,51 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	open := NewServer(nil)
+	assert.True(t, open.allowed(``, ScopeRead), `read-only without tokens`)
+	assert.False(t, open.allowed(``, ScopeWrite))
+
+	server := NewServer(&ServerSettings{Tokens: map[string]string{`viewer`: ScopeRead, `admin`: ScopeWrite}})
+	assert.False(t, server.allowed(``, ScopeRead))
+	assert.False(t, server.allowed(`nope`, ScopeRead))
+	assert.True(t, server.allowed(`viewer`, ScopeRead))
+	assert.False(t, server.allowed(`viewer`, ScopeWrite))
+	assert.True(t, server.allowed(`admin`, ScopeRead))
+	assert.True(t, server.allowed(`admin`, ScopeWrite))
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := NewServer(&ServerSettings{Tokens: map[string]string{`viewer`: ScopeRead}}).Handler()
+
+	for url, code := range map[string]int{`/quotes`: http.StatusUnauthorized, `/quotes?token=viewer`: http.StatusUnauthorized, `/`: http.StatusOK} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(`GET`, url, nil))
+		assert.Equal(t, code, recorder.Code, url)
+	}
+
+	recorder, request := httptest.NewRecorder(), httptest.NewRequest(`GET`, `/quotes`, nil)
+	request.Header.Set(`Authorization`, `Bearer viewer`)
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code, `let in, nothing published yet`)
+}
+
+func TestToken(t *testing.T) {
+	request := httptest.NewRequest(`GET`, `/stream?token=viewer`, nil)
+	assert.Equal(t, ``, token(request), `query only for the websocket`)
+	request.Header.Set(`Upgrade`, `websocket`)
+	assert.Equal(t, `viewer`, token(request))
+	request.Header.Set(`Authorization`, `Bearer admin`)
+	assert.Equal(t, `admin`, token(request), `header first`)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`os`
+	`path/filepath`
+	`sort`
+	`time`
+)
+
+// Number of profile backups to keep.
+const maxBackups = 5
+
+// Backups returns the list of profile backups, oldest first. Backups are
+// kept next to the profile, ex. ~/.moprc.20190628-153000.bak.
+func Backups(filename string) []string {
+	backups, _ := filepath.Glob(filename + `.*.bak`)
+	sort.Strings(backups) // Timestamps sort chronologically.
+
+	return backups
+}
+
+// RestoreBackup replaces the profile with the given backup, or with the
+// latest one if the backup is blank. The profile being replaced is backed
+// up first so the restore could be undone. It returns the name of the
+// backup that has been restored.
+func RestoreBackup(filename, backup string) (string, error) {
+	backup, data, err := readBackup(filename, backup)
+	if err != nil {
+		return ``, err
+	}
+	if err = saveWithBackup(filename, data); err != nil {
+		return ``, err
+	}
+
+	return backup, nil
+}
+
+// PreviewRestore returns the diff between the profile and the given backup
+// (or the latest one if the backup is blank) without restoring it, along
+// with the name of the backup.
+func PreviewRestore(filename, backup string) (diff string, restored string, err error) {
+	backup, data, err := readBackup(filename, backup)
+	if err != nil {
+		return ``, ``, err
+	}
+	current, _ := ioutil.ReadFile(filename)
+
+	return diffProfiles(current, data), backup, nil
+}
+
+// Reads the given backup, or the latest one if the backup is blank.
+//-----------------------------------------------------------------------------
+func readBackup(filename, backup string) (string, []byte, error) {
+	if backup == `` {
+		backups := Backups(filename)
+		if len(backups) == 0 {
+			return ``, nil, errors.New(`No backups of ` + filename + ` found`)
+		}
+		backup = backups[len(backups)-1]
+	}
+	data, err := ioutil.ReadFile(backup)
+
+	return backup, data, err
+}
+
+// Backs up the existing file, then writes the data to it atomically. Only
+// the most recent backups are kept.
+//-----------------------------------------------------------------------------
+func saveWithBackup(filename string, data []byte) error {
+	if previous, err := ioutil.ReadFile(filename); err == nil {
+		backup := fmt.Sprintf(`%s.%s.bak`, filename, time.Now().Format(`20060102-150405`))
+		if err := writeAtomically(backup, previous); err != nil {
+			return err
+		}
+		if backups := Backups(filename); len(backups) > maxBackups {
+			for _, stale := range backups[:len(backups)-maxBackups] {
+				os.Remove(stale)
+			}
+		}
+	}
+
+	return writeAtomically(filename, data)
+}
+
+// Writes the data to the temporary file in the same directory, then renames
+// it so the file is never left partially written if the machine dies in
+// the middle of the save.
+//-----------------------------------------------------------------------------
+func writeAtomically(filename string, data []byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+`.*.tmp`)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // No-op once renamed.
+
+	if _, err = temp.Write(data); err == nil {
+		err = temp.Sync()
+	}
+	if e := temp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), filename)
+}
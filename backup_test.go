@@ -0,0 +1,44 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackups(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `mop`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, `.moprc`)
+	require.NoError(t, saveWithBackup(filename, []byte(`first`)))
+	assert.Empty(t, Backups(filename), `nothing to back up yet`)
+
+	require.NoError(t, saveWithBackup(filename, []byte(`second`)))
+	require.Len(t, Backups(filename), 1)
+
+	for i := 0; i < maxBackups+2; i++ { // Backups from the previous days.
+		ioutil.WriteFile(fmt.Sprintf(`%s.2019010%d-120000.bak`, filename, i+1), []byte(`old`), 0644)
+	}
+	require.NoError(t, saveWithBackup(filename, []byte(`third`)))
+	assert.Len(t, Backups(filename), maxBackups)
+
+	restored, err := RestoreBackup(filename, ``)
+	require.NoError(t, err)
+	data, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, `second`, string(data))
+	assert.Equal(t, Backups(filename)[maxBackups-1], restored, `latest backup is restored`)
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, `*.tmp`))
+	assert.Empty(t, leftovers)
+}
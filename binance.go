@@ -0,0 +1,318 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/url`
+	`regexp`
+	`strconv`
+	`strings`
+	`sync`
+)
+
+const binanceURL = `https://api.binance.com/api/v3/ticker/24hr?symbols=%s`
+const binanceStreamURL = `wss://stream.binance.com:9443/ws`
+
+// Binance spot pairs are the base asset followed by the quote asset, ex.
+// BTCUSDT or ETHBTC. U.S. stock tickers are five letters at most, so six
+// or more letters ending with a common quote asset are taken for a pair.
+var binancePair = regexp.MustCompile(`^[A-Z0-9]{2,}(USDT|USDC|FDUSD|BUSD|TUSD|BTC|ETH|BNB|EUR|TRY)$`)
+
+// withBinance routes Binance spot pairs in the watchlist to Binance, and
+// the rest of the tickers to the given stock quotes provider, so crypto
+// pairs could be mixed with stocks.
+type withBinance struct {
+	stocks Provider // Provider of the stock quotes.
+	crypto *binance // Provider of the spot pair quotes.
+}
+
+// Fetch requests the stock quotes and the spot pair quotes from their
+// providers. Either provider's error is returned along with whatever
+// quotes the other one has returned.
+func (mixed *withBinance) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	stocks, pairs := []string{}, []string{}
+	for _, ticker := range tickers {
+		if isBinancePair(ticker) {
+			pairs = append(pairs, ticker)
+		} else {
+			stocks = append(stocks, ticker)
+		}
+	}
+
+	var fetched []Stock
+	var err error
+	if len(stocks) > 0 {
+		fetched, err = mixed.stocks.Fetch(ctx, stocks)
+	}
+	if len(pairs) > 0 {
+		quotes, e := mixed.crypto.Fetch(ctx, pairs)
+		fetched = append(fetched, quotes...)
+		err = joinErrors(err, e)
+	}
+
+	return fetched, err
+}
+
+// binance fetches 24-hour rolling quotes of Binance spot pairs. The quotes
+// are requested over REST until the websocket stream delivers them, and
+// from then on kept current by the stream.
+type binance struct {
+	url        string           // 24-hour ticker URL, with placeholder for the symbols.
+	streamURL  string           // Websocket URL, blank to disable streaming.
+	mutex      sync.Mutex       // Guards the streaming state below.
+	streamed   map[string]Stock // Latest streamed quote per pair.
+	stream     *websocket       // Open websocket connection, nil when not streaming.
+	subscribed map[string]bool  // Pairs subscribed to over the websocket.
+	id         int              // Last subscription request ID.
+}
+
+// binanceTicker is the 24-hour rolling quote as returned by Binance REST
+// API. The websocket stream reports the same values with one-letter keys.
+type binanceTicker struct {
+	Symbol    string `json:"symbol"`
+	LastPrice string `json:"lastPrice"`
+	OpenPrice string `json:"openPrice"`
+	HighPrice string `json:"highPrice"`
+	LowPrice  string `json:"lowPrice"`
+	Volume    string `json:"volume"`
+	PrevClose string `json:"prevClosePrice"`
+	BidPrice  string `json:"bidPrice"`
+	AskPrice  string `json:"askPrice"`
+}
+
+// Returns true if the ticker looks like Binance spot pair, ex. BTCUSDT.
+func isBinancePair(ticker string) bool {
+	return len(ticker) > 5 && binancePair.MatchString(strings.ToUpper(ticker))
+}
+
+// Returns new Binance provider. Binance market data needs no API key.
+func newBinance() *binance {
+	return &binance{
+		url:        binanceURL,
+		streamURL:  binanceStreamURL,
+		streamed:   make(map[string]Stock),
+		subscribed: make(map[string]bool),
+	}
+}
+
+// Fetch returns the latest quotes of the given pairs, requesting the ones
+// that haven't been streamed yet over REST.
+func (binance *binance) Fetch(ctx context.Context, pairs []string) ([]Stock, error) {
+	binance.subscribe(pairs) // Best effort, same as Finnhub.
+
+	fetched := make(map[string]Stock)
+	binance.mutex.Lock()
+	missing := []string{}
+	for _, pair := range pairs {
+		if stock, ok := binance.streamed[strings.ToUpper(pair)]; ok {
+			fetched[strings.ToUpper(pair)] = stock
+		} else {
+			missing = append(missing, strings.ToUpper(pair))
+		}
+	}
+	binance.mutex.Unlock()
+
+	var err error
+	for _, batch := range batches(missing, maxTickersPerRequest) {
+		stocks, e := binance.quotes(ctx, batch)
+		if e != nil {
+			err = e
+			continue
+		}
+		for _, stock := range stocks {
+			if _, ok := fetched[stock.Ticker]; !ok { // Streamed quotes are newer.
+				fetched[stock.Ticker] = stock
+			}
+		}
+	}
+
+	stocks := []Stock{}
+	for _, pair := range pairs {
+		if stock, ok := fetched[strings.ToUpper(pair)]; ok {
+			stock.Ticker = pair
+			stocks = append(stocks, stock)
+		}
+	}
+
+	return stocks, err
+}
+
+//-----------------------------------------------------------------------------
+func (binance *binance) quotes(ctx context.Context, pairs []string) ([]Stock, error) {
+	symbols, _ := json.Marshal(pairs)
+	response, err := get(ctx, fmt.Sprintf(binance.url, url.QueryEscape(string(symbols))))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBinance(response.StatusCode, body)
+}
+
+// Subscribes to the ticker streams of the given pairs, and unsubscribes
+// from the ones no longer needed. The websocket gets opened on first use
+// and reopened if the connection has been dropped.
+//-----------------------------------------------------------------------------
+func (binance *binance) subscribe(pairs []string) {
+	binance.mutex.Lock()
+	defer binance.mutex.Unlock()
+
+	if binance.streamURL == `` {
+		return
+	}
+	if binance.stream == nil {
+		stream, err := dialWebsocket(binance.streamURL)
+		if err != nil {
+			return
+		}
+		binance.stream, binance.subscribed = stream, make(map[string]bool)
+		go binance.listen(stream)
+	}
+
+	wanted, subscribe, unsubscribe := make(map[string]bool), []string{}, []string{}
+	for _, pair := range pairs {
+		pair = strings.ToUpper(pair)
+		wanted[pair] = true
+		if !binance.subscribed[pair] {
+			subscribe = append(subscribe, strings.ToLower(pair)+`@ticker`)
+		}
+	}
+	for pair := range binance.subscribed {
+		if !wanted[pair] {
+			unsubscribe = append(unsubscribe, strings.ToLower(pair)+`@ticker`)
+			delete(binance.streamed, pair)
+		}
+	}
+	binance.send(`SUBSCRIBE`, subscribe)
+	binance.send(`UNSUBSCRIBE`, unsubscribe)
+	binance.subscribed = wanted
+}
+
+//-----------------------------------------------------------------------------
+func (binance *binance) send(method string, streams []string) {
+	if len(streams) == 0 {
+		return
+	}
+	binance.id++
+	message, _ := json.Marshal(map[string]interface{}{`method`: method, `params`: streams, `id`: binance.id})
+	binance.stream.WriteText(message)
+}
+
+// Receives the ticker updates until the connection is dropped, ex.
+//
+//   { "e": "24hrTicker", "s": "BTCUSDT", "c": "10850.01", "o": "11250.00", "h": "11300.00",
+//     "l": "10600.00", "v": "41235.12", "x": "11249.99", "b": "10850.00", "a": "10850.02" }
+//
+//-----------------------------------------------------------------------------
+func (binance *binance) listen(stream *websocket) {
+	for {
+		message, err := stream.ReadMessage()
+		if err != nil {
+			stream.Close()
+			binance.mutex.Lock()
+			if binance.stream == stream {
+				binance.stream, binance.streamed = nil, make(map[string]Stock) // Fall back to REST.
+			}
+			binance.mutex.Unlock()
+			return
+		}
+
+		// Decoded by hand since the keys differ only in case, ex. "c" is
+		// the last price while "C" is the time of the last trade.
+		fields := map[string]interface{}{}
+		if json.Unmarshal(message, &fields) != nil || fields[`e`] != `24hrTicker` {
+			continue // Subscription results and errors.
+		}
+		value := func(key string) string {
+			str, _ := fields[key].(string)
+			return str
+		}
+		ticker := binanceTicker{
+			Symbol:    value(`s`),
+			LastPrice: value(`c`),
+			OpenPrice: value(`o`),
+			HighPrice: value(`h`),
+			LowPrice:  value(`l`),
+			Volume:    value(`v`),
+			PrevClose: value(`x`),
+			BidPrice:  value(`b`),
+			AskPrice:  value(`a`),
+		}
+		binance.mutex.Lock()
+		if binance.subscribed[ticker.Symbol] {
+			binance.streamed[ticker.Symbol] = ticker.stock()
+		}
+		binance.mutex.Unlock()
+	}
+}
+
+// Parses Binance 24-hour tickers, ex.
+//
+//   [ { "symbol": "BTCUSDT", "lastPrice": "10850.01", "openPrice": "11250.00", "highPrice": "11300.00",
+//       "lowPrice": "10600.00", "volume": "41235.12", "prevClosePrice": "11249.99", ... } ]
+//
+// Errors are reported as { "code": -1121, "msg": "Invalid symbol." }.
+//-----------------------------------------------------------------------------
+func parseBinance(status int, body []byte) ([]Stock, error) {
+	if status != http.StatusOK {
+		failure := struct{ Msg string }{}
+		json.Unmarshal(body, &failure)
+		if failure.Msg == `` {
+			failure.Msg = strings.TrimSpace(string(body))
+		}
+		return nil, fmt.Errorf("Binance responded with %d: %s", status, failure.Msg)
+	}
+
+	tickers := []binanceTicker{}
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, err
+	}
+
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		stocks = append(stocks, ticker.stock())
+	}
+
+	return stocks, nil
+}
+
+// Returns the stock quote for the pair. The change is reported over the
+// last 24 hours since the markets never close.
+//-----------------------------------------------------------------------------
+func (ticker binanceTicker) stock() Stock {
+	number := func(str string) *float64 {
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil
+		}
+		return &value
+	}
+
+	last, open := number(ticker.LastPrice), number(ticker.OpenPrice)
+	stock := Stock{
+		Ticker:    ticker.Symbol,
+		LastTrade: orNA(last),
+		Open:      orNA(open),
+		Low:       orNA(number(ticker.LowPrice)),
+		High:      orNA(number(ticker.HighPrice)),
+		Volume:    orNA(number(ticker.Volume)),
+		PrevClose: orNA(number(ticker.PrevClose)),
+		Bid:       orNA(number(ticker.BidPrice)),
+		Ask:       orNA(number(ticker.AskPrice)),
+	}
+	withChange(&stock, last, open)
+
+	return stock
+}
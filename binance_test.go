@@ -0,0 +1,103 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBinancePair(t *testing.T) {
+	assert.True(t, isBinancePair(`BTCUSDT`))
+	assert.True(t, isBinancePair(`ethbtc`))
+	assert.True(t, isBinancePair(`1INCHUSDT`))
+	assert.False(t, isBinancePair(`AAPL`))
+	assert.False(t, isBinancePair(`SETH`), `too short for a pair`)
+	assert.False(t, isBinancePair(`BTC-USD`))
+	assert.False(t, isBinancePair(`^GSPC`))
+}
+
+func TestParseBinance(t *testing.T) {
+	stocks, err := parseBinance(http.StatusOK, []byte(`[{"symbol":"BTCUSDT","priceChange":"-399.99","lastPrice":"10850.01",
+	  "openPrice":"11250.00","highPrice":"11300.00","lowPrice":"10600.00","volume":"41235.12","prevClosePrice":"11249.99",
+	  "bidPrice":"10850.00","askPrice":"10850.02"}]`))
+	require.NoError(t, err)
+	require.Len(t, stocks, 1)
+	assert.Equal(t, `BTCUSDT`, stocks[0].Ticker)
+	assert.Equal(t, `10850.010`, stocks[0].LastTrade.String())
+	assert.Equal(t, `-399.990`, stocks[0].Change.String())
+	assert.Equal(t, `-3.555`, stocks[0].ChangePct.String())
+	assert.Equal(t, `10850.020`, stocks[0].Ask.String())
+	assert.Equal(t, `N/A`, stocks[0].MarketCap.String())
+	assert.False(t, stocks[0].Advancing)
+
+	_, err = parseBinance(http.StatusBadRequest, []byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+	assert.EqualError(t, err, `Binance responded with 400: Invalid symbol.`)
+}
+
+func TestBinanceFetch(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get(`symbols`))
+		fmt.Fprint(w, `[{"symbol":"BTCUSDT","lastPrice":"10000","openPrice":"9000"},{"symbol":"ETHBTC","lastPrice":"0.02","openPrice":"0.02"}]`)
+	}))
+	defer server.Close()
+
+	subscribed, release := make(chan string, 1), make(chan bool)
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := upgradeWebsocket(w, r)
+		require.NoError(t, err)
+		defer client.Close()
+
+		message, _ := client.ReadMessage()
+		subscribed <- string(message)
+
+		<-release // Stream the ticker once the quotes have been fetched.
+		client.WriteText([]byte(`{"result":null,"id":1}`))
+		client.WriteText([]byte(`{"e":"24hrTicker","E":1561730400000,"s":"BTCUSDT","c":"11000","C":1561730400000,"o":"10000","O":1561644000000}`))
+		time.Sleep(time.Second)
+	}))
+	defer stream.Close()
+
+	crypto := newBinance()
+	crypto.url = server.URL + `?symbols=%s`
+	crypto.streamURL = strings.Replace(stream.URL, `http://`, `ws://`, 1)
+
+	stocks, err := crypto.Fetch(context.Background(), []string{`BTCUSDT`, `ETHBTC`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 2)
+	assert.Equal(t, `10000.000`, stocks[0].LastTrade.String())
+	assert.Equal(t, `11.111`, stocks[0].ChangePct.String())
+	assert.Equal(t, `{"id":1,"method":"SUBSCRIBE","params":["btcusdt@ticker","ethbtc@ticker"]}`, <-subscribed)
+
+	close(release)
+
+	require.Eventually(t, func() bool {
+		stocks, _ = crypto.Fetch(context.Background(), []string{`BTCUSDT`, `ETHBTC`})
+		return len(stocks) == 2 && stocks[0].LastTrade.String() == `11000.000`
+	}, time.Second, 10*time.Millisecond, `streamed ticker updates the quote`)
+	assert.Equal(t, `10.000`, stocks[0].ChangePct.String())
+	assert.Equal(t, `["ETHBTC"]`, requested[len(requested)-1], `streamed pairs are not requested over REST`)
+}
+
+func TestWithBinance(t *testing.T) {
+	stocks := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`}, `IBM`: {Ticker: `IBM`}}}
+	crypto := newBinance()
+	crypto.url, crypto.streamURL = `http://127.0.0.1:0/?symbols=%s`, ``
+
+	mixed := &withBinance{stocks: stocks, crypto: crypto}
+	fetched, err := mixed.Fetch(context.Background(), []string{`AAPL`, `BTCUSDT`, `IBM`})
+	assert.Error(t, err, `Binance is not reachable`)
+	assert.Len(t, fetched, 2, `stock quotes are returned anyway`)
+	assert.Equal(t, [][]string{{`AAPL`, `IBM`}}, stocks.requested)
+}
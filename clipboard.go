@@ -0,0 +1,64 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/base64`
+	`os`
+	`os/exec`
+	`strings`
+)
+
+// Programs that copy their stdin to the system clipboard, tried in order
+// until one of them succeeds.
+var clipboardHelpers = [][]string{
+	{`pbcopy`},                           // macOS.
+	{`clip.exe`},                         // Windows, including WSL.
+	{`wl-copy`},                          // Wayland.
+	{`xclip`, `-selection`, `clipboard`}, // X11.
+	{`xsel`, `--clipboard`, `--input`},   // X11 without xclip.
+}
+
+// Copies the text to the system clipboard with the first clipboard helper
+// that works. Over SSH, or when there are no helpers, the text is sent to
+// the terminal as OSC 52 escape sequence instead, and the terminal puts it
+// in the clipboard on its side, if it supports that.
+//-----------------------------------------------------------------------------
+func copyToClipboard(text string) error {
+	if os.Getenv(`SSH_TTY`) == `` && os.Getenv(`SSH_CONNECTION`) == `` {
+		for _, helper := range clipboardHelpers {
+			if _, err := exec.LookPath(helper[0]); err != nil {
+				continue
+			}
+			command := exec.Command(helper[0], helper[1:]...)
+			command.Stdin = strings.NewReader(text)
+			if command.Run() == nil {
+				return nil
+			}
+		}
+	}
+
+	tty, err := os.OpenFile(`/dev/tty`, os.O_WRONLY, 0)
+	if err != nil {
+		tty = os.Stdout
+	} else {
+		defer tty.Close()
+	}
+	_, err = tty.WriteString(osc52(text, os.Getenv(`TMUX`) != ``))
+
+	return err
+}
+
+// Returns the OSC 52 escape sequence that sets the clipboard to the text,
+// wrapped to be passed through to the terminal when running within tmux.
+//-----------------------------------------------------------------------------
+func osc52(text string, tmux bool) string {
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		return "\x1bPtmux;" + strings.Replace(sequence, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	}
+
+	return sequence
+}
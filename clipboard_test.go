@@ -0,0 +1,16 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSC52(t *testing.T) {
+	assert.Equal(t, "\x1b]52;c;QUFQTCAkMjA3LjQ4\a", osc52(`AAPL $207.48`, false))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;QUFQTCAkMjA3LjQ4\a\x1b\\", osc52(`AAPL $207.48`, true))
+}
@@ -0,0 +1,674 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mop-tracker/mop"
+)
+
+// options are the command line flags shared by the subcommands. Each
+// subcommand picks the ones it accepts.
+type options struct {
+	profile   string // Path to the profile.
+	source    string // Stock quotes provider that overrides the profile.
+	listen    string // Address to serve the web view at.
+	replay    string // Directory of the recorded snapshots to play back.
+	socks5    string // SOCKS5 proxy address that overrides the profile.
+	control   string // Path to the control socket of the session.
+	once      string // Tickers to print the quotes of instead of the interactive session.
+	all       bool   // True to include the settings left at their defaults.
+	checkOnly bool   // True to check for the update without installing it.
+	dryRun    bool   // True to print the profile changes without saving them.
+	dumpJSON  bool   // True to print the quotes as JSON instead of the table.
+	output    string // Format the quotes are printed in: "table", "csv", or "json".
+}
+
+// command is mop subcommand along with its flags and help text.
+type command struct {
+	name    string                       // Subcommand name, ex. "export".
+	args    string                       // Arguments that follow the flags, ex. "[<ticker>,...]".
+	summary string                       // One line description for the list of commands.
+	help    string                       // Longer description for `mop help <command>`.
+	flags   []string                     // Names of the flags the command accepts.
+	run     func(*options, []string) int // Runs the command and returns the exit code.
+}
+
+// Flag definitions by name. The current value becomes the default, so the
+// flags given before the subcommand carry over to it.
+var flags = map[string]func(*flag.FlagSet, *options){
+	`profile`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.profile, `profile`, options.profile, `path to profile`)
+	},
+	`source`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.source, `source`, options.source, `stock quotes provider: yahoo, alphavantage, finnhub, iex, polygon, sse, stooq, tiingo, or file:// URL (overrides the profile)`)
+	},
+	`listen`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.listen, `listen`, options.listen, `serve the web view at the given address, ex. :8080`)
+	},
+	`replay`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.replay, `replay`, options.replay, `play back the snapshots recorded in the given directory instead of fetching the data`)
+	},
+	`socks5`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.socks5, `socks5`, options.socks5, `route the requests through the SOCKS5 proxy at host:port, or user:password@host:port (overrides the profile)`)
+	},
+	`control`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.control, `control`, options.control, `path to the unix socket the session listens for the commands at, blank for none`)
+	},
+	`all`: func(set *flag.FlagSet, options *options) {
+		set.BoolVar(&options.all, `all`, options.all, `include the settings left at their defaults`)
+	},
+	`check-only`: func(set *flag.FlagSet, options *options) {
+		set.BoolVar(&options.checkOnly, `check-only`, options.checkOnly, `check for the update without installing it`)
+	},
+	`dry-run`: func(set *flag.FlagSet, options *options) {
+		set.BoolVar(&options.dryRun, `dry-run`, options.dryRun, `print the profile changes without saving them`)
+	},
+	`once`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.once, `once`, options.once, `print the quotes of the given tickers, ex. AAPL,MSFT, and exit, same as mop once`)
+	},
+	`dump-json`: func(set *flag.FlagSet, options *options) {
+		set.BoolVar(&options.dumpJSON, `dump-json`, options.dumpJSON, `print the quotes as JSON document instead of the table, same as -output json`)
+	},
+	`output`: func(set *flag.FlagSet, options *options) {
+		set.StringVar(&options.output, `output`, options.output, `format the quotes are printed in: table, csv, or json`)
+	},
+}
+
+// Subcommands in the order they are listed in the help. The first one runs
+// when no subcommand is given.
+var commands []*command
+
+func init() { // Commands refer to the list for the help, hence the init.
+	commands = []*command{
+		{
+			name:    `run`,
+			summary: `track the stocks in the terminal (default)`,
+			help:    `Displays market data and stock quotes of the profile's watchlist, refreshing them until you quit. With -once it prints the quotes of the given tickers instead, ex. mop -once AAPL,MSFT -output json.`,
+			flags:   []string{`profile`, `source`, `listen`, `replay`, `socks5`, `control`, `once`, `output`, `dump-json`},
+			run:     run,
+		},
+		{
+			name:    `once`,
+			args:    `[<ticker>,...]`,
+			summary: `print stock quotes once and exit`,
+			help:    `Fetches the quotes of the given tickers, or of the profile's watchlist, and prints them to stdout without taking over the terminal: as the table, or as CSV or the JSON document with -output csv or -output json, ex. mop once -output csv AAPL,MSFT.`,
+			flags:   []string{`profile`, `source`, `socks5`, `output`, `dump-json`},
+			run:     once,
+		},
+		{
+			name:    `serve`,
+			summary: `serve the web view without the terminal`,
+			help:    `Keeps refreshing the stock quotes and serves them as the web view at the -listen address, ex. on a headless box.`,
+			flags:   []string{`profile`, `source`, `listen`, `replay`, `socks5`},
+			run:     serve,
+		},
+		{
+			name:    `feed`,
+			args:    `[<ticker>,...]`,
+			summary: `display the quotes piped to stdin`,
+			help:    `Reads the stock quotes from stdin as newline-delimited JSON, a quote or an array of quotes per line, and displays them as they arrive. The given tickers are listed first, followed by the ones that arrive; the profile is left intact.`,
+			flags:   []string{`profile`, `listen`, `socks5`, `control`},
+			run:     feed,
+		},
+		{
+			name:    `export`,
+			summary: `export stock quotes to CSV file`,
+			help:    `Fetches stock quotes of the profile's watchlist and saves them in the current directory as timestamped CSV file, same as exporting the rows selected in bulk edit mode.`,
+			flags:   []string{`profile`, `source`, `socks5`},
+			run:     export,
+		},
+		{
+			name:    `report`,
+			summary: `save stock quotes as HTML report`,
+			help:    `Fetches stock quotes of the profile's watchlist and saves them in the current directory as timestamped HTML page with the gains and losses colored and the sparklines of the recent prices, ex. for emailing or archiving the daily snapshots.`,
+			flags:   []string{`profile`, `source`, `socks5`},
+			run:     report,
+		},
+		{
+			name:    `history`,
+			args:    `[<ticker>,...]`,
+			summary: `print the logged stock quotes`,
+			help:    `Prints the stock quotes logged next to the profile, ex. ~/.moprc.quotes.csv, while its QuoteLog is on, for the given tickers or all of them: as the table, or as CSV with -output csv, ex. mop history -output csv AAPL.`,
+			flags:   []string{`profile`, `output`},
+			run:     history,
+		},
+		{
+			name:    `config`,
+			args:    `backups|restore [<backup>]|set <setting> <value>|dump`,
+			summary: `change the profile and restore its backups`,
+			help:    `Lists the backups of the profile made every time it was saved, restores the profile from the given backup (the latest one by default), changes the setting to the value given as JSON, ex. mop config set QuotesRefresh 10, or prints the settings in effect along with where they come from: flag, env, or file, ex. mop config dump -all.`,
+			flags:   []string{`profile`, `dry-run`, `all`, `source`, `socks5`},
+			run:     config,
+		},
+		{
+			name:    `watchlist`,
+			args:    `add|remove <ticker>,...`,
+			summary: `add or remove the tickers`,
+			help:    `Adds the tickers to the profile's watchlist, or removes them from it.`,
+			flags:   []string{`profile`, `dry-run`},
+			run:     watchlist,
+		},
+		{
+			name:    `control`,
+			args:    `<command>`,
+			summary: `send the command to the running session`,
+			help:    `Sends the command to the interactive session listening at the -control socket and prints its reply, ex. mop control add TSLA. The commands are add and remove followed by the tickers, pause, resume, dump that prints the quotes as JSON document, and the startup script commands: list, account, filter, sort, and set.`,
+			flags:   []string{`control`},
+			run:     control,
+		},
+		{
+			name:    `doctor`,
+			summary: `check the profile and the connectivity`,
+			help:    `Checks that the profile is valid and that the stock quotes provider and the market data are reachable.`,
+			flags:   []string{`profile`, `source`, `socks5`},
+			run:     doctor,
+		},
+		{
+			name:    `update`,
+			summary: `update mop to the latest release`,
+			help:    `Downloads the latest release for the profile's release channel, verifies its checksum, and replaces the running binary.`,
+			flags:   []string{`profile`, `check-only`},
+			run:     update,
+		},
+		{
+			name:    `help`,
+			args:    `[<command>]`,
+			summary: `show help for the command`,
+			help:    `Lists the commands, or describes the given one along with its flags.`,
+			run:     help,
+		},
+	}
+}
+
+// Returns the command with the given name, or nil if there is none.
+// -----------------------------------------------------------------------------
+func lookup(name string) *command {
+	for _, command := range commands {
+		if command.name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// Returns the flag set of the command. Usage errors print the command's
+// help.
+// -----------------------------------------------------------------------------
+func (command *command) flagSet(options *options) *flag.FlagSet {
+	set := flag.NewFlagSet(`mop `+command.name, flag.ContinueOnError)
+	for _, name := range command.flags {
+		flags[name](set, options)
+	}
+	set.Usage = func() { command.usage(set, os.Stderr) }
+
+	return set
+}
+
+// Parses the flags that could be mixed with the arguments, ex. mop watchlist
+// add -dry-run AAPL, and returns the arguments.
+// -----------------------------------------------------------------------------
+func parse(set *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := set.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = set.Args(); len(args) == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+}
+
+// Prints the help of the command along with its flags.
+// -----------------------------------------------------------------------------
+func (command *command) usage(set *flag.FlagSet, output io.Writer) {
+	fmt.Fprintf(output, "usage: mop %s%s\n\n%s\n", command.name, command.synopsis(), command.help)
+	if len(command.flags) > 0 {
+		fmt.Fprintln(output, "\nflags:")
+		set.SetOutput(output)
+		set.PrintDefaults()
+	}
+}
+
+// Returns the flags and the arguments of the command as displayed in the
+// usage line, ex. " [-profile <value>] [<ticker>,...]".
+// -----------------------------------------------------------------------------
+func (command *command) synopsis() string {
+	str := ``
+	for _, name := range command.flags {
+		str += ` [-` + name + `]`
+	}
+	if command.args != `` {
+		str += ` ` + command.args
+	}
+	return str
+}
+
+// Prints the list of commands.
+// -----------------------------------------------------------------------------
+func commandList(output io.Writer) {
+	fmt.Fprintf(output, "usage: mop [<command>] [<flags>] [<args>]\n\ncommands:\n")
+	writer := tabwriter.NewWriter(output, 0, 8, 2, ' ', 0)
+	for _, command := range commands {
+		fmt.Fprintf(writer, "  %s\t%s\n", command.name, command.summary)
+	}
+	writer.Flush()
+	fmt.Fprintln(output, "\nRun `mop help <command>` for the command's flags.")
+}
+
+// Handles `mop help [<command>]`, as well as `mop help man` that generates
+// the man page from the commands.
+// -----------------------------------------------------------------------------
+func help(options *options, args []string) int {
+	switch {
+	case len(args) == 0:
+		commandList(os.Stdout)
+	case len(args) == 1 && args[0] == `man`:
+		manual(os.Stdout)
+	case len(args) == 1 && lookup(args[0]) != nil:
+		command := lookup(args[0])
+		command.usage(command.flagSet(options), os.Stdout)
+	default:
+		commandList(os.Stderr)
+		return 2
+	}
+
+	return 0
+}
+
+// Writes the man page, in troff format.
+// -----------------------------------------------------------------------------
+func manual(output io.Writer) {
+	escape := func(str string) string {
+		return strings.Replace(str, `-`, `\-`, -1)
+	}
+
+	fmt.Fprintf(output, ".TH MOP 1 \"\" \"mop %s\" \"User Commands\"\n", mop.Version)
+	fmt.Fprintln(output, ".SH NAME\nmop \\- track stocks the hacker way")
+	fmt.Fprintln(output, ".SH SYNOPSIS\n.B mop\n[\\fIcommand\\fR] [\\fIflags\\fR] [\\fIargs\\fR]")
+	fmt.Fprintln(output, ".SH DESCRIPTION\nMop displays continuous stock quotes and market data in the terminal.\nThe settings are kept in the ~/.moprc profile.")
+	fmt.Fprintln(output, ".SH COMMANDS")
+	for _, command := range commands {
+		fmt.Fprintf(output, ".TP\n.B %s%s\n%s\n", command.name, escape(command.synopsis()), escape(command.help))
+	}
+	fmt.Fprintln(output, ".SH FLAGS")
+	names := []string{}
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	defaults := &options{} // Home directory doesn't belong to the man page.
+	for _, name := range names {
+		set := flag.NewFlagSet(``, flag.ContinueOnError)
+		flags[name](set, defaults)
+		fmt.Fprintf(output, ".TP\n.B \\-%s\n%s\n", escape(name), escape(set.Lookup(name).Usage))
+	}
+	fmt.Fprintln(output, ".SH FILES\n.TP\n.I ~/.moprc\nThe profile. Its backups are kept next to it.")
+}
+
+// Returns the profile with the provider given on the command line, if any.
+// -----------------------------------------------------------------------------
+func (options *options) load() *mop.Profile {
+	return mop.NewProfile(options.profile).UseSource(options.source).UseSocks5(options.socks5)
+}
+
+// Returns the profile and the market data the session tracks, played back
+// from the recorded snapshots with -replay.
+// -----------------------------------------------------------------------------
+func (options *options) session() (*mop.Profile, *mop.Market, bool) {
+	profile := options.load()
+	market := mop.NewMarket().UseProxy(profile.ProxySetting()).UseRetry(profile.Retry)
+	if options.replay != `` {
+		replay, err := mop.NewReplay(options.replay)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, false
+		}
+		profile.UseReplay(replay)
+		market.UseReplay(replay)
+	}
+	return profile, market, true
+}
+
+// Handles `mop run`, the interactive session.
+// -----------------------------------------------------------------------------
+func run(options *options, args []string) int {
+	if len(args) > 0 {
+		commandList(os.Stderr)
+		return 2
+	}
+	if options.once != `` { // Never takes over the terminal.
+		return once(options, []string{options.once})
+	}
+	profile, market, ok := options.session()
+	if !ok {
+		return 1
+	}
+
+	return options.interactive(profile, market)
+}
+
+// Handles `mop feed [<ticker>,...]` that displays the quotes read from
+// stdin. The profile's settings apply, but nothing gets saved.
+// -----------------------------------------------------------------------------
+func feed(options *options, args []string) int {
+	profile := mop.NewProfile(options.profile).DryRun().UseSocks5(options.socks5).UseFeed(mop.NewFeed(os.Stdin))
+	profile.Tickers = []string{}
+	if len(args) > 0 {
+		profile.Tickers = strings.Split(strings.ToUpper(strings.Join(args, `,`)), `,`)
+	}
+
+	return options.interactive(profile, mop.NewMarket().UseProxy(profile.ProxySetting()).UseRetry(profile.Retry))
+}
+
+// Runs the interactive session, serving the web view at the -listen
+// address if given.
+// -----------------------------------------------------------------------------
+func (options *options) interactive(profile *mop.Profile, market *mop.Market) int {
+	var server *mop.Server
+	if options.listen != `` {
+		server = mop.NewServer(profile.Server)
+		if err := server.Listen(options.listen); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	screen := mop.NewScreen()
+	defer screen.Close()
+
+	mainLoop(screen, market, profile, server, options.control)
+
+	return 0
+}
+
+// Handles `mop once [<ticker>,...]` that prints the stock quotes table.
+// -----------------------------------------------------------------------------
+func once(options *options, args []string) int {
+	format := options.output
+	if options.dumpJSON {
+		format = `json`
+	}
+	if format != `` && format != `table` && format != `csv` && format != `json` {
+		fmt.Fprintf(os.Stderr, "mop: unknown output format %q, expected table, csv, or json\n", format)
+		return 2
+	}
+
+	profile := options.load()
+	if len(args) > 0 { // Replaces the watchlist for this run only.
+		profile.Tickers = strings.Split(strings.ToUpper(strings.Join(args, `,`)), `,`)
+	}
+
+	quotes := mop.NewQuotes(mop.NewMarket(), profile).Fetch()
+	if ok, err := quotes.Ok(); !ok {
+		fmt.Fprintln(os.Stderr, strings.TrimSpace(err))
+		return 1
+	}
+
+	switch format {
+	case `json`:
+		data, err := json.MarshalIndent(mop.NewSnapshot(nil, quotes, time.Now()), ``, `  `)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	case `csv`:
+		if err := mop.WriteCSV(os.Stdout, mop.NewLayout().Table(quotes)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+		for _, row := range mop.NewLayout().Table(quotes) {
+			fmt.Fprintln(writer, strings.Join(row, "\t")+"\t")
+		}
+		writer.Flush()
+	}
+	for _, ticker := range profile.Tickers { // The rest of the quotes are good.
+		if message, ok := quotes.Failures()[strings.ToUpper(strings.TrimSpace(ticker))]; ok {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", ticker, message)
+		}
+	}
+
+	return 0
+}
+
+// Handles `mop serve` that keeps the web view up to date without the
+// terminal.
+// -----------------------------------------------------------------------------
+func serve(options *options, args []string) int {
+	if options.listen == `` {
+		options.listen = `:8080`
+	}
+	profile, market, ok := options.session()
+	if !ok {
+		return 1
+	}
+	server := mop.NewServer(profile.Server)
+	if err := server.Listen(options.listen); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Serving the web view at %s\n", options.listen)
+
+	quotes := mop.NewQuotes(market, profile)
+	marketQueue := time.NewTicker(profile.MarketInterval())
+	quotesQueue := time.NewTicker(profile.RefreshInterval())
+	server.Publish(market.Fetch(), quotes.Fetch())
+	for {
+		select {
+		case <-marketQueue.C:
+			market.Fetch()
+		case <-quotesQueue.C:
+			quotes.SendDigest(time.Now())
+			server.Publish(market, quotes.Fetch())
+			quotes.RecordValue(time.Now())
+		case change := <-server.Changes():
+			change.Apply(quotes)
+			server.Publish(market, quotes.Fetch())
+		}
+	}
+}
+
+// Handles `mop export` that saves the stock quotes as CSV file.
+// -----------------------------------------------------------------------------
+func export(options *options, args []string) int {
+	quotes := mop.NewQuotes(mop.NewMarket(), options.load()).Fetch()
+	if ok, err := quotes.Ok(); !ok {
+		fmt.Fprintln(os.Stderr, strings.TrimSpace(err))
+		return 1
+	}
+
+	filename, err := mop.ExportCSV(mop.NewLayout().Table(quotes))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Exported to %s\n", filename)
+
+	return 0
+}
+
+// Handles `mop report` that saves the stock quotes as HTML page.
+// -----------------------------------------------------------------------------
+func report(options *options, args []string) int {
+	quotes := mop.NewQuotes(mop.NewMarket(), options.load()).Fetch()
+	if ok, err := quotes.Ok(); !ok {
+		fmt.Fprintln(os.Stderr, strings.TrimSpace(err))
+		return 1
+	}
+
+	filename, err := mop.ExportHTML(mop.NewLayout().Report(quotes, time.Now()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Exported to %s\n", filename)
+
+	return 0
+}
+
+// Handles `mop history [<ticker>,...]` that prints the quote log.
+// -----------------------------------------------------------------------------
+func history(options *options, args []string) int {
+	if options.output != `` && options.output != `table` && options.output != `csv` {
+		fmt.Fprintf(os.Stderr, "mop: unknown output format %q, expected table or csv\n", options.output)
+		return 2
+	}
+
+	rows, err := options.load().LoggedQuotes(strings.Split(strings.Join(args, `,`), `,`))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if options.output == `csv` {
+		if err := mop.WriteCSV(os.Stdout, rows); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	writer.Flush()
+
+	return 0
+}
+
+// Handles `mop watchlist add|remove <ticker>,...`.
+// -----------------------------------------------------------------------------
+func watchlist(options *options, args []string) int {
+	if len(args) < 2 || (args[0] != `add` && args[0] != `remove`) {
+		lookup(`watchlist`).flagSet(options).Usage()
+		return 2
+	}
+	profile, ok := options.edit()
+	if !ok {
+		return 1
+	}
+
+	tickers := strings.Split(strings.ToUpper(strings.Join(args[1:], `,`)), `,`)
+	message, count, err := `Added %d ticker(s)`, 0, error(nil)
+	if args[0] == `add` {
+		_, notes := profile.CheckTickers(tickers)
+		for _, note := range notes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		count, err = profile.AddTickers(tickers)
+	} else {
+		message = `Removed %d ticker(s)`
+		count, err = profile.RemoveTickers(tickers)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return options.saved(profile, fmt.Sprintf(message, count))
+}
+
+// Handles `mop control <command>` that drives the running session.
+// -----------------------------------------------------------------------------
+func control(options *options, args []string) int {
+	if len(args) == 0 || options.control == `` {
+		lookup(`control`).flagSet(options).Usage()
+		return 2
+	}
+
+	reply, err := mop.SendControl(options.control, strings.Join(args, ` `))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if strings.HasPrefix(reply, `error: `) {
+		fmt.Fprintln(os.Stderr, strings.TrimPrefix(reply, `error: `))
+		return 1
+	}
+	fmt.Println(reply)
+
+	return 0
+}
+
+// Returns the profile to be changed from the command line. In the dry run
+// the profile has to exist, since creating it would be a change too.
+// -----------------------------------------------------------------------------
+func (options *options) edit() (*mop.Profile, bool) {
+	if !options.dryRun {
+		return mop.NewProfile(options.profile), true
+	}
+	if _, err := os.Stat(options.profile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	return mop.NewProfile(options.profile).DryRun(), true
+}
+
+// Reports the outcome of changing the profile: the changes in the dry run,
+// or the message otherwise.
+// -----------------------------------------------------------------------------
+func (options *options) saved(profile *mop.Profile, message string) int {
+	if options.dryRun {
+		fmt.Print(profile.Changes())
+	} else {
+		fmt.Println(message)
+	}
+	return 0
+}
+
+// Handles `mop doctor` that runs the profile checks followed by the
+// connectivity checks.
+// -----------------------------------------------------------------------------
+func doctor(options *options, args []string) int {
+	report := func(name string, err error) bool {
+		if err != nil {
+			fmt.Printf("✗ %s: %s\n", name, err)
+			return false
+		}
+		fmt.Printf("✓ %s\n", name)
+		return true
+	}
+
+	healthy := true
+	for _, diagnosis := range mop.Diagnose(options.profile) {
+		healthy = report(diagnosis.Check, diagnosis.Err) && healthy
+	}
+
+	profile := options.load()
+	if len(profile.Tickers) > 0 {
+		quotes := mop.NewQuotes(mop.NewMarket(), profile).Subset(profile.Tickers[:1])
+		healthy = report(`Stock quotes`, failure(quotes.Ok())) && healthy
+	}
+	healthy = report(`Market data`, failure(mop.NewMarket().UseProxy(profile.ProxySetting()).UseRetry(profile.Retry).Fetch().Ok())) && healthy
+
+	if !healthy {
+		return 1
+	}
+	return 0
+}
+
+// Turns the Ok() results of the market and the quotes into the error.
+// -----------------------------------------------------------------------------
+func failure(ok bool, message string) error {
+	if ok {
+		return nil
+	}
+	return errors.New(strings.Replace(strings.TrimSpace(message), "\n", ` `, -1))
+}
@@ -5,9 +5,12 @@
 package main
 
 import (
-	"flag"
+	"fmt"
+	"os"
 	"os/user"
 	"path"
+	"path/filepath"
+	"text/tabwriter"
 	"time"
 
 	"github.com/mop-tracker/mop"
@@ -17,20 +20,15 @@ import (
 // File name in user's home directory where we store the settings.
 const defaultProfile = `.moprc`
 
-const help = `Mop v0.2.0 -- Copyright (c) 2013-2016 by Michael Dvorkin. All Rights Reserved.
+// File name in user's home directory of the socket the session listens for
+// the commands at.
+const defaultControl = `.mop.sock`
+
+const helpTemplate = `Mop v0.2.0 -- Copyright (c) 2013-2016 by Michael Dvorkin. All Rights Reserved.
 NO WARRANTIES OF ANY KIND WHATSOEVER. SEE THE LICENSE FILE FOR DETAILS.
 
 <u>Command</u>    <u>Description                                </u>
-   +       Add stocks to the list.
-   -       Remove stocks from the list.
-   ?       Display this help screen.
-   f       Set filtering expression.
-   F       Unset filtering expression.
-   g       Group stocks by advancing/declining issues.
-   o       Change column sort order.
-   p       Pause market data and stock updates.
-   q       Quit mop.
-  esc      Ditto.
+%s
 
 Enter comma-delimited list of stock tickers when prompted.
 
@@ -38,26 +36,70 @@ Enter comma-delimited list of stock tickers when prompted.
 `
 
 //-----------------------------------------------------------------------------
-func mainLoop(screen *mop.Screen, profile *mop.Profile) {
+func mainLoop(screen *mop.Screen, market *mop.Market, profile *mop.Profile, server *mop.Server, socket string) {
 	var lineEditor *mop.LineEditor
 	var columnEditor *mop.ColumnEditor
+	var picker *mop.Picker
+	var selection *mop.Selection
+	var stats *mop.Statistics     // Watchlist statistics while they are displayed.
+	var panes *mop.PaneManager    // Market overview dashboard while it's displayed.
+	var recording []termbox.Event // Keystrokes of the macro being recorded, nil unless recording.
+	var dialog *mop.Dialog        // Quit confirmation while it's displayed.
+
+	help := fmt.Sprintf(helpTemplate, mop.Commands(mop.NormalMode))
 
 	keyboardQueue := make(chan termbox.Event)
-	timestampQueue := time.NewTicker(1 * time.Second)
-	quotesQueue := time.NewTicker(5 * time.Second)
-	marketQueue := time.NewTicker(12 * time.Second)
-	showingHelp := false
-	paused := false
+	var resizeQueue <-chan time.Time // Fires once the resize storm is over.
+	updateQueue := make(chan *mop.Release, 1)
+	showingHelp, quitting := false, false
+	guard := mop.NewQuitGuard(profile)
+	paused := profile.RestoreState()
+	timers := newTimers(profile).pause(paused)
+	defer timers.pause(true)
 
+	retrying := func(status string) { screen.Retrying(status) }
+	quotes := mop.NewQuotes(market, profile).OnRetry(retrying)
+	market.OnRetry(retrying)
 	go func() {
 		for {
-			keyboardQueue <- termbox.PollEvent()
+			event := termbox.PollEvent()
+			if event.Type == termbox.EventKey && guard.Cancels(event) {
+				quotes.Cancel() // Quitting or pausing makes the fetch under way moot.
+				market.Cancel()
+			}
+			keyboardQueue <- event
 		}
 	}()
 
-	market := mop.NewMarket()
-	quotes := mop.NewQuotes(market, profile)
+	if profile.UpdateCheckDue(time.Now()) { // Once a day, in the background.
+		go func() {
+			if release, err := profile.Updater().Check(); err == nil {
+				updateQueue <- release
+			}
+		}()
+	}
+
+	notice := profile.UpdateNotice()
+	if err := profile.RunStartup(); err != nil {
+		notice = err.Error()
+	}
+	var requests <-chan *mop.ControlRequest // Commands received over the control socket, if any.
+	if socket != `` {
+		if control, err := mop.NewControl(socket); err != nil {
+			notice = err.Error()
+		} else {
+			defer control.Close()
+			requests = control.Requests()
+		}
+	}
 	screen.Draw(market, quotes)
+	screen.Pause(paused).Draw(time.Now())
+	screen.Notify(notice)
+	var changes <-chan *mop.Change // Watchlist changes requested over the web, if served.
+	if server != nil {
+		server.Publish(market, quotes)
+		changes = server.Changes()
+	}
 
 loop:
 	for {
@@ -65,82 +107,417 @@ loop:
 		case event := <-keyboardQueue:
 			switch event.Type {
 			case termbox.EventKey:
-				if lineEditor == nil && columnEditor == nil && !showingHelp {
-					if event.Key == termbox.KeyEsc || event.Ch == 'q' || event.Ch == 'Q' {
-						break loop
+				if recording != nil {
+					recording = append(recording, event)
+				}
+				if lineEditor == nil && columnEditor == nil && picker == nil && selection == nil && stats == nil && panes == nil && dialog == nil && !showingHelp {
+					if guard.Quits(event) {
+						if !profile.ConfirmQuit {
+							profile.SaveState(paused)
+							break loop
+						}
+						dialog = mop.NewConfirmDialog(screen, `Quit`, `Quit mop?`, func(ok bool) {
+							if quitting = ok; !ok {
+								screen.Draw(quotes) // Erase the dialog.
+							}
+						})
 					} else if event.Ch == '+' || event.Ch == '-' {
 						lineEditor = mop.NewLineEditor(screen, quotes)
 						lineEditor.Prompt(event.Ch)
-					} else if event.Ch == 'f' {
+					} else if event.Ch == 'f' || event.Ch == 'a' || event.Ch == '!' {
 						lineEditor = mop.NewLineEditor(screen, quotes)
 						lineEditor.Prompt(event.Ch)
+					} else if event.Ch == 'r' || event.Ch == 'R' {
+						if profile.ChangeReference() == nil {
+							screen.Draw(quotes)
+						}
 					} else if event.Ch == 'F' {
 						profile.SetFilter("")
 					} else if event.Ch == 'o' || event.Ch == 'O' {
 						columnEditor = mop.NewColumnEditor(screen, quotes)
+					} else if event.Ch == 's' || event.Ch == 'S' {
+						picker = mop.NewColumnPicker(screen, quotes)
+					} else if event.Ch == 'v' || event.Ch == 'V' {
+						selection = mop.NewSelection(screen, quotes)
+					} else if event.Ch == 'e' || event.Ch == 'E' {
+						table := mop.NewLayout().Table(quotes)
+						dialog = mop.NewExportDialog(screen, `csv`, func(filename string) error {
+							return mop.SaveCSV(filename, table)
+						}, func(message string) {
+							screen.Draw(quotes) // Erase the dialog.
+							screen.Notify(message)
+						})
+					} else if event.Ch == 'j' || event.Ch == 'J' {
+						snapshot := mop.NewSnapshot(market, quotes, time.Now())
+						dialog = mop.NewExportDialog(screen, `json`, func(filename string) error {
+							return mop.SaveJSON(filename, snapshot)
+						}, func(message string) {
+							screen.Draw(quotes) // Erase the dialog.
+							screen.Notify(message)
+						})
 					} else if event.Ch == 'g' || event.Ch == 'G' {
 						if profile.Regroup() == nil {
 							screen.Draw(quotes)
 						}
+					} else if event.Ch == 'i' || event.Ch == 'I' {
+						stats = mop.NewStatistics(quotes)
+						screen.Clear().Mode(mop.HelpMode).Draw(stats)
+					} else if event.Ch == 'm' || event.Ch == 'M' {
+						panes = mop.NewPaneManager(screen, market, quotes)
+					} else if event.Ch == 'A' {
+						picker = mop.NewAccountPicker(screen, quotes)
+					} else if event.Ch == 'L' {
+						picker = mop.NewWatchlistPicker(screen, quotes)
+					} else if event.Ch == '*' {
+						if recording == nil {
+							recording = []termbox.Event{}
+							screen.Notify("Recording the macro, press * to stop")
+						} else if keys := recording[:len(recording)-1]; len(keys) == 0 { // Leave out the * itself.
+							recording = nil
+							screen.Notify("Nothing recorded")
+						} else {
+							recording = nil
+							lineEditor = mop.NewLineEditor(screen, quotes).NameMacro(keys)
+						}
+					} else if event.Ch == '@' {
+						if len(profile.Macros) == 0 {
+							screen.Notify("No macros yet, press * to record one")
+						} else {
+							picker = mop.NewMacroPicker(screen, quotes, func(keys []termbox.Event) {
+								go func() { // Replayed as if typed, once the picker is closed.
+									for _, key := range keys {
+										keyboardQueue <- key
+									}
+								}()
+							})
+						}
+					} else if event.Ch == 'b' || event.Ch == 'B' {
+						if profile.ToggleHistogram() == nil {
+							screen.Clear().Draw(market, quotes)
+						}
+					} else if event.Ch == 'd' || event.Ch == 'D' {
+						if profile.Redensify() == nil {
+							screen.Clear().Draw(market, quotes)
+						}
 					} else if event.Ch == 'p' || event.Ch == 'P' {
 						paused = !paused
+						timers.pause(paused)
 						screen.Pause(paused).Draw(time.Now())
 					} else if event.Ch == '?' || event.Ch == 'h' || event.Ch == 'H' {
 						showingHelp = true
-						screen.Clear().Draw(help)
+						screen.Clear().Mode(mop.HelpMode).Draw(help)
 					}
 				} else if lineEditor != nil {
 					if done := lineEditor.Handle(event); done {
 						lineEditor = nil
+						guard.Closed()
 					}
 				} else if columnEditor != nil {
 					if done := columnEditor.Handle(event); done {
 						columnEditor = nil
+						guard.Closed()
+					}
+				} else if picker != nil {
+					if done := picker.Handle(event); done {
+						picker = nil
+						guard.Closed()
+					}
+				} else if selection != nil {
+					if done := selection.Handle(event); done {
+						selection = nil
+						guard.Closed()
+					}
+				} else if panes != nil {
+					if done := panes.Handle(event); done {
+						panes = nil
+						guard.Closed()
+						screen.Clear().Draw(market, quotes)
+					}
+				} else if dialog != nil {
+					if done := dialog.Handle(event); done {
+						dialog = nil
+						if quitting {
+							profile.SaveState(paused)
+							break loop
+						}
+						guard.Closed() // Esc closing the export dialog doesn't quit.
 					}
-				} else if showingHelp {
-					showingHelp = false
-					screen.Clear().Draw(market, quotes)
+				} else if showingHelp || stats != nil {
+					showingHelp, stats = false, nil
+					guard.Closed()
+					screen.Clear().Mode(mop.NormalMode).Draw(market, quotes)
 				}
 			case termbox.EventResize:
-				screen.Resize()
-				if !showingHelp {
-					screen.Draw(market, quotes)
-				} else {
-					screen.Draw(help)
-				}
+				// Debounce: redraw only when no more resize events arrive
+				// within a short interval.
+				resizeQueue = time.After(250 * time.Millisecond)
+			}
+			guard.Covered(lineEditor != nil || columnEditor != nil || picker != nil || selection != nil || stats != nil || panes != nil || dialog != nil || showingHelp)
+
+		case <-resizeQueue:
+			resizeQueue = nil
+			screen.Resize()
+			if showingHelp {
+				screen.Draw(help)
+			} else if stats != nil {
+				screen.Draw(stats)
+			} else if panes != nil {
+				screen.Draw(panes)
+			} else {
+				screen.Draw(market, quotes)
 			}
 
-		case <-timestampQueue.C:
-			if !showingHelp && !paused {
+		case <-timers.clock:
+			if !showingHelp && stats == nil && panes == nil {
 				screen.Draw(time.Now())
 			}
 
-		case <-quotesQueue.C:
-			if !showingHelp && !paused {
+		case <-timers.quotes:
+			quotes.SendDigest(time.Now()) // Errors are displayed along with the alerts.
+			quotes.RecordValue(time.Now())
+			if profile.WatchPreMarket(time.Now()) && !showingHelp && stats == nil && panes == nil {
+				screen.Clear().Draw(market, quotes)
+			} else if stats != nil {
+				stats = mop.NewStatistics(quotes.Fetch())
+				screen.Draw(stats)
+			} else if panes != nil {
+				quotes.Fetch()
+				screen.Clear().Draw(panes)
+			} else if !showingHelp {
+				screen.Draw(quotes)
+			}
+			if timers.clockless() && !showingHelp && stats == nil && panes == nil {
+				screen.Draw(time.Now())
+			}
+			if server != nil { // The web view mirrors the terminal.
+				server.Publish(market, quotes)
+			}
+
+		case release := <-updateQueue:
+			profile.CheckedForUpdate(release, time.Now())
+			screen.Notify(profile.UpdateNotice())
+
+		case request := <-requests:
+			if request.Command == `pause` || request.Command == `resume` {
+				paused = request.Command == `pause`
+				timers.pause(paused)
+				screen.Pause(paused).Draw(time.Now())
+				request.Reply(`ok`)
+				continue
+			}
+			request.Execute(market, quotes)
+			if request.Command != `dump` && !showingHelp && stats == nil && panes == nil {
+				screen.Draw(quotes)
+			}
+
+		case change := <-changes:
+			change.Apply(quotes)
+			if !showingHelp && stats == nil && panes == nil {
 				screen.Draw(quotes)
+			} else {
+				quotes.Fetch()
 			}
+			server.Publish(market, quotes)
 
-		case <-marketQueue.C:
-			if !showingHelp && !paused {
+		case <-timers.market:
+			if panes != nil {
+				market.Fetch()
+				screen.Clear().Draw(panes)
+			} else if !showingHelp && stats == nil {
 				screen.Draw(market)
 			}
 		}
 	}
 }
 
+// timers drive the main loop: the clock, the stock quotes, and the market
+// data refreshes. They all stop while paused so that idle mop doesn't wake
+// up the CPU, and the clock doesn't tick at all if it's disabled in the
+// profile.
+type timers struct {
+	clock     <-chan time.Time // Fires when the clock is due for a redraw, nil while stopped.
+	quotes    <-chan time.Time // Fires when the stock quotes are due for a refresh, nil while stopped.
+	market    <-chan time.Time // Fires when the market data is due for a refresh, nil while stopped.
+	tickers   [3]*time.Ticker  // Clock, quotes, and market tickers, nil clock ticker if it's disabled.
+	intervals [3]time.Duration // Clock, quotes, and market refresh intervals.
+}
+
+// Returns the stopped timers with the refresh intervals set in the profile.
+// -----------------------------------------------------------------------------
+func newTimers(profile *mop.Profile) *timers {
+	timers := &timers{intervals: [3]time.Duration{
+		profile.ClockInterval(),
+		profile.RefreshInterval(),
+		profile.MarketInterval(),
+	}}
+	for i, interval := range timers.intervals {
+		if interval > 0 {
+			timers.tickers[i] = time.NewTicker(interval)
+			timers.tickers[i].Stop()
+		}
+	}
+
+	return timers
+}
+
+// Stops the timers while paused, and restarts them once resumed.
+// -----------------------------------------------------------------------------
+func (timers *timers) pause(paused bool) *timers {
+	channels := [3]*<-chan time.Time{&timers.clock, &timers.quotes, &timers.market}
+	for i, ticker := range timers.tickers {
+		if ticker == nil {
+			continue
+		}
+		if paused {
+			ticker.Stop()
+			*channels[i] = nil
+		} else {
+			ticker.Reset(timers.intervals[i])
+			*channels[i] = ticker.C
+		}
+	}
+
+	return timers
+}
+
+// Returns true if the clock doesn't tick, and therefore only shows the time
+// of the last refresh.
+// -----------------------------------------------------------------------------
+func (timers *timers) clockless() bool {
+	return timers.tickers[0] == nil
+}
+
+// Handles `mop config` subcommands that manage the profile outside of the
+// interactive session. Returns the exit code.
 //-----------------------------------------------------------------------------
-func main() {
-	screen := mop.NewScreen()
-	defer screen.Close()
+func config(options *options, args []string) int {
+	profileName := options.profile
+	switch {
+	case len(args) == 1 && args[0] == `backups`:
+		for _, backup := range mop.Backups(profileName) {
+			fmt.Println(backup)
+		}
+	case len(args) >= 1 && len(args) <= 2 && args[0] == `restore`:
+		backup := ``
+		if len(args) == 2 {
+			backup = args[1]
+		}
+		if options.dryRun {
+			diff, _, err := mop.PreviewRestore(profileName, backup)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			fmt.Print(diff)
+			return 0
+		}
+		restored, err := mop.RestoreBackup(profileName, backup)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Restored %s from %s\n", profileName, restored)
+	case len(args) == 3 && args[0] == `set`:
+		profile, ok := options.edit()
+		if !ok {
+			return 1
+		}
+		if err := profile.Set(args[1], args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return options.saved(profile, fmt.Sprintf("%s is set to %s", args[1], args[2]))
+	case len(args) == 1 && args[0] == `dump`:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, setting := range options.load().Resolved() {
+			if options.all || setting.Source != `default` {
+				fmt.Fprintf(writer, "%s\t%s\t# %s\n", setting.Name, setting.Value, setting.Source)
+			}
+		}
+		writer.Flush()
+	default:
+		lookup(`config`).flagSet(options).Usage()
+		return 2
+	}
+
+	return 0
+}
 
+// Parses the flags given before the subcommand, if any, then the ones that
+// follow it, and runs the subcommand.
+//-----------------------------------------------------------------------------
+func main() {
 	usr, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
+	options := &options{profile: path.Join(usr.HomeDir, defaultProfile), control: path.Join(usr.HomeDir, defaultControl)}
 
-	profileName := flag.String("profile", path.Join(usr.HomeDir, defaultProfile), "path to profile")
-	flag.Parse()
+	global := commands[0].flagSet(options) // Flags of the default command, ex. mop -profile p config backups.
+	global.Usage = func() { commandList(os.Stderr) }
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	command, args := commands[0], global.Args()
+	if len(args) > 0 {
+		if lookup(args[0]) == nil && options.output != `` { // Quote utility, ex. mop -output csv AAPL,MSFT.
+			os.Exit(once(options, args))
+		}
+		if command = lookup(args[0]); command == nil {
+			fmt.Fprintf(os.Stderr, "mop: unknown command %q\n\n", args[0])
+			commandList(os.Stderr)
+			os.Exit(2)
+		}
+		if args, err = parse(command.flagSet(options), args[1:]); err != nil {
+			os.Exit(2)
+		}
+	}
+
+	os.Exit(command.run(options, args))
+}
+
+// Handles `mop update [--check-only]` that replaces the running binary with
+// the latest release in the profile's release channel. Returns the exit code.
+//-----------------------------------------------------------------------------
+func update(options *options, args []string) int {
+	if len(args) > 0 {
+		lookup(`update`).flagSet(options).Usage()
+		return 2
+	}
+
+	updater := mop.NewProfile(options.profile).Updater()
+	release, err := updater.Check()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if release == nil {
+		fmt.Printf("mop %s is up to date\n", mop.Version)
+		return 0
+	}
+	fmt.Printf("mop %s is available (running %s)\n", release.Version, mop.Version)
+	if options.checkOnly {
+		return 0
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if command := mop.Managed(executable); command != `` {
+		fmt.Printf("mop is managed by the package manager, run `%s` instead\n", command)
+		return 1
+	}
+	if err := updater.Install(release, executable); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Updated %s to %s\n", executable, release.Version)
 
-	profile := mop.NewProfile(*profileName)
-	mainLoop(screen, profile)
+	return 0
 }
@@ -6,9 +6,9 @@ package mop
 
 import (
 	`bytes`
+	`context`
+	`errors`
 	`fmt`
-	`io/ioutil`
-	`net/http`
 	`regexp`
 	`strings`
 )
@@ -31,8 +31,13 @@ type Market struct {
 	Yen       map[string]string
 	Euro      map[string]string
 	Gold      map[string]string
-	regex     *regexp.Regexp // Regex to parse market data from HTML.
-	errors    string         // Error(s), if any.
+	regex     *regexp.Regexp      // Regex to parse market data from HTML.
+	errors    string              // Error(s), if any.
+	replay    *Replay             // Recorded snapshots played back instead of fetching, if any.
+	proxy     string              // Proxy set in the profile, blank for the one from the environment.
+	inflight  inflight            // Fetch under way, if any.
+	retry     *RetrySettings      // How the failed requests get retried, nil for the defaults.
+	retrying  func(status string) // Reports the retry status, nil to keep quiet.
 }
 
 // Returns new initialized Market struct.
@@ -83,29 +88,70 @@ func NewMarket() *Market {
 
 // Fetch downloads HTML page from the 'marketURL', parses it, and stores resulting data
 // in internal hashes. If download or data parsing fails Fetch populates 'market.errors'.
-func (market *Market) Fetch() (self *Market) {
-	self = market // <-- This ensures we return correct market after recover() from panic().
-	defer func() {
-		if err := recover(); err != nil {
-			market.errors = fmt.Sprintf("Error fetching market data...\n%s", err)
-		} else {
-			market.errors = ""
-		}
-	}()
-
-	response, err := http.Get(marketURL)
-	if err != nil {
-		panic(err)
+func (market *Market) Fetch() *Market {
+	return market.FetchContext(context.Background())
+}
+
+// FetchContext is Fetch that gets abandoned as soon as the context is
+// cancelled or Cancel is called. The market data fetched before is kept
+// then, and no error is reported. The request that fails on the way to CNN
+// is retried as per the retry settings.
+func (market *Market) FetchContext(ctx context.Context) *Market {
+	ctx = market.inflight.begin(ctx)
+	defer market.inflight.done()
+
+	market.errors = ``
+	if err := market.refresh(ctx); err != nil && ctx.Err() == nil {
+		market.errors = fmt.Sprintf("Error fetching market data...\n%s", err)
+	}
+
+	return market
+}
+
+// UseRetry sets how the failed market data requests get retried, nil for
+// the defaults.
+func (market *Market) UseRetry(settings *RetrySettings) *Market {
+	market.retry = settings
+	return market
+}
+
+// OnRetry sets the function that gets called with the status, ex.
+// "retrying market… (2/3)", while the failed fetch is about to be retried,
+// and with the blank status once it's done.
+func (market *Market) OnRetry(notify func(status string)) *Market {
+	market.retrying = notify
+	return market
+}
+
+// Fetches the market data, or plays it back from the recorded snapshots.
+//-----------------------------------------------------------------------------
+func (market *Market) refresh(ctx context.Context) error {
+	if market.replay != nil {
+		return market.replay.market(market)
+	}
+	if err := SetProxy(market.proxy); err != nil {
+		return err
 	}
 
-	defer response.Body.Close()
-	body, err := ioutil.ReadAll(response.Body)
+	var body []byte
+	err := newRetryPolicy(market.retry, market.retrying).do(ctx, `market`, func(ctx context.Context) (err error) {
+		body, err = getBody(ctx, marketURL)
+		return err
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
+	snippet, err := market.trim(market.isMarketOpen(body))
+	if err != nil {
+		return err
+	}
+
+	return market.extract(snippet)
+}
 
-	body = market.isMarketOpen(body)
-	return market.extract(market.trim(body))
+// Cancel abandons the fetch under way, if any, ex. on quit or pause.
+func (market *Market) Cancel() {
+	market.inflight.done()
 }
 
 // Ok returns two values: 1) boolean indicating whether the error has occured,
@@ -121,21 +167,24 @@ func (market *Market) isMarketOpen(body []byte) []byte {
 }
 
 //-----------------------------------------------------------------------------
-func (market *Market) trim(body []byte) []byte {
+func (market *Market) trim(body []byte) ([]byte, error) {
 	start := bytes.Index(body, []byte(`Markets Overview`))
 	finish := bytes.LastIndex(body, []byte(`Gainers`))
+	if start < 0 || finish < start {
+		return nil, errors.New(`Unable to parse ` + marketURL)
+	}
 	snippet := bytes.Replace(body[start:finish], []byte{'\n'}, []byte{}, -1)
 	snippet = bytes.Replace(snippet, []byte(`&amp;`), []byte{'&'}, -1)
 
-	return snippet
+	return snippet, nil
 }
 
 //-----------------------------------------------------------------------------
-func (market *Market) extract(snippet []byte) *Market {
+func (market *Market) extract(snippet []byte) error {
 	matches := market.regex.FindStringSubmatch(string(snippet))
 
-	if len(matches) < 31 {
-		panic(`Unable to parse ` + marketURL)
+	if len(matches) < 32 {
+		return errors.New(`Unable to parse ` + marketURL)
 	}
 
 	market.Dow[`change`] = matches[1]
@@ -183,5 +232,5 @@ func (market *Market) extract(snippet []byte) *Market {
 	market.Frankfurt[`latest`] = matches[30]
 	market.Frankfurt[`percent`] = matches[31]
 
-	return market
+	return nil
 }
@@ -26,6 +26,7 @@ func NewColumnEditor(screen *Screen, quotes *Quotes) *ColumnEditor {
 		profile: quotes.profile,
 	}
 
+	screen.Mode(ColumnEditorMode)
 	editor.selectCurrentColumn()
 
 	return editor
@@ -53,6 +54,28 @@ func (editor *ColumnEditor) Handle(event termbox.Event) bool {
 	return false
 }
 
+// NewColumnPicker lets user pick the sort column by typing (part of) its
+// title rather than stepping through the columns with the arrow keys.
+func NewColumnPicker(screen *Screen, quotes *Quotes) *Picker {
+	titles, columns := []string{}, screen.layout.columnsFor(quotes.profile)
+	for _, column := range columns {
+		if !screen.layout.hidden(column, quotes.profile) {
+			titles = append(titles, column.title)
+		}
+	}
+
+	return NewPicker(screen, `Sort by`, titles, func(title string, ok bool) {
+		for i, column := range columns {
+			if ok && column.title == title && i != quotes.profile.SortColumn {
+				quotes.profile.selectedColumn = i
+				quotes.profile.Reorder()
+				quotes.profile.selectedColumn = -1
+			}
+		}
+		screen.Draw(quotes)
+	})
+}
+
 //-----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectCurrentColumn() *ColumnEditor {
 	editor.profile.selectedColumn = editor.profile.SortColumn
@@ -62,18 +85,28 @@ func (editor *ColumnEditor) selectCurrentColumn() *ColumnEditor {
 
 //-----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectLeftColumn() *ColumnEditor {
-	editor.profile.selectedColumn--
-	if editor.profile.selectedColumn < 0 {
-		editor.profile.selectedColumn = editor.layout.TotalColumns() - 1
+	for total := editor.layout.TotalColumns(editor.profile); total > 0; total-- { // Skip hidden columns.
+		editor.profile.selectedColumn--
+		if editor.profile.selectedColumn < 0 {
+			editor.profile.selectedColumn = editor.layout.TotalColumns(editor.profile) - 1
+		}
+		if editor.layout.IsVisible(editor.profile.selectedColumn, editor.profile) {
+			break
+		}
 	}
 	return editor
 }
 
 //-----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectRightColumn() *ColumnEditor {
-	editor.profile.selectedColumn++
-	if editor.profile.selectedColumn > editor.layout.TotalColumns()-1 {
-		editor.profile.selectedColumn = 0
+	for total := editor.layout.TotalColumns(editor.profile); total > 0; total-- { // Skip hidden columns.
+		editor.profile.selectedColumn++
+		if editor.profile.selectedColumn > editor.layout.TotalColumns(editor.profile)-1 {
+			editor.profile.selectedColumn = 0
+		}
+		if editor.layout.IsVisible(editor.profile.selectedColumn, editor.profile) {
+			break
+		}
 	}
 	return editor
 }
@@ -90,11 +123,12 @@ func (editor *ColumnEditor) execute() *ColumnEditor {
 //-----------------------------------------------------------------------------
 func (editor *ColumnEditor) done() bool {
 	editor.profile.selectedColumn = -1
+	editor.screen.Mode(NormalMode)
 	return true
 }
 
 //-----------------------------------------------------------------------------
 func (editor *ColumnEditor) redrawHeader() {
-	editor.screen.DrawLine(0, 4, editor.layout.Header(editor.profile))
+	editor.screen.DrawLine(0, editor.layout.HeaderRow(editor.profile), editor.layout.Header(editor.profile))
 	termbox.Flush()
 }
@@ -0,0 +1,191 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Knetic/govaluate"
+)
+
+// computedColumn is user-defined column whose values get calculated by
+// evaluating the expression for each stock. The expression can use the
+// same stock properties as the filter as well as the values of computed
+// columns defined before it.
+type computedColumn struct {
+	name       string                         // Column name, also used as the column title.
+	kind       string                         // Declared type of the values, blank to tell by the values themselves.
+	expression *govaluate.EvaluableExpression // The expression to evaluate.
+}
+
+// columnType tells how the values of the computed column declared to be of
+// that type get formatted, compared when sorting, and read back by the
+// filter expression.
+type columnType struct {
+	format  func(result float64) string  // Formats the numeric result; others are displayed as is.
+	compare Comparator                   // Compares the formatted values.
+	value   func(str string) interface{} // Turns the formatted value into what the filter sees.
+}
+
+// Column types by the name they are declared with, ex. "age:duration".
+// Durations and timestamps are calculated in seconds, and the timestamps
+// are seconds since epoch.
+var columnTypes = map[string]columnType{
+	`number`: {
+		format:  func(result float64) string { return fmt.Sprintf(`%.2f`, result) },
+		compare: CompareNumbers,
+		value:   func(str string) interface{} { value, _ := number(str); return value },
+	},
+	`percent`: {
+		format:  func(result float64) string { return fmt.Sprintf(`%.2f%%`, result) },
+		compare: ComparePercents,
+		value:   func(str string) interface{} { value, _ := number(strings.Replace(str, `%`, ``, 1)); return value },
+	},
+	`string`: {
+		format:  func(result float64) string { return fmt.Sprintf(`%v`, result) },
+		compare: CompareStrings,
+		value:   func(str string) interface{} { return strings.TrimSpace(str) },
+	},
+	`duration`: {
+		format:  formatDuration,
+		compare: CompareDurations,
+		value:   func(str string) interface{} { value, _ := seconds(str); return value },
+	},
+	`timestamp`: {
+		format:  func(result float64) string { return time.Unix(int64(result), 0).Format(`2006-01-02 15:04`) },
+		compare: CompareTimestamps,
+		value: func(str string) interface{} {
+			if at, ok := moment(str); ok {
+				return float64(at.Unix())
+			}
+			return 0.0
+		},
+	},
+}
+
+// Parses column definition such as "gapPct = (open - low) / low * 100"
+// into computed column. The name could be followed by the type of the
+// values, ex. "gapPct:percent = ...".
+func newComputedColumn(definition string) (computedColumn, error) {
+	split := strings.SplitN(definition, `=`, 2)
+	if len(split) != 2 || strings.TrimSpace(split[0]) == `` {
+		return computedColumn{}, fmt.Errorf("Column `%s` should look like `name = expression`", definition)
+	}
+
+	name, kind := strings.TrimSpace(split[0]), ``
+	if i := strings.Index(name, `:`); i >= 0 {
+		name, kind = strings.TrimSpace(name[:i]), strings.ToLower(strings.TrimSpace(name[i+1:]))
+		if _, ok := columnTypes[kind]; !ok || name == `` {
+			return computedColumn{}, fmt.Errorf("Column `%s` type should be one of: %s", definition, strings.Join(columnTypeNames(), `, `))
+		}
+	}
+
+	expression, err := newExpression(split[1])
+	if err != nil {
+		return computedColumn{}, fmt.Errorf("Invalid column `%s`: %s", definition, err)
+	}
+
+	return computedColumn{name: name, kind: kind, expression: expression}, nil
+}
+
+// Compares the formatted values of the column when sorting. The values of
+// the columns without the declared type compare as numbers unless they are
+// not, ex. "yes" and "no".
+func (column computedColumn) compare(a, b string) int {
+	if kind, ok := columnTypes[column.kind]; ok {
+		return kind.compare(a, b)
+	}
+	if _, ok := number(a); !ok {
+		if _, ok := number(b); !ok {
+			return CompareStrings(a, b)
+		}
+	}
+	return CompareNumbers(a, b)
+}
+
+// Converts formatted value of the column back to the type the filter
+// expression expects.
+func (column computedColumn) value(str string) interface{} {
+	if kind, ok := columnTypes[column.kind]; ok {
+		return kind.value(str)
+	}
+	return computedValue(str)
+}
+
+// Returns the names of the column types in alphabetical order.
+//-----------------------------------------------------------------------------
+func columnTypeNames() []string {
+	names := []string{}
+	for name := range columnTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Formats the duration given in seconds, ex. "2d 3h", "3h 25m", or "45s".
+//-----------------------------------------------------------------------------
+func formatDuration(result float64) string {
+	sign, total := ``, int64(result)
+	if total < 0 {
+		sign, total = `-`, -total
+	}
+	parts := []string{}
+	for _, unit := range []struct {
+		suffix  string
+		seconds int64
+	}{{`d`, 86400}, {`h`, 3600}, {`m`, 60}, {`s`, 1}} {
+		if count := total / unit.seconds; count > 0 && len(parts) < 2 {
+			parts = append(parts, fmt.Sprintf(`%d%s`, count, unit.suffix))
+			total -= count * unit.seconds
+		} else if len(parts) > 0 {
+			break // Only the two most significant units are shown.
+		}
+	}
+	if len(parts) == 0 {
+		return `0s`
+	}
+
+	return sign + strings.Join(parts, ` `)
+}
+
+// Evaluates computed columns from the profile for the given stock and
+// returns formatted values, one per column.
+func compute(stock Stock, profile *Profile) []string {
+	values := variables(stock, profile)
+	computed := make([]string, len(profile.computed))
+
+	for i, column := range profile.computed {
+		result, err := column.expression.Evaluate(values)
+		if err != nil {
+			computed[i] = `ERR`
+			continue
+		}
+		values[column.name] = result // Make the value available to subsequent columns.
+
+		switch result.(type) {
+		case float64:
+			if kind, ok := columnTypes[column.kind]; ok {
+				computed[i] = kind.format(result.(float64))
+			} else {
+				computed[i] = fmt.Sprintf(`%.2f`, result.(float64))
+			}
+		case bool:
+			if result.(bool) {
+				computed[i] = `yes`
+			} else {
+				computed[i] = `no`
+			}
+		default:
+			computed[i] = fmt.Sprintf(`%v`, result)
+		}
+	}
+
+	return computed
+}
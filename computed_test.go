@@ -0,0 +1,110 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputedColumns(t *testing.T) {
+	_, err := newComputedColumn(`(high - low)`)
+	assert.Error(t, err)
+
+	spread, err := newComputedColumn(`spread = high - low`)
+	require.NoError(t, err)
+	assert.Equal(t, `spread`, spread.name)
+
+	wide, err := newComputedColumn(`wide = spread >= 2`)
+	require.NoError(t, err)
+
+	profile := &Profile{computed: []computedColumn{spread, wide}}
+	stock := Stock{Ticker: `GOOG`, High: numberOf(1220.50), Low: numberOf(1218.00)}
+	assert.Equal(t, []string{`2.50`, `yes`}, compute(stock, profile))
+}
+
+func TestExpressionFunctions(t *testing.T) {
+	profile := &Profile{Holdings: map[string]Holding{`GOOG`: {Shares: 10, CostBasis: 12000}}}
+	stock := Stock{Ticker: `GOOG`, LastTrade: numberOf(1214.38), Change: numberOf(-2.5)}
+
+	for expression, expected := range map[string]string{
+		`value = round(shares * last - costBasis, 1)`:               `143.80`,
+		`move = abs(change)`:                                        `2.50`,
+		`big = max(1, change, 2) == 2`:                              `yes`,
+		`tiny = min(1, change)`:                                     `-2.50`,
+		`goog = startsWith(ticker, 'GO') && contains(ticker, 'OG')`: `yes`,
+		`side = change < 0 ? 'down' : 'up'`:                         `down`,
+		`oops = abs('GOOG')`:                                        `ERR`,
+	} {
+		column, err := newComputedColumn(expression)
+		require.NoError(t, err, expression)
+		profile.computed = []computedColumn{column}
+		assert.Equal(t, []string{expected}, compute(stock, profile), expression)
+	}
+}
+
+func TestTypedComputedColumns(t *testing.T) {
+	_, err := newComputedColumn(`age:weeks = 1`)
+	assert.Error(t, err)
+	_, err = newComputedColumn(`:number = 1`)
+	assert.Error(t, err)
+
+	profile := &Profile{}
+	for _, definition := range []string{
+		`gap:percent = (open - prevClose) / prevClose * 100`,
+		`side:string = change < 0 ? 'down' : 'up'`,
+		`held:duration = 100000`,
+		`since:timestamp = 1559851200`,
+		`plain = 1 > 0`,
+	} {
+		column, err := newComputedColumn(definition)
+		require.NoError(t, err, definition)
+		profile.computed = append(profile.computed, column)
+	}
+	assert.Equal(t, `gap`, profile.computed[0].name)
+	assert.Equal(t, `percent`, profile.computed[0].kind)
+
+	stock := Stock{Ticker: `AAPL`, Open: numberOf(202.00), PrevClose: numberOf(200.00), Change: numberOf(-1.00)}
+	values := compute(stock, profile)
+	assert.Equal(t, `1.00%`, values[0])
+	assert.Equal(t, `down`, values[1])
+	assert.Equal(t, `1d 3h`, values[2])
+	assert.Equal(t, time.Unix(1559851200, 0).Format(`2006-01-02 15:04`), values[3])
+	assert.Equal(t, `yes`, values[4])
+
+	assert.Equal(t, 1.0, profile.computed[0].value(values[0]))
+	assert.Equal(t, `down`, profile.computed[1].value(values[1]))
+	assert.Equal(t, 97200.0, profile.computed[2].value(values[2]))
+	assert.Equal(t, float64(1559851200/60*60), profile.computed[3].value(values[3]))
+	assert.Equal(t, true, profile.computed[4].value(values[4]))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, `0s`, formatDuration(0))
+	assert.Equal(t, `45s`, formatDuration(45))
+	assert.Equal(t, `3h 25m`, formatDuration(3*3600+25*60+10))
+	assert.Equal(t, `2d`, formatDuration(2*86400+30))
+	assert.Equal(t, `-1h 30m`, formatDuration(-5400))
+}
+
+func TestFilterByTypedComputedColumn(t *testing.T) {
+	profile := &Profile{}
+	require.NoError(t, profile.SetColumns([]string{`side:string = change < 0 ? 'down' : 'up'`}))
+	expression, err := newExpression(`side == 'down'`)
+	require.NoError(t, err)
+	profile.Filter, profile.filterExpression = `side == 'down'`, expression
+
+	stocks := []Stock{{Ticker: `AAPL`, Change: numberOf(-1.00)}, {Ticker: `IBM`, Change: numberOf(2.00)}}
+	for i := range stocks {
+		stocks[i].Computed = compute(stocks[i], profile)
+	}
+	filtered, err := NewFilter(profile).Apply(stocks)
+	require.NoError(t, err)
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, `AAPL`, filtered[0].Ticker)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bufio`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`net`
+	`os`
+	`regexp`
+	`strings`
+	`time`
+)
+
+// How long the control client waits for the session to reply.
+const controlTimeout = 10 * time.Second
+
+// Control listens for the commands on the unix socket, so the shell scripts
+// and the other terminals could drive the running session, ex.
+//
+//   $ echo "add TSLA" | nc -U ~/.mop.sock
+//   Added 1 ticker(s)
+//
+// Each line is the command, and each command gets the line in reply. The
+// commands are handed over to the session's main loop, which owns the
+// profile and the screen.
+type Control struct {
+	listener net.Listener         // Unix socket listener.
+	requests chan *ControlRequest // Commands received, for the main loop.
+}
+
+// ControlRequest is the command received over the control socket, ex.
+// "add AAPL,MSFT".
+type ControlRequest struct {
+	Command string      // Command name, ex. "add".
+	Args    string      // The rest of the line, ex. "AAPL,MSFT".
+	reply   chan string // Reply to send back to the client.
+}
+
+// NewControl starts listening for the commands on the unix socket at the
+// given path. The socket left behind by the session that is gone gets
+// replaced, but the one of the running session does not.
+func NewControl(path string) (*Control, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial(`unix`, path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("Another mop session is listening at %s", path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen(`unix`, path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0600) // Only the user could drive the session.
+
+	control := &Control{listener: listener, requests: make(chan *ControlRequest)}
+	go control.accept()
+
+	return control, nil
+}
+
+// Requests returns the channel the commands received arrive at. Each of
+// them is expected to be replied to.
+func (control *Control) Requests() <-chan *ControlRequest {
+	return control.requests
+}
+
+// Close stops listening and removes the socket.
+func (control *Control) Close() error {
+	return control.listener.Close()
+}
+
+// Reply sends the reply back to the client. Multi-line replies are sent as
+// one line.
+func (request *ControlRequest) Reply(reply string) {
+	request.reply <- strings.Replace(strings.TrimSpace(reply), "\n", ` `, -1)
+}
+
+// Execute runs the command against the stock quotes and replies with the
+// outcome. Besides the startup script commands, ex. "filter last > 100",
+// it knows "add" and "remove" followed by the tickers, and "dump" that
+// replies with the quotes and the market data as JSON document. The
+// commands that need the screen, ex. "pause", are left to the caller.
+func (request *ControlRequest) Execute(market *Market, quotes *Quotes) {
+	reply, err := `ok`, error(nil)
+	tickers := regexp.MustCompile(`[,\s]+`).Split(strings.ToUpper(strings.Trim(request.Args, `, `)), -1)
+
+	switch {
+	case (request.Command == `add` || request.Command == `remove`) && tickers[0] == ``:
+		err = errors.New(`Expected the tickers`)
+	case request.Command == `add`:
+		_, notes := quotes.profile.CheckTickers(tickers)
+		count := 0
+		count, err = quotes.AddTickers(tickers)
+		reply = strings.Join(append([]string{fmt.Sprintf(`Added %d ticker(s)`, count)}, notes...), `; `)
+	case request.Command == `remove`:
+		count := 0
+		count, err = quotes.RemoveTickers(tickers)
+		reply = fmt.Sprintf(`Removed %d ticker(s)`, count)
+	case request.Command == `dump`:
+		data, _ := json.Marshal(NewSnapshot(market, quotes, time.Now()))
+		reply = string(data)
+	default:
+		err = quotes.profile.run(request.Command + ` ` + request.Args)
+	}
+
+	if err != nil {
+		reply = `error: ` + err.Error()
+	}
+	request.Reply(reply)
+}
+
+// SendControl sends the command to the session listening at the given
+// path and returns its reply.
+func SendControl(path, command string) (string, error) {
+	conn, err := net.DialTimeout(`unix`, path, controlTimeout)
+	if err != nil {
+		return ``, fmt.Errorf("No mop session is listening at %s", path)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return ``, err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+
+	return strings.TrimSpace(reply), err
+}
+
+// Accepts the connections until the listener is closed.
+//-----------------------------------------------------------------------------
+func (control *Control) accept() {
+	for {
+		conn, err := control.listener.Accept()
+		if err != nil {
+			return
+		}
+		go control.serve(conn)
+	}
+}
+
+// Reads the commands off the connection, one per line, and writes back the
+// replies.
+//-----------------------------------------------------------------------------
+func (control *Control) serve(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		request := &ControlRequest{
+			Command: strings.ToLower(fields[0]),
+			Args:    strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), fields[0])),
+			reply:   make(chan string, 1),
+		}
+		control.requests <- request
+		if _, err := fmt.Fprintln(conn, <-request.reply); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControl(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, `mop.sock`)
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600)) // Left behind by the session that is gone.
+
+	control, err := NewControl(path)
+	require.NoError(t, err)
+	defer control.Close()
+	_, err = NewControl(path)
+	assert.EqualError(t, err, `Another mop session is listening at `+path)
+
+	profile := &Profile{filename: filepath.Join(dir, `.moprc`), Tickers: []string{`AAPL`}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200)}}}
+	go func() {
+		for request := range control.Requests() {
+			request.Execute(nil, quotes)
+		}
+	}()
+	send := func(command string) string {
+		reply, err := SendControl(path, command)
+		require.NoError(t, err)
+		return reply
+	}
+
+	assert.Equal(t, `{"schema":1,"time":`, send(`dump`)[:19])
+	assert.Equal(t, `Added 2 ticker(s)`, send(`ADD tsla, ibm`))
+	assert.Equal(t, `Removed 1 ticker(s)`, send(`remove AAPL,GOOG`))
+	assert.Equal(t, []string{`IBM`, `TSLA`}, profile.Tickers)
+	assert.Equal(t, `ok`, send(`filter last > 100`))
+	assert.Equal(t, `last > 100`, profile.Filter)
+	assert.Equal(t, "error: Unknown command `fly`", send(`fly away`))
+	assert.Equal(t, `error: Expected the tickers`, send(`add ,`))
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`math`
+	`sort`
+	`strings`
+)
+
+// Cost basis methods: the shares sold are taken from the oldest lots first
+// (FIFO), from the newest ones (LIFO), or at the average cost per share.
+const (
+	FIFO        = `fifo`
+	LIFO        = `lifo`
+	AverageCost = `average`
+)
+
+// lot is the number of shares bought at the same price.
+type lot struct {
+	shares float64 // Number of shares left.
+	price  float64 // Price paid per share.
+}
+
+// basis is the outcome of going through the ledger for the ticker.
+type basis struct {
+	shares   float64 // Number of shares left.
+	cost     float64 // Cost basis of the shares left.
+	realized float64 // Profit or loss realized by selling the shares.
+}
+
+// Returns the cost basis method of the given account, FIFO unless set
+// otherwise. The holdings outside of the accounts are always FIFO.
+//-----------------------------------------------------------------------------
+func (profile *Profile) costMethod(account string) string {
+	if method := strings.ToLower(profile.Accounts[account].CostMethod); method != `` {
+		return method
+	}
+	return FIFO
+}
+
+// Goes through the ledger of the given account, blank for the transactions
+// outside of the accounts, and returns the cost basis and the realized
+// profit or loss per ticker as per the account's cost basis method.
+//-----------------------------------------------------------------------------
+func (profile *Profile) ledgerBasis(account string) map[string]basis {
+	ledger := []Transaction{}
+	for _, transaction := range profile.Transactions {
+		if transaction.Account == account {
+			ledger = append(ledger, transaction)
+		}
+	}
+	sort.SliceStable(ledger, func(i, j int) bool { return ledger[i].Date < ledger[j].Date })
+
+	method, lots, realized := profile.costMethod(account), make(map[string][]lot), make(map[string]float64)
+	for _, transaction := range ledger {
+		ticker := transaction.Ticker
+		if transaction.Shares >= 0 {
+			lots[ticker] = append(lots[ticker], lot{transaction.Shares, transaction.Price})
+			continue
+		}
+		var cost float64
+		lots[ticker], cost = sell(lots[ticker], -transaction.Shares, method)
+		realized[ticker] += -transaction.Shares*transaction.Price - cost
+	}
+
+	result := make(map[string]basis)
+	for ticker, held := range lots {
+		entry := basis{realized: realized[ticker]}
+		for _, lot := range held {
+			entry.shares += lot.shares
+			entry.cost += lot.shares * lot.price
+		}
+		result[ticker] = entry
+	}
+
+	return result
+}
+
+// Takes the shares sold out of the lots as per the cost basis method, and
+// returns the lots left along with the cost of the shares sold. Selling
+// more shares than there are in the lots costs nothing for the excess.
+//-----------------------------------------------------------------------------
+func sell(lots []lot, shares float64, method string) ([]lot, float64) {
+	cost := 0.0
+	switch method {
+	case AverageCost:
+		total, paid := 0.0, 0.0
+		for _, lot := range lots {
+			total, paid = total+lot.shares, paid+lot.shares*lot.price
+		}
+		if total <= 0 {
+			return lots, 0
+		}
+		if shares > total {
+			shares = total
+		}
+		average := paid / total
+		return []lot{{total - shares, average}}, shares * average
+	case LIFO:
+		for i := len(lots) - 1; i >= 0 && shares > 0; i-- {
+			taken := math.Min(shares, lots[i].shares)
+			lots[i].shares, shares, cost = lots[i].shares-taken, shares-taken, cost+taken*lots[i].price
+		}
+	default:
+		for i := 0; i < len(lots) && shares > 0; i++ {
+			taken := math.Min(shares, lots[i].shares)
+			lots[i].shares, shares, cost = lots[i].shares-taken, shares-taken, cost+taken*lots[i].price
+		}
+	}
+
+	left := lots[:0]
+	for _, lot := range lots {
+		if lot.shares > 0 {
+			left = append(left, lot)
+		}
+	}
+	return left, cost
+}
+
+// Returns an error if the account has unknown cost basis method.
+//-----------------------------------------------------------------------------
+func diagnoseCostMethods(profile *Profile) error {
+	for _, name := range profile.AccountNames() {
+		switch strings.ToLower(profile.Accounts[name].CostMethod) {
+		case ``, FIFO, LIFO, AverageCost:
+		default:
+			return fmt.Errorf("Unknown cost basis method %q in %s account", profile.Accounts[name].CostMethod, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostMethods(t *testing.T) {
+	ledger := func(account string) []Transaction {
+		return []Transaction{
+			{Date: `2019-03-01`, Ticker: `AAPL`, Shares: -15, Price: 200, Account: account},
+			{Date: `2019-01-02`, Ticker: `AAPL`, Shares: 10, Price: 100, Account: account},
+			{Date: `2019-02-01`, Ticker: `AAPL`, Shares: 10, Price: 160, Account: account},
+		}
+	}
+	profile := &Profile{Accounts: map[string]Account{
+		`fifo`:    {Holdings: map[string]Holding{`AAPL`: {Shares: 5, CostBasis: 1}}},
+		`lifo`:    {Holdings: map[string]Holding{`AAPL`: {Shares: 5}}, CostMethod: `LIFO`},
+		`average`: {Holdings: map[string]Holding{`AAPL`: {Shares: 5}}, CostMethod: AverageCost},
+	}}
+	for _, account := range profile.AccountNames() {
+		profile.Transactions = append(profile.Transactions, ledger(account)...)
+	}
+
+	for account, expected := range map[string][2]float64{
+		`fifo`:    {800, 3000 - 1000 - 800}, // Sold 10 @ 100 and 5 @ 160, 5 @ 160 left.
+		`lifo`:    {500, 3000 - 1600 - 500}, // Sold 10 @ 160 and 5 @ 100, 5 @ 100 left.
+		`average`: {650, 3000 - 15*130},     // Sold 15 @ 130, 5 @ 130 left.
+	} {
+		shares, cost := profile.position(account, `AAPL`)
+		assert.Equal(t, 5.0, shares, account)
+		assert.InDelta(t, expected[0], cost, 1e-9, account+` cost basis from the ledger`)
+		assert.InDelta(t, expected[1], profile.realized(account), 1e-9, account+` realized`)
+	}
+	_, cost := profile.position(``, `AAPL`)
+	assert.InDelta(t, 800+500+650.0, cost, 1e-9, `each account with its own method`)
+	assert.InDelta(t, 1200+900+1050.0, profile.realized(``), 1e-9)
+
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(210.00), Change: numberOf(1.00)}}}
+	assert.InDelta(t, 3150.0, NewPortfolio(quotes).variables()[`realized`], 1e-9)
+
+	assert.NoError(t, diagnoseCostMethods(profile))
+	profile.Accounts[`hifo`] = Account{CostMethod: `hifo`}
+	assert.Error(t, diagnoseCostMethods(profile))
+}
+
+func TestOversold(t *testing.T) {
+	lots, cost := sell([]lot{{10, 100}}, 15, FIFO)
+	assert.Empty(t, lots)
+	assert.Equal(t, 1000.0, cost, `the excess costs nothing`)
+
+	lots, cost = sell([]lot{{10, 100}, {10, 200}}, 25, AverageCost)
+	assert.Equal(t, []lot{{0, 150}}, lots)
+	assert.Equal(t, 3000.0, cost)
+}
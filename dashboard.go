@@ -0,0 +1,289 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`math`
+	`sort`
+	`strings`
+	`time`
+)
+
+// Number of the biggest gainers and losers, and the number of days ahead
+// the upcoming earnings are listed for on the dashboard.
+const (
+	dashboardMovers = 5
+	dashboardDays   = 14
+)
+
+// Strips the highlighting the market data gets once it's displayed.
+var unhighlighter = strings.NewReplacer(`<green>`, ``, `</>`, ``)
+
+// Dashboard is the market overview, i.e. mop's home page: the indices,
+// currencies, and commodities along with the sectors, the biggest movers,
+// and the upcoming events of the watchlist, all on one screen.
+type Dashboard struct {
+	Market  *Market        // Market data fetched last.
+	Sectors []SectorChange // Average change of the watchlist stocks by sector, the best performing first.
+	Gainers []mover        // Biggest gainers of the watchlist, the biggest first.
+	Losers  []mover        // Biggest losers of the watchlist, the biggest first.
+	Events  []Event        // Upcoming events of the watchlist stocks, the soonest first.
+	quotes  *Quotes        // Stock quotes the watchlist and chart panes show.
+}
+
+// SectorChange is the average change of the watchlist stocks in the sector.
+type SectorChange struct {
+	Name      string  // Sector, ex. "Technology".
+	Count     int     // Number of the watchlist stocks in the sector.
+	ChangePct float64 // Average change, in percent.
+}
+
+// Event is the upcoming event of the watchlist stock.
+type Event struct {
+	Ticker string    // Stock ticker, ex. "AAPL".
+	What   string    // What is going to happen, ex. "earnings".
+	Time   time.Time // When it's going to happen.
+}
+
+// NewDashboard composes the dashboard from the market data and the stock
+// quotes fetched last. The sectors are only known if the fundamentals are
+// fetched.
+func NewDashboard(market *Market, quotes *Quotes, now time.Time) *Dashboard {
+	dashboard := &Dashboard{Market: market, quotes: quotes}
+	sectors := make(map[string]*SectorChange)
+
+	for _, stock := range quotes.stocks {
+		values := variables(stock, quotes.profile)
+		ticker, change := quotes.profile.Label(stock.Ticker), values[`changePercent`].(float64)
+		if change > 0 {
+			dashboard.Gainers = append(dashboard.Gainers, mover{ticker, values[`last`].(float64), change})
+		} else if change < 0 {
+			dashboard.Losers = append(dashboard.Losers, mover{ticker, values[`last`].(float64), change})
+		}
+		if stock.Sector != `` {
+			if sectors[stock.Sector] == nil {
+				sectors[stock.Sector] = &SectorChange{Name: stock.Sector}
+			}
+			sectors[stock.Sector].Count++
+			sectors[stock.Sector].ChangePct += change
+		}
+		if at := time.Unix(stock.Earnings, 0); stock.Earnings > 0 && !at.Before(now) && at.Before(now.AddDate(0, 0, dashboardDays)) {
+			dashboard.Events = append(dashboard.Events, Event{ticker, `earnings`, at})
+		}
+	}
+
+	for _, sector := range sectors {
+		sector.ChangePct /= float64(sector.Count)
+		dashboard.Sectors = append(dashboard.Sectors, *sector)
+	}
+	sort.Slice(dashboard.Sectors, func(i, j int) bool {
+		if dashboard.Sectors[i].ChangePct != dashboard.Sectors[j].ChangePct {
+			return dashboard.Sectors[i].ChangePct > dashboard.Sectors[j].ChangePct
+		}
+		return dashboard.Sectors[i].Name < dashboard.Sectors[j].Name
+	})
+	sort.SliceStable(dashboard.Gainers, func(i, j int) bool {
+		return dashboard.Gainers[i].ChangePercent > dashboard.Gainers[j].ChangePercent
+	})
+	sort.SliceStable(dashboard.Losers, func(i, j int) bool {
+		return dashboard.Losers[i].ChangePercent < dashboard.Losers[j].ChangePercent
+	})
+	sort.SliceStable(dashboard.Events, func(i, j int) bool { return dashboard.Events[i].Time.Before(dashboard.Events[j].Time) })
+	if len(dashboard.Gainers) > dashboardMovers {
+		dashboard.Gainers = dashboard.Gainers[:dashboardMovers]
+	}
+	if len(dashboard.Losers) > dashboardMovers {
+		dashboard.Losers = dashboard.Losers[:dashboardMovers]
+	}
+
+	return dashboard
+}
+
+// Dashboard formats the dashboard as the grid of the panes set in the
+// profile, or as the single column of the market, sectors, movers, and
+// events panes by default.
+func (layout *Layout) Dashboard(dashboard *Dashboard) string {
+	return newPaneManager(layout, dashboard.quotes.profile).format(dashboard)
+}
+
+// Returns the lines of the indices pane, or none without the market data.
+//-----------------------------------------------------------------------------
+func indicesPane(market *Market) []string {
+	if market == nil {
+		return nil
+	}
+
+	lines := []string{`<u>Indices</u>`}
+	for _, index := range []struct {
+		name   string
+		values map[string]string
+	}{
+		{`Dow`, market.Dow}, {`S&P 500`, market.Sp500}, {`NASDAQ`, market.Nasdaq},
+		{`Tokyo`, market.Tokyo}, {`Hong Kong`, market.HongKong}, {`London`, market.London}, {`Frankfurt`, market.Frankfurt},
+	} {
+		change, moves := unhighlighter.Replace(index.values[`change`]), ``
+		if value := decimal(change); value != nil {
+			moves = moved(change+` (`+index.values[`percent`]+`)`, *value)
+		}
+		lines = append(lines, fmt.Sprintf(`  %-16s %12s  %s`, index.name, blankAsNA(index.values[`latest`]), moves))
+	}
+
+	return lines
+}
+
+// Returns the lines of the currencies, commodities, and rates pane, or none
+// without the market data.
+//-----------------------------------------------------------------------------
+func currenciesPane(market *Market) []string {
+	if market == nil {
+		return nil
+	}
+
+	lines := []string{`<u>Currencies, commodities, and rates</u>`}
+	for _, asset := range []struct {
+		name, format string
+		values       map[string]string
+	}{
+		{`Euro`, `$%s`, market.Euro}, {`Yen`, `¥%s`, market.Yen}, {`Oil`, `$%s`, market.Oil},
+		{`Gold`, `$%s`, market.Gold}, {`10-Year Yield`, `%s%%`, market.Yield},
+	} {
+		latest, change, moves := `-`, unhighlighter.Replace(asset.values[`change`]), ``
+		if asset.values[`latest`] != `` {
+			latest = fmt.Sprintf(asset.format, asset.values[`latest`])
+		}
+		if value := decimal(change); value != nil {
+			moves = moved(`(`+change+`%)`, *value)
+		}
+		lines = append(lines, fmt.Sprintf(`  %-16s %12s  %s`, asset.name, latest, moves))
+	}
+
+	return lines
+}
+
+// Returns the lines of the sectors pane.
+//-----------------------------------------------------------------------------
+func sectorsPane(sectors []SectorChange) []string {
+	lines := []string{`<u>Sectors</u>`}
+	for _, sector := range sectors {
+		lines = append(lines, fmt.Sprintf(`  %-22s %3d stock(s)  %s`, sector.Name, sector.Count, moved(fmt.Sprintf(`%+.2f%%`, sector.ChangePct), sector.ChangePct)))
+	}
+	if len(sectors) == 0 {
+		lines = append(lines, `  - (fetched along with the fundamentals)`)
+	}
+
+	return lines
+}
+
+// Returns the lines of the pane with the given title that lists the movers.
+//-----------------------------------------------------------------------------
+func moversPane(title string, movers []mover) []string {
+	lines := []string{`<u>` + title + `</u>`}
+	for _, mover := range movers {
+		lines = append(lines, fmt.Sprintf(`  <yellow>%-10s</> %10.2f  %s`, mover.Ticker, mover.Last, moved(fmt.Sprintf(`%+.2f%%`, mover.ChangePercent), mover.ChangePercent)))
+	}
+	if len(movers) == 0 {
+		lines = append(lines, `  -`)
+	}
+
+	return lines
+}
+
+// Returns the lines of the upcoming events pane.
+//-----------------------------------------------------------------------------
+func eventsPane(events []Event) []string {
+	lines := []string{fmt.Sprintf(`<u>Upcoming events</u> (%d days ahead)`, dashboardDays)}
+	for _, event := range events {
+		lines = append(lines, fmt.Sprintf(`  <yellow>%-10s</> %-12s %s`, event.Ticker, event.What, event.Time.Local().Format(`Mon, Jan 2`)))
+	}
+	if len(events) == 0 {
+		lines = append(lines, `  -`)
+	}
+
+	return lines
+}
+
+// Returns the lines of the watchlist pane: the last trade and the change of
+// the stocks the way they are displayed, i.e. filtered and sorted.
+//-----------------------------------------------------------------------------
+func (layout *Layout) watchlistPane(quotes *Quotes) []string {
+	lines := []string{`<u>Watchlist</u>`}
+	stocks, _ := layout.prettify(quotes)
+	for _, stock := range stocks {
+		change := stock.ChangePct.Value()
+		lines = append(lines, fmt.Sprintf(`  <yellow>%-10s</> %10.2f  %s`, quotes.profile.Label(stock.Ticker), stock.LastTrade.Value(), moved(fmt.Sprintf(`%+.2f%%`, change), change)))
+	}
+
+	return lines
+}
+
+// Returns the lines of the chart pane: the sparklines of the recent prices
+// of the stocks the way they are displayed, as many prices as fit the given
+// width, or a month's worth if it's not set.
+//-----------------------------------------------------------------------------
+func (layout *Layout) chartPane(quotes *Quotes, width int) []string {
+	points := sparklineDays
+	if width > 14 { // Leave room for the ticker.
+		points = width - 14
+	}
+
+	lines := []string{`<u>Recent prices</u>`}
+	stocks, _ := layout.prettify(quotes)
+	for _, stock := range stocks {
+		chart, prices := `-`, quotes.sparkline(stock.Ticker)
+		if len(prices) > points {
+			prices = prices[len(prices)-points:]
+		}
+		if len(prices) > 1 {
+			chart = moved(blocks(prices), prices[len(prices)-1]-prices[0])
+		}
+		lines = append(lines, fmt.Sprintf(`  <yellow>%-10s</>  %s`, quotes.profile.Label(stock.Ticker), chart))
+	}
+
+	return lines
+}
+
+// Returns the prices drawn with the block characters, one per price, the
+// taller the higher, ex. "▁▃▅▇".
+//-----------------------------------------------------------------------------
+func blocks(prices []float64) string {
+	low, high := prices[0], prices[0]
+	for _, price := range prices {
+		low, high = math.Min(low, price), math.Max(high, price)
+	}
+
+	chart := []rune{}
+	for _, price := range prices {
+		height := 4 // Flat line through the middle.
+		if high > low {
+			height = 1 + int((price-low)*7/(high-low)+0.5)
+		}
+		chart = append(chart, histogramBlocks[height])
+	}
+
+	return string(chart)
+}
+
+// Returns the string highlighted in green if the change is up, or in red if
+// it's down.
+//-----------------------------------------------------------------------------
+func moved(str string, change float64) string {
+	switch {
+	case change > 0:
+		return `<green>` + str + `</>`
+	case change < 0:
+		return `<red>` + str + `</>`
+	}
+	return str
+}
+
+// Returns the string as is, or "-" if it's blank.
+//-----------------------------------------------------------------------------
+func blankAsNA(str string) string {
+	if str == `` {
+		return `-`
+	}
+	return str
+}
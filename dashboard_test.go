@@ -0,0 +1,67 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboard(t *testing.T) {
+	now := time.Unix(1561752000, 0) // Jun 28, 2019 4pm EDT.
+	quotes := &Quotes{profile: &Profile{}, stocks: []Stock{
+		{Ticker: `AAPL`, Sector: `Technology`, LastTrade: numberOf(200), Change: numberOf(4), ChangePct: numberOf(2), Earnings: now.AddDate(0, 0, 30).Unix()},
+		{Ticker: `MSFT`, Sector: `Technology`, LastTrade: numberOf(130), Change: numberOf(-1.3), ChangePct: numberOf(-1), Earnings: now.AddDate(0, 0, 3).Unix()},
+		{Ticker: `KO`, Sector: `Consumer Defensive`, LastTrade: numberOf(50), Change: numberOf(-1), ChangePct: numberOf(-2), Earnings: now.AddDate(0, 0, 1).Unix()},
+		{Ticker: `IBM`, LastTrade: numberOf(140), Change: numberOf(0), ChangePct: numberOf(0), Earnings: now.AddDate(0, 0, -1).Unix()},
+	}}
+
+	dashboard := NewDashboard(nil, quotes, now)
+	assert.Equal(t, []SectorChange{{`Technology`, 2, 0.5}, {`Consumer Defensive`, 1, -2}}, dashboard.Sectors)
+	assert.Equal(t, []mover{{`AAPL`, 200, 2}}, dashboard.Gainers)
+	assert.Equal(t, []mover{{`KO`, 50, -2}, {`MSFT`, 130, -1}}, dashboard.Losers, `unchanged are neither`)
+	assert.Equal(t, []Event{{`KO`, `earnings`, now.AddDate(0, 0, 1)}, {`MSFT`, `earnings`, now.AddDate(0, 0, 3)}}, dashboard.Events, `within two weeks`)
+
+	market := NewMarket()
+	market.IsClosed, market.Dow = true, map[string]string{`latest`: `26,599.96`, `change`: `<green>+85.10</>`, `percent`: `+0.32%`}
+	market.Euro = map[string]string{`latest`: `1.137`, `change`: `-0.11`}
+	screen := NewLayout().Dashboard(NewDashboard(market, quotes, now))
+	assert.Contains(t, screen, "<u>Market overview</u><right>U.S. markets closed</right>\n")
+	assert.Contains(t, screen, "  Dow                 26,599.96  <green>+85.10 (+0.32%)</>\n")
+	assert.Contains(t, screen, "  Euro                   $1.137  <red>(-0.11%)</>\n")
+	assert.Contains(t, screen, "  Tokyo                       -  \n", `not fetched`)
+	assert.Contains(t, screen, "  Technology               2 stock(s)  <green>+0.50%</>\n")
+	assert.Contains(t, screen, "<u>Biggest losers</u>\n  <yellow>KO        </>      50.00  <red>-2.00%</>\n")
+}
+
+func TestDashboardPanes(t *testing.T) {
+	profile := &Profile{DashboardPanes: [][]Pane{
+		{{Name: `watchlist`, Width: 24}, {Name: `news`}, {Name: `chart`, Width: 18}},
+		{{Name: `gainers`, Height: 2}},
+	}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200), ChangePct: numberOf(2)},
+		{Ticker: `IBM`, LastTrade: numberOf(140), ChangePct: numberOf(-1)},
+	}}
+	quotes.samples = map[string]*quoteRing{`AAPL`: {}}
+	for _, price := range []float64{190, 195, 200} {
+		quotes.samples[`AAPL`].push(quoteSample{price: price}, 10)
+	}
+
+	lines := strings.Split(NewLayout().Dashboard(NewDashboard(nil, quotes, time.Now())), "\n")
+	assert.Equal(t, []string{
+		`<u>Market overview</u>`,
+		``,
+		`<u>Watchlist</u>                   <u>Recent prices</u>`,
+		`  <yellow>IBM       </>     140.00 <red></>      <yellow>IBM       </>  -`,
+		`  <yellow>AAPL      </>     200.00 <green></>      <yellow>AAPL      </>  <green>▁▅█</>`,
+		``,
+		`<u>Biggest gainers</u>`,
+		`  <yellow>AAPL      </>     200.00  <green>+2.00%</>`,
+	}, lines[:8], `cut to the width, unknown panes left out`)
+}
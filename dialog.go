@@ -0,0 +1,211 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`strings`
+
+	`github.com/nsf/termbox-go`
+)
+
+// Dialog is a modal box displayed in the middle of the screen. Message
+// dialogs simply show the text until any key is pressed, confirmation
+// dialogs ask OK/Cancel question and report the answer to the callback,
+// and input dialogs ask for a line of text, ex. the file name. The dialog
+// picking an item from a list is the Picker.
+type Dialog struct {
+	screen   *Screen    // Pointer to Screen.
+	title    string     // Dialog title displayed in the top border.
+	message  string     // Dialog text, possibly multiline.
+	callback func(bool) // Gets called with the answer when the dialog is closed.
+	confirm  bool       // True for OK/Cancel dialogs, false for message dialogs.
+	input    bool       // True for text input dialogs.
+	value    string     // Text typed into the input dialog.
+	ok       bool       // True when OK button is selected.
+	mode     string     // Screen mode to restore when the dialog is closed.
+	top      int        // Topmost screen row taken by the dialog.
+	bottom   int        // Bottommost screen row taken by the dialog.
+}
+
+// NewMessageDialog displays the message until user presses any key. The
+// callback (if any) is invoked with true when the dialog is closed, and
+// is expected to redraw the screen area taken by the dialog.
+func NewMessageDialog(screen *Screen, title, message string, callback func(bool)) *Dialog {
+	dialog := &Dialog{
+		screen:   screen,
+		title:    title,
+		message:  message,
+		callback: callback,
+		ok:       true,
+		mode:     screen.mode,
+	}
+	screen.Mode(DialogMode)
+
+	return dialog.draw()
+}
+
+// NewConfirmDialog asks OK/Cancel question and invokes the callback with
+// user's answer. The OK button is selected by default.
+func NewConfirmDialog(screen *Screen, title, message string, callback func(bool)) *Dialog {
+	dialog := NewMessageDialog(screen, title, message, callback)
+	dialog.confirm = true
+
+	return dialog.draw()
+}
+
+// Handle takes over the keyboard events while the dialog is displayed. It
+// returns true when the dialog is closed.
+func (dialog *Dialog) Handle(event termbox.Event) bool {
+	if dialog.input {
+		return dialog.edit(event)
+	}
+	if !dialog.confirm {
+		return dialog.done(true)
+	}
+
+	switch {
+	case event.Key == termbox.KeyEsc || event.Ch == 'n' || event.Ch == 'N':
+		return dialog.done(false)
+
+	case event.Ch == 'y' || event.Ch == 'Y':
+		return dialog.done(true)
+
+	case event.Key == termbox.KeyEnter:
+		return dialog.done(dialog.ok)
+
+	case event.Key == termbox.KeyArrowLeft, event.Key == termbox.KeyArrowRight, event.Key == termbox.KeyTab:
+		dialog.ok = !dialog.ok
+		dialog.draw()
+	}
+
+	return false
+}
+
+// NewInputDialog asks for a line of text, initially set to the given value,
+// and invokes the callback with the text typed and true when Enter is
+// pressed, or false when the dialog is closed with Esc.
+func NewInputDialog(screen *Screen, title, message, value string, callback func(string, bool)) *Dialog {
+	dialog := NewMessageDialog(screen, title, message, nil)
+	dialog.input, dialog.value = true, value
+	dialog.callback = func(ok bool) {
+		callback(dialog.value, ok)
+	}
+	screen.Mode(InputMode)
+
+	return dialog.draw()
+}
+
+// Edits the text of the input dialog.
+//-----------------------------------------------------------------------------
+func (dialog *Dialog) edit(event termbox.Event) bool {
+	switch event.Key {
+	case termbox.KeyEsc:
+		return dialog.done(false)
+
+	case termbox.KeyEnter:
+		return dialog.done(true)
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if value := []rune(dialog.value); len(value) > 0 {
+			dialog.value = string(value[:len(value)-1])
+		}
+
+	case termbox.KeySpace:
+		dialog.value += ` `
+
+	default:
+		if event.Ch != 0 {
+			dialog.value += string(event.Ch)
+		}
+	}
+	dialog.draw()
+
+	return false
+}
+
+//-----------------------------------------------------------------------------
+func (dialog *Dialog) done(answer bool) bool {
+	for row := dialog.top; row <= dialog.bottom; row++ {
+		dialog.screen.ClearLine(0, row)
+	}
+	if dialog.input {
+		termbox.HideCursor()
+	}
+	dialog.screen.Mode(dialog.mode)
+	if dialog.callback != nil {
+		dialog.callback(answer)
+	}
+
+	return true
+}
+
+//-----------------------------------------------------------------------------
+func (dialog *Dialog) draw() *Dialog {
+	lines := strings.Split(dialog.message, "\n")
+	buttons, length := dialog.buttons()
+
+	// Inner width of the box: wide enough for the title, the longest line
+	// of text, and the buttons.
+	width := length
+	if w := len([]rune(dialog.title)) + 4; w > width {
+		width = w
+	}
+	for _, line := range lines {
+		if w := len([]rune(line)); w > width {
+			width = w
+		}
+	}
+	if w := len([]rune(dialog.value)) + 1; dialog.input && w > width {
+		width = w // Room for the cursor past the text.
+	}
+	if dialog.input && width < 30 {
+		width = 30
+	}
+	width += 4 // Left and right margins.
+
+	x := (dialog.screen.width - width - 2) / 2
+	y := (dialog.screen.height - len(lines) - 6) / 2
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+
+	rows := []string{strings.Repeat(` `, width)}
+	for _, line := range lines {
+		rows = append(rows, `  `+line+strings.Repeat(` `, width-len([]rune(line))-2))
+	}
+	if dialog.input {
+		rows = append(rows, strings.Repeat(` `, width))
+		rows = append(rows, `  <r>`+padRight(dialog.value, width-4)+`</r>  `)
+	}
+	left := (width - length) / 2
+	rows = append(rows, strings.Repeat(` `, width))
+	rows = append(rows, strings.Repeat(` `, left)+buttons+strings.Repeat(` `, width-length-left))
+
+	dialog.top, dialog.bottom = dialog.screen.DrawBox(x, y, width, dialog.title, rows)
+	if dialog.input {
+		termbox.SetCursor(x+3+len([]rune(dialog.value)), y+len(lines)+3)
+		termbox.Flush()
+	}
+
+	return dialog
+}
+
+// Returns buttons markup along with its visible length.
+func (dialog *Dialog) buttons() (string, int) {
+	if dialog.input {
+		return `<r>  OK  </r>  Cancel `, 15
+	}
+	if !dialog.confirm {
+		return `<r>  OK  </r>`, 6
+	}
+	if dialog.ok {
+		return `<r>  OK  </r>  Cancel `, 15
+	}
+
+	return `  OK   <r> Cancel </r>`, 15
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`encoding/json`
+	`fmt`
+	`math`
+	`sort`
+	`strings`
+	`time`
+)
+
+// Number of the biggest movers listed in the digest.
+const digestMovers = 5
+
+// Digest describes the daily snapshot of the watchlist, i.e. the biggest
+// movers and portfolio P&L, that gets delivered at the scheduled time.
+type Digest struct {
+	At      string // Time of day to send the digest at, ex. "17:30", or "close" for the market close.
+	Webhook string // URL the digest gets posted to as JSON.
+	Sent    string // Date the digest was sent last, ex. "2019-06-28".
+}
+
+// snapshot is the digest payload. The text is readable as is so the
+// webhook could be pointed to a chat, the rest is there for the scripts.
+type snapshot struct {
+	Text      string                 `json:"text"`
+	Date      string                 `json:"date"`
+	Advancing int                    `json:"advancing"`
+	Declining int                    `json:"declining"`
+	Movers    []mover                `json:"movers"`
+	Portfolio map[string]interface{} `json:"portfolio,omitempty"`
+}
+
+// mover is the stock listed in the digest.
+type mover struct {
+	Ticker        string  `json:"ticker"`
+	Last          float64 `json:"last"`
+	ChangePercent float64 `json:"changePercent"`
+}
+
+// SendDigest delivers the digest if it's scheduled in the profile and is
+// due at the given time. The digest gets sent once a day; the date is
+// saved in the profile so restarting mop doesn't send it again. Nothing is
+// sent while the recorded snapshots are played back.
+func (quotes *Quotes) SendDigest(now time.Time) error {
+	digest := quotes.profile.Digest
+	if digest == nil || digest.Webhook == `` || !digest.due(now) || quotes.profile.replay != nil {
+		return nil
+	}
+
+	digest.Sent = digest.today(now)
+	quotes.profile.Save()
+
+	data, err := json.Marshal(quotes.snapshot(digest.Sent))
+	if err == nil {
+		err = post(digest.Webhook, data)
+	}
+	if err != nil {
+		quotes.digestError = fmt.Sprintf("Digest failed: %s", err)
+	} else {
+		quotes.digestError = ``
+	}
+
+	return err
+}
+
+// Returns true if the digest hasn't been sent today and it's past the
+// scheduled time. Market close digest is skipped on weekends.
+func (digest *Digest) due(now time.Time) bool {
+	at := digest.At
+	if at == `` || at == `close` {
+		now, at = inNewYork(now), `16:00`
+		if !weekday(now) {
+			return false
+		}
+	}
+
+	scheduled, err := time.Parse(`15:04`, at)
+	if err != nil || digest.Sent == digest.today(now) {
+		return false
+	}
+
+	return now.Hour()*60+now.Minute() >= scheduled.Hour()*60+scheduled.Minute()
+}
+
+// Returns the date of the digest in the time zone it is scheduled in.
+func (digest *Digest) today(now time.Time) string {
+	if digest.At == `` || digest.At == `close` {
+		now = inNewYork(now)
+	}
+
+	return now.Format(`2006-01-02`)
+}
+
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) snapshot(date string) *snapshot {
+	stats := NewStatistics(quotes)
+	digest := &snapshot{Date: date, Advancing: stats.Advancing, Declining: stats.Declining, Movers: []mover{}}
+
+	for _, stock := range quotes.stocks {
+		values := variables(stock, quotes.profile)
+		digest.Movers = append(digest.Movers, mover{
+			Ticker:        strings.TrimSpace(stock.Ticker),
+			Last:          values[`last`].(float64),
+			ChangePercent: values[`changePercent`].(float64),
+		})
+	}
+	sort.SliceStable(digest.Movers, func(i, j int) bool {
+		return math.Abs(digest.Movers[i].ChangePercent) > math.Abs(digest.Movers[j].ChangePercent)
+	})
+	if len(digest.Movers) > digestMovers {
+		digest.Movers = digest.Movers[:digestMovers]
+	}
+
+	lines := []string{fmt.Sprintf(`Mop digest for %s: %d advancing, %d declining.`, date, stats.Advancing, stats.Declining)}
+	for _, mover := range digest.Movers {
+		lines = append(lines, fmt.Sprintf(`%-10s %10.2f %+8.2f%%`, mover.Ticker, mover.Last, mover.ChangePercent))
+	}
+	if quotes.profile.hasHoldings() {
+		digest.Portfolio = NewPortfolio(quotes).variables()
+		lines = append(lines, fmt.Sprintf(`Portfolio %.2f, day P&L %+.2f (%+.2f%%), total P&L %+.2f (%+.2f%%)`,
+			digest.Portfolio[`value`], digest.Portfolio[`dayPnl`], digest.Portfolio[`dayPnlPct`],
+			digest.Portfolio[`pnl`], digest.Portfolio[`pnlPct`]))
+	}
+	digest.Text = strings.Join(lines, "\n")
+
+	return digest
+}
+
+// Posts JSON data to the webhook URL.
+func post(url string, data []byte) error {
+	response, err := client.Post(url, `application/json`, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", response.Status)
+	}
+
+	return nil
+}
+
+// Returns the given time in New York where the market closes at 4pm. If
+// the time zone database is not available the local time is used.
+func inNewYork(now time.Time) time.Time {
+	if location, err := time.LoadLocation(`America/New_York`); err == nil {
+		return now.In(location)
+	}
+
+	return now
+}
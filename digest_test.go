@@ -0,0 +1,57 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	var received snapshot
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	digest := &Digest{At: `17:30`, Webhook: server.URL}
+	profile := &Profile{Digest: digest, Holdings: map[string]Holding{`AAPL`: {Shares: 10, CostBasis: 1500}}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200.00), Change: numberOf(-10.00), ChangePct: numberOf(-4.76)},
+		{Ticker: `IBM`, LastTrade: numberOf(140.00), Change: numberOf(-2.00), ChangePct: numberOf(-1.41)},
+		{Ticker: `KO`, LastTrade: numberOf(46.00), Change: numberOf(0.50), ChangePct: numberOf(1.10)},
+	}}
+
+	before := time.Date(2019, 6, 28, 17, 29, 0, 0, time.Local)
+	require.NoError(t, quotes.SendDigest(before))
+	assert.Equal(t, ``, digest.Sent)
+
+	after := time.Date(2019, 6, 28, 17, 30, 0, 0, time.Local)
+	require.NoError(t, quotes.SendDigest(after))
+	assert.Equal(t, `2019-06-28`, digest.Sent)
+	assert.Equal(t, `2019-06-28`, received.Date)
+	assert.Equal(t, 1, received.Advancing)
+	assert.Equal(t, 2, received.Declining)
+	require.Len(t, received.Movers, 3)
+	assert.Equal(t, `AAPL`, received.Movers[0].Ticker)
+	assert.Equal(t, `KO`, received.Movers[2].Ticker)
+	assert.InDelta(t, -100.0, received.Portfolio[`dayPnl`], 0.01)
+	assert.Contains(t, received.Text, `day P&L -100.00`)
+
+	assert.False(t, digest.due(after.Add(time.Hour)), `sent once a day`)
+	assert.True(t, digest.due(after.Add(24*time.Hour)))
+
+	friday := &Digest{At: `close`}
+	assert.True(t, friday.due(time.Date(2019, 6, 28, 20, 5, 0, 0, time.UTC)))
+	assert.False(t, friday.due(time.Date(2019, 6, 29, 20, 5, 0, 0, time.UTC)), `no digest on weekends`)
+}
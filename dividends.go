@@ -0,0 +1,43 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import `sort`
+
+// Dividend is the dividend received for the holding, ex.
+//
+//   { "ExDate": "2019-05-10", "Amount": 0.77, "Price": 190.72 }
+//
+// The price is the share price on the ex-date, which is what the dividend
+// gets reinvested at when the holding is set to reinvest the dividends.
+type Dividend struct {
+	ExDate string  // Ex-dividend date, ex. "2019-05-10".
+	Amount float64 // Dividend per share.
+	Price  float64 // Share price on the ex-date.
+}
+
+// Position returns the number of shares held and their cost basis. When the
+// dividends are reinvested each dividend buys more shares at the ex-date
+// price, in the order of the ex-dates, so the later dividends are paid on
+// the shares bought by the earlier ones. The reinvested amount is added to
+// the cost basis, same as the brokers report it.
+func (holding Holding) Position() (shares, cost float64) {
+	shares, cost = holding.Shares, holding.CostBasis
+	if !holding.Reinvest {
+		return shares, cost
+	}
+
+	dividends := append([]Dividend{}, holding.Dividends...)
+	sort.SliceStable(dividends, func(i, j int) bool { return dividends[i].ExDate < dividends[j].ExDate })
+	for _, dividend := range dividends {
+		if dividend.Price > 0 {
+			amount := shares * dividend.Amount
+			shares += amount / dividend.Price
+			cost += amount
+		}
+	}
+
+	return shares, cost
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosition(t *testing.T) {
+	dividends := []Dividend{{ExDate: `2019-08-09`, Amount: 1, Price: 200}, {ExDate: `2019-05-10`, Amount: 1, Price: 100}}
+	holding := Holding{Shares: 100, CostBasis: 10000, Dividends: dividends}
+
+	shares, cost := holding.Position()
+	assert.Equal(t, 100.0, shares, `dividends paid out in cash`)
+	assert.Equal(t, 10000.0, cost)
+
+	holding.Reinvest = true
+	shares, cost = holding.Position()
+	assert.InDelta(t, 101.505, shares, 1e-9, `+1 share in May, then +101/200 in August`)
+	assert.InDelta(t, 10201.0, cost, 1e-9)
+}
+
+func TestReinvestedPortfolio(t *testing.T) {
+	profile := &Profile{Holdings: map[string]Holding{
+		`KO`: {Shares: 100, CostBasis: 5000, Reinvest: true, Dividends: []Dividend{{ExDate: `2019-06-14`, Amount: 0.4, Price: 50}}},
+	}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `KO`, LastTrade: numberOf(51.00), Change: numberOf(1.00)}}}
+
+	portfolio := NewPortfolio(quotes)
+	assert.InDelta(t, 100.8*51, portfolio.Value, 1e-9)
+	assert.InDelta(t, 5040.0, portfolio.Cost, 1e-9)
+	assert.InDelta(t, 100.8, variables(quotes.stocks[0], profile)[`shares`], 1e-9)
+}
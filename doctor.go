@@ -0,0 +1,154 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`os`
+	`sort`
+	`strings`
+)
+
+// Diagnosis is the outcome of one of the profile checks run by `mop doctor`.
+type Diagnosis struct {
+	Check string // What has been checked, ex. "Filter".
+	Err   error  // What's wrong, nil if the check has passed.
+}
+
+// Diagnose checks the profile stored in the given file without loading it
+// into mop: the file has to be valid JSON, and the filter, user-defined
+// columns, alert rules, cost basis methods, and the quotes and ESG
+// providers have to make sense. Unlike the
+// interactive session, which skips whatever it can't parse, all problems
+// get reported.
+func Diagnose(filename string) []Diagnosis {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return []Diagnosis{{Check: `Profile ` + filename + ` (not created yet, defaults apply)`}}
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &Profile{})
+	}
+	diagnoses := []Diagnosis{{Check: `Profile ` + filename, Err: err}}
+	if err != nil {
+		return diagnoses
+	}
+
+	profile := &Profile{}
+	json.Unmarshal(data, profile)
+	if profile.Filter != `` {
+		_, err := newExpression(profile.Filter)
+		diagnoses = append(diagnoses, Diagnosis{Check: `Filter`, Err: err})
+	}
+	if len(profile.Columns) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Columns`, Err: profile.SetColumns(profile.Columns)})
+	}
+	if len(profile.Alerts) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Alerts`, Err: profile.SetAlerts(profile.Alerts)})
+	}
+	if len(profile.Accounts) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Accounts`, Err: diagnoseCostMethods(profile)})
+	}
+	diagnoses = append(diagnoses, Diagnosis{Check: `Provider`, Err: diagnoseProvider(profile)})
+	if profile.ESG != `` {
+		diagnoses = append(diagnoses, Diagnosis{Check: `ESG`, Err: diagnoseESG(profile)})
+	}
+	if len(profile.DashboardPanes) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Dashboard`, Err: diagnosePanes(profile)})
+	}
+	if len(profile.Macros) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Macros`, Err: diagnoseMacros(profile)})
+	}
+	if len(profile.Aliases) > 0 {
+		diagnoses = append(diagnoses, Diagnosis{Check: `Aliases`, Err: diagnoseAliases(profile)})
+	}
+	if setting := profile.ProxySetting(); setting != `` {
+		_, err := parseProxy(setting)
+		diagnoses = append(diagnoses, Diagnosis{Check: `Proxy`, Err: err})
+	}
+
+	return diagnoses
+}
+
+//-----------------------------------------------------------------------------
+func diagnoseESG(profile *Profile) error {
+	if profile.ESG != `yahoo` {
+		return fmt.Errorf("Unknown ESG provider %q", profile.ESG)
+	}
+	if !profile.Fundamentals {
+		return errors.New(`ESG scores are fetched along with the fundamentals, set Fundamentals to true`)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+func diagnosePanes(profile *Profile) error {
+	for _, row := range profile.DashboardPanes {
+		for _, pane := range row {
+			if _, ok := dashboardPanes[pane.Name]; !ok {
+				return fmt.Errorf("Unknown dashboard pane %q", pane.Name)
+			}
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+func diagnoseMacros(profile *Profile) error {
+	for _, name := range profile.MacroNames() {
+		if _, err := DecodeKeys(profile.Macros[name]); err != nil {
+			return fmt.Errorf("Macro %q: %s", name, err)
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+func diagnoseAliases(profile *Profile) error {
+	tickers := []string{}
+	for ticker := range profile.Aliases {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+
+	labels := map[string]string{}
+	for _, ticker := range tickers {
+		label := profile.Aliases[ticker]
+		if strings.TrimSpace(label) == `` || strings.ContainsAny(label, ", \t") {
+			return fmt.Errorf("Alias %q of %s can't be typed in the prompts, use one word", label, ticker)
+		}
+		if other, ok := labels[strings.ToUpper(label)]; ok {
+			return fmt.Errorf("Alias %q is used for both %s and %s", label, other, ticker)
+		}
+		labels[strings.ToUpper(label)] = ticker
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+func diagnoseProvider(profile *Profile) error {
+	switch profile.Provider {
+	case ``, `yahoo`, `stooq`:
+		return nil
+	case `sse`:
+		if profile.Endpoint == `` {
+			return errors.New(`Endpoint is not set for sse`)
+		}
+		return nil
+	case `alphavantage`, `finnhub`, `iex`, `polygon`, `tiingo`:
+		if profile.APIKey == `` {
+			return errors.New(`APIKey is not set for ` + profile.Provider)
+		}
+		return nil
+	}
+	if strings.HasPrefix(profile.Provider, fileScheme) {
+		_, err := os.Stat(strings.TrimPrefix(profile.Provider, fileScheme))
+		return err
+	}
+	return fmt.Errorf("Unknown provider %q", profile.Provider)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `.moprc`)
+	diagnoses := Diagnose(filename)
+	require.Len(t, diagnoses, 1)
+	assert.NoError(t, diagnoses[0].Err, `missing profile gets created`)
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"Tickers": [`), 0644))
+	diagnoses = Diagnose(filename)
+	require.Len(t, diagnoses, 1)
+	assert.Error(t, diagnoses[0].Err)
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"Filter": "last >", "Columns": ["gap = open -"],
+	  "Alerts": ["AAPL: last > 200"], "Provider": "iex"}`), 0644))
+	failed := map[string]bool{}
+	for _, diagnosis := range Diagnose(filename) {
+		failed[diagnosis.Check] = diagnosis.Err != nil
+	}
+	assert.Equal(t, map[string]bool{`Profile ` + filename: false, `Filter`: true, `Columns`: true, `Alerts`: false, `Provider`: true}, failed)
+
+	assert.EqualError(t, diagnoseESG(&Profile{ESG: `msci`, Fundamentals: true}), `Unknown ESG provider "msci"`)
+	assert.Error(t, diagnoseESG(&Profile{ESG: `yahoo`}), `fetched with the fundamentals`)
+	assert.NoError(t, diagnoseESG(&Profile{ESG: `yahoo`, Fundamentals: true}))
+	assert.EqualError(t, diagnosePanes(&Profile{DashboardPanes: [][]Pane{{{Name: `chart`}, {Name: `news`}}}}), `Unknown dashboard pane "news"`)
+	assert.NoError(t, diagnoseAliases(&Profile{Aliases: map[string]string{`BRK-B`: `Berkshire`, `BF-B`: `Brown`}}))
+	assert.EqualError(t, diagnoseAliases(&Profile{Aliases: map[string]string{`BRK-A`: `Berkshire`, `BRK-B`: `berkshire`}}),
+		`Alias "berkshire" is used for both BRK-A and BRK-B`)
+	assert.Error(t, diagnoseAliases(&Profile{Aliases: map[string]string{`BRK-B`: `Berkshire Hathaway`}}))
+}
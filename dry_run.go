@@ -0,0 +1,85 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`encoding/json`
+	`io/ioutil`
+	`strings`
+)
+
+// DryRun makes the profile keep the changes in memory instead of saving
+// them, so the command line operations could be previewed with Changes().
+func (profile *Profile) DryRun() *Profile {
+	profile.dryRun = true
+
+	return profile
+}
+
+// Changes returns the diff between the saved profile and the one that
+// would have been saved during the dry run, or blank if nothing would have
+// changed.
+func (profile *Profile) Changes() string {
+	if profile.pending == nil {
+		return ``
+	}
+	saved, _ := ioutil.ReadFile(profile.filename)
+
+	return diffProfiles(saved, profile.pending)
+}
+
+// Returns the line by line diff of the profiles, ex. "-  \"Grouped\": false"
+// and "+  \"Grouped\": true". Both profiles are indented first, so every
+// setting gets on its own line.
+//-----------------------------------------------------------------------------
+func diffProfiles(before, after []byte) string {
+	lines := func(data []byte) []string {
+		var indented bytes.Buffer
+		if json.Indent(&indented, data, ``, `  `) != nil {
+			indented.Reset()
+			indented.Write(data)
+		}
+		if indented.Len() == 0 {
+			return nil
+		}
+		return strings.Split(indented.String(), "\n")
+	}
+	a, b := lines(before), lines(after)
+
+	// Longest common subsequence of the lines: common[i][j] is its length
+	// for a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	diff, i, j := ``, 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			diff += `-` + a[i] + "\n"
+			i++
+		default:
+			diff += `+` + b[j] + "\n"
+			j++
+		}
+	}
+
+	return diff
+}
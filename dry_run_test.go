@@ -0,0 +1,70 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffProfiles(t *testing.T) {
+	assert.Equal(t, ``, diffProfiles([]byte(`{"Grouped":false}`), []byte(`{"Grouped":false}`)))
+	assert.Equal(t, "-  \"Grouped\": false\n+  \"Grouped\": true\n", diffProfiles([]byte(`{"Grouped":false}`), []byte(`{"Grouped":true}`)))
+	assert.Equal(t, "+    \"IBM\",\n", diffProfiles([]byte(`{"Tickers":["AAPL","KO"]}`), []byte(`{"Tickers":["AAPL","IBM","KO"]}`)))
+	assert.Equal(t, "+{\n+  \"Grouped\": true\n+}\n", diffProfiles(nil, []byte(`{"Grouped":true}`)), `new profile`)
+}
+
+func TestDryRun(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `.moprc`)
+	NewProfile(filename)
+	saved, _ := ioutil.ReadFile(filename)
+
+	profile := NewProfile(filename).DryRun()
+	assert.Equal(t, ``, profile.Changes(), `nothing changed yet`)
+	_, err := profile.AddTickers([]string{`TSLA`})
+	require.NoError(t, err)
+	require.NoError(t, profile.Set(`quotesrefresh`, `10`))
+	assert.Contains(t, profile.Changes(), "+    \"TSLA\",\n")
+	assert.Contains(t, profile.Changes(), "-  \"QuotesRefresh\": 5,\n+  \"QuotesRefresh\": 10,\n")
+
+	data, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, saved, data, `dry run saves nothing`)
+}
+
+func TestSet(t *testing.T) {
+	profile := NewProfile(filepath.Join(t.TempDir(), `.moprc`))
+	require.NoError(t, profile.Set(`Filter`, `last > 10`))
+	require.NoError(t, profile.Set(`RowShading`, `blue`), `plain strings don't need quotes`)
+	require.NoError(t, profile.Set(`Tickers`, `["IBM"]`))
+	assert.Equal(t, `last > 10`, profile.Filter)
+	assert.NotNil(t, profile.filterExpression)
+	assert.Equal(t, `blue`, profile.RowShading)
+	assert.Equal(t, []string{`IBM`}, profile.Tickers)
+
+	assert.EqualError(t, profile.Set(`Nope`, `1`), "Unknown setting `Nope`")
+	assert.Error(t, profile.Set(`QuotesRefresh`, `soon`))
+	assert.Error(t, profile.Set(`Columns`, `["gap = open -"]`))
+	assert.Error(t, profile.Set(`Filter`, `last >`))
+	assert.Equal(t, `last > 10`, profile.Filter, `invalid values change nothing`)
+	assert.Empty(t, profile.Columns)
+}
+
+func TestPreviewRestore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `.moprc`)
+	require.NoError(t, saveWithBackup(filename, []byte(`{"Grouped":false}`)))
+	ioutil.WriteFile(filename+time.Now().Format(`.20060102-150405.bak`), []byte(`{"Grouped":true}`), 0644)
+
+	diff, backup, err := PreviewRestore(filename, ``)
+	require.NoError(t, err)
+	assert.Equal(t, "-  \"Grouped\": false\n+  \"Grouped\": true\n", diff)
+	assert.Equal(t, Backups(filename)[0], backup)
+	data, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, `{"Grouped":false}`, string(data), `nothing restored`)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/csv`
+	`encoding/json`
+	`io`
+	`io/ioutil`
+	`os`
+	`strings`
+	`time`
+)
+
+// ExportCSV saves the table (as returned by Layout.Table()) in the current
+// directory to the CSV file with timestamped name, ex. mop-20190412-093000.csv.
+// It returns the name of the file.
+func ExportCSV(table [][]string) (string, error) {
+	filename := ExportFilename(`csv`)
+
+	return filename, SaveCSV(filename, table)
+}
+
+// SaveCSV saves the table (as returned by Layout.Table()) to the CSV file
+// with the given name.
+func SaveCSV(filename string, table [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return WriteCSV(file, table)
+}
+
+// WriteCSV writes the table (as returned by Layout.Table()) as CSV, ex. to
+// stdout.
+func WriteCSV(output io.Writer, table [][]string) error {
+	return csv.NewWriter(output).WriteAll(table)
+}
+
+// ExportJSON saves the snapshot (as returned by NewSnapshot()) in the current
+// directory to the JSON file with timestamped name, ex. mop-20190412-093000.json.
+// It returns the name of the file.
+func ExportJSON(snapshot *Snapshot) (string, error) {
+	filename := ExportFilename(`json`)
+
+	return filename, SaveJSON(filename, snapshot)
+}
+
+// SaveJSON saves the snapshot (as returned by NewSnapshot()) to the JSON
+// file with the given name.
+func SaveJSON(filename string, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, ``, `  `)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// ExportHTML saves the report (as returned by Layout.Report()) in the current
+// directory to the HTML file with timestamped name, ex. mop-20190412-093000.html.
+// It returns the name of the file.
+func ExportHTML(report string) (string, error) {
+	filename := ExportFilename(`html`)
+
+	return filename, ioutil.WriteFile(filename, []byte(report), 0644)
+}
+
+// ExportFilename returns the timestamped name the export with the given
+// extension is saved under by default, ex. mop-20190412-093000.csv.
+func ExportFilename(extension string) string {
+	return time.Now().Format(`mop-20060102-150405.`) + extension
+}
+
+// NewExportDialog asks for the name of the file to export to, the
+// timestamped one by default, and saves it. The callback is invoked with
+// the outcome to display, ex. "Exported to mop-20190412-093000.csv", or
+// with blank string if the export is cancelled. It is expected to redraw
+// the screen area taken by the dialog.
+func NewExportDialog(screen *Screen, extension string, save func(string) error, callback func(string)) *Dialog {
+	return NewInputDialog(screen, `Export`, `Save as:`, ExportFilename(extension), func(filename string, ok bool) {
+		if filename = strings.TrimSpace(filename); !ok || filename == `` {
+			callback(``)
+		} else if err := save(filename); err != nil {
+			callback(err.Error())
+		} else {
+			callback(`Exported to ` + filename)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFilename(t *testing.T) {
+	assert.Regexp(t, regexp.MustCompile(`^mop-\d{8}-\d{6}\.csv$`), ExportFilename(`csv`))
+}
+
+func TestSaveCSV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `stocks.csv`)
+	require.NoError(t, SaveCSV(filename, [][]string{{`Ticker`, `Last`}, {`AAPL`, `200.00`}}))
+
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "Ticker,Last\nAAPL,200.00\n", string(data))
+	assert.Error(t, SaveCSV(filepath.Join(t.TempDir(), `missing`, `stocks.csv`), nil))
+}
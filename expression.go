@@ -0,0 +1,114 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Functions available to the filter and computed columns expressions in
+// addition to the govaluate operators (including `cond ? a : b` ternary).
+var functions = map[string]govaluate.ExpressionFunction{
+	`abs`: func(args ...interface{}) (interface{}, error) {
+		numbers, err := numeric(`abs`, 1, 1, args)
+		if err != nil {
+			return nil, err
+		}
+		return math.Abs(numbers[0]), nil
+	},
+	`min`: func(args ...interface{}) (interface{}, error) {
+		numbers, err := numeric(`min`, 1, -1, args)
+		if err != nil {
+			return nil, err
+		}
+		result := numbers[0]
+		for _, number := range numbers[1:] {
+			result = math.Min(result, number)
+		}
+		return result, nil
+	},
+	`max`: func(args ...interface{}) (interface{}, error) {
+		numbers, err := numeric(`max`, 1, -1, args)
+		if err != nil {
+			return nil, err
+		}
+		result := numbers[0]
+		for _, number := range numbers[1:] {
+			result = math.Max(result, number)
+		}
+		return result, nil
+	},
+	`round`: func(args ...interface{}) (interface{}, error) {
+		numbers, err := numeric(`round`, 1, 2, args)
+		if err != nil {
+			return nil, err
+		}
+		scale := 1.0
+		if len(numbers) == 2 { // Optional number of decimal places.
+			scale = math.Pow(10, math.Trunc(numbers[1]))
+		}
+		return math.Round(numbers[0]*scale) / scale, nil
+	},
+	`contains`: func(args ...interface{}) (interface{}, error) {
+		strs, err := textual(`contains`, args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Contains(strs[0], strs[1]), nil
+	},
+	`startsWith`: func(args ...interface{}) (interface{}, error) {
+		strs, err := textual(`startsWith`, args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(strs[0], strs[1]), nil
+	},
+}
+
+// Parses the expression making the functions above available to it.
+func newExpression(str string) (*govaluate.EvaluableExpression, error) {
+	return govaluate.NewEvaluableExpressionWithFunctions(str, functions)
+}
+
+// Makes sure function arguments are numbers and there is expected number
+// of them (-1 for any).
+func numeric(name string, least, most int, args []interface{}) ([]float64, error) {
+	if len(args) < least || (most >= 0 && len(args) > most) {
+		return nil, fmt.Errorf("%s() got %d argument(s)", name, len(args))
+	}
+
+	numbers := make([]float64, len(args))
+	for i, arg := range args {
+		number, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects numbers, got %v", name, arg)
+		}
+		numbers[i] = number
+	}
+
+	return numbers, nil
+}
+
+// Makes sure the function got exactly two string arguments.
+func textual(name string, args []interface{}) ([]string, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s() expects 2 arguments, got %d", name, len(args))
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects strings, got %v", name, arg)
+		}
+		strs[i] = str
+	}
+
+	return strs, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bufio`
+	`context`
+	`io`
+	`strings`
+	`sync`
+)
+
+// Feed reads the stock quotes as newline-delimited JSON, ex. piped to mop's
+// stdin by another program, so mop could display the quotes coming from
+// anywhere. Each line carries a quote, or an array of quotes, with the
+// same fields as the server-sent events (see sseQuote):
+//
+//   { "ticker": "AAPL", "last": 201.46, "change": 1.81, "volume": 27316739 }
+//
+// The tickers get added to the watchlist as they first arrive.
+type Feed struct {
+	latest *latestQuotes // Latest quote per ticker.
+	mutex  sync.Mutex    // Guards the error.
+	err    error         // Error reading the feed, if any.
+}
+
+// NewFeed starts reading the quotes from the given reader in the background
+// until it's exhausted.
+func NewFeed(reader io.Reader) *Feed {
+	feed := &Feed{latest: newLatestQuotes()}
+	go feed.read(reader)
+
+	return feed
+}
+
+// UseFeed makes the stock quotes come from the feed rather than the
+// provider for the current session only.
+func (profile *Profile) UseFeed(feed *Feed) *Profile {
+	profile.feed = feed
+	return profile
+}
+
+// Fetch returns the latest quotes read for the given tickers. The quotes
+// read last keep being returned after the feed has ended.
+func (feed *Feed) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	feed.mutex.Lock()
+	err := feed.err
+	feed.mutex.Unlock()
+
+	return feed.latest.pick(tickers), err
+}
+
+//-----------------------------------------------------------------------------
+func (feed *Feed) read(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != `` {
+			feed.latest.receive([]byte(line))
+		}
+	}
+
+	feed.mutex.Lock()
+	feed.err = scanner.Err()
+	feed.mutex.Unlock()
+}
+
+// Returns the watchlist with the tickers that have arrived over the feed
+// added at the end.
+//-----------------------------------------------------------------------------
+func (feed *Feed) watchlist(tickers []string) []string {
+	listed := make(map[string]bool)
+	for _, ticker := range tickers {
+		listed[strings.ToUpper(ticker)] = true
+	}
+
+	feed.latest.mutex.Lock()
+	defer feed.latest.mutex.Unlock()
+	for _, ticker := range feed.latest.tickers {
+		if !listed[ticker] {
+			tickers = append(tickers, ticker)
+		}
+	}
+	return tickers
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeed(t *testing.T) {
+	reader, writer := io.Pipe()
+	feed := NewFeed(reader)
+	profile := (&Profile{Tickers: []string{`IBM`}}).UseFeed(feed)
+	quotes := NewQuotes(NewMarket(), profile)
+
+	io.WriteString(writer, "{ \"ticker\": \"aapl\", \"last\": 201.46, \"prevClose\": 199.65 }\n\n")
+	io.WriteString(writer, `[ { "ticker": "MSFT", "last": 135.68 }, { "ticker": "IBM", "last": 139.2 } ]`+"\n")
+	io.WriteString(writer, "not json\n")
+	require.Eventually(t, func() bool {
+		stocks, _ := feed.Fetch(context.Background(), []string{`AAPL`, `MSFT`, `IBM`})
+		return len(stocks) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	quotes.Fetch()
+	ok, _ := quotes.Ok()
+	require.True(t, ok)
+	assert.Equal(t, []string{`IBM`, `AAPL`, `MSFT`}, profile.Tickers, `added as they arrive`)
+	require.Len(t, quotes.stocks, 3)
+	assert.Equal(t, `1.810`, quotes.stocks[1].Change.String())
+
+	writer.CloseWithError(errors.New(`broken pipe`))
+	require.Eventually(t, func() bool {
+		_, err := feed.Fetch(context.Background(), nil)
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	stocks, _ := feed.Fetch(context.Background(), []string{`AAPL`})
+	assert.Len(t, stocks, 1, `the quotes read last are kept`)
+
+	ended := NewFeed(strings.NewReader(`{ "ticker": "KO", "last": 51.2 }`))
+	require.Eventually(t, func() bool {
+		stocks, err := ended.Fetch(context.Background(), []string{`KO`})
+		return err == nil && len(stocks) == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -4,7 +4,10 @@
 
 package mop
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Filter gets called to sort stock quotes by one of the columns. The
 // setup is rather lengthy; there should probably be more concise way
@@ -20,43 +23,29 @@ func NewFilter(profile *Profile) *Filter {
 	}
 }
 
-// Apply builds a list of sort interface based on current sort
-// order, then calls sort.Sort to do the actual job.
-func (filter *Filter) Apply(stocks []Stock) []Stock {
+// Apply evaluates the filter expression for each stock and returns the
+// stocks for which the expression is true. If the expression fails or does
+// not return a boolean value Apply returns all the stocks along with the
+// error.
+func (filter *Filter) Apply(stocks []Stock) ([]Stock, error) {
 	var filteredStocks []Stock
 
 	for _, stock := range stocks {
-		var values = map[string]interface{}{
-			"ticker":        strings.TrimSpace(stock.Ticker),
-			"last":          m(stock.LastTrade),
-			"change":        c(stock.Change),
-			"changePercent": c(stock.ChangePct),
-			"open":          m(stock.Open),
-			"low":           m(stock.Low),
-			"high":          m(stock.High),
-			"low52":         m(stock.Low52),
-			"high52":        m(stock.High52),
-			"volume":        m(stock.Volume),
-			"avgVolume":     m(stock.AvgVolume),
-			"pe":            m(stock.PeRatio),
-			"peX":           m(stock.PeRatioX),
-			"dividend":      m(stock.Dividend),
-			"yield":         m(stock.Yield),
-			"mktCap":        m(stock.MarketCap),
-			"mktCapX":       m(stock.MarketCapX),
-			"advancing":     stock.Advancing,
+		values := variables(stock, filter.profile)
+		for i, column := range filter.profile.computed {
+			if i < len(stock.Computed) {
+				values[column.name] = column.value(stock.Computed[i])
+			}
 		}
 
 		result, err := filter.profile.filterExpression.Evaluate(values)
-
 		if err != nil {
-			panic(err)
+			return stocks, fmt.Errorf("Filter `%s` failed: %s", filter.profile.Filter, err)
 		}
 
 		truthy, ok := result.(bool)
-
 		if !ok {
-			panic("Expression `" + filter.profile.Filter + "` should return a boolean value")
+			return stocks, fmt.Errorf("Filter `%s` should return a boolean value", filter.profile.Filter)
 		}
 
 		if truthy {
@@ -64,5 +53,76 @@ func (filter *Filter) Apply(stocks []Stock) []Stock {
 		}
 	}
 
-	return filteredStocks
+	return filteredStocks, nil
+}
+
+// Returns stock properties available to the filter and computed columns
+// expressions. Note that govaluate only deals with float64 numbers.
+func variables(stock Stock, profile *Profile) map[string]interface{} {
+	shares, cost := profile.position(profile.Account, strings.TrimSpace(stock.Ticker))
+
+	return map[string]interface{}{
+		"ticker":        strings.TrimSpace(stock.Ticker),
+		"last":          stock.LastTrade.Value(),
+		"change":        stock.Change.Value(),
+		"changePercent": stock.ChangePct.Value(),
+		"open":          stock.Open.Value(),
+		"low":           stock.Low.Value(),
+		"high":          stock.High.Value(),
+		"low52":         stock.Low52.Value(),
+		"high52":        stock.High52.Value(),
+		"volume":        stock.Volume.Value(),
+		"avgVolume":     stock.AvgVolume.Value(),
+		"pe":            stock.PeRatio.Value(),
+		"peX":           stock.PeRatioX.Value(),
+		"dividend":      stock.Dividend.Value(),
+		"yield":         stock.Yield.Value(),
+		"mktCap":        stock.MarketCap.Value(),
+		"mktCapX":       stock.MarketCapX.Value(),
+		"prevClose":     stock.PrevClose.Value(),
+		"prePrice":      stock.PrePrice.Value(),
+		"preVolume":     stock.PreVolume.Value(),
+		"postPrice":     stock.PostPrice.Value(),
+		"postVolume":    stock.PostVolume.Value(),
+		"bid":           stock.Bid.Value(),
+		"ask":           stock.Ask.Value(),
+		"advancing":     stock.Advancing,
+		"newHigh":       stock.NewHigh,
+		"newLow":        stock.NewLow,
+		"quoteType":     stock.QuoteType,
+		"expenseRatio":  stock.ExpenseRatio.Value(),
+		"netAssets":     stock.NetAssets.Value(),
+		"sector":        stock.Sector,
+		"industry":      stock.Industry,
+		"country":       stock.Country,
+		"exchange":      stock.Exchange,
+		"rating":        stock.Rating,
+		"targetPrice":   stock.TargetPrice.Value(),
+		"volatility":    stock.Volatility.Value(),
+		"atr":           stock.ATR.Value(),
+		"sharesOut":     stock.SharesOut.Value(),
+		"floatShares":   stock.FloatShares.Value(),
+		"lowFloat":      isLowFloat(stock, profile),
+		"esg":           stock.ESG.Value(),
+		"esgEnv":        stock.ESGEnv.Value(),
+		"esgSocial":     stock.ESGSocial.Value(),
+		"esgGov":        stock.ESGGov.Value(),
+		"sessionChange": stock.SessionChange.Value(),
+		"trend":         stock.Trend,
+		"trendSlope":    stock.TrendSlope.Value(),
+		"shares":        shares,
+		"costBasis":     cost,
+	}
+}
+
+// Converts formatted value of computed column back to the type the filter
+// expression expects.
+func computedValue(str string) interface{} {
+	switch str = strings.TrimSpace(str); str {
+	case `yes`:
+		return true
+	case `no`:
+		return false
+	}
+	return float64(m(str))
 }
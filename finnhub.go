@@ -0,0 +1,272 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/url`
+	`strings`
+	`sync`
+)
+
+const finnhubURL = `https://finnhub.io/api/v1/quote?symbol=%s&token=%s`
+const finnhubStreamURL = `wss://ws.finnhub.io?token=%s`
+
+// Number of requests per minute allowed by Finnhub free API token.
+const finnhubLimit = 60
+
+// finnhub fetches stock quotes using Finnhub API. The daily quote of each
+// ticker is requested over REST within the rate limit, same as with Alpha
+// Vantage, while the last trade prices are streamed over the websocket as
+// they happen, so the watchlist stays current between the REST requests.
+type finnhub struct {
+	rateLimiter
+	url        string                  // Quote URL, with placeholders for the ticker and the API token.
+	streamURL  string                  // Websocket URL, with placeholder for the API token, blank to disable streaming.
+	token      string                  // Finnhub API token.
+	next       int                     // Index of the ticker to request next.
+	mutex      sync.Mutex              // Guards the streaming state below.
+	quotes     map[string]finnhubQuote // Latest REST quote per ticker.
+	trades     map[string]float64      // Latest streamed trade price per ticker.
+	stream     *websocket              // Open websocket connection, nil when not streaming.
+	subscribed map[string]bool         // Tickers subscribed to over the websocket.
+}
+
+// finnhubQuote is the quote as returned by Finnhub REST API.
+type finnhubQuote struct {
+	Current   float64 `json:"c"`
+	Change    float64 `json:"d"`
+	ChangePct float64 `json:"dp"`
+	High      float64 `json:"h"`
+	Low       float64 `json:"l"`
+	Open      float64 `json:"o"`
+	PrevClose float64 `json:"pc"`
+}
+
+// Returns new Finnhub provider for the given API token and the number of
+// requests per minute (0 for the free API token limit).
+func newFinnhub(token string, limit int) *finnhub {
+	if limit <= 0 {
+		limit = finnhubLimit
+	}
+	return &finnhub{
+		rateLimiter: newRateLimiter(limit),
+		url:         finnhubURL,
+		streamURL:   finnhubStreamURL,
+		token:       token,
+		quotes:      make(map[string]finnhubQuote),
+		trades:      make(map[string]float64),
+		subscribed:  make(map[string]bool),
+	}
+}
+
+// Fetch requests quotes of as many tickers as the rate limit allows and
+// returns the latest quotes of all the tickers requested so far, updated
+// with the streamed trade prices.
+func (hub *finnhub) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if hub.token == `` {
+		return nil, errors.New(`Finnhub API token is not set in the profile`)
+	}
+
+	// Streaming is best effort: if the websocket is not available the
+	// quotes still get updated over REST.
+	hub.subscribe(tickers)
+
+	var err error
+	requested := 0
+	for count := hub.budget(); count > 0 && requested < len(tickers); count-- {
+		if hub.next >= len(tickers) {
+			hub.next = 0
+		}
+		ticker := tickers[hub.next]
+		quote, e := hub.quote(ctx, ticker)
+		if e != nil {
+			err = e
+			break
+		}
+		hub.mutex.Lock()
+		hub.quotes[ticker] = quote
+		delete(hub.trades, ticker) // The quote is newer than the trades streamed so far.
+		hub.mutex.Unlock()
+		hub.next++
+		requested++
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if quote, ok := hub.quotes[ticker]; ok {
+			if price, ok := hub.trades[ticker]; ok {
+				quote = quote.at(price)
+			}
+			stocks = append(stocks, quote.stock(ticker))
+		}
+	}
+
+	return stocks, err
+}
+
+// Subscribes to the trades of the given tickers over the websocket, and
+// unsubscribes from the ones no longer needed. The websocket gets opened
+// on first use and reopened if the connection has been dropped.
+//-----------------------------------------------------------------------------
+func (hub *finnhub) subscribe(tickers []string) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.streamURL == `` {
+		return
+	}
+	if hub.stream == nil {
+		stream, err := dialWebsocket(fmt.Sprintf(hub.streamURL, url.QueryEscape(hub.token)))
+		if err != nil {
+			return
+		}
+		hub.stream, hub.subscribed = stream, make(map[string]bool)
+		go hub.listen(stream)
+	}
+
+	wanted := make(map[string]bool)
+	for _, ticker := range tickers {
+		wanted[ticker] = true
+		if !hub.subscribed[ticker] {
+			hub.send(`subscribe`, ticker)
+		}
+	}
+	for ticker := range hub.subscribed {
+		if !wanted[ticker] {
+			hub.send(`unsubscribe`, ticker)
+			delete(hub.trades, ticker)
+		}
+	}
+	hub.subscribed = wanted
+}
+
+//-----------------------------------------------------------------------------
+func (hub *finnhub) send(action, ticker string) {
+	message, _ := json.Marshal(map[string]string{`type`: action, `symbol`: ticker})
+	hub.stream.WriteText(message)
+}
+
+// Receives the trades until the connection is dropped, ex.
+//
+//   { "type": "trade", "data": [ { "s": "AAPL", "p": 201.46, "t": 1561730400000, "v": 100 } ] }
+//
+//-----------------------------------------------------------------------------
+func (hub *finnhub) listen(stream *websocket) {
+	for {
+		message, err := stream.ReadMessage()
+		if err != nil {
+			stream.Close()
+			hub.mutex.Lock()
+			if hub.stream == stream {
+				hub.stream = nil
+			}
+			hub.mutex.Unlock()
+			return
+		}
+
+		update := struct {
+			Type string
+			Data []struct {
+				Symbol string  `json:"s"`
+				Price  float64 `json:"p"`
+			}
+		}{}
+		if json.Unmarshal(message, &update) != nil || update.Type != `trade` {
+			continue // Pings and errors.
+		}
+		hub.mutex.Lock()
+		for _, trade := range update.Data { // Trades come in chronological order.
+			if hub.subscribed[trade.Symbol] {
+				hub.trades[trade.Symbol] = trade.Price
+			}
+		}
+		hub.mutex.Unlock()
+	}
+}
+
+//-----------------------------------------------------------------------------
+func (hub *finnhub) quote(ctx context.Context, ticker string) (finnhubQuote, error) {
+	hub.record()
+	response, err := get(ctx, fmt.Sprintf(hub.url, url.QueryEscape(ticker), url.QueryEscape(hub.token)))
+	if err != nil {
+		return finnhubQuote{}, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return finnhubQuote{}, err
+	}
+
+	return parseFinnhub(response.StatusCode, body)
+}
+
+// Parses Finnhub quote, ex.
+//
+//   { "c": 201.46, "d": 1.81, "dp": 0.9066, "h": 202, "l": 199.6, "o": 200, "pc": 199.65, "t": 1561730400 }
+//
+// Errors are reported as { "error": "Invalid API key" }, and unknown tickers
+// come back with all zeros.
+//-----------------------------------------------------------------------------
+func parseFinnhub(status int, body []byte) (finnhubQuote, error) {
+	failure := struct{ Error string }{}
+	json.Unmarshal(body, &failure)
+	if failure.Error != `` {
+		return finnhubQuote{}, errors.New(failure.Error)
+	}
+	if status != http.StatusOK {
+		return finnhubQuote{}, fmt.Errorf("Finnhub responded with %d: %s", status, strings.TrimSpace(string(body)))
+	}
+
+	quote := finnhubQuote{}
+	if err := json.Unmarshal(body, &quote); err != nil {
+		return finnhubQuote{}, err
+	}
+
+	return quote, nil
+}
+
+// Returns the quote updated with the last trade price.
+//-----------------------------------------------------------------------------
+func (quote finnhubQuote) at(price float64) finnhubQuote {
+	quote.Current, quote.Change = price, price-quote.PrevClose
+	if quote.PrevClose != 0 {
+		quote.ChangePct = quote.Change / quote.PrevClose * 100
+	}
+	if price > quote.High {
+		quote.High = price
+	}
+	if price < quote.Low || quote.Low == 0 {
+		quote.Low = price
+	}
+	return quote
+}
+
+//-----------------------------------------------------------------------------
+func (quote finnhubQuote) stock(ticker string) Stock {
+	if quote.Current == 0 && quote.PrevClose == 0 { // Unknown ticker.
+		return Stock{Ticker: ticker}
+	}
+	return Stock{
+		Ticker:    ticker,
+		LastTrade: numberOf(quote.Current),
+		Change:    numberOf(quote.Change),
+		ChangePct: numberOf(quote.ChangePct),
+		Open:      numberOf(quote.Open),
+		Low:       numberOf(quote.Low),
+		High:      numberOf(quote.High),
+		PrevClose: numberOf(quote.PrevClose),
+		Advancing: quote.Change >= 0,
+	}
+}
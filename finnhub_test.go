@@ -0,0 +1,100 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFinnhub(t *testing.T) {
+	quote, err := parseFinnhub(http.StatusOK, []byte(`{"c":201.46,"d":1.81,"dp":0.9066,"h":202,"l":199.6,"o":200,"pc":199.65,"t":1561730400}`))
+	require.NoError(t, err)
+
+	stock := quote.stock(`AAPL`)
+	assert.Equal(t, `201.460`, stock.LastTrade.String())
+	assert.Equal(t, `0.907`, stock.ChangePct.String())
+	assert.Equal(t, `199.650`, stock.PrevClose.String())
+	assert.True(t, stock.Advancing)
+
+	stock = quote.at(198.65).stock(`AAPL`)
+	assert.Equal(t, `198.650`, stock.LastTrade.String())
+	assert.Equal(t, `-1.000`, stock.Change.String())
+	assert.Equal(t, `-0.501`, stock.ChangePct.String())
+	assert.Equal(t, `198.650`, stock.Low.String())
+	assert.False(t, stock.Advancing)
+
+	assert.Equal(t, Stock{Ticker: `NOPE`}, finnhubQuote{}.stock(`NOPE`))
+
+	_, err = parseFinnhub(http.StatusUnauthorized, []byte(`{"error":"Invalid API key."}`))
+	assert.EqualError(t, err, `Invalid API key.`)
+	_, err = parseFinnhub(http.StatusTooManyRequests, []byte(`Too many requests`))
+	assert.EqualError(t, err, `Finnhub responded with 429: Too many requests`)
+}
+
+func TestFinnhubFetch(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get(`symbol`))
+		fmt.Fprint(w, `{"c":100,"d":1,"dp":1.0101,"h":101,"l":99,"o":99.5,"pc":99}`)
+	}))
+	defer server.Close()
+
+	subscribed, release := make(chan string, 2), make(chan bool)
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := upgradeWebsocket(w, r)
+		require.NoError(t, err)
+		defer client.Close()
+
+		for i := 0; i < 2; i++ {
+			message, _ := client.ReadMessage()
+			subscribed <- string(message)
+		}
+
+		<-release // Stream the trades once the quotes have been fetched.
+		trade := []byte(`{"type":"trade","data":[{"s":"AAPL","p":102.5},{"s":"AAPL","p":103}]}`)
+		client.WriteText(trade)
+		time.Sleep(time.Second)
+	}))
+	defer stream.Close()
+
+	now := time.Date(2019, 6, 28, 10, 0, 0, 0, time.UTC)
+	hub := newFinnhub(`secret`, 1)
+	hub.url, hub.now = server.URL+`?symbol=%s&token=%s`, func() time.Time { return now }
+	hub.streamURL = strings.Replace(stream.URL, `http://`, `ws://`, 1) + `?token=%s`
+
+	stocks, err := hub.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 1, `rate limit allows 1 request per minute`)
+	assert.Equal(t, `100.000`, stocks[0].LastTrade.String())
+	assert.Equal(t, `{"symbol":"AAPL","type":"subscribe"}`, <-subscribed)
+	assert.Equal(t, `{"symbol":"IBM","type":"subscribe"}`, <-subscribed)
+
+	close(release)
+
+	require.Eventually(t, func() bool {
+		stocks, _ = hub.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+		return stocks[0].LastTrade.String() == `103.000`
+	}, time.Second, 10*time.Millisecond, `streamed trades update the last price`)
+	assert.Equal(t, `4.000`, stocks[0].Change.String())
+	assert.Equal(t, `103.000`, stocks[0].High.String())
+
+	now = now.Add(time.Minute)
+	stocks, _ = hub.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	assert.Len(t, stocks, 2)
+	assert.Equal(t, []string{`AAPL`, `IBM`}, requested)
+
+	_, err = newFinnhub(``, 0).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
+
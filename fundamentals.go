@@ -0,0 +1,320 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`fmt`
+	`net/url`
+	`strings`
+	`sync`
+	`time`
+)
+
+const fundamentalsURL = `https://query2.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=%s`
+
+// How long to wait before trying again when fetching the fundamentals fails.
+const fundamentalsRetry = time.Hour
+
+// Stocks with fewer shares available for trading are flagged as low float
+// unless the profile says otherwise.
+const lowFloat = 20e6
+
+// Kinds of the fundamentals, the Yahoo module each of them comes from, and
+// how often they get refreshed: the sector and industry hardly ever change
+// so they are refreshed weekly, and the analyst ratings, the upcoming
+// earnings dates, the share counts, and the volatility daily. The
+// volatility is calculated from the chart of the daily prices rather than
+// the module. The ESG scores are fetched weekly, and only if the profile
+// asks for them.
+var fundamentalsSchedule = []struct {
+	kind     string        // Kind of the data, part of the cache key.
+	module   string        // Yahoo quote summary module with the data.
+	ttl      time.Duration // How long the data is good for.
+	optional bool          // True when the data is fetched only if the profile asks for it.
+}{
+	{`profile`, `assetProfile`, 7 * 24 * time.Hour, false},
+	{`analyst`, `financialData`, 24 * time.Hour, false},
+	{`earnings`, `calendarEvents`, 24 * time.Hour, false},
+	{`statistics`, `defaultKeyStatistics`, 24 * time.Hour, false},
+	{`history`, ``, 24 * time.Hour, false},
+	{`esg`, `esgScores`, 7 * 24 * time.Hour, true},
+}
+
+// fundamentals is the slowly changing data on the company that enriches its
+// stock quote. Each kind of data fills in its own fields only.
+type fundamentals struct {
+	Sector      string    // Sector, ex. "Technology".
+	Industry    string    // Industry, ex. "Consumer Electronics".
+	Country     string    // Country of the company's headquarters, ex. "United States".
+	Rating      string    // Analysts' consensus, ex. "buy".
+	TargetPrice float64   // Analysts' mean target price, 0 if none.
+	Earnings    int64     // Time of the upcoming earnings report, seconds since epoch, 0 if unknown.
+	Volatility  float64   // 20-day historical volatility, annualized, in percent, 0 if unknown.
+	ATR         float64   // 14-day average true range, 0 if unknown.
+	Closes      []float64 // Daily closing prices of the last month, the oldest first, nil if unknown.
+	Shares      float64   // Shares outstanding, 0 if unknown.
+	Float       float64   // Shares available for trading, 0 if unknown.
+	ESG         float64   // Total ESG risk score, 0 if unknown.
+	Environment float64   // Environment risk score, 0 if unknown.
+	Social      float64   // Social risk score, 0 if unknown.
+	Governance  float64   // Governance risk score, 0 if unknown.
+}
+
+// fundamentalsCache fetches the fundamentals in the background as they
+// expire, and keeps them in the cache saved next to the profile, ex.
+// ~/.moprc.cache, so they don't add to the API traffic on every refresh.
+type fundamentalsCache struct {
+	disk     *ttlCache                                                            // Fundamentals by kind and ticker, saved to the disk.
+	fetch    func(ticker string, kinds []string) (map[string]fundamentals, error) // Fetches the given kinds of the ticker's fundamentals.
+	optional map[string]bool                                                      // Optional kinds of the fundamentals the profile asks for.
+	mutex    sync.Mutex                                                           // Guards the refresh state below.
+	running  bool                                                                 // True while the refresh is under way.
+	retryAt  time.Time                                                            // No refresh until then after the last one failed.
+}
+
+// Returns the fundamentals cache saved in the given file, with the
+// fundamentals fetched from Yahoo.
+//-----------------------------------------------------------------------------
+func newFundamentalsCache(filename string) *fundamentalsCache {
+	session := newYahooSession()
+	return &fundamentalsCache{
+		disk: openCache(filename),
+		fetch: func(ticker string, kinds []string) (map[string]fundamentals, error) {
+			return fetchFundamentals(session, ticker, kinds)
+		},
+	}
+}
+
+// Starts fetching the expired fundamentals of the given tickers in the
+// background unless it's already under way, or the last attempt has failed
+// within the hour.
+//-----------------------------------------------------------------------------
+func (cache *fundamentalsCache) refresh(tickers []string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.running || cache.disk.now().Before(cache.retryAt) {
+		return
+	}
+
+	stale := make(map[string][]string)
+	for _, ticker := range tickers {
+		for _, schedule := range fundamentalsSchedule {
+			if schedule.optional && !cache.optional[schedule.kind] {
+				continue
+			}
+			if !cache.disk.fresh(fundamentalsKey(schedule.kind, ticker), schedule.ttl) {
+				stale[ticker] = append(stale[ticker], schedule.kind)
+			}
+		}
+	}
+	if len(stale) > 0 {
+		cache.running = true
+		go cache.update(stale)
+	}
+}
+
+// Fetches the given kinds of fundamentals per ticker, and saves the cache.
+//-----------------------------------------------------------------------------
+func (cache *fundamentalsCache) update(stale map[string][]string) {
+	var err error
+	for ticker, kinds := range stale {
+		var fetched map[string]fundamentals
+		if fetched, err = cache.fetch(ticker, kinds); err != nil {
+			break
+		}
+		for _, kind := range kinds {
+			cache.disk.put(fundamentalsKey(kind, ticker), fetched[kind])
+		}
+	}
+	if e := cache.disk.save(); err == nil {
+		err = e
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.running = false
+	if err != nil {
+		cache.retryAt = cache.disk.now().Add(fundamentalsRetry)
+	}
+}
+
+// Fills in the cached fundamentals of the stocks, including the expired
+// ones that are kept until they get refreshed. The earnings date reported
+// by the provider, if any, takes precedence. The optional fundamentals the
+// profile no longer asks for are left blank.
+//-----------------------------------------------------------------------------
+func (cache *fundamentalsCache) apply(stocks []Stock) {
+	for i := range stocks {
+		ticker := strings.TrimSpace(stocks[i].Ticker)
+		for _, schedule := range fundamentalsSchedule {
+			cached := fundamentals{}
+			if !schedule.optional || cache.optional[schedule.kind] {
+				cache.disk.get(fundamentalsKey(schedule.kind, ticker), schedule.ttl, &cached)
+			}
+			switch schedule.kind {
+			case `profile`:
+				stocks[i].Sector, stocks[i].Industry, stocks[i].Country = cached.Sector, cached.Industry, cached.Country
+			case `analyst`:
+				stocks[i].Rating, stocks[i].TargetPrice = cached.Rating, Number{}
+				if cached.TargetPrice > 0 {
+					stocks[i].TargetPrice = numberOf(cached.TargetPrice)
+				}
+			case `earnings`:
+				if stocks[i].Earnings == 0 && cached.Earnings > 0 {
+					stocks[i].Earnings = cached.Earnings
+				}
+			case `statistics`:
+				stocks[i].SharesOut, stocks[i].FloatShares = Number{}, Number{}
+				if cached.Shares > 0 {
+					stocks[i].SharesOut = numberOf(cached.Shares)
+				}
+				if cached.Float > 0 {
+					stocks[i].FloatShares = numberOf(cached.Float)
+				}
+			case `esg`:
+				stocks[i].ESG, stocks[i].ESGEnv, stocks[i].ESGSocial, stocks[i].ESGGov = Number{}, Number{}, Number{}, Number{}
+				if cached.ESG > 0 {
+					stocks[i].ESG = numberOf(cached.ESG)
+					stocks[i].ESGEnv = numberOf(cached.Environment)
+					stocks[i].ESGSocial = numberOf(cached.Social)
+					stocks[i].ESGGov = numberOf(cached.Governance)
+				}
+			case `history`:
+				stocks[i].Volatility, stocks[i].ATR = Number{}, Number{}
+				if cached.Volatility > 0 {
+					stocks[i].Volatility = numberOf(cached.Volatility)
+				}
+				if cached.ATR > 0 {
+					stocks[i].ATR = numberOf(cached.ATR)
+				}
+			}
+		}
+	}
+}
+
+// Returns the cached daily closing prices of the ticker, the oldest first,
+// including the expired ones, or nil if there are none.
+//-----------------------------------------------------------------------------
+func (cache *fundamentalsCache) closes(ticker string) []float64 {
+	cached := fundamentals{}
+	cache.disk.get(fundamentalsKey(`history`, strings.TrimSpace(ticker)), 0, &cached)
+
+	return cached.Closes
+}
+
+// Returns true if the stock's float is known and is below the threshold set
+// in the profile, or 20 million shares.
+//-----------------------------------------------------------------------------
+func isLowFloat(stock Stock, profile *Profile) bool {
+	threshold := profile.LowFloat
+	if threshold <= 0 {
+		threshold = lowFloat
+	}
+	return stock.FloatShares.Known() && stock.FloatShares.Value() < threshold
+}
+
+// Returns the cache key of the kind of the ticker's fundamentals.
+//-----------------------------------------------------------------------------
+func fundamentalsKey(kind, ticker string) string {
+	return kind + `:` + strings.ToUpper(ticker)
+}
+
+// Fetches the given kinds of the ticker's fundamentals from Yahoo. Unknown
+// tickers, ex. crypto pairs, have no fundamentals but that's not an error.
+//-----------------------------------------------------------------------------
+func fetchFundamentals(session *yahooSession, ticker string, kinds []string) (map[string]fundamentals, error) {
+	modules, history := []string{}, false
+	for _, schedule := range fundamentalsSchedule {
+		for _, kind := range kinds {
+			if kind == schedule.kind && schedule.module == `` {
+				history = true
+			} else if kind == schedule.kind {
+				modules = append(modules, schedule.module)
+			}
+		}
+	}
+
+	result := make(map[string]fundamentals)
+	if len(modules) > 0 {
+		body, err := session.Get(context.Background(), fmt.Sprintf(fundamentalsURL, url.PathEscape(ticker), strings.Join(modules, `,`)))
+		if err != nil {
+			return nil, err
+		}
+		if result, err = parseFundamentals(body); err != nil {
+			return nil, err
+		}
+	}
+	if history {
+		body, err := session.Get(context.Background(), fmt.Sprintf(historyURL, url.PathEscape(ticker)))
+		if err != nil {
+			return nil, err
+		}
+		if result[`history`], err = parseHistory(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Parses Yahoo quote summary into the fundamentals by kind.
+//-----------------------------------------------------------------------------
+func parseFundamentals(body []byte) (map[string]fundamentals, error) {
+	type raw struct {
+		Raw float64 `json:"raw"`
+	}
+	summary := struct {
+		QuoteSummary struct {
+			Result []struct {
+				AssetProfile struct {
+					Sector   string `json:"sector"`
+					Industry string `json:"industry"`
+					Country  string `json:"country"`
+				} `json:"assetProfile"`
+				FinancialData struct {
+					RecommendationKey string `json:"recommendationKey"`
+					TargetMeanPrice   raw    `json:"targetMeanPrice"`
+				} `json:"financialData"`
+				CalendarEvents struct {
+					Earnings struct {
+						EarningsDate []raw `json:"earningsDate"`
+					} `json:"earnings"`
+				} `json:"calendarEvents"`
+				DefaultKeyStatistics *struct {
+					SharesOutstanding raw `json:"sharesOutstanding"`
+					FloatShares       raw `json:"floatShares"`
+				} `json:"defaultKeyStatistics"`
+				EsgScores *struct {
+					TotalEsg         raw `json:"totalEsg"`
+					EnvironmentScore raw `json:"environmentScore"`
+					SocialScore      raw `json:"socialScore"`
+					GovernanceScore  raw `json:"governanceScore"`
+				} `json:"esgScores"`
+			} `json:"result"`
+		} `json:"quoteSummary"`
+	}{}
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]fundamentals)
+	for _, found := range summary.QuoteSummary.Result {
+		result[`profile`] = fundamentals{Sector: found.AssetProfile.Sector, Industry: found.AssetProfile.Industry, Country: found.AssetProfile.Country}
+		result[`analyst`] = fundamentals{Rating: found.FinancialData.RecommendationKey, TargetPrice: found.FinancialData.TargetMeanPrice.Raw}
+		if dates := found.CalendarEvents.Earnings.EarningsDate; len(dates) > 0 {
+			result[`earnings`] = fundamentals{Earnings: int64(dates[0].Raw)}
+		}
+		if statistics := found.DefaultKeyStatistics; statistics != nil {
+			result[`statistics`] = fundamentals{Shares: statistics.SharesOutstanding.Raw, Float: statistics.FloatShares.Raw}
+		}
+		if scores := found.EsgScores; scores != nil {
+			result[`esg`] = fundamentals{ESG: scores.TotalEsg.Raw, Environment: scores.EnvironmentScore.Raw, Social: scores.SocialScore.Raw, Governance: scores.GovernanceScore.Raw}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fundamentalsSample = `{"quoteSummary":{"result":[{
+	"assetProfile":{"sector":"Technology","industry":"Consumer Electronics"},
+	"financialData":{"recommendationKey":"buy","targetMeanPrice":{"raw":230.5,"fmt":"230.50"}},
+	"calendarEvents":{"earnings":{"earningsDate":[{"raw":1559851200,"fmt":"2019-06-06"}]}},
+	"defaultKeyStatistics":{"sharesOutstanding":{"raw":4601080000},"floatShares":{"raw":4598630000}},
+	"esgScores":{"totalEsg":{"raw":16.68},"environmentScore":{"raw":0.41},"socialScore":{"raw":7.18},"governanceScore":{"raw":9.09}}
+}],"error":null}}`
+
+func TestParseFundamentals(t *testing.T) {
+	parsed, err := parseFundamentals([]byte(fundamentalsSample))
+	require.NoError(t, err)
+	assert.Equal(t, fundamentals{Sector: `Technology`, Industry: `Consumer Electronics`}, parsed[`profile`])
+	assert.Equal(t, fundamentals{Rating: `buy`, TargetPrice: 230.5}, parsed[`analyst`])
+	assert.Equal(t, fundamentals{Earnings: 1559851200}, parsed[`earnings`])
+	assert.Equal(t, fundamentals{Shares: 4601080000, Float: 4598630000}, parsed[`statistics`])
+	assert.Equal(t, fundamentals{ESG: 16.68, Environment: 0.41, Social: 7.18, Governance: 9.09}, parsed[`esg`])
+
+	parsed, err = parseFundamentals([]byte(`{"quoteSummary":{"result":null,"error":{"code":"Not Found"}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, parsed)
+}
+
+// Returns the fundamentals cache with the fetches recorded per ticker, and
+// the clock moved by the test.
+func testFundamentalsCache(t *testing.T, now *time.Time, fail *bool) (*fundamentalsCache, func() map[string][]string) {
+	mutex, fetched := sync.Mutex{}, map[string][]string{}
+	cache := &fundamentalsCache{
+		disk: openCache(filepath.Join(t.TempDir(), `cache`)),
+		fetch: func(ticker string, kinds []string) (map[string]fundamentals, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if *fail {
+				return nil, errors.New(`Yahoo is down`)
+			}
+			fetched[ticker] = append(fetched[ticker], kinds...)
+			result, err := parseFundamentals([]byte(fundamentalsSample))
+			result[`history`] = fundamentals{Volatility: 24.5, ATR: 3.25}
+			return result, err
+		},
+	}
+	cache.disk.now = func() time.Time { return *now }
+
+	return cache, func() map[string][]string {
+		for !func() bool { cache.mutex.Lock(); defer cache.mutex.Unlock(); return !cache.running }() {
+			time.Sleep(time.Millisecond)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		result := fetched
+		fetched = map[string][]string{}
+		for _, kinds := range result {
+			sort.Strings(kinds)
+		}
+		return result
+	}
+}
+
+func TestFundamentalsRefreshSchedule(t *testing.T) {
+	now, fail := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC), false
+	cache, fetches := testFundamentalsCache(t, &now, &fail)
+
+	cache.refresh([]string{`AAPL`})
+	assert.Equal(t, map[string][]string{`AAPL`: {`analyst`, `earnings`, `history`, `profile`, `statistics`}}, fetches())
+
+	cache.refresh([]string{`AAPL`})
+	assert.Empty(t, fetches(), `nothing expired yet`)
+
+	now = now.Add(25 * time.Hour)
+	cache.refresh([]string{`AAPL`})
+	assert.Equal(t, map[string][]string{`AAPL`: {`analyst`, `earnings`, `history`, `statistics`}}, fetches(), `daily ones only`)
+
+	now = now.Add(7 * 24 * time.Hour)
+	cache.refresh([]string{`AAPL`})
+	assert.Equal(t, map[string][]string{`AAPL`: {`analyst`, `earnings`, `history`, `profile`, `statistics`}}, fetches())
+}
+
+func TestOptionalFundamentals(t *testing.T) {
+	now, fail := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC), false
+	cache, fetches := testFundamentalsCache(t, &now, &fail)
+	cache.optional = map[string]bool{`esg`: true}
+
+	cache.refresh([]string{`AAPL`})
+	assert.Equal(t, map[string][]string{`AAPL`: {`analyst`, `earnings`, `esg`, `history`, `profile`, `statistics`}}, fetches())
+	stocks := []Stock{{Ticker: `AAPL`}}
+	cache.apply(stocks)
+	assert.Equal(t, `16.680`, stocks[0].ESG.String())
+	assert.Equal(t, `9.090`, stocks[0].ESGGov.String())
+
+	cache.optional = nil
+	cache.apply(stocks)
+	assert.False(t, stocks[0].ESG.Known(), `no longer asked for`)
+}
+
+func TestFundamentalsRetryAfterFailure(t *testing.T) {
+	now, fail := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC), true
+	cache, fetches := testFundamentalsCache(t, &now, &fail)
+
+	cache.refresh([]string{`AAPL`})
+	fetches()
+	fail = false
+	cache.refresh([]string{`AAPL`})
+	assert.Empty(t, fetches(), `waits before trying again`)
+
+	now = now.Add(fundamentalsRetry)
+	cache.refresh([]string{`AAPL`})
+	assert.Len(t, fetches()[`AAPL`], 5)
+}
+
+func TestFundamentalsApply(t *testing.T) {
+	now, fail := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC), false
+	cache, fetches := testFundamentalsCache(t, &now, &fail)
+	cache.refresh([]string{`AAPL`})
+	fetches()
+
+	now = now.Add(30 * 24 * time.Hour) // Expired values are shown until refreshed.
+	stocks := []Stock{{Ticker: `AAPL`}, {Ticker: `IBM`}, {Ticker: `MSFT`, Earnings: 1560000000}}
+	cache.apply(stocks)
+	assert.Equal(t, `Technology`, stocks[0].Sector)
+	assert.Equal(t, `Consumer Electronics`, stocks[0].Industry)
+	assert.Equal(t, `buy`, stocks[0].Rating)
+	assert.Equal(t, `230.500`, stocks[0].TargetPrice.String())
+	assert.Equal(t, int64(1559851200), stocks[0].Earnings)
+	assert.Equal(t, `24.500`, stocks[0].Volatility.String())
+	assert.Equal(t, `3.250`, stocks[0].ATR.String())
+	assert.Equal(t, `4.601B`, stocks[0].SharesOut.String())
+	assert.Equal(t, `4.599B`, stocks[0].FloatShares.String())
+	assert.Equal(t, Stock{Ticker: `IBM`}, stocks[1])
+	assert.Equal(t, int64(1560000000), stocks[2].Earnings, `provider's earnings date takes precedence`)
+}
+
+func TestLowFloat(t *testing.T) {
+	profile := &Profile{}
+	assert.True(t, isLowFloat(Stock{FloatShares: numberOf(12e6)}, profile))
+	assert.False(t, isLowFloat(Stock{FloatShares: numberOf(4.6e9)}, profile))
+	assert.False(t, isLowFloat(Stock{}, profile), `unknown float`)
+
+	profile.LowFloat = 5e6
+	assert.False(t, isLowFloat(Stock{FloatShares: numberOf(12e6)}, profile))
+	assert.Equal(t, true, variables(Stock{FloatShares: numberOf(3e6)}, profile)[`lowFloat`])
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`strings`
+)
+
+// Number of screen rows taken by the change distribution histogram: the
+// title, two rows of bars, and the labels.
+const histogramRows = 4
+
+// Upper bounds of the histogram bins, in percent. The last bin collects
+// everything above the last bound.
+var histogramBins = []float64{-3, -2, -1, 0, 1, 2, 3}
+
+// Block characters used to draw the bars, from blank to full height.
+var histogramBlocks = []rune(` ▁▂▃▄▅▆▇█`)
+
+// Histogram formats the distribution of today's percent changes across
+// the watchlist as a small bar chart that shows at a glance whether the
+// moves are broad or concentrated in a few stocks.
+func (layout *Layout) Histogram(quotes *Quotes) string {
+	counts, top := distribution(quotes), 0
+	for _, count := range counts {
+		if count > top {
+			top = count
+		}
+	}
+
+	upper, lower, labels := ``, ``, ``
+	for i, count := range counts {
+		height := 0 // Bar height in eighths of the row, two rows total.
+		if top > 0 {
+			height = (count*16 + top - 1) / top
+		}
+		color := `green`
+		if i < len(histogramBins) && histogramBins[i] <= 0 {
+			color = `red`
+		}
+
+		block := histogramBlocks[0]
+		if height > 8 {
+			block = histogramBlocks[height-8]
+		}
+		upper += fmt.Sprintf(`  <%s>%s</>  `, color, strings.Repeat(string(block), 4))
+		if height > 8 {
+			height = 8
+		}
+		lower += fmt.Sprintf(`  <%s>%s</>  `, color, strings.Repeat(string(histogramBlocks[height]), 4))
+		labels += fmt.Sprintf(`%-8s`, fmt.Sprintf(`%s (%d)`, binLabel(i), count))
+	}
+
+	return "<u>Change% distribution</u>\n" + upper + "\n" + lower + "\n" + labels
+}
+
+// Returns the number of stocks within each histogram bin.
+func distribution(quotes *Quotes) []int {
+	counts := make([]int, len(histogramBins)+1)
+	for _, stock := range quotes.stocks {
+		change, bin := stock.ChangePct.Value(), len(histogramBins)
+		for i, bound := range histogramBins {
+			if change < bound {
+				bin = i
+				break
+			}
+		}
+		counts[bin]++
+	}
+
+	return counts
+}
+
+// Returns the label of the given histogram bin, i.e. its lower bound.
+func binLabel(bin int) string {
+	switch bin {
+	case 0:
+		return fmt.Sprintf(`<%g`, histogramBins[0])
+	case len(histogramBins):
+		return fmt.Sprintf(`>%g`, histogramBins[bin-1])
+	}
+	return fmt.Sprintf(`%+g`, histogramBins[bin-1])
+}
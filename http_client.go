@@ -0,0 +1,80 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`crypto/tls`
+	`fmt`
+	`io/ioutil`
+	`net`
+	`net/http`
+	`time`
+)
+
+// Time limits of the outgoing requests, so a slow or stalled endpoint can't
+// hang the fetch indefinitely.
+const (
+	dialTimeout     = 10 * time.Second // Connecting to the host.
+	tlsTimeout      = 10 * time.Second // TLS handshake.
+	responseTimeout = 20 * time.Second // Waiting for the response headers once the request is sent.
+	requestTimeout  = 30 * time.Second // Whole request, including reading the response body.
+	downloadTimeout = 5 * time.Minute  // Downloading the release binary.
+	idleTimeout     = 90 * time.Second // Keeping the idle connection open for reuse.
+)
+
+// transport keeps the connections to the providers alive between the
+// refreshes, so each refresh doesn't pay for the TCP and TLS handshakes.
+var transport = &http.Transport{
+	Proxy:                 proxyFor,
+	DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+	TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
+	TLSHandshakeTimeout:   tlsTimeout,
+	ResponseHeaderTimeout: responseTimeout,
+	IdleConnTimeout:       idleTimeout,
+	MaxIdleConnsPerHost:   4,
+	ForceAttemptHTTP2:     true,
+}
+
+// client makes all the requests to the providers. Its requests are made
+// conditional, go through the throttle, and share the transport's
+// connection pool.
+var client = newClient(requestTimeout)
+
+// Returns new client that gives up on the request after the given time,
+// zero for the requests that never finish, ex. the event stream.
+//-----------------------------------------------------------------------------
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: conditional, Timeout: timeout}
+}
+
+// Requests the URL with the shared client. The request is abandoned as soon
+// as the context is cancelled.
+//-----------------------------------------------------------------------------
+func get(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, `GET`, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(request)
+}
+
+// Requests the URL with the shared client and returns the response body,
+// or the error if the server responds with anything but success, ex. the
+// error page that would otherwise be taken for the data.
+//-----------------------------------------------------------------------------
+func getBody(ctx context.Context, url string) ([]byte, error) {
+	response, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s responded with %s", response.Request.URL.Host, response.Status)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTransports(t *testing.T) {
+	assert.Equal(t, conditional, client.Transport)
+	assert.Equal(t, outgoing, conditional.next)
+	assert.Equal(t, requestTimeout, client.Timeout)
+	assert.NotNil(t, transport.Proxy)
+}
+
+func TestClientTimesOutStalledResponse(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`partial`))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	response, err := newClient(100 * time.Millisecond).Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	_, err = ioutil.ReadAll(response.Body)
+	assert.Error(t, err)
+}
+
+func TestGetBodyStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == `/down` {
+			http.Error(w, `<html>Service Unavailable</html>`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`data`))
+	}))
+	defer server.Close()
+
+	body, err := getBody(context.Background(), server.URL+`/up`)
+	require.NoError(t, err)
+	assert.Equal(t, `data`, string(body))
+
+	body, err = getBody(context.Background(), server.URL+`/down`)
+	assert.Nil(t, body, `the error page is not the data`)
+	assert.EqualError(t, err, strings.TrimPrefix(server.URL, `http://`)+` responded with 503 Service Unavailable`)
+}
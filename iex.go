@@ -0,0 +1,112 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/url`
+	`strings`
+)
+
+const iexURL = `https://cloud.iexapis.com/stable/stock/market/batch?types=quote&symbols=%s&token=%s`
+
+// Maximum number of tickers IEX Cloud accepts in one batch request.
+const iexBatchSize = 100
+
+// iex fetches stock quotes using IEX Cloud batch API.
+type iex struct {
+	url   string // Batch URL, with placeholders for the tickers and the API token.
+	token string // IEX Cloud API token.
+}
+
+// Returns new IEX Cloud provider for the given API token.
+func newIEX(token string) *iex {
+	return &iex{url: iexURL, token: token}
+}
+
+// Fetch requests the quotes in batches of up to 100 tickers.
+func (iex *iex) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if iex.token == `` {
+		return nil, errors.New(`IEX Cloud API token is not set in the profile`)
+	}
+
+	stocks := []Stock{}
+	for _, batch := range batches(tickers, iexBatchSize) {
+		response, err := get(ctx, fmt.Sprintf(iex.url, url.QueryEscape(strings.Join(batch, `,`)), url.QueryEscape(iex.token)))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode != http.StatusOK { // IEX reports errors as plain text.
+			return nil, fmt.Errorf("IEX Cloud responded with %s: %s", response.Status, strings.TrimSpace(string(body)))
+		}
+
+		parsed, err := parseIEX(body, batch)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, parsed...)
+	}
+
+	return stocks, nil
+}
+
+// Parses IEX Cloud batch response, ex.
+//
+//   { "AAPL": { "quote": { "symbol": "AAPL", "latestPrice": 200.12, ... } } }
+//
+// The quotes are returned in the order of the given tickers; the tickers
+// IEX knows nothing about are skipped.
+//-----------------------------------------------------------------------------
+func parseIEX(body []byte, tickers []string) ([]Stock, error) {
+	data := map[string]map[string]map[string]interface{}{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		quote, ok := data[strings.ToUpper(ticker)][`quote`]
+		if !ok {
+			continue
+		}
+		number := func(key string) Number {
+			if value, ok := quote[key].(float64); ok {
+				return numberOf(value)
+			}
+			return Number{}
+		}
+		stock := Stock{
+			Ticker:    fmt.Sprintf(`%v`, quote[`symbol`]),
+			LastTrade: number(`latestPrice`),
+			Change:    number(`change`),
+			ChangePct: percentage(quote[`changePercent`]),
+			Open:      number(`open`),
+			Low:       number(`low`),
+			High:      number(`high`),
+			Low52:     number(`week52Low`),
+			High52:    number(`week52High`),
+			Volume:    number(`latestVolume`),
+			AvgVolume: number(`avgTotalVolume`),
+			PeRatio:   number(`peRatio`),
+			MarketCap: number(`marketCap`),
+			PrevClose: number(`previousClose`),
+		}
+		stock.PeRatioX, stock.MarketCapX = stock.PeRatio, stock.MarketCap
+		stock.Advancing = stock.Change.Value() >= 0
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const iexBatch = `{
+  "AAPL": { "quote": { "symbol": "AAPL", "latestPrice": 201.5, "change": 2.25, "changePercent": 0.01129,
+    "open": 199.8, "low": 199.1, "high": 202.05, "week52Low": 142, "week52High": 233.47,
+    "latestVolume": 31250000, "avgTotalVolume": 27800000, "peRatio": 16.9, "marketCap": 927000000000,
+    "previousClose": 199.25 } },
+  "KO": { "quote": { "symbol": "KO", "latestPrice": 47.1, "change": -0.3, "changePercent": -0.00633,
+    "open": null, "peRatio": null, "previousClose": 47.4 } }
+}`
+
+func TestParseIEX(t *testing.T) {
+	stocks, err := parseIEX([]byte(iexBatch), []string{`KO`, `aapl`, `NOPE`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 2)
+
+	assert.Equal(t, `KO`, stocks[0].Ticker)
+	assert.Equal(t, `-0.633`, stocks[0].ChangePct.String())
+	assert.Equal(t, ``, stocks[0].Open.String())
+	assert.Equal(t, ``, stocks[0].PeRatio.String())
+	assert.False(t, stocks[0].Advancing)
+
+	aapl := stocks[1]
+	assert.Equal(t, `AAPL`, aapl.Ticker)
+	assert.Equal(t, `201.500`, aapl.LastTrade.String())
+	assert.Equal(t, `1.129`, aapl.ChangePct.String())
+	assert.Equal(t, `233.470`, aapl.High52.String())
+	assert.Equal(t, `31.250M`, aapl.Volume.String())
+	assert.Equal(t, `927.000B`, aapl.MarketCap.String())
+	assert.Equal(t, aapl.MarketCap, aapl.MarketCapX)
+	assert.True(t, aapl.Advancing)
+}
+
+func TestIEXFetch(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.URL.Query().Get(`token`) != `secret` {
+			http.Error(w, `Forbidden`, http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, iexBatch)
+	}))
+	defer server.Close()
+
+	provider := newIEX(`secret`)
+	provider.url = server.URL + `?symbols=%s&token=%s`
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `KO`})
+	require.NoError(t, err)
+	assert.Len(t, stocks, 2)
+	assert.Contains(t, query, `symbols=AAPL%2CKO`)
+
+	provider.token = `wrong`
+	_, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	assert.EqualError(t, err, `IEX Cloud responded with 403 Forbidden: Forbidden`)
+
+	_, err = newIEX(``).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
+
+func TestUseSource(t *testing.T) {
+	profile := &Profile{Provider: `alphavantage`}
+	assert.IsType(t, &alphaVantage{}, profile.provider().(*withBinance).stocks.(*quoteCache).provider)
+	assert.IsType(t, &iex{}, profile.UseSource(`iex`).provider().(*withBinance).stocks.(*quoteCache).provider)
+	assert.Equal(t, `alphavantage`, profile.Provider)
+}
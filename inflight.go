@@ -0,0 +1,42 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`sync`
+)
+
+// inflight keeps the cancel function of the fetch under way, so the fetch
+// could be cancelled by the event that makes it moot (ex. quitting or the
+// change of the tickers) even if the event comes from another goroutine.
+// The zero value is ready to use.
+type inflight struct {
+	mutex  sync.Mutex         // Guards the cancel function.
+	cancel context.CancelFunc // Cancels the fetch under way, nil if there is none.
+}
+
+// Returns the context of the fetch that is about to begin, derived from the
+// given one. The fetch must call done once it's over.
+//-----------------------------------------------------------------------------
+func (inflight *inflight) begin(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	inflight.mutex.Lock()
+	inflight.cancel = cancel
+	inflight.mutex.Unlock()
+
+	return ctx
+}
+
+// Cancels the fetch under way, if any. It's also how the fetch is done.
+//-----------------------------------------------------------------------------
+func (inflight *inflight) done() {
+	inflight.mutex.Lock()
+	defer inflight.mutex.Unlock()
+	if inflight.cancel != nil {
+		inflight.cancel()
+		inflight.cancel = nil
+	}
+}
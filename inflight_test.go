@@ -0,0 +1,64 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stalledProvider never returns the quotes, only gives up when the fetch
+// gets cancelled.
+type stalledProvider struct {
+	started chan bool
+}
+
+func (provider *stalledProvider) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	provider.started <- true
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestInflightDone(t *testing.T) {
+	var fetch inflight
+	fetch.done() // Nothing under way.
+
+	ctx := fetch.begin(context.Background())
+	assert.NoError(t, ctx.Err())
+	fetch.done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Nil(t, fetch.cancel)
+}
+
+func TestQuotesCancel(t *testing.T) {
+	provider := &stalledProvider{started: make(chan bool)}
+	store := NewQuoteStore(provider)
+	profile := &Profile{Tickers: []string{`AAPL`}}
+	quotes := &Quotes{market: &Market{}, profile: profile, store: store, subscription: store.Subscribe(profile.Tickers)}
+
+	go func() {
+		<-provider.started
+		quotes.Cancel()
+	}()
+	quotes.Fetch()
+	ok, _ := quotes.Ok()
+	assert.True(t, ok, `cancelled fetch is not an error`)
+	assert.Empty(t, quotes.stocks)
+}
+
+func TestQuotesFetchContext(t *testing.T) {
+	provider := &stalledProvider{started: make(chan bool, 1)}
+	store := NewQuoteStore(provider)
+	profile := &Profile{Tickers: []string{`AAPL`}}
+	quotes := &Quotes{market: &Market{}, profile: profile, store: store, subscription: store.Subscribe(profile.Tickers)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	quotes.FetchContext(ctx)
+	ok, _ := quotes.Ok()
+	assert.True(t, ok)
+}
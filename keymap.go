@@ -0,0 +1,149 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`strings`
+)
+
+// Screen modes, each with its own set of keyboard commands.
+const (
+	NormalMode       = `normal`
+	LineEditorMode   = `line editor`
+	ColumnEditorMode = `column editor`
+	HelpMode         = `help`
+	DialogMode       = `dialog`
+	InputMode        = `input`
+	PickerMode       = `picker`
+	SelectionMode    = `selection`
+	PanesMode        = `panes`
+)
+
+// Key describes keyboard command as it appears on the help screen and in
+// the footer hint bar.
+type Key struct {
+	Name        string // Key name as displayed to the user, ex. `+` or `esc`.
+	Hint        string // Short hint for the footer (blank to leave it out).
+	Description string // Full description for the help screen.
+}
+
+// Keymap lists keyboard commands available in each screen mode. The most
+// relevant commands go first since the footer gets truncated to fit the
+// screen width.
+var Keymap = map[string][]Key{
+	NormalMode: {
+		{`?`, `Help`, `Display this help screen.`},
+		{`q`, `Quit`, `Quit mop.`},
+		{`esc`, ``, `Ditto.`},
+		{`+`, `Add`, `Add stocks to the list.`},
+		{`-`, `Remove`, `Remove stocks from the list.`},
+		{`f`, `Filter`, `Set filtering expression.`},
+		{`F`, ``, `Unset filtering expression.`},
+		{`r`, `Ref`, `Change reference price (previous close, open, anchor).`},
+		{`a`, ``, `Set anchor price for the ticker.`},
+		{`o`, `Sort`, `Change column sort order.`},
+		{`s`, ``, `Pick sort column by name.`},
+		{`v`, `Select`, `Select rows for bulk actions (remove, export, compare, copy).`},
+		{`e`, ``, `Export the stocks as displayed to CSV file.`},
+		{`j`, ``, `Export the quotes and the market to JSON file.`},
+		{`!`, ``, `Add alert rule.`},
+		{`g`, `Group`, `Group stocks by advancing/declining issues.`},
+		{`i`, `Stats`, `Display watchlist statistics.`},
+		{`m`, `Overview`, `Display market overview dashboard.`},
+		{`A`, ``, `Pick the account the portfolio is shown for.`},
+		{`L`, ``, `Pick the watchlist the stock quotes are shown for.`},
+		{`*`, ``, `Start or stop recording the keystrokes as macro.`},
+		{`@`, ``, `Pick the macro to replay.`},
+		{`b`, ``, `Toggle change distribution histogram.`},
+		{`p`, `Pause`, `Pause market data and stock updates.`},
+		{`d`, `Density`, `Cycle layout density (normal, compact, comfortable).`},
+	},
+	LineEditorMode: {
+		{`enter`, `Done`, `Process the input.`},
+		{`esc`, `Cancel`, `Discard the input.`},
+		{`←→`, `Move`, `Move the cursor.`},
+		{`^A`, `Start`, `Jump to the beginning of the line.`},
+		{`^E`, `End`, `Jump to the end of the line.`},
+	},
+	ColumnEditorMode: {
+		{`←→`, `Column`, `Select another column.`},
+		{`enter`, `Reverse`, `Sort by selected column or reverse sort order.`},
+		{`esc`, `Done`, `Exit the column editor.`},
+	},
+	SelectionMode: {
+		{`space`, `Mark`, `Mark or unmark the row under the cursor.`},
+		{`↑↓`, `Move`, `Move the cursor.`},
+		{`a`, `All`, `Mark or unmark all the rows.`},
+		{`-`, `Remove`, `Remove marked stocks from the list.`},
+		{`e`, `Export`, `Export marked stocks to CSV file.`},
+		{`c`, `Compare`, `Compare 2 to 5 marked stocks side by side.`},
+		{`y`, `Copy`, `Copy ticker and price of marked stocks to clipboard.`},
+		{`enter`, ``, `List constituents of the index under the cursor.`},
+		{`esc`, `Done`, `Exit bulk edit mode.`},
+	},
+	DialogMode: {
+		{`enter`, `Choose`, `Choose selected button.`},
+		{`y`, `OK`, `Confirm.`},
+		{`n`, `Cancel`, `Cancel.`},
+		{`←→`, `Select`, `Select another button.`},
+	},
+	InputMode: {
+		{`enter`, `OK`, `Accept the text.`},
+		{`esc`, `Cancel`, `Close the dialog.`},
+		{`abc`, `Type`, `Edit the text.`},
+	},
+	PickerMode: {
+		{`enter`, `Pick`, `Pick selected item.`},
+		{`esc`, `Cancel`, `Close the picker.`},
+		{`↑↓`, `Select`, `Select another item.`},
+		{`abc`, `Search`, `Narrow down the list.`},
+	},
+	PanesMode: {
+		{`tab`, `Focus`, `Focus the next pane.`},
+		{`↑↓`, `Scroll`, `Scroll the focused pane.`},
+		{`<>`, `Width`, `Make the focused pane narrower or wider.`},
+		{`+-`, `Height`, `Make the focused pane shorter or taller.`},
+		{`any`, `Continue`, `Return to the stock quotes.`},
+	},
+	HelpMode: { // Also used by the watchlist statistics screen.
+		{`any`, `Continue`, `Return to the stock quotes.`},
+	},
+}
+
+// Commands formats keyboard commands of the given mode as a table for the
+// help screen.
+func Commands(mode string) string {
+	lines := []string{}
+	for _, key := range Keymap[mode] {
+		left := (7 - len([]rune(key.Name))) / 2 // Center key name under the `Command`.
+		if left < 0 {
+			left = 0
+		}
+		lines = append(lines, fmt.Sprintf(`%*s%-*s%s`, left, ``, 11-left, key.Name, key.Description))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Hints formats keyboard commands of the given mode for the footer hint
+// bar, i.e. `<r> + </r> Add <r> - </r> Remove ...`, making sure the text
+// fits the given width.
+func Hints(mode string, width int) string {
+	str, length := ``, 0
+	for _, key := range Keymap[mode] {
+		if key.Hint == `` {
+			continue
+		}
+		hint := ` ` + key.Name + `  ` + key.Hint + ` `
+		if length+len([]rune(hint)) > width {
+			break
+		}
+		str += `<r> ` + key.Name + ` </r> ` + key.Hint + ` `
+		length += len([]rune(hint))
+	}
+
+	return str
+}
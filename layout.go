@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -27,6 +28,7 @@ type Column struct {
 	width     int                    // Column width.
 	name      string                 // The name of the field in the Stock struct.
 	title     string                 // Column title to display in the header.
+	brief     string                 // Shortened column title for the compact layout.
 	formatter func(...string) string // Optional function to format the contents of the column.
 }
 
@@ -39,33 +41,93 @@ type Layout struct {
 	regex          *regexp.Regexp     // Pointer to regular expression to align decimal points.
 	marketTemplate *template.Template // Pointer to template to format market data.
 	quotesTemplate *template.Template // Pointer to template to format the list of stock quotes.
+	cardsTemplate  *template.Template // Pointer to template to format stock quotes as cards.
+	statsTemplate  *template.Template // Pointer to template to format watchlist statistics.
+	types          map[string]bool    // Quote types of the stocks displayed last, ex. EQUITY or ETF.
+}
+
+// card is the stock quote formatted for the card view, i.e. the values of
+// the columns the card displays without the column padding cards have no
+// room for.
+type card struct {
+	Ticker    string // Ticker, ex. "AAPL".
+	LastTrade string // Last trade, ex. "$201.46".
+	Change    string // Change, ex. "+$1.25".
+	ChangePct string // Change percent, ex. "+0.62%".
+	Open      string // Open price.
+	Low       string // Day's low.
+	High      string // Day's high.
+	Volume    string // Volume, ex. "27.317M".
+	Low52     string // 52-weeks low.
+	High52    string // 52-weeks high.
+	MarketCap string // Market cap, ex. "$927.32B".
+	Advancing bool   // True when change is >= $0.
+	Error     string // Why the ticker has failed, if it has.
+}
+
+// Titles and brief titles of the Change and Change% columns when the change
+// is calculated from the price other than previous close.
+var referenceTitles = map[string][2][2]string{
+	`open`:   {{`vsOpen`, `vsO`}, {`vsOpen%`, `vsO%`}},
+	`anchor`: {{`vsAnchor`, `vsA`}, {`vsAnchor%`, `vsA%`}},
+}
+
+// Columns displayed only when the profile lists them in ExtraColumns.
+var optionalColumns = map[string]bool{
+	`PrePrice`: true, `PreVolume`: true, `PostPrice`: true, `PostVolume`: true,
+	`Exchange`: true, `Country`: true, `ESG`: true,
+}
+
+// Default columns displayed for the stocks of particular quote type. The
+// quote types not listed here display all the columns but the optional ones.
+var columnSets = map[string][]string{
+	`INDEX`:          {`LastTrade`, `Change`, `ChangePct`, `Open`, `Low`, `High`, `Low52`, `High52`},
+	`CURRENCY`:       {`LastTrade`, `Change`, `ChangePct`, `Open`, `Low`, `High`, `Low52`, `High52`},
+	`FUTURE`:         {`LastTrade`, `Change`, `ChangePct`, `Open`, `Low`, `High`, `Low52`, `High52`, `Volume`, `PreOpen`, `AfterHours`},
+	`CRYPTOCURRENCY`: {`LastTrade`, `Change`, `ChangePct`, `Open`, `Low`, `High`, `Low52`, `High52`, `Volume`, `AvgVolume`, `MarketCap`},
+}
+
+// Titles and brief titles of the equity columns that show fund metrics in
+// ETF and mutual fund rows.
+var fundTitles = map[string][2]string{
+	`PeRatio`:   {`P/E|Exp`, `PE|Exp`},
+	`MarketCap`: {`MktCap|AUM`, `MCap|AUM`},
 }
 
 // Creates the layout and assigns the default values that stay unchanged.
 func NewLayout() *Layout {
 	layout := &Layout{}
 	layout.columns = []Column{
-		{-10, `Ticker`, `Ticker`, nil},
-		{10, `LastTrade`, `Last`, currency},
-		{10, `Change`, `Change`, currency},
-		{10, `ChangePct`, `Change%`, last},
-		{10, `Open`, `Open`, currency},
-		{10, `Low`, `Low`, currency},
-		{10, `High`, `High`, currency},
-		{10, `Low52`, `52w Low`, currency},
-		{10, `High52`, `52w High`, currency},
-		{11, `Volume`, `Volume`, nil},
-		{11, `AvgVolume`, `AvgVolume`, nil},
-		{9, `PeRatio`, `P/E`, blank},
-		{9, `Dividend`, `Dividend`, zero},
-		{9, `Yield`, `Yield`, percent},
-		{11, `MarketCap`, `MktCap`, currency},
-		{13, `PreOpen`, `PreMktChg%`, last},
-		{13, `AfterHours`, `AfterMktChg%`, last},
+		{-10, `Ticker`, `Ticker`, `Ticker`, nil},
+		{10, `LastTrade`, `Last`, `Last`, currency},
+		{10, `Change`, `Change`, `Chg`, currency},
+		{10, `ChangePct`, `Change%`, `Chg%`, last},
+		{10, `Open`, `Open`, `Open`, currency},
+		{10, `Low`, `Low`, `Low`, currency},
+		{10, `High`, `High`, `High`, currency},
+		{10, `Low52`, `52w Low`, `52wL`, currency},
+		{10, `High52`, `52w High`, `52wH`, currency},
+		{11, `Volume`, `Volume`, `Vol`, nil},
+		{11, `AvgVolume`, `AvgVolume`, `AvgVol`, nil},
+		{9, `PeRatio`, `P/E`, `P/E`, blank},
+		{9, `Dividend`, `Dividend`, `Div`, zero},
+		{9, `Yield`, `Yield`, `Yld`, percent},
+		{11, `MarketCap`, `MktCap`, `MCap`, currency},
+		{13, `PreOpen`, `PreMktChg%`, `Pre%`, last},
+		{13, `AfterHours`, `AfterMktChg%`, `After%`, last},
+		{10, `PrePrice`, `PreMkt`, `PreP`, currency},
+		{11, `PreVolume`, `PreMktVol`, `PreV`, blank},
+		{10, `PostPrice`, `AfterMkt`, `AftP`, currency},
+		{13, `PostVolume`, `AfterMktVol`, `AftV`, blank},
+		{11, `Exchange`, `Exchange`, `Exch`, nil},
+		{16, `Country`, `Country`, `Ctry`, nil},
+		{9, `ESG`, `ESG`, `ESG`, blank},
 	}
 	layout.regex = regexp.MustCompile(`(\.\d+)[BMK]?$`)
 	layout.marketTemplate = buildMarketTemplate()
 	layout.quotesTemplate = buildQuotesTemplate()
+	layout.cardsTemplate = buildCardsTemplate()
+	layout.statsTemplate = buildStatsTemplate()
 
 	return layout
 }
@@ -95,14 +157,35 @@ func (layout *Layout) Quotes(quotes *Quotes) string {
 		return err // then simply return the error string.
 	}
 
+	stocks, err := layout.prettify(quotes)
+	stocks = append(stocks, layout.failed(quotes)...)
+	open, close := stylesFor(stocks, quotes.profile)
+	columns, cells := layout.columnsFor(quotes.profile), [][]string{}
+	for _, stock := range stocks {
+		cells = append(cells, layout.cells(stock, columns, quotes.profile))
+	}
 	vars := struct {
-		Now    string  // Current timestamp.
-		Header string  // Formatted header line.
-		Stocks []Stock // List of formatted stock quotes.
+		Now     string     // Current timestamp.
+		Banner  string     // Pre-market banner, if any.
+		Header  string     // Formatted header line.
+		Stocks  []Stock    // List of formatted stock quotes.
+		Cells   [][]string // Values of visible columns for each stock.
+		Open    []string   // Opening style tags for each row (shading, selection).
+		Close   []string   // Matching closing tags for each row.
+		Sep     string     // Column separator, if any.
+		Compact bool       // True when blank lines are dropped.
+		Error   string     // Filter error, if any.
 	}{
 		time.Now().Format(`3:04:05pm ` + zonename),
+		layout.preMarketBanner(quotes, time.Now()),
 		layout.Header(quotes.profile),
-		layout.prettify(quotes),
+		stocks,
+		cells,
+		open,
+		close,
+		separatorFor(quotes.profile),
+		quotes.profile.Density == `compact`,
+		errorOf(err),
 	}
 
 	buffer := new(bytes.Buffer)
@@ -111,70 +194,363 @@ func (layout *Layout) Quotes(quotes *Quotes) string {
 	return buffer.String()
 }
 
+// Cards is used instead of Quotes when the terminal is too narrow for the
+// table. Each stock quote gets rendered as a block of three lines: ticker
+// and price, change, and the trading range.
+func (layout *Layout) Cards(quotes *Quotes) string {
+	zonename, _ := time.Now().In(time.Local).Zone()
+	if ok, err := quotes.Ok(); !ok { // If there was an error fetching stock quotes...
+		return err // then simply return the error string.
+	}
+
+	stocks, err := layout.prettify(quotes)
+	stocks = append(stocks, layout.failed(quotes)...)
+	open, close := stylesFor(stocks, quotes.profile)
+	cards := []card{}
+	for _, stock := range stocks {
+		cards = append(cards, layout.card(stock, quotes.profile))
+	}
+
+	vars := struct {
+		Now     string   // Current timestamp.
+		Header  string   // Formatted header line.
+		Stocks  []card   // List of formatted stock quotes.
+		Open    []string // Opening style tags for each card (shading, selection).
+		Close   []string // Matching closing tags for each card.
+		Compact bool     // True when blank lines are dropped.
+		Error   string   // Filter error, if any.
+	}{
+		time.Now().Format(`3:04:05pm ` + zonename),
+		`<u>Sorted by ` + arrowFor(quotes.profile.SortColumn, quotes.profile) + layout.titleOf(quotes.profile.SortColumn, quotes.profile) + `</u>`,
+		cards,
+		open,
+		close,
+		quotes.profile.Density == `compact`,
+		errorOf(err),
+	}
+
+	buffer := new(bytes.Buffer)
+	layout.cardsTemplate.Execute(buffer, vars)
+
+	return buffer.String()
+}
+
+// Statistics merges given watchlist statistics with the statistics template
+// and returns formatted string that includes highlighting markup.
+func (layout *Layout) Statistics(stats *Statistics) string {
+	vars := struct {
+		*Statistics
+		Share      string   // Share of advancing stocks.
+		PE         string   // Average P/E ratio.
+		Median     string   // Median change.
+		MarketCap  string   // Total market cap.
+		Move       string   // Change of the biggest mover.
+		Value      string   // Portfolio value.
+		Cash       string   // Cash balance, blank if none.
+		Unrealized string   // Unrealized profit or loss.
+		Realized   string   // Realized profit or loss.
+		TWR        string   // Time-weighted return.
+		IRR        string   // Money-weighted annual return.
+		Countries  []string // Geographic allocation, one line per country.
+	}{
+		stats,
+		fmt.Sprintf(`%.0f%%`, stats.Breadth()),
+		fmt.Sprintf(`%.2f`, stats.AveragePE),
+		change(stats.MedianChange),
+		currency(float2Str(stats.TotalMarketCap), ``),
+		change(stats.BiggestMove),
+		``, ``, ``, ``, `-`, `-`, nil,
+	}
+	if portfolio := stats.Portfolio; portfolio != nil {
+		vars.Value = fmt.Sprintf(`%.2f`, portfolio.Value)
+		if portfolio.Cash != 0 {
+			vars.Cash = fmt.Sprintf(`%.2f`, portfolio.Cash)
+		}
+		vars.Unrealized = fmt.Sprintf(`%+.2f`, portfolio.Value-portfolio.Cost)
+		vars.Realized = fmt.Sprintf(`%+.2f`, portfolio.Realized)
+		if portfolio.TWR != nil {
+			vars.TWR = change(*portfolio.TWR)
+		}
+		if portfolio.IRR != nil {
+			vars.IRR = change(*portfolio.IRR) + ` a year`
+		}
+		for _, country := range portfolio.Countries {
+			vars.Countries = append(vars.Countries, fmt.Sprintf(`%-17s %5.1f%%`, country.Name, country.Percent))
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+	layout.statsTemplate.Execute(buffer, vars)
+
+	return buffer.String()
+}
+
+// Width returns total number of characters it takes to display one line
+// of the stock quotes table.
+func (layout *Layout) Width(profile *Profile) int {
+	width, visible := 0, 0
+	for _, column := range layout.columnsFor(profile) {
+		if layout.hidden(column, profile) {
+			continue
+		}
+		if w := widthFor(column, profile); w < 0 {
+			width -= w
+		} else {
+			width += w
+		}
+		visible++
+	}
+	if separatorFor(profile) != `` && visible > 0 {
+		width += visible - 1
+	}
+
+	return width
+}
+
 // Header iterates over column titles and formats the header line. The
 // formatting includes placing an arrow next to the sorted column title.
 // When the column editor is active it knows how to highlight currently
 // selected column title.
 func (layout *Layout) Header(profile *Profile) string {
-	str, selectedColumn := ``, profile.selectedColumn
+	titles, selectedColumn := []string{}, profile.selectedColumn
 
-	for i, col := range layout.columns {
-		arrow := arrowFor(i, profile)
+	for i, col := range layout.columnsFor(profile) {
+		if layout.hidden(col, profile) {
+			continue
+		}
+		arrow, title, width := arrowFor(i, profile), col.title, widthFor(col, profile)
+		if profile.Density == `compact` {
+			title = col.brief
+		}
 		if i != selectedColumn {
-			str += fmt.Sprintf(`%*s`, col.width, arrow+col.title)
+			titles = append(titles, fmt.Sprintf(`%*s`, width, arrow+title))
 		} else {
-			str += fmt.Sprintf(`<r>%*s</r>`, col.width, arrow+col.title)
+			titles = append(titles, fmt.Sprintf(`<r>%*s</r>`, width, arrow+title))
 		}
 	}
 
-	return `<u>` + str + `</u>`
+	return `<u>` + strings.Join(titles, separatorFor(profile)) + `</u>`
+}
+
+// HeaderRow returns screen row where the stock quotes header is displayed.
+// The compact layout drops the blank line between the market data and the
+// header so the line editor prompt temporarily replaces the header.
+func (layout *Layout) HeaderRow(profile *Profile) int {
+	if profile.Density == `compact` {
+		return 3
+	}
+	return 4
 }
 
 // TotalColumns is the utility method for the column editor that returns
-// total number of columns.
-func (layout *Layout) TotalColumns() int {
-	return len(layout.columns)
+// total number of columns including user-defined ones.
+func (layout *Layout) TotalColumns(profile *Profile) int {
+	return len(layout.columnsFor(profile))
+}
+
+// IsVisible returns true if the column with the given index is displayed,
+// i.e. it is relevant for at least one of the stocks.
+func (layout *Layout) IsVisible(column int, profile *Profile) bool {
+	columns := layout.columnsFor(profile)
+	return column >= 0 && column < len(columns) && !layout.hidden(columns[column], profile)
 }
 
+// Table returns stock quotes the way they are currently displayed, i.e.
+// formatted, filtered, and sorted, but without padding and markup. The
+// first row contains column titles.
+func (layout *Layout) Table(quotes *Quotes) [][]string {
+	return layout.table(quotes, nil)
+}
+
+// Returns the Table rows of the stocks with the given tickers only, or all
+// of them if none are given. The rows are picked by the ticker rather than
+// by the first column, which gets the ticker's alias if it has one.
 //-----------------------------------------------------------------------------
-func (layout *Layout) prettify(quotes *Quotes) []Stock {
+func (layout *Layout) table(quotes *Quotes, tickers map[string]bool) [][]string {
+	stocks, _ := layout.prettify(quotes)
+	columns, titles := layout.columnsFor(quotes.profile), []string{}
+	for _, column := range columns {
+		if !layout.hidden(column, quotes.profile) {
+			titles = append(titles, column.title)
+		}
+	}
+
+	table := [][]string{titles}
+	for _, stock := range stocks {
+		if len(tickers) > 0 && !tickers[strings.TrimSpace(stock.Ticker)] {
+			continue
+		}
+		row := layout.cells(stock, columns, quotes.profile)
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		table = append(table, row)
+	}
+
+	return table
+}
+
+// Compare lays out the stocks with the given tickers side by side, one line
+// per column, so they could be compared across all the metrics. The result
+// does not include any markup.
+func (layout *Layout) Compare(quotes *Quotes, tickers []string) string {
+	wanted := make(map[string]bool)
+	for _, ticker := range tickers {
+		wanted[ticker] = true
+	}
+
+	table := layout.table(quotes, wanted)
+	compared := table[1:]
+
+	lines := []string{}
+	for i, title := range table[0] {
+		line := fmt.Sprintf(`%-14s`, title)
+		for _, row := range compared {
+			line += fmt.Sprintf(`%12s`, row[i])
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Summary returns the ticker, the last trade, and the change of the stocks
+// with the given tickers, one line per stock in the order they are
+// displayed, ex. "AAPL $207.48 +$3.10 +1.52%". The result does not include
+// any markup.
+func (layout *Layout) Summary(quotes *Quotes, tickers []string) string {
+	wanted := make(map[string]bool)
+	for _, ticker := range tickers {
+		wanted[ticker] = true
+	}
+
+	stocks, _ := layout.prettify(quotes)
+	lines := []string{}
+	for _, stock := range stocks {
+		if wanted[strings.TrimSpace(stock.Ticker)] {
+			cells := layout.cells(stock, layout.columns[:4], quotes.profile) // Ticker, last trade, and the change.
+			lines = append(lines, strings.Join(strings.Fields(strings.Join(cells, ` `)), ` `))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Constituents lists the stocks (ex. index constituents) along with their
+// prices and changes, biggest gainers first, so that the index move could
+// be attributed at a glance. The result does not include any markup.
+func (layout *Layout) Constituents(quotes *Quotes) string {
+	if ok, err := quotes.Ok(); !ok {
+		return strings.TrimSpace(err)
+	}
+
+	stocks := append([]Stock{}, quotes.stocks...)
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[j].ChangePct.Value() < stocks[i].ChangePct.Value()
+	})
+
+	lines := []string{}
+	for _, stock := range stocks {
+		lines = append(lines, fmt.Sprintf(`%-10s%s%s%s`, quotes.profile.Label(stock.Ticker),
+			layout.pad(currency(stock.LastTrade.String(), stock.Currency), 10), layout.pad(currency(stock.Change.String(), stock.Currency), 10), layout.pad(last(stock.ChangePct.String()), 10)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Returns the list of stock quotes columns followed by user-defined columns
+// from the profile. Change column titles reflect the reference price, or
+// pre-market prices while in the pre-market watch mode.
+func (layout *Layout) columnsFor(profile *Profile) []Column {
+	columns := append([]Column{}, layout.columns...)
+	if profile.preMarket {
+		for i, titles := range preMarketTitles {
+			columns[1+i].title, columns[1+i].brief = titles[0], titles[1]
+		}
+	} else if titles, ok := referenceTitles[profile.Reference]; ok {
+		for i := range titles {
+			columns[2+i].title, columns[2+i].brief = titles[i][0], titles[i][1]
+		}
+	}
+	if layout.types[`ETF`] || layout.types[`MUTUALFUND`] { // Fund rows show fund metrics instead of P/E and market cap.
+		for i, column := range columns {
+			if titles, ok := fundTitles[column.name]; ok {
+				columns[i].title, columns[i].brief = titles[0], titles[1]
+			}
+		}
+	}
+	for _, computed := range profile.computed {
+		width := 10
+		if w := len(computed.name) + 2; w > width {
+			width = w
+		}
+		columns = append(columns, Column{width, ``, computed.name, computed.name, nil})
+	}
+
+	return columns
+}
+
+// Returns the title of the given column, blank if there is no such column.
+func (layout *Layout) titleOf(column int, profile *Profile) string {
+	if columns := layout.columnsFor(profile); column < len(columns) {
+		return columns[column].title
+	}
+	return ``
+}
+
+// Adjusts stock quotes as requested by the profile, ex. for the pre-market
+// watch mode, evaluates user-defined columns, then filters, sorts, and
+// groups them. The stock quotes keep their numeric values; they only get
+// formatted when they are displayed. In case of filter error all the stocks
+// are returned along with the error.
+func (layout *Layout) prettify(quotes *Quotes) ([]Stock, error) {
+	var err error
+	profile := quotes.profile
 	pretty := make([]Stock, len(quotes.stocks))
-	//
-	// Iterate over the list of stocks and properly format all its columns.
-	//
+	layout.types = make(map[string]bool)
+	for _, stock := range quotes.stocks {
+		layout.types[stock.QuoteType] = true
+	}
+
 	for i, stock := range quotes.stocks {
-		pretty[i].Advancing = stock.Advancing
+		pretty[i] = premarket(sessions(rebase(lookthrough(stock), profile)), profile)
 		//
-		// Iterate over the list of stock columns. For each column name:
-		// - Get current column value.
-		// - If the column has the formatter method then call it.
-		// - Set the column value padding it to the given width.
+		// Evaluate user-defined columns, if any.
 		//
-		for _, column := range layout.columns {
-			// ex. value = stock.Change
-			value := reflect.ValueOf(&stock).Elem().FieldByName(column.name).String()
-			if column.formatter != nil {
-				// ex. value = currency(value)
-				value = column.formatter(value, stock.Currency)
+		if len(profile.computed) > 0 {
+			columns := layout.columnsFor(profile)[len(layout.columns):]
+			pretty[i].Computed = compute(pretty[i], profile)
+			for j, value := range pretty[i].Computed {
+				pretty[i].Computed[j] = layout.pad(value, widthFor(columns[j], profile))
 			}
-			// ex. pretty[i].Change = layout.pad(value, 10)
-			reflect.ValueOf(&pretty[i]).Elem().FieldByName(column.name).SetString(layout.pad(value, column.width))
 		}
 	}
 
-	profile := quotes.profile
+	if profile.List != `` {
+		listed := pretty[:0]
+		for _, stock := range pretty {
+			if profile.listed(stock.Ticker) {
+				listed = append(listed, stock)
+			}
+		}
+		pretty = listed
+	}
 
 	if profile.filterExpression != nil {
 		if layout.filter == nil { // Initialize filter on first invocation.
 			layout.filter = NewFilter(profile)
 		}
-		pretty = layout.filter.Apply(pretty)
+		pretty, err = layout.filter.Apply(pretty)
 	}
 
 	if layout.sorter == nil { // Initialize sorter on first invocation.
 		layout.sorter = NewSorter(profile)
 	}
-	layout.sorter.SortByCurrentColumn(pretty)
+	if profile.SortColumn < len(layout.columns) {
+		layout.sorter.SortByCurrentColumn(pretty)
+	} else {
+		layout.sorter.SortByComputedColumn(pretty, profile.SortColumn-len(layout.columns))
+	}
 	//
 	// Group stocks by advancing/declining unless sorted by Chanage or Change%
 	// in which case the grouping has been done already.
@@ -183,7 +559,97 @@ func (layout *Layout) prettify(quotes *Quotes) []Stock {
 		pretty = group(pretty)
 	}
 
-	return pretty
+	return pretty, err
+}
+
+// Returns the placeholder rows of the tickers that have failed the last
+// fetch, ex. delisted or mistyped, in the watchlist order. They go below
+// the stock quotes, and are neither sorted nor filtered.
+func (layout *Layout) failed(quotes *Quotes) []Stock {
+	placeholders := []Stock{}
+	for _, ticker := range unique(quotes.profile.Tickers) {
+		if message, ok := quotes.failures[ticker]; ok {
+			placeholders = append(placeholders, Stock{Ticker: ticker, Error: message})
+		}
+	}
+
+	return placeholders
+}
+
+// Returns true if the column is irrelevant for all the stocks displayed
+// last, or for the pre-market watch mode, or is the optional column the
+// profile doesn't ask for, and therefore should be hidden.
+func (layout *Layout) hidden(column Column, profile *Profile) bool {
+	if optionalColumns[column.name] && !extra(column.name, profile) {
+		return true
+	}
+	if profile.preMarket && column.name != `` && column.name != `Ticker` && !preMarketColumns[column.name] {
+		return true
+	}
+	for quoteType := range layout.types {
+		if relevant(column, quoteType, profile) {
+			return false
+		}
+	}
+	return len(layout.types) > 0
+}
+
+// Returns formatted values of the visible columns for the given stock. The
+// columns irrelevant for the stock quote type are left blank. The failed
+// ticker gets the error marker in place of all the columns but the ticker.
+func (layout *Layout) cells(stock Stock, columns []Column, profile *Profile) []string {
+	cells := []string{}
+	for i, column := range columns {
+		if layout.hidden(column, profile) {
+			continue
+		}
+		if stock.Error != `` && column.name != `Ticker` {
+			return append(cells, `<red>✗ `+stock.Error+`</>`)
+		}
+		value := ``
+		if !relevant(column, stock.QuoteType, profile) {
+			value = fmt.Sprintf(`%*s`, widthFor(column, profile), `-`)
+		} else if i < len(layout.columns) {
+			value = layout.format(stock, column, profile)
+		} else if j := i - len(layout.columns); j < len(stock.Computed) {
+			value = stock.Computed[j]
+		}
+		cells = append(cells, value)
+	}
+
+	return cells
+}
+
+// Returns the formatted value of the stock quotes column padded to the
+// column width, ex. "   $201.46". The fund's expense ratio shown in place
+// of P/E gets the percent sign.
+//-----------------------------------------------------------------------------
+func (layout *Layout) format(stock Stock, column Column, profile *Profile) string {
+	value := fmt.Sprint(reflect.ValueOf(stock).FieldByName(column.name).Interface())
+	if column.name == `Ticker` {
+		value = profile.Label(value)
+	}
+	if column.name == `PeRatio` && isFund(stock) && stock.PeRatio.Known() {
+		value += `%`
+	}
+	if column.formatter != nil {
+		value = column.formatter(value, stock.Currency)
+	}
+
+	return layout.pad(value, widthFor(column, profile))
+}
+
+// Returns the stock quote formatted for the card view.
+//-----------------------------------------------------------------------------
+func (layout *Layout) card(stock Stock, profile *Profile) card {
+	card := card{Advancing: stock.Advancing, Error: stock.Error}
+	for _, column := range layout.columns {
+		if field := reflect.ValueOf(&card).Elem().FieldByName(column.name); field.Kind() == reflect.String {
+			field.SetString(strings.TrimSpace(layout.format(stock, column, profile)))
+		}
+	}
+
+	return card
 }
 
 //-----------------------------------------------------------------------------
@@ -214,13 +680,61 @@ func buildMarketTemplate() *template.Template {
 func buildQuotesTemplate() *template.Template {
 	markup := `<right><white>{{.Now}}</></right>
 
+{{if not .Compact}}{{with .Error}}<red>{{.}}</>{{end}}
+{{.Banner}}{{end}}
+{{.Header}}
+{{range $i, $stock := .Stocks}}{{if .Advancing}}<green>{{end}}{{index $.Open $i}}{{join (index $.Cells $i) $.Sep}}</>{{index $.Close $i}}
+{{end}}{{if .Compact}}{{with .Error}}<red>{{.}}</>{{else}}{{$.Banner}}{{end}}{{end}}`
 
+	return template.Must(template.New(`quotes`).Funcs(template.FuncMap{`join`: strings.Join}).Parse(markup))
+}
 
+//-----------------------------------------------------------------------------
+func buildCardsTemplate() *template.Template {
+	markup := `<right><white>{{.Now}}</></right>
+
+{{if not .Compact}}{{with .Error}}<red>{{.}}</>{{end}}
+{{end}}
 {{.Header}}
-{{range.Stocks}}{{if .Advancing}}<green>{{end}}{{.Ticker}}{{.LastTrade}}{{.Change}}{{.ChangePct}}{{.Open}}{{.Low}}{{.High}}{{.Low52}}{{.High52}}{{.Volume}}{{.AvgVolume}}{{.PeRatio}}{{.Dividend}}{{.Yield}}{{.MarketCap}}{{.PreOpen}}{{.AfterHours}}</>
-{{end}}`
+{{range $i, $stock := .Stocks}}{{if .Error}}{{index $.Open $i}}<b>{{.Ticker}}</b> <red>✗ {{.Error}}</>{{index $.Close $i}}
+{{else}}{{if .Advancing}}<green>{{end}}{{index $.Open $i}}<b>{{.Ticker}}</b> {{.LastTrade}} {{.Change}} ({{.ChangePct}})</>{{index $.Close $i}}
+  Open {{.Open}} Low {{.Low}} High {{.High}} Vol {{.Volume}}
+  52w {{.Low52}} - {{.High52}} MktCap {{.MarketCap}}
+{{end}}{{end}}{{if .Compact}}{{with .Error}}<red>{{.}}</>{{end}}{{end}}`
 
-	return template.Must(template.New(`quotes`).Parse(markup))
+	return template.Must(template.New(`cards`).Parse(markup))
+}
+
+//-----------------------------------------------------------------------------
+func buildStatsTemplate() *template.Template {
+	markup := `<u>Watchlist statistics</u>
+
+  Stocks            {{.Count}}
+  Advancing         <green>{{.Advancing}}</>
+  Declining         {{.Declining}}
+  Unchanged         {{.Unchanged}}
+  Breadth           {{.Share}} advancing
+  Average P/E       {{.PE}}
+  Median change     {{.Median}}
+  Total market cap  {{.MarketCap}}
+  Biggest mover     {{with .BiggestMover}}<yellow>{{.}}</> {{$.Move}}{{else}}-{{end}}
+{{if .Portfolio}}
+<u>Portfolio performance</u>{{with .Portfolio.Account}} <yellow>{{.}}</>{{end}}
+
+  Value             {{.Value}}
+{{with .Cash}}  Cash              {{.}}
+{{end}}  Unrealized P&L    {{.Unrealized}}
+  Realized P&L      {{.Realized}}
+  Time-weighted     {{.TWR}}
+  Money-weighted    {{.IRR}}
+{{with .Countries}}
+<u>Geographic allocation</u>
+
+{{range .}}  {{.}}
+{{end}}{{end}}{{end}}
+<r> Press any key to continue </r>`
+
+	return template.Must(template.New(`stats`).Parse(markup))
 }
 
 //-----------------------------------------------------------------------------
@@ -264,6 +778,188 @@ func arrowFor(column int, profile *Profile) string {
 	return ``
 }
 
+// Returns column width adjusted for the current layout density: compact
+// layout squeezes one character off each column while comfortable layout
+// adds some padding.
+//-----------------------------------------------------------------------------
+func widthFor(column Column, profile *Profile) int {
+	width, sign := column.width, 1
+	if width < 0 {
+		width, sign = -width, -1
+	}
+
+	switch profile.Density {
+	case `compact`:
+		width--
+	case `comfortable`:
+		width += 2
+	}
+
+	return width * sign
+}
+
+// Formats change percent, highlighting positive values, i.e. 1.5 => +1.50%.
+//-----------------------------------------------------------------------------
+func change(value float64) string {
+	if value > 0 {
+		return fmt.Sprintf(`<green>%+.2f%%</>`, value)
+	}
+	return fmt.Sprintf(`%+.2f%%`, value)
+}
+
+//-----------------------------------------------------------------------------
+func errorOf(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ``
+}
+
+//-----------------------------------------------------------------------------
+func separatorFor(profile *Profile) string {
+	if profile.GridLines {
+		return `│`
+	}
+	return ``
+}
+
+// Returns background tag name for shaded rows, i.e. `blue` => `on-blue`,
+// or blank string when row shading is off.
+//-----------------------------------------------------------------------------
+func shadingFor(profile *Profile) string {
+	if profile.RowShading == `` {
+		return ``
+	}
+	return `on-` + profile.RowShading
+}
+
+// Returns true if the column should be displayed for the stock of the given
+// quote type as configured in the profile, or by default column sets. All
+// the columns are relevant for the stocks of unknown type and user-defined
+// columns are always relevant.
+//-----------------------------------------------------------------------------
+func relevant(column Column, quoteType string, profile *Profile) bool {
+	names, ok := profile.ColumnSets[quoteType]
+	if !ok {
+		names, ok = columnSets[quoteType]
+	}
+	if !ok || column.name == `` || column.name == `Ticker` {
+		return true
+	}
+	for _, name := range names {
+		if name == column.name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if the profile asks for the optional column.
+//-----------------------------------------------------------------------------
+func extra(name string, profile *Profile) bool {
+	for _, wanted := range profile.ExtraColumns {
+		if wanted == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true for ETFs and mutual funds.
+//-----------------------------------------------------------------------------
+func isFund(stock Stock) bool {
+	return stock.QuoteType == `ETF` || stock.QuoteType == `MUTUALFUND`
+}
+
+// Substitutes equity metrics that make no sense for funds with the fund
+// ones: P/E gets replaced with expense ratio, market cap with assets under
+// management, and dividend yield with fund yield.
+//-----------------------------------------------------------------------------
+func lookthrough(stock Stock) Stock {
+	if !isFund(stock) {
+		return stock
+	}
+
+	stock.PeRatio = unavailable
+	if stock.ExpenseRatio.Known() {
+		stock.PeRatio = stock.ExpenseRatio
+	}
+	if stock.NetAssets.Known() {
+		stock.MarketCap = stock.NetAssets
+	}
+	if stock.FundYield.Known() {
+		stock.Yield = stock.FundYield
+	}
+
+	return stock
+}
+
+// Recalculates the change and change percent from the reference price set
+// in the profile unless it's previous close, or the price is not available
+// (ex. no anchor is set for the ticker).
+//-----------------------------------------------------------------------------
+func rebase(stock Stock, profile *Profile) Stock {
+	reference := 0.0
+	switch profile.Reference {
+	case `open`:
+		reference = stock.Open.Value()
+	case `anchor`:
+		reference = profile.Anchors[stock.Ticker]
+	}
+	if reference <= 0 {
+		return stock
+	}
+
+	change := stock.LastTrade.Value() - reference
+	stock.Change = numberOf(change)
+	stock.ChangePct = numberOf(change * 100 / reference)
+	stock.Advancing = change >= 0
+
+	return stock
+}
+
+// Returns opening and closing style tags for each row: marked rows get
+// highlighted, rows of the stocks that have just made new session high or
+// low flash green or red, other rows get shaded as requested by the profile,
+// changed rows are displayed in bold if requested, and the row under the
+// cursor is displayed in reverse while in bulk edit mode.
+//-----------------------------------------------------------------------------
+func stylesFor(stocks []Stock, profile *Profile) (open, close []string) {
+	open, close = make([]string, len(stocks)), make([]string, len(stocks))
+	shading := shadingFor(profile)
+
+	for i, stock := range stocks {
+		tags := []string{}
+		if profile.marked[strings.TrimSpace(stock.Ticker)] {
+			tags = append(tags, `on-blue`)
+		} else if stock.NewHigh {
+			tags = append(tags, `on-green`, `black`)
+		} else if stock.NewLow {
+			tags = append(tags, `on-red`, `white`)
+		} else if shading != `` && odd(i) {
+			tags = append(tags, shading)
+		}
+		if profile.FlashChanges && stock.Changed {
+			tags = append(tags, `b`)
+		}
+		if profile.marked != nil && i == profile.selectedRow {
+			tags = append(tags, `r`)
+		}
+		for j, tag := range tags {
+			open[i] += `<` + tag + `>`
+			close[i] += `</` + tags[len(tags)-1-j] + `>`
+		}
+	}
+
+	return
+}
+
+//-----------------------------------------------------------------------------
+func odd(i int) bool {
+	return i%2 == 1
+}
+
 //-----------------------------------------------------------------------------
 func blank(str ...string) string {
 	if len(str) < 1 {
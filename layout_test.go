@@ -0,0 +1,196 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStylesFor(t *testing.T) {
+	stocks := []Stock{{Ticker: `AAPL  `}, {Ticker: `GOOG  `}, {Ticker: `IBM   `}}
+
+	profile := &Profile{RowShading: `black`}
+	open, close := stylesFor(stocks, profile)
+	assert.Equal(t, []string{``, `<on-black>`, ``}, open)
+	assert.Equal(t, []string{``, `</on-black>`, ``}, close)
+
+	profile.marked, profile.selectedRow = map[string]bool{`GOOG`: true, `IBM`: true}, 2
+	open, close = stylesFor(stocks, profile)
+	assert.Equal(t, []string{``, `<on-blue>`, `<on-blue><r>`}, open)
+	assert.Equal(t, []string{``, `</on-blue>`, `</r></on-blue>`}, close)
+}
+
+func TestCompare(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{Ascending: true}, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(207.48)},
+		{Ticker: `IBM`, LastTrade: numberOf(143.90)},
+		{Ticker: `KO`, LastTrade: numberOf(46.76)},
+	}}
+
+	lines := strings.Split(NewLayout().Compare(quotes, []string{`KO`, `AAPL`}), "\n")
+	assert.Len(t, lines, 17)
+	assert.Equal(t, `Ticker                AAPL          KO`, lines[0])
+	assert.Equal(t, `Last               $207.48      $46.76`, lines[1])
+
+	quotes.profile.Aliases = map[string]string{`KO`: `Coke`}
+	lines = strings.Split(NewLayout().Compare(quotes, []string{`KO`, `AAPL`}), "\n")
+	assert.Equal(t, `Ticker                AAPL        Coke`, lines[0], `aliased ticker`)
+}
+
+func TestExportAliasedTickers(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{Ascending: true, Aliases: map[string]string{`BRK-B`: `Berkshire`}}, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(207.48)},
+		{Ticker: `BRK-B`, LastTrade: numberOf(411.20)},
+		{Ticker: `KO`, LastTrade: numberOf(46.76)},
+	}}
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, WriteCSV(buffer, NewLayout().table(quotes, map[string]bool{`BRK-B`: true, `KO`: true})))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], `Berkshire,$411.20,`), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], `KO,$46.76,`), lines[2])
+}
+
+func TestPrettifyKeepsNumbers(t *testing.T) {
+	expression, err := newExpression(`sector == 'Technology' && mktCap > 100000000000`)
+	require.NoError(t, err)
+	profile := &Profile{SortColumn: 14, filterExpression: expression}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, Sector: `Technology`, MarketCap: numberOf(927e9)},
+		{Ticker: `CSCO`, Sector: `Technology`, MarketCap: numberOf(99e9)},
+		{Ticker: `MSFT`, Sector: `Technology`, MarketCap: numberOf(1.05e12)},
+		{Ticker: `KO`, Sector: `Consumer Defensive`, MarketCap: numberOf(219e9)},
+	}}
+
+	stocks, err := NewLayout().prettify(quotes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`MSFT`, `AAPL`}, tickersOf(stocks), `filtered and sorted by the value rather than the formatted one`)
+	assert.Equal(t, 927e9, stocks[1].MarketCap.Value())
+	assert.Equal(t, `Technology`, stocks[1].Sector)
+}
+
+func TestRebase(t *testing.T) {
+	stock := Stock{Ticker: `AAPL`, LastTrade: numberOf(198.00), Open: numberOf(200.00), Change: numberOf(3.00), ChangePct: numberOf(1.54), Advancing: true}
+	profile := &Profile{}
+	assert.Equal(t, stock, rebase(stock, profile))
+
+	profile.Reference = `open`
+	rebased := rebase(stock, profile)
+	assert.Equal(t, `-2.000`, rebased.Change.String())
+	assert.Equal(t, `-1.000`, rebased.ChangePct.String())
+	assert.False(t, rebased.Advancing)
+
+	profile.Reference = `anchor`
+	assert.Equal(t, stock, rebase(stock, profile))
+
+	profile.Anchors = map[string]float64{`AAPL`: 180}
+	rebased = rebase(stock, profile)
+	assert.Equal(t, `18.000`, rebased.Change.String())
+	assert.Equal(t, `10.000`, rebased.ChangePct.String())
+	assert.True(t, rebased.Advancing)
+}
+
+func TestLookthrough(t *testing.T) {
+	stock := Stock{Ticker: `IBM`, QuoteType: `EQUITY`, PeRatio: numberOf(13.20), MarketCap: numberOf(127.4e9)}
+	assert.Equal(t, stock, lookthrough(stock))
+
+	fund := lookthrough(Stock{Ticker: `SPY`, QuoteType: `ETF`, PeRatio: numberOf(24.10), MarketCap: unavailable,
+		ExpenseRatio: numberOf(0.090), NetAssets: numberOf(412.3e9), Yield: numberOf(1.210), FundYield: numberOf(1.350)})
+	assert.Equal(t, `0.090`, fund.PeRatio.String())
+	assert.Equal(t, `   0.090%`, NewLayout().format(fund, NewLayout().columns[11], &Profile{}), `expense ratio is displayed in percent`)
+	assert.Equal(t, `412.300B`, fund.MarketCap.String())
+	assert.Equal(t, `1.350`, fund.Yield.String())
+
+	layout := NewLayout()
+	layout.prettify(&Quotes{profile: &Profile{}, stocks: []Stock{fund}})
+	assert.Equal(t, `P/E|Exp`, layout.titleOf(11, &Profile{}))
+	assert.Equal(t, `MktCap|AUM`, layout.titleOf(14, &Profile{}))
+}
+
+func TestColumnSets(t *testing.T) {
+	profile := &Profile{Ascending: true}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `^DJI`, QuoteType: `INDEX`, LastTrade: numberOf(26543.33), Volume: numberOf(321e6)},
+	}}
+
+	layout := NewLayout()
+	table := layout.Table(quotes)
+	assert.Equal(t, []string{`Ticker`, `Last`, `Change`, `Change%`, `Open`, `Low`, `High`, `52w Low`, `52w High`}, table[0])
+
+	profile.ColumnSets = map[string][]string{`INDEX`: {`LastTrade`, `Volume`}}
+	table = layout.Table(quotes)
+	assert.Equal(t, []string{`Ticker`, `Last`, `Volume`}, table[0])
+	assert.Equal(t, []string{`^DJI`, `$26543.33`, `321.00M`}, table[1])
+
+	quotes.stocks = append(quotes.stocks, Stock{Ticker: `IBM`, QuoteType: `EQUITY`, LastTrade: numberOf(143.90)})
+	table = layout.Table(quotes)
+	assert.Len(t, table[0], 17)
+	assert.Equal(t, `-`, table[2][2]) // Change is not in the index column set.
+}
+
+func TestConstituents(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{}, stocks: []Stock{
+		{Ticker: `MSFT`, LastTrade: numberOf(137.00), Change: numberOf(-1.20), ChangePct: numberOf(-0.87)},
+		{Ticker: `AAPL`, LastTrade: numberOf(207.48), Change: numberOf(3.10), ChangePct: numberOf(1.52)},
+	}}
+
+	lines := strings.Split(NewLayout().Constituents(quotes), "\n")
+	assert.Equal(t, `AAPL         $207.48     $3.10     1.52%`, lines[0])
+	assert.Equal(t, `MSFT         $137.00    -$1.20    -0.87%`, lines[1])
+}
+
+func TestFailedTickers(t *testing.T) {
+	profile := &Profile{Tickers: []string{`XYZZ`, `AAPL`, `QQQQ`}}
+	quotes := &Quotes{profile: profile,
+		stocks:   []Stock{{Ticker: `AAPL`, LastTrade: numberOf(207.48), Change: numberOf(3.10), ChangePct: numberOf(1.52), Advancing: true}},
+		failures: map[string]string{`QQQQ`: `delisted`, `XYZZ`: unknownSymbol},
+	}
+
+	lines := strings.Split(NewLayout().Quotes(quotes), "\n")
+	require.Len(t, lines, 9)
+	assert.Contains(t, lines[5], `AAPL`)
+	assert.Equal(t, `XYZZ      <red>✗ unknown symbol</></>`, lines[6], `watchlist order, below the quotes`)
+	assert.Equal(t, `QQQQ      <red>✗ delisted</></>`, lines[7])
+
+	cards := NewLayout().Cards(quotes)
+	assert.Contains(t, cards, "<b>XYZZ</b> <red>✗ unknown symbol</>\n<b>QQQQ</b> <red>✗ delisted</>\n")
+}
+
+func TestExtraColumns(t *testing.T) {
+	profile := &Profile{}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, QuoteType: `EQUITY`, MarketState: `POST`, LastTrade: numberOf(207.48), PostPrice: numberOf(208.10), PrePrice: numberOf(206.00)},
+	}}
+
+	layout := NewLayout()
+	assert.Len(t, layout.Table(quotes)[0], 17, `optional columns are hidden`)
+
+	profile.ExtraColumns = []string{`PrePrice`, `PostPrice`, `PostVolume`}
+	table := layout.Table(quotes)
+	assert.Equal(t, []string{`PreMkt`, `AfterMkt`, `AfterMktVol`}, table[0][17:])
+	assert.Equal(t, []string{`-`, `$208.10`, `-`}, table[1][17:], `no pre-market price after hours, no volume reported`)
+
+	quotes.stocks[0].Exchange, quotes.stocks[0].Country = `NasdaqGS`, `United States`
+	profile.ExtraColumns = []string{`Country`, `Exchange`}
+	table = layout.Table(quotes)
+	assert.Equal(t, []string{`Exchange`, `Country`}, table[0][17:], `in the layout order`)
+	assert.Equal(t, []string{`NasdaqGS`, `United States`}, table[1][17:])
+}
+
+func TestSummary(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{}, stocks: []Stock{
+		{Ticker: `AAPL`, QuoteType: `EQUITY`, LastTrade: numberOf(207.48), Change: numberOf(3.1), ChangePct: numberOf(1.52)},
+		{Ticker: `IBM`, QuoteType: `EQUITY`, LastTrade: numberOf(138.2), Change: numberOf(-1.5), ChangePct: numberOf(-1.07)},
+		{Ticker: `KO`, QuoteType: `EQUITY`, LastTrade: numberOf(50.35), Change: numberOf(0.2), ChangePct: numberOf(0.4)},
+	}}
+
+	assert.Equal(t, "IBM $138.20 -$1.50 -1.07%\nAAPL $207.48 $3.10 1.52%", NewLayout().Summary(quotes, []string{`AAPL`, `IBM`}), `in the displayed order`)
+}
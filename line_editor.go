@@ -6,6 +6,7 @@ package mop
 
 import (
 	`regexp`
+	`strconv`
 	`strings`
 
 	`github.com/nsf/termbox-go`
@@ -16,13 +17,15 @@ import (
 // data and keep track of cursor movements (left, right, beginning of the
 // line, end of the line, and backspace).
 type LineEditor struct {
-	command rune           // Keyboard command such as '+' or '-'.
-	cursor  int            // Current cursor position within the input line.
-	prompt  string         // Prompt string for the command.
-	input   string         // User typed input string.
-	screen  *Screen        // Pointer to Screen.
-	quotes  *Quotes        // Pointer to Quotes.
-	regex   *regexp.Regexp // Regex to split comma-delimited input string.
+	command rune            // Keyboard command such as '+' or '-'.
+	cursor  int             // Current cursor position within the input line.
+	prompt  string          // Prompt string for the command.
+	input   string          // User typed input string.
+	screen  *Screen         // Pointer to Screen.
+	quotes  *Quotes         // Pointer to Quotes.
+	regex   *regexp.Regexp  // Regex to split comma-delimited input string.
+	dialog  *Dialog         // Confirmation dialog, if any.
+	keys    []termbox.Event // Keystrokes of the macro to be named, if any.
 }
 
 // Returns new initialized LineEditor struct.
@@ -46,12 +49,14 @@ func (editor *LineEditor) Prompt(command rune) *LineEditor {
 
 	prompts := map[rune]string{
 		'+': `Add tickers: `, '-': `Remove tickers: `,
-		'f': filterPrompt,
+		'f': filterPrompt, 'a': `Set anchor price (ticker price): `,
+		'*': `Save macro as: `, '!': `Add alert rule: `,
 	}
 	if prompt, ok := prompts[command]; ok {
 		editor.prompt = prompt
 		editor.command = command
 
+		editor.screen.Mode(LineEditorMode).ClearLine(0, 3)
 		editor.screen.DrawLine(0, 3, `<white>`+editor.prompt+`</>`)
 		termbox.SetCursor(len(editor.prompt), 3)
 		termbox.Flush()
@@ -68,12 +73,23 @@ func (editor *LineEditor) Prompt(command rune) *LineEditor {
 func (editor *LineEditor) Handle(ev termbox.Event) bool {
 	defer termbox.Flush()
 
+	if editor.dialog != nil {
+		if done := editor.dialog.Handle(ev); done {
+			editor.dialog = nil
+			return editor.done()
+		}
+		return false
+	}
+
 	switch ev.Key {
 	case termbox.KeyEsc:
 		return editor.done()
 
 	case termbox.KeyEnter:
-		return editor.execute().done()
+		if editor.execute().dialog != nil {
+			return false // Wait for confirmation.
+		}
+		return editor.done()
 
 	case termbox.KeyBackspace, termbox.KeyBackspace2:
 		editor.deletePreviousCharacter()
@@ -102,6 +118,12 @@ func (editor *LineEditor) Handle(ev termbox.Event) bool {
 	return false
 }
 
+// NameMacro prompts for the name to save the recorded keystrokes under.
+func (editor *LineEditor) NameMacro(keys []termbox.Event) *LineEditor {
+	editor.keys = keys
+	return editor.Prompt('*')
+}
+
 //-----------------------------------------------------------------------------
 func (editor *LineEditor) deletePreviousCharacter() *LineEditor {
 	if editor.cursor > 0 {
@@ -176,32 +198,61 @@ func (editor *LineEditor) execute() *LineEditor {
 	case '+':
 		tickers := editor.tokenize()
 		if len(tickers) > 0 {
+			_, notes := editor.quotes.profile.CheckTickers(tickers)
 			if added, _ := editor.quotes.AddTickers(tickers); added > 0 {
 				editor.screen.Draw(editor.quotes)
 			}
+			if len(notes) > 0 {
+				editor.screen.Notify(strings.Join(notes, `; `))
+			}
 		}
 	case '-':
 		tickers := editor.tokenize()
 		if len(tickers) > 0 {
-			before := len(editor.quotes.profile.Tickers)
-			if removed, _ := editor.quotes.RemoveTickers(tickers); removed > 0 {
-				editor.screen.Draw(editor.quotes)
-
-				// Clear the lines at the bottom of the list, if any.
-				after := before - removed
-				for i := before; i > after; i-- {
-					editor.screen.ClearLine(0, i+4)
+			question := `Remove ` + strings.Join(tickers, `, `) + `?`
+			editor.dialog = NewConfirmDialog(editor.screen, `Remove tickers`, question, func(ok bool) {
+				if ok {
+					editor.quotes.RemoveTickers(tickers)
 				}
-			}
+				editor.screen.Draw(editor.quotes) // Also erases the dialog.
+			})
 		}
 	case 'f':
 		if len(editor.input) == 0 {
 			editor.input = editor.quotes.profile.Filter
 		}
 
-		editor.quotes.profile.SetFilter(editor.input)
+		if err := editor.quotes.profile.SetFilter(editor.input); err != nil {
+			editor.dialog = NewMessageDialog(editor.screen, `Filter`, err.Error(), func(bool) {
+				editor.screen.Draw(editor.quotes) // Erase the dialog.
+			})
+		}
 	case 'F':
 		editor.quotes.profile.SetFilter("")
+	case 'a':
+		if tokens := editor.tokenize(); len(tokens) > 0 && tokens[0] != `` {
+			price := 0.0 // Ticker without the price removes the anchor.
+			if len(tokens) > 1 {
+				price, _ = strconv.ParseFloat(tokens[1], 64)
+			}
+			if editor.quotes.profile.SetAnchor(editor.quotes.profile.NormalizeTicker(tokens[0]), price) == nil {
+				editor.screen.Draw(editor.quotes)
+			}
+		}
+	case '!':
+		if err := editor.quotes.profile.AddAlert(editor.input); err != nil {
+			editor.dialog = NewMessageDialog(editor.screen, `Alert`, err.Error(), func(bool) {
+				editor.screen.Draw(editor.quotes) // Erase the dialog.
+			})
+		}
+	case '*':
+		if err := editor.quotes.profile.SaveMacro(editor.input, editor.keys); err != nil {
+			editor.dialog = NewMessageDialog(editor.screen, `Macro`, err.Error(), func(bool) {
+				editor.screen.Draw(editor.quotes) // Erase the dialog.
+			})
+		} else {
+			editor.screen.Notify(`Saved the macro, press @ to replay it`)
+		}
 	}
 
 	return editor
@@ -210,6 +261,11 @@ func (editor *LineEditor) execute() *LineEditor {
 //-----------------------------------------------------------------------------
 func (editor *LineEditor) done() bool {
 	editor.screen.ClearLine(0, 3)
+	if layout, profile := editor.screen.layout, editor.quotes.profile; layout.HeaderRow(profile) == 3 {
+		// Compact layout: restore the header the prompt was drawn over.
+		editor.screen.DrawLine(0, 3, layout.Header(profile))
+	}
+	editor.screen.Mode(NormalMode)
 	termbox.HideCursor()
 
 	return true
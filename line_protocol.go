@@ -0,0 +1,81 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`fmt`
+	`net/http`
+	`strings`
+	`time`
+)
+
+// Measurement the quotes are written as unless the profile says otherwise.
+const lineMeasurement = `quote`
+
+// Escapes the measurement, and the tag keys and values, of the line protocol.
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	tagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// LineProtocol is the InfluxDB (or any other line protocol) endpoint the
+// quotes get written to on every refresh, ex. for Grafana dashboards.
+type LineProtocol struct {
+	URL         string // Write endpoint, ex. "http://localhost:8086/api/v2/write?org=home&bucket=mop".
+	Token       string // API token, blank if none.
+	Measurement string // Measurement the quotes are written as, blank for "quote".
+}
+
+// Writes the quotes of the given tickers fetched as of the given time to the
+// line protocol endpoint, if the profile has one. Each stock is a point
+// tagged with its ticker, and the numbers it has are the fields, ex.
+// quote,ticker=AAPL lastTrade=207.48,change=3.1 1561735800000000000
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) writeLines(ctx context.Context, now time.Time, tickers []string) error {
+	settings := quotes.profile.LineProtocol
+	if settings == nil || settings.URL == `` {
+		return nil
+	}
+	measurement := settings.Measurement
+	if measurement == `` {
+		measurement = lineMeasurement
+	}
+
+	body := &strings.Builder{}
+	for _, stock := range quotes.fetched(tickers) {
+		fields := []string{}
+		for _, field := range exportedFields {
+			if number := field.value(stock); number.Known() {
+				fields = append(fields, strings.ToLower(field.name[:1])+field.name[1:]+`=`+logged(number))
+			}
+		}
+		fmt.Fprintf(body, "%s,ticker=%s %s %d\n", measurementEscaper.Replace(measurement),
+			tagEscaper.Replace(strings.TrimSpace(stock.Ticker)), strings.Join(fields, `,`), now.UnixNano())
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, `POST`, settings.URL, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set(`Content-Type`, `text/plain; charset=utf-8`)
+	if settings.Token != `` {
+		request.Header.Set(`Authorization`, `Token `+settings.Token)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("line protocol endpoint responded with %s", response.Status)
+	}
+
+	return nil
+}
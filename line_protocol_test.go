@@ -0,0 +1,42 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLines(t *testing.T) {
+	var body, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body, authorization = string(data), r.Header.Get(`Authorization`)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	profile := &Profile{LineProtocol: &LineProtocol{URL: server.URL, Token: `secret`, Measurement: `stock quote`}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `BRK B`, LastTrade: numberOf(207.48), Change: numberOf(-3.1), Open: unavailable},
+		{Ticker: `IBM`, LastTrade: numberOf(143.9)},
+	}}
+	now := time.Unix(1561735800, 0)
+	require.NoError(t, quotes.writeLines(context.Background(), now, []string{`BRK B`}))
+	assert.Equal(t, "stock\\ quote,ticker=BRK\\ B lastTrade=207.48,change=-3.1 1561735800000000000\n", body, `escaped, known numbers only`)
+	assert.Equal(t, `Token secret`, authorization)
+
+	profile.LineProtocol.URL = server.URL + `/missing`
+	server.Config.Handler = http.NotFoundHandler()
+	quotes.export(context.Background(), now, []string{`IBM`})
+	assert.Equal(t, []string{`Line protocol export failed: line protocol endpoint responded with 404 Not Found`}, quotes.Alerts())
+}
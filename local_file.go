@@ -0,0 +1,128 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`context`
+	`encoding/csv`
+	`encoding/json`
+	`fmt`
+	`io/ioutil`
+	`path/filepath`
+	`strconv`
+	`strings`
+)
+
+// Provider name prefix of the local file, ex. "file:///home/me/quotes.csv".
+const fileScheme = `file://`
+
+// localFile reads the stock quotes from the file on the disk, ex. written
+// by the user's own script or exported by the broker. The file is read
+// again on every refresh. CSV files have the header row naming the
+// columns, and the rest are JSON, with the same fields as the server-sent
+// events (see sseQuote):
+//
+//   ticker,last,change,changePct,volume
+//   AAPL,201.46,1.81,0.91,27316739
+//
+type localFile struct {
+	path string // Path to the file.
+}
+
+// Returns the provider that reads the quotes from the file at the given
+// file:// URL.
+func newLocalFile(url string) *localFile {
+	return &localFile{path: strings.TrimPrefix(url, fileScheme)}
+}
+
+// Fetch reads the file and returns the quotes of the given tickers found in
+// it.
+func (file *localFile) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	data, err := ioutil.ReadFile(file.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotes []sseQuote
+	if strings.EqualFold(filepath.Ext(file.path), `.csv`) {
+		quotes, err = parseQuotesCSV(data)
+	} else {
+		quotes, err = parseQuotesJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %s", file.path, err)
+	}
+
+	found := make(map[string]Stock)
+	for _, quote := range quotes {
+		if quote.Ticker != `` && quote.Last != nil {
+			found[strings.ToUpper(quote.Ticker)] = quote.stock()
+		}
+	}
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if stock, ok := found[strings.ToUpper(ticker)]; ok {
+			stock.Ticker = ticker
+			stocks = append(stocks, stock)
+		}
+	}
+
+	return stocks, nil
+}
+
+// Parses the array of quotes, or a single quote.
+//-----------------------------------------------------------------------------
+func parseQuotesJSON(data []byte) ([]sseQuote, error) {
+	quotes := []sseQuote{}
+	if err := json.Unmarshal(data, &quotes); err != nil {
+		quote := sseQuote{}
+		if json.Unmarshal(data, &quote) != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, nil
+}
+
+// Parses the quotes one per row, with the header row naming the columns,
+// ex. "ticker,last,prevClose". The column names are not case sensitive,
+// the unknown columns are ignored, and so are the blank and N/A values.
+// The numbers could be formatted, ex. "$1,234.50".
+//-----------------------------------------------------------------------------
+func parseQuotesCSV(data []byte) ([]sseQuote, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord, reader.TrimLeadingSpace = -1, true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []sseQuote{}, nil
+	}
+
+	header, quotes := rows[0], []sseQuote{}
+	for _, row := range rows[1:] {
+		fields := map[string]interface{}{}
+		for i, value := range row {
+			if i >= len(header) {
+				break
+			}
+			name := strings.TrimSpace(header[i])
+			if strings.EqualFold(name, `ticker`) {
+				fields[`ticker`] = strings.TrimSpace(value)
+			} else if number, err := strconv.ParseFloat(strings.NewReplacer(`$`, ``, `,`, ``, `%`, ``).Replace(strings.TrimSpace(value)), 64); err == nil {
+				fields[name] = number
+			}
+		}
+		encoded, _ := json.Marshal(fields)
+		quote := sseQuote{}
+		json.Unmarshal(encoded, &quote) // Matches the column names regardless of case.
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `quotes.csv`)
+	require.NoError(t, ioutil.WriteFile(path, []byte("Ticker,Last,PrevClose,Volume,Note\nAAPL,201.46,199.65,\"27,316,739\",held\nIBM,N/A,140,,\n"), 0644))
+
+	provider := NewProvider(`file://`+path, ``, ``, 0)
+	stocks, err := provider.Fetch(context.Background(), []string{`aapl`, `IBM`, `GOOG`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 1, `no last price for IBM, GOOG is missing`)
+	assert.Equal(t, `aapl`, stocks[0].Ticker)
+	assert.Equal(t, `201.460`, stocks[0].LastTrade.String())
+	assert.Equal(t, `1.810`, stocks[0].Change.String())
+	assert.Equal(t, `27.317M`, stocks[0].Volume.String())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("ticker,last\nAAPL,205\n"), 0644))
+	stocks, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, `205.000`, stocks[0].LastTrade.String(), `read again on every refresh`)
+}
+
+func TestLocalFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `quotes.json`)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[ { "ticker": "AAPL", "last": 201.46, "change": 1.81 } ]`), 0644))
+	stocks, err := newLocalFile(`file://`+path).Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 1)
+	assert.Equal(t, `1.810`, stocks[0].Change.String())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{ "ticker": "AAPL", "last": 202 }`), 0644))
+	stocks, err = newLocalFile(`file://`+path).Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, `202.000`, stocks[0].LastTrade.String(), `single quote`)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`not json`), 0644))
+	_, err = newLocalFile(`file://`+path).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+	_, err = newLocalFile(`file:///no/such/file.csv`).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`errors`
+	`fmt`
+	`sort`
+	`strings`
+
+	`github.com/nsf/termbox-go`
+)
+
+// Names of the keys that don't type a character, as they are spelled in
+// the recorded macros. A literal "<" is spelled "<lt>".
+var macroKeys = map[termbox.Key]string{
+	termbox.KeyEnter:      `<enter>`,
+	termbox.KeyEsc:        `<esc>`,
+	termbox.KeySpace:      `<space>`,
+	termbox.KeyTab:        `<tab>`,
+	termbox.KeyBackspace2: `<backspace>`,
+	termbox.KeyArrowUp:    `<up>`,
+	termbox.KeyArrowDown:  `<down>`,
+	termbox.KeyArrowLeft:  `<left>`,
+	termbox.KeyArrowRight: `<right>`,
+	termbox.KeyCtrlA:      `<^A>`,
+	termbox.KeyCtrlB:      `<^B>`,
+	termbox.KeyCtrlE:      `<^E>`,
+	termbox.KeyCtrlF:      `<^F>`,
+	termbox.KeyCtrlN:      `<^N>`,
+	termbox.KeyCtrlP:      `<^P>`,
+}
+
+// MacroNames returns the names of the recorded macros in alphabetical
+// order.
+func (profile *Profile) MacroNames() []string {
+	names := []string{}
+	for name := range profile.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SaveMacro saves the keystrokes under the given name, replacing the macro
+// recorded under the same name before, if any.
+func (profile *Profile) SaveMacro(name string, keys []termbox.Event) error {
+	if name = strings.TrimSpace(name); name == `` {
+		return errors.New(`The macro needs a name`)
+	}
+	if profile.Macros == nil {
+		profile.Macros = make(map[string]string)
+	}
+	profile.Macros[name] = EncodeKeys(keys)
+
+	return profile.Save()
+}
+
+// NewMacroPicker displays the picker to choose the macro to replay, and
+// calls back with its keystrokes once it's picked.
+func NewMacroPicker(screen *Screen, quotes *Quotes, play func(keys []termbox.Event)) *Picker {
+	profile := quotes.profile
+	return NewPicker(screen, `Macro`, profile.MacroNames(), func(name string, ok bool) {
+		screen.Draw(quotes) // Erase the picker before the macro takes over.
+		if ok {
+			if keys, err := DecodeKeys(profile.Macros[name]); err != nil {
+				screen.Notify(err.Error())
+			} else {
+				play(keys)
+			}
+		}
+	})
+}
+
+// EncodeKeys spells the keystrokes as they are saved in the profile, ex.
+// "fchangePercent > 0<enter>". The keys mop doesn't use are left out.
+func EncodeKeys(keys []termbox.Event) string {
+	spelled := ``
+	for _, key := range keys {
+		switch {
+		case key.Ch == '<':
+			spelled += `<lt>`
+		case key.Ch != 0:
+			spelled += string(key.Ch)
+		case key.Key == termbox.KeyBackspace:
+			spelled += macroKeys[termbox.KeyBackspace2]
+		default:
+			spelled += macroKeys[key.Key]
+		}
+	}
+
+	return spelled
+}
+
+// DecodeKeys turns the keystrokes spelled in the profile back into the
+// keyboard events to replay.
+func DecodeKeys(spelled string) ([]termbox.Event, error) {
+	names := map[string]termbox.Key{}
+	for key, name := range macroKeys {
+		names[name] = key
+	}
+
+	keys := []termbox.Event{}
+	for len(spelled) > 0 {
+		if strings.HasPrefix(spelled, `<`) {
+			end := strings.Index(spelled, `>`)
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated key name in `%s`", spelled)
+			}
+			name := spelled[:end+1]
+			if name == `<lt>` {
+				keys = append(keys, termbox.Event{Type: termbox.EventKey, Ch: '<'})
+			} else if key, ok := names[name]; ok {
+				keys = append(keys, termbox.Event{Type: termbox.EventKey, Key: key})
+			} else {
+				return nil, fmt.Errorf("Unknown key %s", name)
+			}
+			spelled = spelled[end+1:]
+			continue
+		}
+		ch := []rune(spelled)[0]
+		keys = append(keys, termbox.Event{Type: termbox.EventKey, Ch: ch})
+		spelled = spelled[len(string(ch)):]
+	}
+
+	return keys, nil
+}
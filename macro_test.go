@@ -0,0 +1,56 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacroKeys(t *testing.T) {
+	keys := []termbox.Event{}
+	for _, ch := range `flast < 100` {
+		keys = append(keys, termbox.Event{Type: termbox.EventKey, Ch: ch})
+	}
+	keys[5] = termbox.Event{Type: termbox.EventKey, Key: termbox.KeySpace}
+	keys = append(keys,
+		termbox.Event{Type: termbox.EventKey, Key: termbox.KeyBackspace},
+		termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEnter},
+		termbox.Event{Type: termbox.EventKey, Ch: 'é'},
+		termbox.Event{Type: termbox.EventKey, Key: termbox.KeyF1}, // Not used by mop.
+	)
+
+	spelled := EncodeKeys(keys)
+	assert.Equal(t, `flast<space><lt> 100<backspace><enter>é`, spelled)
+
+	decoded, err := DecodeKeys(spelled)
+	require.NoError(t, err)
+	assert.Equal(t, EncodeKeys(decoded), spelled)
+	assert.Len(t, decoded, len(keys)-1)
+	assert.Equal(t, termbox.KeyBackspace2, decoded[11].Key)
+
+	_, err = DecodeKeys(`e<home>`)
+	assert.EqualError(t, err, `Unknown key <home>`)
+	_, err = DecodeKeys(`e<enter`)
+	assert.Error(t, err)
+}
+
+func TestSaveMacro(t *testing.T) {
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `.moprc`)}
+	keys := []termbox.Event{{Type: termbox.EventKey, Ch: 'g'}, {Type: termbox.EventKey, Ch: 'e'}}
+	require.NoError(t, profile.SaveMacro(` export `, keys))
+	require.NoError(t, profile.SaveMacro(`group`, keys[:1]))
+	assert.Error(t, profile.SaveMacro(` `, keys))
+	assert.Equal(t, []string{`export`, `group`}, profile.MacroNames())
+	assert.Equal(t, map[string]string{`export`: `ge`, `group`: `g`}, profile.Macros)
+
+	assert.NoError(t, diagnoseMacros(profile))
+	profile.Macros[`broken`] = `<f1>`
+	assert.EqualError(t, diagnoseMacros(profile), `Macro "broken": Unknown key <f1>`)
+}
@@ -23,11 +23,16 @@ import (
 //
 // The <right>...</right> tag is used to right align the enclosed string
 // (ex. when displaying current time in the upper right corner).
+//
+// Background colors are set with <on-color-name>...</on-color-name> tags,
+// for example <on-blue>...</on-blue>. Like attributes they require matching
+// closing tag.
 type Markup struct {
 	Foreground   termbox.Attribute            // Foreground color.
-	Background   termbox.Attribute            // Background color (so far always termbox.ColorDefault).
+	Background   termbox.Attribute            // Background color.
 	RightAligned bool                         // True when the string is right aligned.
 	tags         map[string]termbox.Attribute // Tags to Termbox translation hash.
+	backgrounds  map[string]termbox.Attribute // Background tags to Termbox translation hash.
 	regex        *regexp.Regexp               // Regex to identify the supported tag names.
 }
 
@@ -53,6 +58,16 @@ func NewMarkup() *Markup {
 	markup.tags[`b`] = termbox.AttrBold         // Attribute = 1 << (iota + 4)
 	markup.tags[`u`] = termbox.AttrUnderline
 	markup.tags[`r`] = termbox.AttrReverse
+
+	markup.backgrounds = make(map[string]termbox.Attribute)
+	markup.backgrounds[`on-black`] = termbox.ColorBlack
+	markup.backgrounds[`on-red`] = termbox.ColorRed
+	markup.backgrounds[`on-green`] = termbox.ColorGreen
+	markup.backgrounds[`on-yellow`] = termbox.ColorYellow
+	markup.backgrounds[`on-blue`] = termbox.ColorBlue
+	markup.backgrounds[`on-magenta`] = termbox.ColorMagenta
+	markup.backgrounds[`on-cyan`] = termbox.ColorCyan
+	markup.backgrounds[`on-white`] = termbox.ColorWhite
 	markup.regex = markup.supportedTags() // Once we have the hash we could build the regex.
 
 	return markup
@@ -108,6 +123,15 @@ func (markup *Markup) IsTag(str string) bool {
 
 //-----------------------------------------------------------------------------
 func (markup *Markup) process(tag string, open bool) bool {
+	if background, ok := markup.backgrounds[tag]; ok {
+		if open {
+			markup.Background = background
+		} else {
+			markup.Background = termbox.ColorDefault
+		}
+		return true
+	}
+
 	if attribute, ok := markup.tags[tag]; ok {
 		switch tag {
 		case `right`:
@@ -140,6 +164,9 @@ func (markup *Markup) supportedTags() *regexp.Regexp {
 	for tag := range markup.tags {
 		arr = append(arr, `</?`+tag+`>`)
 	}
+	for tag := range markup.backgrounds {
+		arr = append(arr, `</?`+tag+`>`)
+	}
 
 	return regexp.MustCompile(strings.Join(arr, `|`))
 }
@@ -0,0 +1,88 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+// States of the number: the zero Number has not been reported at all.
+const (
+	blankNumber = iota // Not reported by the provider.
+	knownNumber        // Reported, and the value is known.
+	naNumber           // Reported as not available, ex. P/E ratio of the currency pair.
+)
+
+// Number is the value of the numeric stock quote field, ex. the last trade
+// price or the volume. Besides the value itself it tells the field that has
+// not been reported at all from the one the provider can't supply, so the
+// stock quotes get sorted, filtered, and displayed the way they used to be
+// when they were kept as strings.
+type Number struct {
+	value float64 // The value itself, zero unless known.
+	state byte    // Whether the value is known, blank, or not available.
+}
+
+// The number the provider can't supply.
+var unavailable = Number{state: naNumber}
+
+// Value returns the number's value, zero if it's unknown.
+func (number Number) Value() float64 {
+	return number.value
+}
+
+// Known returns true if the number's value is known.
+func (number Number) Known() bool {
+	return number.state == knownNumber
+}
+
+// String returns the number formatted the way the stock quotes columns
+// expect it, ex. "201.460" or "27.317M", "N/A" if the number is not
+// available, or blank if it's not been reported.
+func (number Number) String() string {
+	switch number.state {
+	case knownNumber:
+		return float2Str(number.value)
+	case naNumber:
+		return noDataIndicator
+	}
+	return ``
+}
+
+// Returns the known number with the given value.
+//-----------------------------------------------------------------------------
+func numberOf(value float64) Number {
+	return Number{value: value, state: knownNumber}
+}
+
+// Returns the number itself if it's known, or the fallback otherwise.
+//-----------------------------------------------------------------------------
+func (number Number) or(fallback Number) Number {
+	if number.Known() {
+		return number
+	}
+	return fallback
+}
+
+// Returns the pointer to the number's value, nil if the value is unknown.
+//-----------------------------------------------------------------------------
+func (number Number) optional() *float64 {
+	if !number.Known() {
+		return nil
+	}
+	value := number.value
+	return &value
+}
+
+// Compares the numbers the way the sorter expects: unknown numbers are less
+// than any known number.
+//-----------------------------------------------------------------------------
+func (number Number) compare(other Number) int {
+	switch {
+	case !number.Known() || !other.Known():
+		return compareBools(number.Known(), other.Known())
+	case number.value < other.value:
+		return -1
+	case number.value > other.value:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumber(t *testing.T) {
+	assert.Equal(t, `201.460`, numberOf(201.46).String())
+	assert.Equal(t, `27.317M`, numberOf(27317000).String())
+	assert.Equal(t, `0.000`, numberOf(0).String())
+	assert.Equal(t, `N/A`, unavailable.String())
+	assert.Equal(t, ``, Number{}.String())
+
+	assert.True(t, numberOf(0).Known())
+	assert.False(t, unavailable.Known())
+	assert.False(t, Number{}.Known())
+	assert.Equal(t, 0.0, unavailable.Value())
+
+	assert.Equal(t, 1.5, *numberOf(1.5).optional())
+	assert.Nil(t, unavailable.optional())
+	assert.Equal(t, numberOf(2), unavailable.or(numberOf(2)))
+	assert.Equal(t, numberOf(1), numberOf(1).or(numberOf(2)))
+}
+
+func TestCompareNumbers(t *testing.T) {
+	assert.Equal(t, -1, numberOf(-2).compare(numberOf(1)))
+	assert.Equal(t, 1, numberOf(1.05e12).compare(numberOf(927e9)))
+	assert.Equal(t, 0, numberOf(1).compare(numberOf(1)))
+	assert.Equal(t, -1, unavailable.compare(numberOf(-1)), `unknown numbers go first`)
+	assert.Equal(t, 0, unavailable.compare(Number{}))
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`strings`
+	`time`
+	`unicode/utf8`
+
+	`github.com/nsf/termbox-go`
+)
+
+// Spaces between the panes of the same row.
+const paneGap = `    `
+
+// Pane is the named section of the dashboard along with its size. The
+// dashboard layout is the list of rows, ex.
+//
+//   "DashboardPanes": [
+//     [ { "Name": "indices", "Width": 48 }, { "Name": "currencies" } ],
+//     [ { "Name": "watchlist", "Height": 12 }, { "Name": "chart", "Width": 36 } ]
+//   ]
+//
+type Pane struct {
+	Name   string // What the pane shows: "market", "indices", "currencies", "sectors", "movers", "gainers", "losers", "events", "watchlist", or "chart".
+	Width  int    // Width in characters, 0 to fit the contents.
+	Height int    // Height in lines including the title, 0 to fit the contents.
+}
+
+// Dashboard layout unless the profile sets its own: one pane per row.
+var defaultPanes = [][]Pane{
+	{{Name: `market`}}, {{Name: `sectors`}}, {{Name: `gainers`}}, {{Name: `losers`}}, {{Name: `events`}},
+}
+
+// Dashboard panes by name. Each returns the lines of the pane, the title
+// first, given the width of the pane, or none if there is nothing to show.
+var dashboardPanes = map[string]func(layout *Layout, dashboard *Dashboard, width int) []string{
+	`market`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		if dashboard.Market == nil {
+			return nil
+		}
+		return append(append(indicesPane(dashboard.Market), ``), currenciesPane(dashboard.Market)...)
+	},
+	`indices`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return indicesPane(dashboard.Market)
+	},
+	`currencies`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return currenciesPane(dashboard.Market)
+	},
+	`sectors`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return sectorsPane(dashboard.Sectors)
+	},
+	`movers`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return append(append(moversPane(`Biggest gainers`, dashboard.Gainers), ``), moversPane(`Biggest losers`, dashboard.Losers)...)
+	},
+	`gainers`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return moversPane(`Biggest gainers`, dashboard.Gainers)
+	},
+	`losers`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return moversPane(`Biggest losers`, dashboard.Losers)
+	},
+	`events`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return eventsPane(dashboard.Events)
+	},
+	`watchlist`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return layout.watchlistPane(dashboard.quotes)
+	},
+	`chart`: func(layout *Layout, dashboard *Dashboard, width int) []string {
+		return layout.chartPane(dashboard.quotes, width)
+	},
+}
+
+// PaneManager lays out the dashboard panes as the grid: the panes of the
+// same row side by side, and the rows one after another, separated by the
+// blank lines. On the dashboard screen it also takes over the keyboard: Tab
+// moves the focus to the next pane, and the focused pane could be resized
+// and scrolled. The sizes and the scroll state last until mop quits.
+type PaneManager struct {
+	screen  *Screen     // Pointer to Screen to draw the dashboard on, nil when it's formatted only.
+	market  *Market     // Market data the dashboard shows.
+	quotes  *Quotes     // Stock quotes the dashboard shows.
+	layout  *Layout     // Layout the panes are formatted with.
+	markup  *Markup     // Markup to tell the tags from the visible text.
+	rows    [][]Pane    // Rows of the panes with the sizes set by the user.
+	focus   int         // Index of the focused pane, left to right and top to bottom, -1 for none.
+	scroll  map[int]int // Number of lines scrolled past in each pane, by the pane index.
+	visible []int       // Indices of the panes drawn last, as some have nothing to show.
+}
+
+// Returns new initialized PaneManager struct that formats the dashboard
+// the profile of the given quotes has set up.
+//-----------------------------------------------------------------------------
+func newPaneManager(layout *Layout, profile *Profile) *PaneManager {
+	rows := defaultPanes
+	if len(profile.DashboardPanes) > 0 {
+		rows = profile.DashboardPanes
+	}
+	copied := make([][]Pane, len(rows)) // The sizes change, the profile doesn't.
+	for i, row := range rows {
+		copied[i] = append([]Pane{}, row...)
+	}
+
+	return &PaneManager{layout: layout, markup: NewMarkup(), rows: copied, focus: -1, scroll: make(map[int]int)}
+}
+
+// NewPaneManager returns new initialized PaneManager struct for the
+// dashboard screen. As part of initialization it focuses the first pane and
+// draws the dashboard.
+func NewPaneManager(screen *Screen, market *Market, quotes *Quotes) *PaneManager {
+	manager := newPaneManager(screen.layout, quotes.profile)
+	manager.screen, manager.market, manager.quotes, manager.focus = screen, market, quotes, 0
+	screen.Clear().Mode(PanesMode).Draw(manager)
+
+	return manager
+}
+
+// Handle takes over the keyboard events on the dashboard screen. It
+// returns true when the user presses any key other than the ones that
+// move the focus, resize, or scroll the panes.
+func (manager *PaneManager) Handle(event termbox.Event) bool {
+	switch {
+	case event.Key == termbox.KeyTab:
+		manager.cycle()
+	case event.Ch == '>' || event.Ch == '<':
+		manager.resize(map[rune]int{'>': 2, '<': -2}[event.Ch], 0)
+	case event.Ch == '+' || event.Ch == '-':
+		manager.resize(0, map[rune]int{'+': 1, '-': -1}[event.Ch])
+	case event.Key == termbox.KeyArrowDown || event.Ch == 'j':
+		manager.scroll[manager.focus]++
+	case event.Key == termbox.KeyArrowUp || event.Ch == 'k':
+		manager.scroll[manager.focus]--
+	default:
+		manager.screen.Mode(NormalMode)
+		return true
+	}
+	manager.screen.Clear().Draw(manager)
+
+	return false
+}
+
+// Moves the focus to the next pane drawn, or back to the first one after
+// the last.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) cycle() {
+	if len(manager.visible) == 0 {
+		return
+	}
+
+	next := 0
+	for i, index := range manager.visible {
+		if index == manager.focus && i+1 < len(manager.visible) {
+			next = i + 1
+		}
+	}
+	manager.focus = manager.visible[next]
+}
+
+// Changes the size of the focused pane by the given number of characters
+// and lines. The pane that fits its contents gets the size it has on the
+// screen first.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) resize(width, height int) {
+	pane := manager.pane(manager.focus)
+	if pane == nil {
+		return
+	}
+
+	content := manager.content(*pane)
+	if pane.Width <= 0 {
+		pane.Width = manager.fit(content)
+	}
+	if pane.Height <= 0 {
+		pane.Height = len(content)
+	}
+	if pane.Width += width; pane.Width < 1 {
+		pane.Width = 1
+	}
+	if pane.Height += height; pane.Height < 2 { // Keep the title and a line.
+		pane.Height = 2
+	}
+}
+
+// Returns the pane with the given index, left to right and top to bottom,
+// or nil if there is no such pane.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) pane(index int) *Pane {
+	for i := range manager.rows {
+		if index < len(manager.rows[i]) {
+			if index < 0 {
+				return nil
+			}
+			return &manager.rows[i][index]
+		}
+		index -= len(manager.rows[i])
+	}
+
+	return nil
+}
+
+// Formats the dashboard from the market data and the stock quotes fetched
+// last.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) String() string {
+	return manager.format(NewDashboard(manager.market, manager.quotes, time.Now()))
+}
+
+// Formats the dashboard with the market overview title at the top and the
+// prompt to leave at the bottom.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) format(dashboard *Dashboard) string {
+	lines := []string{`<u>Market overview</u>`}
+	if dashboard.Market != nil && dashboard.Market.IsClosed {
+		lines[0] += `<right>U.S. markets closed</right>`
+	}
+	lines = append(lines, manager.compose(dashboard)...)
+	prompt := `<r> Press any key to continue </r>`
+	if manager.screen != nil {
+		prompt = `<r> Press any other key to continue </r>`
+	}
+
+	return strings.Join(append(lines, ``, prompt), "\n")
+}
+
+// Returns the lines of the panes. The panes with unknown names, and the
+// ones that have nothing to show, are left out. The panes are cut to their
+// size if it's set, scrolled past the lines the user has scrolled, and all
+// but the last pane of the row are padded to their width.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) compose(dashboard *Dashboard) []string {
+	lines, index := []string{}, -1
+	manager.visible = nil
+	for _, row := range manager.rows {
+		panes, widths, height := [][]string{}, []int{}, 0
+		for _, pane := range row {
+			index++
+			content := manager.render(pane, dashboard)
+			if len(content) == 0 {
+				continue
+			}
+			manager.visible = append(manager.visible, index)
+			if pane.Height > 0 && len(content) > pane.Height {
+				content = manager.scrolled(index, content, pane.Height)
+			}
+			if index == manager.focus {
+				content[0] = `<r>` + content[0] + `</r>`
+			}
+			width := pane.Width
+			if width <= 0 {
+				width = manager.fit(content)
+			}
+			if len(content) > height {
+				height = len(content)
+			}
+			panes, widths = append(panes, content), append(widths, width)
+		}
+		if len(panes) == 0 {
+			continue
+		}
+
+		lines = append(lines, ``)
+		for i := 0; i < height; i++ {
+			line := ``
+			for j, pane := range panes {
+				cell := ``
+				if i < len(pane) {
+					cell = manager.cut(pane[i], widths[j])
+				}
+				if j < len(panes)-1 {
+					cell += strings.Repeat(` `, widths[j]-manager.length(cell)) + paneGap
+				}
+				line += cell
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// Returns the lines of the pane, or none if it's unknown.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) render(pane Pane, dashboard *Dashboard) []string {
+	if render, ok := dashboardPanes[pane.Name]; ok {
+		return render(manager.layout, dashboard, pane.Width)
+	}
+
+	return nil
+}
+
+// Returns the lines of the pane as they are drawn on the screen, ex. to
+// resize the pane that fits its contents.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) content(pane Pane) []string {
+	if manager.quotes == nil {
+		return nil
+	}
+
+	return manager.render(pane, NewDashboard(manager.market, manager.quotes, time.Now()))
+}
+
+// Returns the title and as many of the following lines as fit the given
+// height, past the lines scrolled. The scroll state of the pane gets
+// clamped to the lines the pane has.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) scrolled(index int, content []string, height int) []string {
+	scroll, most := manager.scroll[index], len(content)-height
+	if scroll > most {
+		scroll = most
+	}
+	if scroll < 0 {
+		scroll = 0
+	}
+	manager.scroll[index] = scroll
+
+	return append([]string{content[0]}, content[1+scroll:scroll+height]...)
+}
+
+// Returns the width of the widest line.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) fit(content []string) int {
+	width := 0
+	for _, line := range content {
+		if length := manager.length(line); length > width {
+			width = length
+		}
+	}
+
+	return width
+}
+
+// Returns the number of visible characters in the line, i.e. without the
+// markup tags.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) length(line string) int {
+	length := 0
+	for _, token := range manager.markup.Tokenize(line) {
+		if !manager.markup.regex.MatchString(token) {
+			length += utf8.RuneCountInString(token)
+		}
+	}
+
+	return length
+}
+
+// Returns the line cut to the given number of visible characters. The tags
+// are kept so the colors don't spill over to the next pane.
+//-----------------------------------------------------------------------------
+func (manager *PaneManager) cut(line string, width int) string {
+	cut := ``
+	for _, token := range manager.markup.Tokenize(line) {
+		if manager.markup.regex.MatchString(token) {
+			cut += token
+			continue
+		}
+		runes := []rune(token)
+		if len(runes) > width {
+			runes = runes[:width]
+		}
+		cut, width = cut+string(runes), width-len(runes)
+	}
+
+	return cut
+}
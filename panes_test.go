@@ -0,0 +1,51 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaneManager(t *testing.T) {
+	profile := &Profile{DashboardPanes: [][]Pane{
+		{{Name: `watchlist`}, {Name: `news`}, {Name: `gainers`}},
+	}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200), ChangePct: numberOf(2)},
+		{Ticker: `IBM`, LastTrade: numberOf(140), ChangePct: numberOf(-1)},
+		{Ticker: `MSFT`, LastTrade: numberOf(130), ChangePct: numberOf(1)},
+	}}
+	manager := newPaneManager(NewLayout(), profile)
+	manager.quotes, manager.focus = quotes, 0
+	compose := func() []string { return manager.compose(NewDashboard(nil, quotes, time.Now())) }
+
+	lines := compose()
+	assert.Equal(t, `<r><u>Watchlist</u></r>                          <u>Biggest gainers</u>`, lines[1], `the focused pane is highlighted`)
+	assert.Equal(t, []int{0, 2}, manager.visible, `the unknown pane is left out`)
+
+	manager.cycle()
+	assert.Equal(t, 2, manager.focus, `the focus skips the unknown pane`)
+	manager.cycle()
+	assert.Equal(t, 0, manager.focus, `the focus goes back to the first pane`)
+
+	manager.resize(-12, -2)
+	assert.Equal(t, Pane{Name: `watchlist`, Width: 19, Height: 2}, manager.rows[0][0], `sized as it's drawn first`)
+	assert.Equal(t, [][]Pane{{{Name: `watchlist`}, {Name: `news`}, {Name: `gainers`}}}, profile.DashboardPanes, `the profile is left as is`)
+	lines = compose()
+	assert.Len(t, lines, 4)
+	assert.Equal(t, `  <yellow>MSFT      </>     13<green></>      <yellow>AAPL      </>     200.00  <green>+2.00%</>`, lines[2], `cut to the width`)
+
+	manager.scroll[0] = 5
+	lines = compose()
+	assert.Equal(t, 2, manager.scroll[0], `the scroll is clamped to the lines the pane has`)
+	assert.Equal(t, `<r><u>Watchlist</u></r>              <u>Biggest gainers</u>`, lines[1], `the title stays`)
+	assert.Equal(t, `  <yellow>AAPL      </>     20<green></>      <yellow>AAPL      </>     200.00  <green>+2.00%</>`, lines[2], `scrolled to the last line`)
+
+	manager.resize(0, -5)
+	assert.Equal(t, 2, manager.rows[0][0].Height, `the title and a line are always there`)
+}
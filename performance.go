@@ -0,0 +1,208 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/json`
+	`io/ioutil`
+	`math`
+	`sort`
+	`time`
+)
+
+// How often the portfolio value of the day gets saved while mop is running.
+const historySaveInterval = 15 * time.Minute
+
+// Transaction is the entry of the ledger of buys and sells. The ledger is
+// what tells the returns earned by the holdings from the money put in.
+type Transaction struct {
+	Date    string  // Trade date, ex. "2019-06-28".
+	Ticker  string  // Stock ticker.
+	Shares  float64 // Number of shares bought, negative if sold.
+	Price   float64 // Price per share.
+	Account string  // Account the trade was made in, blank if none.
+}
+
+// Valuation is the market value of the holdings at the end of the day, as
+// recorded in the value history next to the profile, ex. ~/.moprc.history.
+type Valuation struct {
+	Date  string  // Ex. "2019-06-28".
+	Value float64 // Market value of all the holdings.
+}
+
+// cashFlow is the money put in the holdings on the given day, negative if
+// taken out, ex. the shares bought or the dividends paid out.
+type cashFlow struct {
+	date   string  // Ex. "2019-06-28".
+	amount float64 // Money put in, negative if taken out.
+}
+
+// valueHistory is the daily value history loaded from the disk.
+type valueHistory struct {
+	valuations []Valuation // Daily values, oldest first.
+	saved      time.Time   // When the history was saved last.
+}
+
+// RecordValue records the current market value of the holdings as the
+// value of the day, all the accounts together. The history gets saved when
+// the day is new, and then every so often. Nothing is recorded while the
+// snapshots are played back.
+func (quotes *Quotes) RecordValue(now time.Time) error {
+	portfolio := newPortfolio(quotes, ``)
+	if portfolio.Value <= 0 || quotes.profile.replay != nil {
+		return nil
+	}
+
+	profile, today := quotes.profile, now.Format(`2006-01-02`)
+	history := profile.valueHistory()
+	last := len(history.valuations) - 1
+	if last >= 0 && history.valuations[last].Date == today {
+		history.valuations[last].Value = portfolio.Value
+		if now.Sub(history.saved) < historySaveInterval {
+			return nil
+		}
+	} else {
+		history.valuations = append(history.valuations, Valuation{Date: today, Value: portfolio.Value})
+	}
+
+	data, err := json.Marshal(history.valuations)
+	if err != nil {
+		return err
+	}
+	history.saved = now
+
+	return writeAtomically(profile.filename+`.history`, data)
+}
+
+// Returns the value history, loading it on first use.
+//-----------------------------------------------------------------------------
+func (profile *Profile) valueHistory() *valueHistory {
+	if profile.history == nil {
+		profile.history = &valueHistory{}
+		if data, err := ioutil.ReadFile(profile.filename + `.history`); err == nil {
+			json.Unmarshal(data, &profile.history.valuations)
+		}
+	}
+	return profile.history
+}
+
+// Returns the cash flows of the ledger along with the dividends paid out in
+// cash, in the given account or in all of them if the account is blank. The
+// reinvested dividends stay in the holdings, so they are not counted as the
+// money taken out.
+//-----------------------------------------------------------------------------
+func (profile *Profile) cashFlows(account string) []cashFlow {
+	flows := []cashFlow{}
+	for _, transaction := range profile.Transactions {
+		if account == `` || account == transaction.Account {
+			flows = append(flows, cashFlow{transaction.Date, transaction.Shares * transaction.Price})
+		}
+	}
+	for _, holdings := range profile.holdings(account) {
+		for _, holding := range holdings {
+			if !holding.Reinvest {
+				for _, dividend := range holding.Dividends {
+					flows = append(flows, cashFlow{dividend.ExDate, -holding.Shares * dividend.Amount})
+				}
+			}
+		}
+	}
+
+	return flows
+}
+
+// Returns the time-weighted return, in percent, from the first day of the
+// value history to the current value. Each day's return leaves out the
+// money put in or taken out that day, so the return reflects the holdings'
+// performance regardless of the deposits.
+// Returns nil if there is not enough history.
+//-----------------------------------------------------------------------------
+func timeWeighted(history []Valuation, flows []cashFlow, value float64, now time.Time) *float64 {
+	today := now.Format(`2006-01-02`)
+	points := []Valuation{}
+	for _, valuation := range history {
+		if valuation.Date < today {
+			points = append(points, valuation)
+		}
+	}
+	points = append(points, Valuation{Date: today, Value: value})
+	if len(points) < 2 {
+		return nil
+	}
+
+	growth := 1.0
+	for i := 1; i < len(points); i++ {
+		flow := 0.0 // Money put in between the days, assumed at the end of the day.
+		for _, cash := range flows {
+			if cash.date > points[i-1].Date && cash.date <= points[i].Date {
+				flow += cash.amount
+			}
+		}
+		if points[i-1].Value > 0 {
+			growth *= (points[i].Value - flow) / points[i-1].Value
+		}
+	}
+	twr := (growth - 1) * 100
+
+	return &twr
+}
+
+// Returns the money-weighted annual return (XIRR), in percent: the rate at
+// which the cash flows, with the current value taken out today, add up to
+// zero. Returns nil if there are no cash flows or the rate can't be found.
+//-----------------------------------------------------------------------------
+func moneyWeighted(flows []cashFlow, value float64, now time.Time) *float64 {
+	type payment struct {
+		years  float64 // Time since the first cash flow.
+		amount float64 // Money taken out, negative if put in.
+	}
+
+	dated := append([]cashFlow{}, flows...)
+	sort.SliceStable(dated, func(i, j int) bool { return dated[i].date < dated[j].date })
+	payments := []payment{}
+	var first time.Time
+	for _, cash := range dated {
+		date, err := time.ParseInLocation(`2006-01-02`, cash.date, now.Location())
+		if err != nil {
+			continue
+		}
+		if len(payments) == 0 {
+			first = date
+		}
+		payments = append(payments, payment{date.Sub(first).Hours() / 24 / 365, -cash.amount})
+	}
+	if len(payments) == 0 || value <= 0 {
+		return nil
+	}
+	payments = append(payments, payment{now.Sub(first).Hours() / 24 / 365, value})
+
+	npv := func(rate float64) float64 {
+		sum := 0.0
+		for _, payment := range payments {
+			sum += payment.amount / math.Pow(1+rate, payment.years)
+		}
+		return sum
+	}
+
+	// The present value falls as the rate goes up, so the root is found by
+	// bisection between -99.99% and the rate high enough to go negative.
+	low, high := -0.9999, 1.0
+	for npv(high) > 0 && high < 1e6 {
+		high *= 2
+	}
+	if npv(low) < 0 || npv(high) > 0 {
+		return nil
+	}
+	for i := 0; i < 200 && high-low > 1e-10; i++ {
+		if middle := (low + high) / 2; npv(middle) > 0 {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	irr := (low + high) / 2 * 100
+
+	return &irr
+}
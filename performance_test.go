@@ -0,0 +1,75 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeWeighted(t *testing.T) {
+	now := time.Date(2019, 6, 28, 16, 0, 0, 0, time.UTC)
+	history := []Valuation{{`2019-06-26`, 1000}, {`2019-06-27`, 1100}, {`2019-06-28`, 1200}}
+	flows := []cashFlow{{`2019-06-01`, 1000}, {`2019-06-28`, 500}}
+
+	twr := timeWeighted(history, flows, 1650, now) // Today's value is the live one.
+	require.NotNil(t, twr)
+	assert.InDelta(t, 15.0, *twr, 1e-9, `+10%, then +4.55% not counting the money put in`)
+
+	assert.Nil(t, timeWeighted(nil, flows, 1650, now), `no history`)
+}
+
+func TestMoneyWeighted(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	irr := moneyWeighted([]cashFlow{{`2019-01-01`, 1000}}, 1100, now)
+	require.NotNil(t, irr)
+	assert.InDelta(t, 10.0, *irr, 0.05)
+
+	flows := []cashFlow{{`2019-01-01`, 1000}, {`2019-07-02`, 1000}, {`2019-07-02`, -600}}
+	irr = moneyWeighted(flows, 1500, now)
+	require.NotNil(t, irr)
+	assert.True(t, *irr > 0 && *irr < 50)
+
+	assert.Nil(t, moneyWeighted(nil, 1100, now), `no cash flows`)
+	assert.Nil(t, moneyWeighted([]cashFlow{{`2019-01-01`, -1000}}, 1100, now), `money taken out only`)
+}
+
+func TestCashFlows(t *testing.T) {
+	dividends := []Dividend{{ExDate: `2019-05-10`, Amount: 0.77, Price: 190}}
+	profile := &Profile{
+		Transactions: []Transaction{{Date: `2019-01-02`, Ticker: `AAPL`, Shares: 10, Price: 150}},
+		Holdings: map[string]Holding{
+			`AAPL`: {Shares: 10, CostBasis: 1500, Dividends: dividends},
+			`KO`:   {Shares: 10, CostBasis: 500, Dividends: dividends, Reinvest: true},
+		},
+	}
+	assert.Equal(t, []cashFlow{{`2019-01-02`, 1500}, {`2019-05-10`, -7.7}}, profile.cashFlows(``), `reinvested dividends stay in`)
+}
+
+func TestRecordValue(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `.moprc`)
+	profile := &Profile{filename: filename, Holdings: map[string]Holding{`AAPL`: {Shares: 10}}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.00), Change: numberOf(1.00)}}}
+
+	now := time.Date(2019, 6, 27, 16, 0, 0, 0, time.UTC)
+	require.NoError(t, quotes.RecordValue(now))
+	quotes.stocks[0].LastTrade = numberOf(210.00)
+	require.NoError(t, quotes.RecordValue(now.Add(time.Minute)))
+	data, _ := ioutil.ReadFile(filename + `.history`)
+	assert.Equal(t, `[{"Date":"2019-06-27","Value":2000}]`, string(data), `saved every so often`)
+
+	require.NoError(t, quotes.RecordValue(now.Add(historySaveInterval)))
+	require.NoError(t, quotes.RecordValue(now.AddDate(0, 0, 1)))
+	data, _ = ioutil.ReadFile(filename + `.history`)
+	assert.Equal(t, `[{"Date":"2019-06-27","Value":2100},{"Date":"2019-06-28","Value":2100}]`, string(data))
+
+	reloaded := &Profile{filename: filename}
+	assert.Len(t, reloaded.valueHistory().valuations, 2)
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Maximum number of items displayed by the picker at once.
+const pickerRows = 10
+
+// Picker is a scrollable list of items displayed in a modal box. As user
+// types the list gets narrowed down to the items that fuzzy match typed
+// characters. Arrow keys move the selection, Enter picks selected item,
+// and Esc closes the picker without picking anything.
+type Picker struct {
+	screen   *Screen            // Pointer to Screen.
+	title    string             // Picker title displayed in the top border.
+	items    []string           // All the items to pick from.
+	matches  []string           // Items that match the query, best matches first.
+	query    string             // Characters typed so far.
+	cursor   int                // Index of selected item within matches.
+	offset   int                // Index of the first visible item within matches.
+	callback func(string, bool) // Gets called with picked item when the picker is closed.
+	mode     string             // Screen mode to restore when the picker is closed.
+	top      int                // Topmost screen row taken by the picker.
+	bottom   int                // Bottommost screen row taken by the picker.
+}
+
+// NewPicker displays the picker and waits for user input. The callback is
+// invoked with picked item and true, or with blank string and false when
+// the picker is closed with Esc. It is expected to redraw the screen area
+// taken by the picker.
+func NewPicker(screen *Screen, title string, items []string, callback func(string, bool)) *Picker {
+	picker := &Picker{
+		screen:   screen,
+		title:    title,
+		items:    items,
+		callback: callback,
+		mode:     screen.mode,
+	}
+	screen.Mode(PickerMode)
+
+	return picker.filter().draw()
+}
+
+// Handle takes over the keyboard events while the picker is displayed. It
+// returns true when the picker is closed.
+func (picker *Picker) Handle(event termbox.Event) bool {
+	switch event.Key {
+	case termbox.KeyEsc:
+		return picker.done(``, false)
+
+	case termbox.KeyEnter:
+		if len(picker.matches) > 0 {
+			return picker.done(picker.matches[picker.cursor], true)
+		}
+
+	case termbox.KeyArrowUp, termbox.KeyCtrlP:
+		picker.moveTo(picker.cursor - 1)
+
+	case termbox.KeyArrowDown, termbox.KeyCtrlN:
+		picker.moveTo(picker.cursor + 1)
+
+	case termbox.KeyPgup:
+		picker.moveTo(picker.cursor - pickerRows)
+
+	case termbox.KeyPgdn:
+		picker.moveTo(picker.cursor + pickerRows)
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(picker.query) > 0 {
+			picker.query = picker.query[:len(picker.query)-1]
+			picker.filter()
+		}
+
+	case termbox.KeySpace:
+		picker.query += ` `
+		picker.filter()
+
+	default:
+		if event.Ch != 0 {
+			picker.query += string(event.Ch)
+			picker.filter()
+		}
+	}
+	picker.draw()
+
+	return false
+}
+
+//-----------------------------------------------------------------------------
+func (picker *Picker) moveTo(cursor int) *Picker {
+	if cursor >= len(picker.matches) {
+		cursor = len(picker.matches) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	picker.cursor = cursor
+
+	// Scroll the list to keep selected item visible.
+	if picker.cursor < picker.offset {
+		picker.offset = picker.cursor
+	} else if picker.cursor >= picker.offset+pickerRows {
+		picker.offset = picker.cursor - pickerRows + 1
+	}
+
+	return picker
+}
+
+// Narrows down the list of items to the ones that match the query and puts
+// the best matches first.
+func (picker *Picker) filter() *Picker {
+	scores := make(map[string]int)
+	picker.matches = picker.matches[:0]
+	for _, item := range picker.items {
+		if score, ok := fuzzy(picker.query, item); ok {
+			scores[item] = score
+			picker.matches = append(picker.matches, item)
+		}
+	}
+	sort.SliceStable(picker.matches, func(i, j int) bool {
+		return scores[picker.matches[i]] > scores[picker.matches[j]]
+	})
+	picker.offset = 0
+
+	return picker.moveTo(0)
+}
+
+//-----------------------------------------------------------------------------
+func (picker *Picker) done(item string, ok bool) bool {
+	for row := picker.top; row <= picker.bottom; row++ {
+		picker.screen.ClearLine(0, row)
+	}
+	termbox.HideCursor()
+	picker.screen.Mode(picker.mode)
+	picker.callback(item, ok)
+
+	return true
+}
+
+//-----------------------------------------------------------------------------
+func (picker *Picker) draw() *Picker {
+	width := len([]rune(picker.title)) + 4
+	for _, item := range picker.items {
+		if w := len([]rune(item)); w > width {
+			width = w
+		}
+	}
+	if width < 30 {
+		width = 30
+	}
+	width += 4 // Left and right margins.
+
+	x := (picker.screen.width - width - 2) / 2
+	y := (picker.screen.height - pickerRows - 5) / 2
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+
+	rows := []string{
+		padRight(`  > `+picker.query, width),
+		strings.Repeat(` `, width),
+	}
+	for i := picker.offset; i < picker.offset+pickerRows; i++ {
+		switch {
+		case i >= len(picker.matches):
+			rows = append(rows, strings.Repeat(` `, width))
+		case i == picker.cursor:
+			rows = append(rows, `<r>`+padRight(`  `+picker.matches[i], width)+`</r>`)
+		default:
+			rows = append(rows, padRight(`  `+picker.matches[i], width))
+		}
+	}
+	rows = append(rows, strings.Repeat(` `, width))
+
+	picker.top, picker.bottom = picker.screen.DrawBox(x, y, width, picker.title, rows)
+	termbox.SetCursor(x+5+len([]rune(picker.query)), y+1)
+	termbox.Flush()
+
+	return picker
+}
+
+// Pads the string with spaces to the given width.
+func padRight(str string, width int) string {
+	if length := len([]rune(str)); length < width {
+		return str + strings.Repeat(` `, width-length)
+	}
+	return str
+}
+
+// Returns true if all the query characters appear in the item in the same
+// order (case insensitive), along with the score: consecutive matches and
+// matches at the beginning of words score higher.
+func fuzzy(query, item string) (int, bool) {
+	needle := []rune(strings.ToLower(query))
+	haystack := []rune(strings.ToLower(item))
+	score, found, previous := 0, 0, -2
+
+	for i := 0; i < len(haystack) && found < len(needle); i++ {
+		if haystack[i] != needle[found] {
+			continue
+		}
+		switch {
+		case previous == i-1:
+			score += 3 // Consecutive characters.
+		case i == 0 || !unicode.IsLetter(haystack[i-1]):
+			score += 2 // Beginning of a word.
+		default:
+			score++
+		}
+		previous = i
+		found++
+	}
+
+	return score, found == len(needle)
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzy(t *testing.T) {
+	_, ok := fuzzy(`chg`, `Change%`)
+	assert.True(t, ok)
+
+	_, ok = fuzzy(`hcg`, `Change%`)
+	assert.False(t, ok)
+
+	_, ok = fuzzy(``, `Volume`)
+	assert.True(t, ok)
+
+	consecutive, _ := fuzzy(`vol`, `Volume`)
+	sparse, _ := fuzzy(`vol`, `AvgVolume`)
+	assert.True(t, consecutive > sparse)
+
+	word, _ := fuzzy(`l`, `52w Low`)
+	middle, _ := fuzzy(`l`, `Yield`)
+	assert.True(t, word > middle)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/url`
+	`strings`
+)
+
+const polygonURL = `https://api.polygon.io/v2/snapshot/locale/us/markets/stocks/tickers?tickers=%s&apiKey=%s`
+
+// polygon fetches stock quotes using Polygon.io snapshot API, which returns
+// the current day, previous day, last trade, and last NBBO quote of all the
+// requested tickers at once.
+type polygon struct {
+	url string // Snapshot URL, with placeholders for the tickers and the API key.
+	key string // Polygon.io API key.
+}
+
+// Returns new Polygon.io provider for the given API key.
+func newPolygon(key string) *polygon {
+	return &polygon{url: polygonURL, key: key}
+}
+
+// Fetch requests the snapshots in batches of 50 tickers.
+func (polygon *polygon) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if polygon.key == `` {
+		return nil, errors.New(`Polygon.io API key is not set in the profile`)
+	}
+
+	stocks := []Stock{}
+	for _, batch := range batches(tickers, maxTickersPerRequest) {
+		response, err := get(ctx, fmt.Sprintf(polygon.url, url.QueryEscape(strings.Join(batch, `,`)), url.QueryEscape(polygon.key)))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := parsePolygon(body)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, parsed...)
+	}
+
+	return stocks, unknownTickers(tickers, stocks)
+}
+
+// polygonBar is the daily bar, ex. "day" or "prevDay" of the snapshot.
+type polygonBar struct {
+	Open   float64 `json:"o"`
+	High   float64 `json:"h"`
+	Low    float64 `json:"l"`
+	Close  float64 `json:"c"`
+	Volume float64 `json:"v"`
+}
+
+// Parses Polygon.io snapshot, ex.
+//
+//   { "status": "OK", "tickers": [ { "ticker": "AAPL", "todaysChange": 1.81, "todaysChangePerc": 0.91,
+//     "day": { "o": 200, "h": 202, "l": 199.6, "c": 201.46, "v": 27316739 },
+//     "lastQuote": { "p": 201.45, "P": 201.47 }, "lastTrade": { "p": 201.46 }, "prevDay": { ... } } ] }
+//
+// Errors are reported in the "error" or "message" fields instead.
+//-----------------------------------------------------------------------------
+func parsePolygon(body []byte) ([]Stock, error) {
+	data := struct {
+		Status  string
+		Error   string
+		Message string
+		Tickers []struct {
+			Ticker           string
+			TodaysChange     float64
+			TodaysChangePerc float64
+			Day              polygonBar
+			PrevDay          polygonBar
+			LastTrade        struct {
+				Price float64 `json:"p"`
+			}
+			LastQuote struct {
+				Bid float64 `json:"p"`
+				Ask float64 `json:"P"`
+			}
+		}
+	}{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	if data.Status != `OK` && len(data.Tickers) == 0 {
+		message := data.Error
+		if message == `` {
+			message = data.Message
+		}
+		if message == `` {
+			message = `Unexpected Polygon.io response: ` + data.Status
+		}
+		return nil, errors.New(message)
+	}
+
+	number := func(value float64) Number {
+		if value == 0 { // Polygon.io reports missing values as zeros.
+			return Number{}
+		}
+		return numberOf(value)
+	}
+	stocks := []Stock{}
+	for _, snapshot := range data.Tickers {
+		last := snapshot.LastTrade.Price
+		if last == 0 {
+			last = snapshot.Day.Close
+		}
+		stocks = append(stocks, Stock{
+			Ticker:    snapshot.Ticker,
+			LastTrade: number(last),
+			Change:    numberOf(snapshot.TodaysChange),
+			ChangePct: numberOf(snapshot.TodaysChangePerc),
+			Open:      number(snapshot.Day.Open),
+			Low:       number(snapshot.Day.Low),
+			High:      number(snapshot.Day.High),
+			Volume:    number(snapshot.Day.Volume),
+			PrevClose: number(snapshot.PrevDay.Close),
+			Bid:       number(snapshot.LastQuote.Bid),
+			Ask:       number(snapshot.LastQuote.Ask),
+			Advancing: snapshot.TodaysChange >= 0,
+		})
+	}
+
+	return stocks, nil
+}
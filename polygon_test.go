@@ -0,0 +1,71 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const polygonSnapshot = `{"status":"OK","count":2,"tickers":[
+  {"ticker":"AAPL","todaysChange":1.81,"todaysChangePerc":0.9066,
+   "day":{"o":200,"h":202,"l":199.6,"c":201.4,"v":27316739},
+   "lastQuote":{"p":201.45,"s":3,"P":201.47,"S":2},"lastTrade":{"p":201.46,"s":100},
+   "prevDay":{"o":198,"h":200.5,"l":197.8,"c":199.65,"v":25111000}},
+  {"ticker":"KO","todaysChange":-0.3,"todaysChangePerc":-0.633,
+   "day":{"o":0,"h":0,"l":0,"c":47.1,"v":0},"lastQuote":{},"lastTrade":{},"prevDay":{"c":47.4}}
+]}`
+
+func TestParsePolygon(t *testing.T) {
+	stocks, err := parsePolygon([]byte(polygonSnapshot))
+	require.NoError(t, err)
+	require.Len(t, stocks, 2)
+
+	aapl := stocks[0]
+	assert.Equal(t, `AAPL`, aapl.Ticker)
+	assert.Equal(t, `201.460`, aapl.LastTrade.String())
+	assert.Equal(t, `0.907`, aapl.ChangePct.String())
+	assert.Equal(t, `27.317M`, aapl.Volume.String())
+	assert.Equal(t, `199.650`, aapl.PrevClose.String())
+	assert.Equal(t, `201.450`, aapl.Bid.String())
+	assert.Equal(t, `201.470`, aapl.Ask.String())
+	assert.True(t, aapl.Advancing)
+
+	ko := stocks[1]
+	assert.Equal(t, `47.100`, ko.LastTrade.String(), `falls back to the day close`)
+	assert.Equal(t, ``, ko.Open.String())
+	assert.Equal(t, ``, ko.Bid.String())
+	assert.False(t, ko.Advancing)
+
+	_, err = parsePolygon([]byte(`{"status":"ERROR","request_id":"x","error":"Unknown API Key"}`))
+	assert.EqualError(t, err, `Unknown API Key`)
+	_, err = parsePolygon([]byte(`{"status":"NOT_AUTHORIZED","message":"Upgrade your plan"}`))
+	assert.EqualError(t, err, `Upgrade your plan`)
+}
+
+func TestPolygonFetch(t *testing.T) {
+	var tickers string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tickers = r.URL.Query().Get(`tickers`)
+		fmt.Fprint(w, polygonSnapshot)
+	}))
+	defer server.Close()
+
+	provider := newPolygon(`secret`)
+	provider.url = server.URL + `?tickers=%s&apiKey=%s`
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `KO`})
+	require.NoError(t, err)
+	assert.Len(t, stocks, 2)
+	assert.Equal(t, `AAPL,KO`, tickers)
+
+	_, err = newPolygon(``).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`sort`
+	`time`
+)
+
+// Portfolio aggregates the holdings defined in the profile using current
+// stock quotes.
+type Portfolio struct {
+	Account   string   // Account the portfolio is of, blank for all the accounts together.
+	Value     float64  // Market value of all the holdings.
+	Cash      float64  // Cash balance of the accounts.
+	Cost      float64  // Total cost basis of all the holdings.
+	Realized  float64  // Profit or loss realized by the sales in the ledger.
+	DayChange float64  // Change of the market value since previous close.
+	Largest   string   // Ticker of the largest position.
+	MaxWeight float64  // Weight of the largest position, in percent.
+	TWR       *float64 // Time-weighted return over the value history, in percent, nil without history or for a single account.
+	IRR       *float64 // Money-weighted annual return of the ledger, in percent, nil without transactions.
+	Countries []Weight // Share of the market value by the company's country, the largest first.
+}
+
+// Weight is the share of the portfolio's market value, ex. of the country.
+type Weight struct {
+	Name    string  // Ex. "United States", or "Unknown" when the fundamentals are not fetched.
+	Percent float64 // Share of the market value, in percent.
+}
+
+// NewPortfolio calculates the portfolio aggregates of the account selected
+// in the profile from the stock quotes fetched last. Stocks without
+// holdings are ignored.
+func NewPortfolio(quotes *Quotes) *Portfolio {
+	return newPortfolio(quotes, quotes.profile.Account)
+}
+
+// Calculates the portfolio aggregates of the given account, blank for all
+// the accounts together. The value history is kept for all the accounts
+// together, so the time-weighted return is left out for a single account.
+//-----------------------------------------------------------------------------
+func newPortfolio(quotes *Quotes, account string) *Portfolio {
+	profile := quotes.profile
+	portfolio, largest := &Portfolio{Account: account, Cash: profile.cash(account), Realized: profile.realized(account)}, 0.0
+	countries := make(map[string]float64)
+
+	for _, stock := range quotes.stocks {
+		shares, cost := profile.position(account, stock.Ticker)
+		if shares == 0 {
+			continue
+		}
+		values := variables(stock, profile)
+		value := shares * values[`last`].(float64)
+		portfolio.Value += value
+		portfolio.Cost += cost
+		portfolio.DayChange += shares * values[`change`].(float64)
+		if value > largest {
+			portfolio.Largest, largest = stock.Ticker, value
+		}
+		country := stock.Country
+		if country == `` {
+			country = `Unknown`
+		}
+		countries[country] += value
+	}
+	if portfolio.Value > 0 {
+		portfolio.MaxWeight = largest * 100 / portfolio.Value
+		portfolio.Countries = weights(countries, portfolio.Value)
+		now, flows := time.Now(), profile.cashFlows(account)
+		if account == `` {
+			portfolio.TWR = timeWeighted(profile.valueHistory().valuations, flows, portfolio.Value, now)
+		}
+		portfolio.IRR = moneyWeighted(flows, portfolio.Value, now)
+	}
+
+	return portfolio
+}
+
+// Returns the shares of the total of the given values by name, the largest
+// first.
+//-----------------------------------------------------------------------------
+func weights(values map[string]float64, total float64) []Weight {
+	shares := []Weight{}
+	for name, value := range values {
+		shares = append(shares, Weight{name, value * 100 / total})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Percent != shares[j].Percent {
+			return shares[i].Percent > shares[j].Percent
+		}
+		return shares[i].Name < shares[j].Name
+	})
+
+	return shares
+}
+
+// Returns portfolio aggregates available to the alert rules.
+func (portfolio *Portfolio) variables() map[string]interface{} {
+	values := map[string]interface{}{
+		`value`:     portfolio.Value,
+		`cash`:      portfolio.Cash,
+		`cost`:      portfolio.Cost,
+		`pnl`:       portfolio.Value - portfolio.Cost,
+		`realized`:  portfolio.Realized,
+		`pnlPct`:    0.0,
+		`dayPnl`:    portfolio.DayChange,
+		`dayPnlPct`: 0.0,
+		`maxWeight`: portfolio.MaxWeight,
+		`twr`:       0.0,
+		`irr`:       0.0,
+	}
+	if portfolio.TWR != nil {
+		values[`twr`] = *portfolio.TWR
+	}
+	if portfolio.IRR != nil {
+		values[`irr`] = *portfolio.IRR
+	}
+	if portfolio.Cost > 0 {
+		values[`pnlPct`] = (portfolio.Value - portfolio.Cost) * 100 / portfolio.Cost
+	}
+	if previous := portfolio.Value - portfolio.DayChange; previous > 0 {
+		values[`dayPnlPct`] = portfolio.DayChange * 100 / previous
+	}
+
+	return values
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`math`
+	`strings`
+	`time`
+)
+
+// U.S. markets open at 9:30am New York time, in minutes since midnight.
+const marketOpen = 9*60 + 30
+
+// Titles and brief titles of the Last, Change, and Change% columns in the
+// pre-market watch mode.
+var preMarketTitles = [3][2]string{{`PreLast`, `PreL`}, {`PreChg`, `PreC`}, {`Gap%`, `Gap%`}}
+
+// Columns displayed in the pre-market watch mode along with the ticker. The
+// intraday columns are meaningless before the open and get hidden.
+var preMarketColumns = map[string]bool{
+	`LastTrade`: true, `Change`: true, `ChangePct`: true, `Low52`: true, `High52`: true,
+	`AvgVolume`: true, `PeRatio`: true, `MarketCap`: true,
+}
+
+// WatchPreMarket turns the pre-market watch mode on when it's time as set
+// in the profile, and turns it off at the bell. It returns true when the
+// mode has changed and the stock quotes need to be redrawn.
+func (profile *Profile) WatchPreMarket(now time.Time) bool {
+	on := false
+	if profile.PreMarket > 0 {
+		now = inNewYork(now)
+		minutes := now.Hour()*60 + now.Minute()
+		on = weekday(now) && minutes >= marketOpen-profile.PreMarket && minutes < marketOpen
+	}
+	changed := on != profile.preMarket
+	profile.preMarket = on
+
+	return changed
+}
+
+// Returns the pre-market banner: time left till the open and the stocks
+// that report earnings today. Blank unless pre-market watch mode is on.
+func (layout *Layout) preMarketBanner(quotes *Quotes, now time.Time) string {
+	if !quotes.profile.preMarket {
+		return ``
+	}
+
+	now = inNewYork(now)
+	left := marketOpen - now.Hour()*60 - now.Minute()
+	banner := fmt.Sprintf(`<yellow>Pre-market</> opens in %dh%02dm`, left/60, left%60)
+	reporting := []string{}
+	for _, stock := range quotes.stocks {
+		if reportsOn(stock, now) {
+			reporting = append(reporting, strings.TrimSpace(stock.Ticker))
+		}
+	}
+	if len(reporting) > 0 {
+		banner += `, earnings today: <yellow>` + strings.Join(reporting, `, `) + `</>`
+	}
+
+	return banner
+}
+
+// Replaces the last trade, change, and change percent with pre-market price,
+// change, and the gap vs previous close while in the pre-market watch mode.
+// The stocks without pre-market trades are left as is.
+//-----------------------------------------------------------------------------
+func premarket(stock Stock, profile *Profile) Stock {
+	price, close := stock.PrePrice.Value(), stock.PrevClose.Value()
+	if !profile.preMarket || price <= 0 || close <= 0 {
+		return stock
+	}
+
+	change := price - close
+	stock.LastTrade = stock.PrePrice
+	stock.Change = numberOf(change)
+	stock.ChangePct = numberOf(change * 100 / close)
+	stock.Advancing = change >= 0
+
+	return stock
+}
+
+// Replaces the pre-market and after hours prices and volumes with N/A
+// unless the quote is from that session, so the ones left over from the
+// session before don't pass for the current ones. The quotes from unknown
+// session, ex. the providers other than Yahoo, are left as is.
+//-----------------------------------------------------------------------------
+func sessions(stock Stock) Stock {
+	switch stock.MarketState {
+	case ``:
+		return stock
+	case `PRE`:
+		stock.PostPrice, stock.PostVolume = unavailable, unavailable
+		stock.PrePrice, stock.PreVolume = stock.PrePrice.or(unavailable), stock.PreVolume.or(unavailable)
+	case `POST`, `POSTPOST`:
+		stock.PrePrice, stock.PreVolume = unavailable, unavailable
+		stock.PostPrice, stock.PostVolume = stock.PostPrice.or(unavailable), stock.PostVolume.or(unavailable)
+	default:
+		stock.PrePrice, stock.PreVolume, stock.PostPrice, stock.PostVolume = unavailable, unavailable, unavailable, unavailable
+	}
+
+	return stock
+}
+
+// Returns true if the stock's earnings are scheduled on the same day in New
+// York as the given time.
+//-----------------------------------------------------------------------------
+func reportsOn(stock Stock, now time.Time) bool {
+	if stock.Earnings <= 0 {
+		return false
+	}
+
+	return inNewYork(time.Unix(stock.Earnings, 0)).Format(`2006-01-02`) == inNewYork(now).Format(`2006-01-02`)
+}
+
+//-----------------------------------------------------------------------------
+func weekday(now time.Time) bool {
+	return now.Weekday() != time.Saturday && now.Weekday() != time.Sunday
+}
+
+// Yahoo reports timestamps as seconds since epoch, ex. 1561665600.
+//-----------------------------------------------------------------------------
+func timestamp(v interface{}) int64 {
+	if seconds, ok := v.(float64); ok {
+		return int64(math.Round(seconds))
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreMarket(t *testing.T) {
+	newYork := inNewYork(time.Now()).Location()
+	profile := &Profile{PreMarket: 90}
+
+	assert.False(t, profile.WatchPreMarket(time.Date(2019, 6, 28, 7, 59, 0, 0, newYork)))
+	assert.True(t, profile.WatchPreMarket(time.Date(2019, 6, 28, 8, 0, 0, 0, newYork)))
+	assert.True(t, profile.preMarket)
+	assert.False(t, profile.WatchPreMarket(time.Date(2019, 6, 28, 9, 29, 0, 0, newYork)))
+	assert.True(t, profile.WatchPreMarket(time.Date(2019, 6, 28, 9, 30, 0, 0, newYork)), `switches back at the bell`)
+	assert.False(t, profile.preMarket)
+	assert.False(t, profile.WatchPreMarket(time.Date(2019, 6, 29, 9, 0, 0, 0, newYork)), `no pre-market on weekends`)
+
+	stock := Stock{Ticker: `AAPL`, LastTrade: numberOf(200.000), PrePrice: numberOf(210.000), PrevClose: numberOf(200.000)}
+	assert.Equal(t, `200.000`, premarket(stock, profile).LastTrade.String())
+	profile.preMarket = true
+	stock = premarket(stock, profile)
+	assert.Equal(t, `210.000`, stock.LastTrade.String())
+	assert.Equal(t, `10.000`, stock.Change.String())
+	assert.Equal(t, `5.000`, stock.ChangePct.String())
+
+	now := time.Date(2019, 6, 28, 8, 15, 0, 0, newYork)
+	assert.True(t, reportsOn(Stock{Earnings: 1561723200}, now)) // 2019-06-28 8am New York.
+	assert.False(t, reportsOn(Stock{Earnings: 1561809600}, now))
+	assert.False(t, reportsOn(Stock{}, now))
+
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, Earnings: 1561723200}, {Ticker: `IBM`}}}
+	assert.Equal(t, `<yellow>Pre-market</> opens in 1h15m, earnings today: <yellow>AAPL</>`, NewLayout().preMarketBanner(quotes, now))
+	assert.Equal(t, `Gap%`, NewLayout().titleOf(3, profile))
+}
+
+func TestSessions(t *testing.T) {
+	stock := Stock{Ticker: `AAPL`, PrePrice: numberOf(210), PreVolume: numberOf(1.2e6), PostPrice: numberOf(205)}
+	assert.Equal(t, stock, sessions(stock), `unknown session`)
+
+	pre := sessions(Stock{Ticker: `AAPL`, MarketState: `PRE`, PrePrice: numberOf(210), PostPrice: numberOf(205)})
+	assert.Equal(t, `210.000`, pre.PrePrice.String())
+	assert.Equal(t, `N/A`, pre.PreVolume.String(), `not reported`)
+	assert.Equal(t, `N/A`, pre.PostPrice.String(), `left over from yesterday`)
+
+	post := sessions(Stock{Ticker: `AAPL`, MarketState: `POST`, PrePrice: numberOf(210), PostPrice: numberOf(205), PostVolume: numberOf(3e5)})
+	assert.Equal(t, `N/A`, post.PrePrice.String())
+	assert.Equal(t, `300.000K`, post.PostVolume.String())
+
+	regular := sessions(Stock{Ticker: `AAPL`, MarketState: `REGULAR`, PrePrice: numberOf(210), PostPrice: numberOf(205)})
+	assert.False(t, regular.PrePrice.Known())
+	assert.False(t, regular.PostPrice.Known())
+}
@@ -6,8 +6,12 @@ package mop
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"reflect"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/Knetic/govaluate"
 )
@@ -17,33 +21,96 @@ import (
 // the ~/.moprc file.
 type Profile struct {
 	Tickers          []string                       // List of stock tickers to display.
+	Provider         string                         // Stock quotes provider: "alphavantage", "finnhub", "iex", "polygon", "sse", "stooq", "tiingo", file:// URL, or blank for Yahoo.
+	APIKey           string                         // API key of the stock quotes provider, if needed.
+	Endpoint         string                         // Event stream URL of the "sse" provider.
+	RateLimit        int                            // Maximum number of provider requests per minute, 0 for the provider default.
+	Budgets          map[string]int                 // Maximum number of requests per minute by provider or host, ex. {"yahoo": 60}, on top of the defaults.
+	Proxy            string                         // HTTP(S) proxy URL, ex. "http://proxy.corp:3128", blank for HTTP_PROXY/HTTPS_PROXY.
+	Socks5           string                         // SOCKS5 proxy address, ex. "user:password@localhost:1080", takes precedence over the Proxy.
 	MarketRefresh    int                            // Time interval to refresh market data.
 	QuotesRefresh    int                            // Time interval to refresh stock quotes.
+	ClockRefresh     int                            // Time interval to redraw the clock, 0 for every second, negative to never redraw it.
 	SortColumn       int                            // Column number by which we sort stock quotes.
 	Ascending        bool                           // True when sort order is ascending.
 	Grouped          bool                           // True when stocks are grouped by advancing/declining.
 	Filter           string                         // Filter in human form
+	RowShading       string                         // Background color of every other row, ex. "blue" (blank for none).
+	GridLines        bool                           // True when columns are separated by vertical lines.
+	FlashChanges     bool                           // True when rows of the stocks changed since last refresh are displayed in bold.
+	Density          string                         // Layout density: "compact", "comfortable", or blank for normal.
+	Columns          []string                       // User-defined columns, ex. "gapPct = (open - low) / low * 100".
+	Holdings         map[string]Holding             // Number of shares held and cost basis per ticker.
+	Accounts         map[string]Account             // Brokerage accounts with their own cash and holdings, by name.
+	Account          string                         // Account the portfolio is shown for, blank for all the accounts together.
+	Transactions     []Transaction                  // Ledger of buys and sells, for the time- and money-weighted returns.
+	Histogram        bool                           // True when change distribution histogram is displayed.
+	Reference        string                         // Price the change is calculated from: "open", "anchor", or blank for previous close.
+	Anchors          map[string]float64             // User-set anchor prices per ticker.
+	ColumnSets       map[string][]string            // Columns displayed for each quote type, ex. "INDEX": ["LastTrade", "Change"].
+	ExtraColumns     []string                       // Optional columns displayed along with the rest, ex. ["PrePrice", "PostVolume"].
+	Constituents     map[string][]string            // Top constituents of the indices, ex. "^DJI": ["AAPL", "MSFT"].
+	Alerts           []string                       // Alert rules, ex. "AAPL: last > 200" or "dayPnlPct < -2".
+	Digest           *Digest                        // Daily digest schedule and delivery, nil when not opted in.
+	Server           *ServerSettings                // Access tokens and TLS for the web view, nil for read-only access without TLS.
+	Updates          *UpdateSettings                // Update checks on startup and the release channel, nil when not opted in.
+	PreMarket        int                            // Minutes before the open pre-market watch mode starts at, 0 to disable.
+	Pinned           []string                       // Tickers refreshed every time even when they are off-screen.
+	OffscreenRefresh int                            // Off-screen tickers get refreshed once every so many refreshes, 0 for every time.
+	Fundamentals     bool                           // True when sector, analyst ratings, earnings dates, share counts, and volatility are fetched (and cached) for the tickers.
+	ESG              string                         // Provider of the ESG scores, only "yahoo" for now, blank to not fetch them.
+	LowFloat         float64                        // Number of shares available for trading below which the stock is flagged as low float, 0 for 20 million.
+	Watchlists       map[string]Watchlist           // Groups of tickers with their own refresh interval and provider, by name.
+	Retry            *RetrySettings                 // How the failed stock quotes and market data requests get retried, nil for the defaults.
+	HistorySize      int                            // Number of recent prices of each stock kept in memory, 0 for the default of 60.
+	TrendWindow      int                            // Number of refreshes the trend is estimated over, 0 for the default of 5.
+	QuoteLog         bool                           // True when the fetched quotes get appended to the log next to the profile, ex. ~/.moprc.quotes.csv.
+	LineProtocol     *LineProtocol                  // InfluxDB or other line protocol endpoint the fetched quotes get written to, nil when not opted in.
+	DashboardPanes   [][]Pane                       // Rows of the dashboard panes, blank for the default layout.
+	List             string                         // Watchlist the stock quotes are shown for, blank for all the tickers.
+	Startup          []string                       // Commands executed on launch, ex. ["list tech", "sort changePercent desc"].
+	Macros           map[string]string              // Recorded keystrokes by macro name, ex. "gainers": "fchangePercent > 0<enter>".
+	ConfirmQuit      bool                           // True to ask for confirmation before quitting.
+	EscapeCancels    bool                           // True when Esc only cancels, and q is the only key that quits.
+	HyphenateClasses bool                           // True to add the share classes the way Yahoo lists them, ex. BRK.B as BRK-B.
+	Aliases          map[string]string              // Labels displayed in place of the tickers and accepted in the prompts, ex. "BRK-B": "Berkshire".
 	filterExpression *govaluate.EvaluableExpression // The filter as a govaluate expression
+	computed         []computedColumn               // User-defined columns as govaluate expressions.
+	alerts           []alert                        // Alert rules as govaluate expressions.
 	selectedColumn   int                            // Stores selected column number when the column editor is active.
+	selectedRow      int                            // Stores row number under the cursor in bulk edit mode.
+	marked           map[string]bool                // Tickers marked for bulk actions, nil unless bulk edit is active.
+	preMarket        bool                           // True while pre-market watch mode is on.
+	source           string                         // Stock quotes provider set on the command line, overrides the Provider.
+	socks5           string                         // SOCKS5 proxy address set on the command line, overrides the Socks5.
+	replay           *Replay                        // Recorded snapshots played back instead of fetching the quotes, if any.
+	feed             *Feed                          // Quotes read from stdin instead of fetching them, if any.
+	dryRun           bool                           // True when the changes are not saved but kept in pending.
+	pending          []byte                         // Profile that would have been saved during the dry run, JSON.
+	history          *valueHistory                  // Daily portfolio values, nil until loaded.
 	filename         string                         // Path to the file in which the configuration is stored
 }
 
+// Holding describes the position in the particular stock.
+type Holding struct {
+	Shares    float64    // Number of shares held.
+	CostBasis float64    // Total amount paid for the shares.
+	Reinvest  bool       // True when the dividends are reinvested in the stock (DRIP).
+	Dividends []Dividend // Dividends received since the shares were bought.
+}
+
 // Creates the profile and attempts to load the settings from ~/.moprc file.
 // If the file is not there it gets created with default values.
 func NewProfile(filename string) *Profile {
 	profile := &Profile{filename: filename}
 	data, err := ioutil.ReadFile(filename)
-	if err != nil { // Set default values:
-		profile.MarketRefresh = 12 // Market data gets fetched every 12s (5 times per minute).
-		profile.QuotesRefresh = 5  // Stock quotes get updated every 5s (12 times per minute).
-		profile.Grouped = false    // Stock quotes are *not* grouped by advancing/declining.
-		profile.Tickers = []string{`AAPL`, `C`, `GOOG`, `IBM`, `KO`, `ORCL`, `V`}
-		profile.SortColumn = 0   // Stock quotes are sorted by ticker name.
-		profile.Ascending = true // A to Z.
-		profile.Filter = ""
+	if err != nil {
+		profile.setDefaults()
 		profile.Save()
 	} else {
 		json.Unmarshal(data, profile)
+		profile.SetColumns(profile.Columns)
+		profile.SetAlerts(profile.Alerts)
 		profile.SetFilter(profile.Filter)
 	}
 	profile.selectedColumn = -1
@@ -51,34 +118,63 @@ func NewProfile(filename string) *Profile {
 	return profile
 }
 
-// Save serializes settings using JSON and saves them in ~/.moprc file.
+// Sets default values of the new profile.
+//-----------------------------------------------------------------------------
+func (profile *Profile) setDefaults() {
+	profile.MarketRefresh = 12 // Market data gets fetched every 12s (5 times per minute).
+	profile.QuotesRefresh = 5  // Stock quotes get updated every 5s (12 times per minute).
+	profile.Grouped = false    // Stock quotes are *not* grouped by advancing/declining.
+	profile.Tickers = []string{`AAPL`, `C`, `GOOG`, `IBM`, `KO`, `ORCL`, `V`}
+	profile.SortColumn = 0   // Stock quotes are sorted by ticker name.
+	profile.Ascending = true // A to Z.
+	profile.Filter = ""
+}
+
+// ClockInterval returns how often the clock should be redrawn, or 0 if it
+// should not be redrawn at all (and therefore not wake up the CPU).
+func (profile *Profile) ClockInterval() time.Duration {
+	switch {
+	case profile.ClockRefresh < 0:
+		return 0
+	case profile.ClockRefresh == 0:
+		return time.Second
+	}
+	return time.Duration(profile.ClockRefresh) * time.Second
+}
+
+// MarketInterval returns how often the market data should be refreshed,
+// falling back to 12 seconds if the profile leaves it unset.
+func (profile *Profile) MarketInterval() time.Duration {
+	if profile.MarketRefresh <= 0 {
+		return 12 * time.Second
+	}
+	return time.Duration(profile.MarketRefresh) * time.Second
+}
+
+// Save serializes settings using JSON and saves them in ~/.moprc file. The
+// file is replaced atomically, and the previous version is backed up.
 func (profile *Profile) Save() error {
 	data, err := json.Marshal(profile)
 	if err != nil {
 		return err
 	}
+	if profile.dryRun {
+		profile.pending = data
+		return nil
+	}
 
-	return ioutil.WriteFile(profile.filename, data, 0644)
+	return saveWithBackup(profile.filename, data)
 }
 
 // AddTickers updates the list of existing tikers to add the new ones making
-// sure there are no duplicates.
+// sure there are no duplicates. The tickers get normalized first, see
+// CheckTickers.
 func (profile *Profile) AddTickers(tickers []string) (added int, err error) {
 	added, err = 0, nil
-	existing := make(map[string]bool)
-
-	// Build a hash of existing tickers so we could look it up quickly.
-	for _, ticker := range profile.Tickers {
-		existing[ticker] = true
-	}
-
-	// Iterate over the list of new tickers excluding the ones that
-	// already exist.
-	for _, ticker := range tickers {
-		if _, found := existing[ticker]; !found {
-			profile.Tickers = append(profile.Tickers, ticker)
-			added++
-		}
+	fresh, _ := profile.CheckTickers(tickers)
+	for _, ticker := range fresh {
+		profile.Tickers = append(profile.Tickers, ticker)
+		added++
 	}
 
 	if added > 0 {
@@ -93,8 +189,9 @@ func (profile *Profile) AddTickers(tickers []string) (added int, err error) {
 func (profile *Profile) RemoveTickers(tickers []string) (removed int, err error) {
 	removed, err = 0, nil
 	for _, ticker := range tickers {
+		ticker = tickerKey(profile.NormalizeTicker(ticker))
 		for i, existing := range profile.Tickers {
-			if ticker == existing {
+			if ticker == tickerKey(existing) {
 				// Requested ticker is there: remove i-th slice item.
 				profile.Tickers = append(profile.Tickers[:i], profile.Tickers[i+1:]...)
 				removed++
@@ -127,20 +224,152 @@ func (profile *Profile) Regroup() error {
 	return profile.Save()
 }
 
-// SetFilter creates a govaluate.EvaluableExpression.
-func (profile *Profile) SetFilter(filter string) {
-	if len(filter) > 0 {
-		var err error
-		profile.filterExpression, err = govaluate.NewEvaluableExpression(filter)
+// ToggleHistogram flips the flag that controls whether the distribution
+// of percent changes is displayed below the stock quotes.
+func (profile *Profile) ToggleHistogram() error {
+	profile.Histogram = !profile.Histogram
+	return profile.Save()
+}
 
-		if err != nil {
-			panic(err)
+// Redensify cycles layout density from normal to compact to comfortable
+// and back to normal.
+func (profile *Profile) Redensify() error {
+	switch profile.Density {
+	case ``:
+		profile.Density = `compact`
+	case `compact`:
+		profile.Density = `comfortable`
+	default:
+		profile.Density = ``
+	}
+	return profile.Save()
+}
+
+// ChangeReference cycles the price the change columns are calculated from:
+// previous close, today's open, and user-set anchor price.
+func (profile *Profile) ChangeReference() error {
+	switch profile.Reference {
+	case ``:
+		profile.Reference = `open`
+	case `open`:
+		profile.Reference = `anchor`
+	default:
+		profile.Reference = ``
+	}
+	return profile.Save()
+}
+
+// SetAnchor sets the anchor price for the given ticker. Zero price removes
+// the anchor.
+func (profile *Profile) SetAnchor(ticker string, price float64) error {
+	if price > 0 {
+		if profile.Anchors == nil {
+			profile.Anchors = make(map[string]float64)
 		}
+		profile.Anchors[ticker] = price
+	} else {
+		delete(profile.Anchors, ticker)
+	}
+	return profile.Save()
+}
 
+// SetColumns parses user-defined column definitions into govaluate
+// expressions. Invalid definitions are skipped, and the first error is
+// returned.
+func (profile *Profile) SetColumns(columns []string) (err error) {
+	profile.computed = nil
+	for _, definition := range columns {
+		column, e := newComputedColumn(definition)
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		profile.computed = append(profile.computed, column)
+	}
+
+	profile.Columns = columns
+	return
+}
+
+// SetAlerts parses alert rules into govaluate expressions. Invalid rules
+// are skipped, and the first error is returned.
+func (profile *Profile) SetAlerts(rules []string) (err error) {
+	profile.alerts = nil
+	for _, rule := range rules {
+		alert, e := newAlert(rule)
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		profile.alerts = append(profile.alerts, alert)
+	}
+
+	profile.Alerts = rules
+	return
+}
+
+// SetFilter creates a govaluate.EvaluableExpression. If the filter does not
+// parse the current filter remains unchanged.
+func (profile *Profile) SetFilter(filter string) error {
+	if len(filter) > 0 {
+		expression, err := newExpression(filter)
+		if err != nil {
+			return fmt.Errorf("Invalid filter `%s`: %s", filter, err)
+		}
+		profile.filterExpression = expression
 	} else if len(filter) == 0 && profile.filterExpression != nil {
 		profile.filterExpression = nil
 	}
 
 	profile.Filter = filter
-	profile.Save()
+	return profile.Save()
+}
+
+// Set changes the setting with the given name, ex. "QuotesRefresh", to the
+// value given as JSON, and saves the profile. Plain strings don't need to
+// be quoted. Invalid values are rejected before anything gets changed.
+func (profile *Profile) Set(setting, value string) error {
+	data, _ := json.Marshal(profile)
+	fields := make(map[string]json.RawMessage)
+	json.Unmarshal(data, &fields)
+
+	name := ``
+	for key := range fields {
+		if strings.EqualFold(key, setting) {
+			name = key
+		}
+	}
+	if name == `` {
+		return fmt.Errorf("Unknown setting `%s`", setting)
+	}
+	raw := json.RawMessage(value)
+	if !json.Valid(raw) {
+		raw, _ = json.Marshal(value)
+	}
+
+	update, _ := json.Marshal(map[string]json.RawMessage{name: raw})
+	check := &Profile{}
+	if err := json.Unmarshal(update, check); err != nil {
+		return fmt.Errorf("Invalid %s: %s", name, err)
+	}
+	if err := check.SetColumns(check.Columns); err != nil {
+		return err
+	}
+	if err := check.SetAlerts(check.Alerts); err != nil {
+		return err
+	}
+	if _, err := newExpression(check.Filter); check.Filter != `` && err != nil {
+		return fmt.Errorf("Invalid filter `%s`: %s", check.Filter, err)
+	}
+
+	field := reflect.ValueOf(profile).Elem().FieldByName(name)
+	field.Set(reflect.ValueOf(check).Elem().FieldByName(name))
+	profile.SetColumns(profile.Columns)
+	profile.SetAlerts(profile.Alerts)
+
+	return profile.SetFilter(profile.Filter) // Saves the profile.
 }
@@ -0,0 +1,173 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`strings`
+	`time`
+)
+
+// Provider fetches the latest stock quotes for the given tickers from the
+// market data service. It may return fewer quotes than requested (ex. to
+// stay within the rate limit); the quotes that are not returned keep their
+// previous values.
+type Provider interface {
+	Fetch(ctx context.Context, tickers []string) ([]Stock, error)
+}
+
+// NewProvider returns the stock quotes provider with the given name, API
+// key, and the maximum number of requests per minute (0 for the provider
+// default). Yahoo is used unless the name is "alphavantage", "binance",
+// "finnhub", "iex", "polygon", "sse", "stooq", or "tiingo", and Stooq
+// steps in whenever Yahoo is not available. The name could also be the
+// file:// URL of the local file to read the quotes from. The endpoint is
+// the event stream URL of the "sse" provider. Binance spot pairs, ex.
+// BTCUSDT, are fetched from Binance whichever provider is in use. The
+// quotes fetched while the market is closed are cached for the identical
+// refreshes.
+func NewProvider(name, key, endpoint string, limit int) Provider {
+	var provider Provider
+	switch {
+	case strings.HasPrefix(name, fileScheme):
+		provider = newLocalFile(name)
+	case name == `binance`:
+		return newBinance()
+	case name == `alphavantage`:
+		provider = newAlphaVantage(key, limit)
+	case name == `finnhub`:
+		provider = newFinnhub(key, limit)
+	case name == `iex`:
+		provider = newIEX(key)
+	case name == `polygon`:
+		provider = newPolygon(key)
+	case name == `sse`:
+		provider = newSSE(endpoint, key)
+	case name == `stooq`:
+		provider = newStooq()
+	case name == `tiingo`:
+		provider = newTiingo(key)
+	default:
+		provider = &fallback{primary: newYahoo(), secondary: newStooq()}
+	}
+	if name != `sse` && !strings.HasPrefix(name, fileScheme) { // These are not downloaded.
+		provider = newQuoteCache(provider)
+	}
+	return &withBinance{stocks: provider, crypto: newBinance()}
+}
+
+// fallback fetches stock quotes from the secondary provider when the
+// primary one fails.
+type fallback struct {
+	primary   Provider // Provider the quotes are normally fetched from.
+	secondary Provider // Provider to fall back to.
+}
+
+// Fetch requests the quotes from the primary provider, then from the
+// secondary one if that fails. The primary provider's error is returned if
+// both fail. The unknown tickers are not the failure to fall back on since
+// the secondary provider wouldn't know them either.
+func (fallback *fallback) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	stocks, err := fallback.primary.Fetch(ctx, tickers)
+	if err != nil && tickerErrorsOf(err) == nil {
+		if secondary, e := fallback.secondary.Fetch(ctx, tickers); e == nil || tickerErrorsOf(e) != nil {
+			return secondary, e
+		}
+	}
+	return stocks, err
+}
+
+// UseSource selects the stock quotes provider for the current session only,
+// ex. from the -source command line flag. The profile's Provider setting is
+// left intact.
+func (profile *Profile) UseSource(name string) *Profile {
+	profile.source = name
+	return profile
+}
+
+// Returns the stock quotes provider selected on the command line or in the
+// profile.
+//-----------------------------------------------------------------------------
+func (profile *Profile) provider() Provider {
+	if profile.replay != nil {
+		return profile.replay
+	}
+	if profile.feed != nil {
+		return profile.feed
+	}
+	name := profile.Provider
+	if profile.source != `` {
+		name = profile.source
+	}
+	return profile.watchlistProviders(NewProvider(name, profile.APIKey, profile.Endpoint, profile.RateLimit))
+}
+
+// Fills in the change since previous close, and reports the fields the
+// providers that only supply prices and volume know nothing about as N/A.
+//-----------------------------------------------------------------------------
+func withChange(stock *Stock, last, prevClose *float64) {
+	stock.Change, stock.ChangePct, stock.Advancing = unavailable, unavailable, true
+	if last != nil && prevClose != nil && *prevClose != 0 {
+		change := *last - *prevClose
+		stock.Change, stock.ChangePct = numberOf(change), numberOf(change/(*prevClose)*100)
+		stock.Advancing = change >= 0
+	}
+	stock.Low52, stock.High52, stock.AvgVolume = unavailable, unavailable, unavailable
+	stock.PeRatio, stock.PeRatioX, stock.Dividend, stock.Yield = unavailable, unavailable, unavailable, unavailable
+	stock.MarketCap, stock.MarketCapX = unavailable, unavailable
+}
+
+//-----------------------------------------------------------------------------
+func orNA(value *float64) Number {
+	if value == nil {
+		return unavailable
+	}
+	return numberOf(*value)
+}
+
+// rateLimiter keeps track of the requests made within the last minute for
+// the providers that limit the number of requests per minute.
+type rateLimiter struct {
+	limit     int              // Maximum number of requests per minute.
+	requested []time.Time      // Times of the requests made within the last minute.
+	now       func() time.Time // Returns current time, replaced in tests.
+}
+
+//-----------------------------------------------------------------------------
+func newRateLimiter(limit int) rateLimiter {
+	return rateLimiter{limit: limit, now: time.Now}
+}
+
+// Returns the number of requests that could be made right now without
+// exceeding the rate limit.
+//-----------------------------------------------------------------------------
+func (limiter *rateLimiter) budget() int {
+	recent, minuteAgo := []time.Time{}, limiter.now().Add(-time.Minute)
+	for _, at := range limiter.requested {
+		if at.After(minuteAgo) {
+			recent = append(recent, at)
+		}
+	}
+	limiter.requested = recent
+
+	return limiter.limit - len(recent)
+}
+
+// Returns how long to wait for the budget to refill so the next request
+// could be made without exceeding the rate limit.
+//-----------------------------------------------------------------------------
+func (limiter *rateLimiter) refill() time.Duration {
+	if limiter.budget() > 0 || len(limiter.requested) == 0 {
+		return 0
+	}
+
+	return limiter.requested[0].Add(time.Minute).Sub(limiter.now())
+}
+
+// Records the request that is about to be made.
+//-----------------------------------------------------------------------------
+func (limiter *rateLimiter) record() {
+	limiter.requested = append(limiter.requested, limiter.now())
+}
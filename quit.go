@@ -0,0 +1,82 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`sync`
+	`time`
+
+	`github.com/nsf/termbox-go`
+)
+
+// How long after the prompt, the picker, or the other screen is closed Esc
+// and q are not taken for quitting, so the key pressed once too many to
+// close it doesn't end the session.
+const quitGrace = 750 * time.Millisecond
+
+// QuitGuard tells the keys that quit mop from the ones pressed by accident.
+// It's safe to ask from the goroutine that reads the keyboard, so the fetch
+// under way could be cancelled as soon as the key that makes it moot is
+// pressed.
+type QuitGuard struct {
+	profile *Profile         // Profile with the quit settings.
+	mutex   sync.Mutex       // Guards the state below.
+	closed  time.Time        // When the prompt or the other screen was closed last.
+	covered bool             // True while the prompt or the other screen takes the keys.
+	now     func() time.Time // Returns current time, replaced in tests.
+}
+
+// NewQuitGuard returns new initialized QuitGuard struct.
+func NewQuitGuard(profile *Profile) *QuitGuard {
+	return &QuitGuard{profile: profile, now: time.Now}
+}
+
+// Closed tells the guard the prompt, the picker, or the other screen has
+// just been closed.
+func (guard *QuitGuard) Closed() {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	guard.closed = guard.now()
+}
+
+// Covered tells the guard whether the prompt, the picker, or the other
+// screen is displayed and takes the keys, so none of them pause or quit.
+func (guard *QuitGuard) Covered(covered bool) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	guard.covered = covered
+}
+
+// Cancels returns true if the key pauses or quits mop right away, which
+// makes the fetch under way moot. The key that only brings up the quit
+// confirmation doesn't.
+func (guard *QuitGuard) Cancels(event termbox.Event) bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	if guard.covered {
+		return false
+	}
+
+	return event.Ch == 'p' || event.Ch == 'P' || (!guard.profile.ConfirmQuit && guard.quits(event))
+}
+
+// Quits returns true if the key quits mop: q, or Esc unless the profile
+// has it only cancel, and not right after the prompt or the other screen
+// has been closed.
+func (guard *QuitGuard) Quits(event termbox.Event) bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	return guard.quits(event)
+}
+
+//-----------------------------------------------------------------------------
+func (guard *QuitGuard) quits(event termbox.Event) bool {
+	if event.Ch != 'q' && event.Ch != 'Q' && (event.Key != termbox.KeyEsc || guard.profile.EscapeCancels) {
+		return false
+	}
+
+	return guard.now().Sub(guard.closed) >= quitGrace
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuitGuard(t *testing.T) {
+	now := time.Unix(1561752000, 0)
+	profile := &Profile{}
+	guard := NewQuitGuard(profile)
+	guard.now = func() time.Time { return now }
+	esc, q := termbox.Event{Key: termbox.KeyEsc}, termbox.Event{Ch: 'q'}
+
+	assert.True(t, guard.Quits(esc))
+	assert.True(t, guard.Quits(q))
+	assert.False(t, guard.Quits(termbox.Event{Ch: 'p'}))
+
+	guard.Closed()
+	now = now.Add(quitGrace / 2)
+	assert.False(t, guard.Quits(esc), `right after the prompt is closed`)
+	assert.False(t, guard.Quits(q))
+	now = now.Add(quitGrace / 2)
+	assert.True(t, guard.Quits(esc))
+
+	profile.EscapeCancels = true
+	assert.False(t, guard.Quits(esc), `Esc only cancels`)
+	assert.True(t, guard.Quits(q))
+}
+
+func TestQuitGuardCancels(t *testing.T) {
+	profile := &Profile{}
+	guard := NewQuitGuard(profile)
+	p, q := termbox.Event{Ch: 'p'}, termbox.Event{Ch: 'q'}
+
+	assert.True(t, guard.Cancels(p))
+	assert.True(t, guard.Cancels(q))
+	assert.False(t, guard.Cancels(termbox.Event{Ch: 's'}))
+
+	guard.Covered(true)
+	assert.False(t, guard.Cancels(p), `typed into the prompt`)
+	assert.False(t, guard.Cancels(q))
+
+	guard.Covered(false)
+	profile.ConfirmQuit = true
+	assert.False(t, guard.Cancels(q), `only asks to confirm`)
+	assert.True(t, guard.Cancels(p))
+}
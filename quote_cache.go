@@ -0,0 +1,91 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`sort`
+	`strings`
+	`sync`
+	`time`
+)
+
+// How long the quotes fetched while the market is closed are good for.
+const closedMarketTTL = 15 * time.Minute
+
+// Extended hours trading in the U.S. starts at 4:00am and ends at 8:00pm New
+// York time, in minutes since midnight. The quotes don't change outside of
+// the extended hours and on weekends.
+const (
+	extendedOpen  = 4 * 60
+	extendedClose = 20 * 60
+)
+
+// quoteCache keeps the quotes fetched while the market is closed, by the
+// set of tickers, and returns them for the identical refreshes instead of
+// downloading and parsing the same quotes again every cycle.
+type quoteCache struct {
+	provider Provider                // Provider that fetches the quotes.
+	mutex    sync.Mutex              // Guards the entries.
+	entries  map[string]cachedQuotes // Quotes by the set of tickers.
+	now      func() time.Time        // Returns current time, replaced in tests.
+}
+
+// cachedQuotes is the quotes along with the time they were fetched.
+type cachedQuotes struct {
+	fetched time.Time // Time the quotes were fetched.
+	stocks  []Stock   // Quotes of all the tickers in the set.
+}
+
+//-----------------------------------------------------------------------------
+func newQuoteCache(provider Provider) *quoteCache {
+	return &quoteCache{provider: provider, entries: map[string]cachedQuotes{}, now: time.Now}
+}
+
+// Fetch returns the cached quotes for the same tickers if the market is
+// closed and they have been fetched within the time to live. Otherwise it
+// fetches the quotes from the provider and caches them, as long as all the
+// tickers have been fetched.
+func (cache *quoteCache) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	now, key := cache.now(), tickerSet(tickers)
+	cache.mutex.Lock()
+	for set, entry := range cache.entries { // Drops the quotes that expire.
+		if !marketClosed(now) || now.Sub(entry.fetched) >= closedMarketTTL {
+			delete(cache.entries, set)
+		}
+	}
+	entry, ok := cache.entries[key]
+	cache.mutex.Unlock()
+	if ok {
+		return append([]Stock{}, entry.stocks...), nil
+	}
+
+	stocks, err := cache.provider.Fetch(ctx, tickers)
+	if err == nil && marketClosed(now) && len(stocks) >= len(unique(tickers)) {
+		cache.mutex.Lock()
+		cache.entries[key] = cachedQuotes{fetched: now, stocks: append([]Stock{}, stocks...)}
+		cache.mutex.Unlock()
+	}
+
+	return stocks, err
+}
+
+// Returns the cache key of the tickers: the same set of tickers gets the
+// same key whatever the order of the tickers.
+//-----------------------------------------------------------------------------
+func tickerSet(tickers []string) string {
+	set := unique(tickers)
+	sort.Strings(set)
+	return strings.Join(set, `,`)
+}
+
+// Returns true outside of the extended trading hours in New York and on
+// weekends. Market holidays are not taken into account.
+//-----------------------------------------------------------------------------
+func marketClosed(now time.Time) bool {
+	now = inNewYork(now)
+	minutes := now.Hour()*60 + now.Minute()
+	return !weekday(now) || minutes < extendedOpen || minutes >= extendedClose
+}
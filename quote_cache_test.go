@@ -0,0 +1,84 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider returns a quote for every ticker and counts the fetches.
+type countingProvider struct {
+	fetches int
+	limit   int
+}
+
+func (provider *countingProvider) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	provider.fetches++
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if provider.limit == 0 || len(stocks) < provider.limit {
+			stocks = append(stocks, Stock{Ticker: ticker})
+		}
+	}
+	return stocks, nil
+}
+
+func newYorkTime(value string) time.Time {
+	location, _ := time.LoadLocation(`America/New_York`)
+	at, _ := time.ParseInLocation(`2006-01-02 15:04`, value, location)
+	return at
+}
+
+func TestQuoteCacheWhileMarketIsOpen(t *testing.T) {
+	provider := &countingProvider{}
+	cache := newQuoteCache(provider)
+	cache.now = func() time.Time { return newYorkTime(`2019-03-05 11:00`) }
+
+	cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	assert.Equal(t, 2, provider.fetches)
+}
+
+func TestQuoteCacheWhileMarketIsClosed(t *testing.T) {
+	provider := &countingProvider{}
+	cache := newQuoteCache(provider)
+	now := newYorkTime(`2019-03-09 11:00`) // Saturday.
+	cache.now = func() time.Time { return now }
+
+	stocks, err := cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	assert.NoError(t, err)
+	cached, _ := cache.Fetch(context.Background(), []string{`ibm`, `AAPL`})
+	assert.Equal(t, 1, provider.fetches)
+	assert.Equal(t, stocks, cached)
+
+	cache.Fetch(context.Background(), []string{`AAPL`})
+	assert.Equal(t, 2, provider.fetches, `different set of tickers`)
+
+	now = now.Add(closedMarketTTL)
+	cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	assert.Equal(t, 3, provider.fetches, `expired`)
+}
+
+func TestQuoteCacheSkipsPartialFetch(t *testing.T) {
+	provider := &countingProvider{limit: 1}
+	cache := newQuoteCache(provider)
+	cache.now = func() time.Time { return newYorkTime(`2019-03-05 22:00`) }
+
+	cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	cache.Fetch(context.Background(), []string{`AAPL`, `IBM`})
+	assert.Equal(t, 2, provider.fetches)
+}
+
+func TestMarketClosed(t *testing.T) {
+	assert.True(t, marketClosed(newYorkTime(`2019-03-05 03:59`)))
+	assert.False(t, marketClosed(newYorkTime(`2019-03-05 04:00`)))
+	assert.False(t, marketClosed(newYorkTime(`2019-03-05 19:59`)))
+	assert.True(t, marketClosed(newYorkTime(`2019-03-05 20:00`)))
+	assert.True(t, marketClosed(newYorkTime(`2019-03-10 12:00`)))
+}
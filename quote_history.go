@@ -0,0 +1,160 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`strings`
+	`time`
+)
+
+// Number of samples of each stock kept in memory unless the profile says
+// otherwise.
+const historySize = 60
+
+// The trend is estimated over so many refreshes unless the profile says
+// otherwise, and shown as the trail of so many arrows, one per each of the
+// last refreshes. The slope below the threshold, in percent of the price
+// per refresh, is flat.
+const (
+	trendWindow = 5
+	trendTrail  = 3
+	trendFlat   = 0.01
+)
+
+// quoteSample is the stock's last trade price as of the refresh.
+type quoteSample struct {
+	at    time.Time // Time of the refresh.
+	price float64   // Last trade price.
+}
+
+// quoteRing keeps the last so many samples of the stock, overwriting the
+// oldest one once it's full, along with the very first sample of the
+// session.
+type quoteRing struct {
+	first   quoteSample   // The first sample taken since mop was started.
+	samples []quoteSample // Samples, the oldest one at next once the ring is full.
+	next    int           // Index of the sample to overwrite next.
+}
+
+// History returns the last trade prices of the stock as of the recent
+// refreshes, the oldest first, ex. for sparklines and trends. The history
+// is kept in memory since mop was started, and costs no extra requests.
+func (quotes *Quotes) History(ticker string) []float64 {
+	ring, ok := quotes.samples[strings.ToUpper(ticker)]
+	if !ok {
+		return nil
+	}
+	prices := []float64{}
+	for _, sample := range ring.ordered() {
+		prices = append(prices, sample.price)
+	}
+
+	return prices
+}
+
+// Records the last trade price of each stock as of the given time, and
+// fills in the change since the first price mop has seen this session
+// along with the trend over the last few refreshes.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) sample(now time.Time) *Quotes {
+	if quotes.samples == nil {
+		quotes.samples = make(map[string]*quoteRing)
+	}
+	size, window := historySize, trendWindow
+	if quotes.profile.HistorySize > 0 {
+		size = quotes.profile.HistorySize
+	}
+	if quotes.profile.TrendWindow > 1 {
+		window = quotes.profile.TrendWindow
+	}
+	for i, stock := range quotes.stocks {
+		last := stock.LastTrade.Value()
+		if last <= 0 {
+			continue
+		}
+		ticker := strings.ToUpper(stock.Ticker)
+		ring, seen := quotes.samples[ticker]
+		if !seen {
+			ring = &quoteRing{first: quoteSample{now, last}}
+			quotes.samples[ticker] = ring
+		}
+		ring.push(quoteSample{now, last}, size)
+		quotes.stocks[i].SessionChange = numberOf((last - ring.first.price) * 100 / ring.first.price)
+		quotes.stocks[i].Trend, quotes.stocks[i].TrendSlope = ring.trend(window)
+	}
+
+	return quotes
+}
+
+// Adds the sample to the ring of the given size. The size could change
+// between the samples, ex. when the profile gets edited.
+//-----------------------------------------------------------------------------
+func (ring *quoteRing) push(sample quoteSample, size int) {
+	if ring.next > 0 && len(ring.samples) != size {
+		ring.samples, ring.next = ring.ordered(), 0
+	}
+	if len(ring.samples) > size {
+		ring.samples = ring.samples[len(ring.samples)-size:]
+	}
+	if len(ring.samples) < size {
+		ring.samples = append(ring.samples, sample)
+		return
+	}
+	ring.samples[ring.next] = sample
+	ring.next = (ring.next + 1) % size
+}
+
+// Returns the trail of arrows, ex. "↗↗→", that shows the direction of the
+// trend as of each of the last few refreshes, the latest one last, along
+// with the latest slope. The trend is the slope of the least squares line
+// through the prices over the given number of refreshes.
+//-----------------------------------------------------------------------------
+func (ring *quoteRing) trend(window int) (string, Number) {
+	samples, trail, latest := ring.ordered(), ``, Number{}
+	for k := trendTrail - 1; k >= 0; k-- {
+		end := len(samples) - k
+		start := end - window
+		if start < 0 {
+			start = 0
+		}
+		if end-start < 2 {
+			continue
+		}
+		slope := slopeOf(samples[start:end])
+		switch {
+		case slope >= trendFlat:
+			trail += `↗`
+		case slope <= -trendFlat:
+			trail += `↘`
+		default:
+			trail += `→`
+		}
+		latest = numberOf(slope)
+	}
+
+	return trail, latest
+}
+
+// Returns the samples in the order they were taken.
+//-----------------------------------------------------------------------------
+func (ring *quoteRing) ordered() []quoteSample {
+	return append(append([]quoteSample{}, ring.samples[ring.next:]...), ring.samples[:ring.next]...)
+}
+
+// Returns the slope of the least squares line through the prices of the
+// samples, one per refresh, in percent of their average price per refresh.
+//-----------------------------------------------------------------------------
+func slopeOf(samples []quoteSample) float64 {
+	count, sumX, sumY, sumXY, sumXX := float64(len(samples)), 0.0, 0.0, 0.0, 0.0
+	for i, sample := range samples {
+		x := float64(i)
+		sumX, sumY, sumXY, sumXX = sumX+x, sumY+sample.price, sumXY+x*sample.price, sumXX+x*x
+	}
+	if denominator := count*sumXX - sumX*sumX; denominator != 0 && sumY != 0 {
+		return (count*sumXY - sumX*sumY) / denominator * 100 / (sumY / count)
+	}
+
+	return 0
+}
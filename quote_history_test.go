@@ -0,0 +1,64 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteRing(t *testing.T) {
+	ring, prices := &quoteRing{}, func(ring *quoteRing) []float64 {
+		values := []float64{}
+		for _, sample := range ring.ordered() {
+			values = append(values, sample.price)
+		}
+		return values
+	}
+	for _, price := range []float64{1, 2, 3, 4, 5} {
+		ring.push(quoteSample{price: price}, 3)
+	}
+	assert.Equal(t, []float64{3, 4, 5}, prices(ring), `oldest samples overwritten`)
+
+	ring.push(quoteSample{price: 6}, 4)
+	assert.Equal(t, []float64{3, 4, 5, 6}, prices(ring), `grown`)
+	ring.push(quoteSample{price: 7}, 4)
+	ring.push(quoteSample{price: 8}, 2)
+	assert.Equal(t, []float64{7, 8}, prices(ring), `shrunk`)
+}
+
+func TestQuotesHistory(t *testing.T) {
+	quotes, now := &Quotes{profile: &Profile{HistorySize: 2}}, time.Now()
+	for _, price := range []float64{200, 210, 190} {
+		quotes.stocks = []Stock{{Ticker: `AAPL`, LastTrade: numberOf(price)}, {Ticker: `IBM`, LastTrade: unavailable}}
+		quotes.sample(now)
+	}
+
+	assert.Equal(t, []float64{210, 190}, quotes.History(`aapl`))
+	assert.Nil(t, quotes.History(`IBM`), `no price, no samples`)
+	assert.Equal(t, `-5.000`, quotes.stocks[0].SessionChange.String(), `since the first price seen`)
+	assert.False(t, quotes.stocks[1].SessionChange.Known())
+}
+
+func TestTrend(t *testing.T) {
+	ring := &quoteRing{}
+	for _, price := range []float64{100, 101, 102, 103, 103, 103, 103} {
+		ring.push(quoteSample{price: price}, 10)
+	}
+	trail, slope := ring.trend(3)
+	assert.Equal(t, `↗→→`, trail, `rise that has stalled`)
+	assert.Equal(t, 0.0, slope.Value())
+
+	ring.push(quoteSample{price: 100}, 10)
+	trail, slope = ring.trend(3)
+	assert.Equal(t, `→→↘`, trail)
+	assert.InDelta(t, -1.47, slope.Value(), 0.01)
+
+	trail, slope = (&quoteRing{samples: []quoteSample{{price: 100}}}).trend(3)
+	assert.Equal(t, ``, trail, `not enough prices`)
+	assert.False(t, slope.Known())
+}
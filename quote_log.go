@@ -0,0 +1,130 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/csv`
+	`fmt`
+	`os`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// Numbers of the stock that get logged and exported, by the Stock field
+// name, in the order of the log columns.
+var exportedFields = []struct {
+	name  string
+	value func(stock Stock) Number
+}{
+	{`LastTrade`, func(stock Stock) Number { return stock.LastTrade }},
+	{`Change`, func(stock Stock) Number { return stock.Change }},
+	{`ChangePct`, func(stock Stock) Number { return stock.ChangePct }},
+	{`Open`, func(stock Stock) Number { return stock.Open }},
+	{`Low`, func(stock Stock) Number { return stock.Low }},
+	{`High`, func(stock Stock) Number { return stock.High }},
+	{`Volume`, func(stock Stock) Number { return stock.Volume }},
+}
+
+// LoggedQuotes returns the quote log next to the profile, ex.
+// ~/.moprc.quotes.csv, as the column titles followed by the logged quotes of
+// the given tickers, or of all the tickers if none are given.
+func (profile *Profile) LoggedQuotes(tickers []string) ([][]string, error) {
+	filename := profile.filename + `.quotes.csv`
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("No quote log at %s, set QuoteLog to true to keep one", filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(rows) == 0 {
+		return rows, err
+	}
+	wanted := make(map[string]bool)
+	for _, ticker := range tickers {
+		if ticker = profile.NormalizeTicker(ticker); ticker != `` {
+			wanted[tickerKey(ticker)] = true
+		}
+	}
+	logged := rows[:1]
+	for _, row := range rows[1:] {
+		if len(wanted) == 0 || len(row) > 1 && wanted[tickerKey(row[1])] {
+			logged = append(logged, row)
+		}
+	}
+
+	return logged, nil
+}
+
+// Appends the quotes of the given tickers fetched as of the given time to
+// the quote log next to the profile, ex. ~/.moprc.quotes.csv, if the profile
+// asks for it. Unlike the recent prices kept in memory, the log survives the
+// restarts, and is meant for the end of day summaries and the analysis
+// elsewhere, ex. sqlite3's .import.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) record(now time.Time, tickers []string) error {
+	if !quotes.profile.QuoteLog {
+		return nil
+	}
+
+	filename := quotes.profile.filename + `.quotes.csv`
+	_, err := os.Stat(filename)
+	fresh := os.IsNotExist(err)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if fresh {
+		header := []string{`Time`, `Ticker`}
+		for _, field := range exportedFields {
+			header = append(header, field.name)
+		}
+		writer.Write(header)
+	}
+	for _, stock := range quotes.fetched(tickers) {
+		row := []string{now.Format(time.RFC3339), stock.Ticker}
+		for _, field := range exportedFields {
+			row = append(row, logged(field.value(stock)))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// Returns the stocks of the given tickers that have the last trade price,
+// i.e. the ones that have just been fetched.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) fetched(tickers []string) []Stock {
+	due, stocks := make(map[string]bool), []Stock{}
+	for _, ticker := range tickers {
+		due[strings.ToUpper(ticker)] = true
+	}
+	for _, stock := range quotes.stocks {
+		if due[strings.ToUpper(stock.Ticker)] && stock.LastTrade.Known() {
+			stocks = append(stocks, stock)
+		}
+	}
+
+	return stocks
+}
+
+// Returns the number as is, without the rounding and the suffixes of the
+// display, or blank if it's unknown.
+//-----------------------------------------------------------------------------
+func logged(number Number) string {
+	if !number.Known() {
+		return ``
+	}
+	return strconv.FormatFloat(number.Value(), 'f', -1, 64)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteLog(t *testing.T) {
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `.moprc`)}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(207.48), Change: numberOf(3.1), Volume: numberOf(27317000), Open: unavailable},
+		{Ticker: `IBM`, LastTrade: numberOf(143.9)},
+		{Ticker: `XYZZ`, LastTrade: unavailable},
+	}}
+	now := time.Date(2019, 6, 28, 15, 30, 0, 0, time.UTC)
+	require.NoError(t, quotes.record(now, []string{`AAPL`, `XYZZ`}))
+	_, err := os.Stat(profile.filename + `.quotes.csv`)
+	assert.True(t, os.IsNotExist(err), `not unless the profile asks for it`)
+
+	profile.QuoteLog = true
+	require.NoError(t, quotes.record(now, []string{`AAPL`, `XYZZ`}))
+	require.NoError(t, quotes.record(now.Add(time.Minute), []string{`IBM`}))
+	data, err := ioutil.ReadFile(profile.filename + `.quotes.csv`)
+	require.NoError(t, err)
+	assert.Equal(t, "Time,Ticker,LastTrade,Change,ChangePct,Open,Low,High,Volume\n"+
+		"2019-06-28T15:30:00Z,AAPL,207.48,3.1,,,,,27317000\n"+
+		"2019-06-28T15:31:00Z,IBM,143.9,,,,,,\n", string(data), `only the quotes fetched, unrounded`)
+}
+
+func TestLoggedQuotes(t *testing.T) {
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `.moprc`)}
+	_, err := profile.LoggedQuotes(nil)
+	assert.EqualError(t, err, `No quote log at `+profile.filename+`.quotes.csv, set QuoteLog to true to keep one`)
+
+	require.NoError(t, ioutil.WriteFile(profile.filename+`.quotes.csv`, []byte("Time,Ticker,LastTrade\n"+
+		"2019-06-28T15:30:00Z,AAPL,207.48\n2019-06-28T15:30:00Z,BRK-B,412.1\n2019-06-28T15:31:00Z,AAPL,207.5\n"), 0644))
+	rows, err := profile.LoggedQuotes([]string{``})
+	require.NoError(t, err)
+	assert.Len(t, rows, 4, `all the tickers`)
+
+	rows, err = profile.LoggedQuotes([]string{`brk.b`})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{`Time`, `Ticker`, `LastTrade`}, {`2019-06-28T15:30:00Z`, `BRK-B`, `412.1`}}, rows)
+}
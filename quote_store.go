@@ -0,0 +1,190 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`strings`
+	`sync`
+)
+
+// QuoteStore owns the latest stock quotes for every ticker that has been
+// subscribed to. The views (stock quotes table, index constituents) and
+// the alerts subscribe to the tickers they need, and the store fetches
+// each ticker once no matter how many subscribers want it. Access to the
+// provider is serialized.
+type QuoteStore struct {
+	mutex         sync.Mutex             // Serializes provider access and guards the quotes.
+	provider      Provider               // Stock quotes provider.
+	stocks        map[string]Stock       // Latest stock quote per ticker.
+	failures      map[string]string      // Why the ticker has failed the last time it was fetched, by ticker.
+	subscriptions map[*Subscription]bool // Active subscriptions.
+}
+
+// Subscription is the list of tickers the subscriber is interested in.
+type Subscription struct {
+	store   *QuoteStore // Pointer to the store the subscription belongs to.
+	tickers []string    // Subscribed tickers, in upper case.
+}
+
+// Returns new QuoteStore that fetches stock quotes from the given provider.
+func NewQuoteStore(provider Provider) *QuoteStore {
+	return &QuoteStore{
+		provider:      provider,
+		stocks:        make(map[string]Stock),
+		failures:      make(map[string]string),
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+// Subscribe returns new subscription to the given tickers.
+func (store *QuoteStore) Subscribe(tickers []string) *Subscription {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	subscription := &Subscription{store: store, tickers: unique(tickers)}
+	store.subscriptions[subscription] = true
+
+	return subscription
+}
+
+// Tickers returns all the subscribed tickers without duplicates.
+func (store *QuoteStore) Tickers() []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	tickers := []string{}
+	for subscription := range store.subscriptions {
+		tickers = append(tickers, subscription.tickers...)
+	}
+
+	return unique(tickers)
+}
+
+// Missing returns the tickers the store has no quotes for.
+func (store *QuoteStore) Missing(tickers []string) []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	missing := []string{}
+	for _, ticker := range unique(tickers) {
+		if _, ok := store.stocks[ticker]; !ok {
+			missing = append(missing, ticker)
+		}
+	}
+
+	return missing
+}
+
+// Refresh fetches the latest quotes for the given tickers, until the
+// context is cancelled. The quotes the provider has not returned (ex. due
+// to the rate limit) keep their previous values. The tickers the provider
+// has failed on its own, ex. delisted or mistyped, are kept as failures
+// rather than failing the refresh.
+func (store *QuoteStore) Refresh(ctx context.Context, tickers []string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if tickers = unique(tickers); len(tickers) == 0 {
+		return nil
+	}
+	fetched, err := store.provider.Fetch(ctx, tickers)
+	for _, stock := range fetched {
+		ticker := strings.ToUpper(stock.Ticker)
+		store.stocks[ticker] = stock
+		delete(store.failures, ticker)
+	}
+	failed := tickerErrorsOf(err)
+	for ticker, message := range failed {
+		delete(store.stocks, ticker)
+		store.failures[ticker] = message
+	}
+	if failed != nil {
+		return nil
+	}
+
+	return err
+}
+
+// Set replaces subscribed tickers with the given ones (ex. after the tickers
+// have been added to the watchlist).
+func (subscription *Subscription) Set(tickers []string) *Subscription {
+	store := subscription.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	subscription.tickers = unique(tickers)
+	store.prune()
+
+	return subscription
+}
+
+// Stocks returns the latest quotes of the subscribed tickers, in the order
+// they were subscribed. The tickers that have not been fetched yet are
+// skipped.
+func (subscription *Subscription) Stocks() []Stock {
+	store := subscription.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stocks := []Stock{}
+	for _, ticker := range subscription.tickers {
+		if stock, ok := store.stocks[ticker]; ok {
+			stocks = append(stocks, stock)
+		}
+	}
+
+	return stocks
+}
+
+// Failures returns why the subscribed tickers that have failed the last
+// time they were fetched have failed, by ticker.
+func (subscription *Subscription) Failures() map[string]string {
+	store := subscription.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	failures := make(map[string]string)
+	for _, ticker := range subscription.tickers {
+		if message, ok := store.failures[ticker]; ok {
+			failures[ticker] = message
+		}
+	}
+
+	return failures
+}
+
+// Unsubscribe cancels the subscription. The quotes nobody subscribes to
+// anymore are dropped.
+func (subscription *Subscription) Unsubscribe() {
+	store := subscription.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.subscriptions, subscription)
+	store.prune()
+}
+
+// Drops the quotes and the failures of the tickers that are not subscribed
+// to.
+//-----------------------------------------------------------------------------
+func (store *QuoteStore) prune() {
+	wanted := make(map[string]bool)
+	for subscription := range store.subscriptions {
+		for _, ticker := range subscription.tickers {
+			wanted[ticker] = true
+		}
+	}
+	for ticker := range store.stocks {
+		if !wanted[ticker] {
+			delete(store.stocks, ticker)
+		}
+	}
+	for ticker := range store.failures {
+		if !wanted[ticker] {
+			delete(store.failures, ticker)
+		}
+	}
+}
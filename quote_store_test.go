@@ -0,0 +1,95 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider returns canned stock quotes and remembers what tickers have
+// been requested.
+type fakeProvider struct {
+	stocks    map[string]Stock
+	requested [][]string
+	err       error
+}
+
+func (provider *fakeProvider) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	provider.requested = append(provider.requested, tickers)
+	if provider.err != nil {
+		return nil, provider.err
+	}
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if stock, ok := provider.stocks[ticker]; ok {
+			stocks = append(stocks, stock)
+		}
+	}
+	if len(stocks) == 0 {
+		return nil, errors.New(`no quotes`)
+	}
+	return stocks, nil
+}
+
+func TestQuoteStore(t *testing.T) {
+	provider := &fakeProvider{stocks: map[string]Stock{
+		`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(200.00)},
+		`IBM`:  {Ticker: `IBM`, LastTrade: numberOf(140.00)},
+		`KO`:   {Ticker: `KO`, LastTrade: numberOf(46.00)},
+	}}
+	store := NewQuoteStore(provider)
+	table := store.Subscribe([]string{`IBM`, `aapl`})
+	alerts := store.Subscribe([]string{`AAPL`, `KO`})
+	assert.Equal(t, []string{`AAPL`, `IBM`, `KO`}, sorted(store.Tickers()))
+
+	require.NoError(t, store.Refresh(context.Background(), store.Tickers()))
+	require.Len(t, provider.requested, 1, `single request for all subscribers`)
+	require.Len(t, table.Stocks(), 2)
+	assert.Equal(t, `IBM`, table.Stocks()[0].Ticker, `subscription order`)
+	assert.Equal(t, `46.000`, alerts.Stocks()[1].LastTrade.String())
+
+	assert.Empty(t, store.Missing([]string{`KO`, `ibm`}))
+	assert.Equal(t, []string{`V`}, store.Missing([]string{`KO`, `V`}))
+	assert.Error(t, store.Refresh(context.Background(), []string{`V`}))
+	assert.Len(t, table.Stocks(), 2, `failed refresh keeps the quotes`)
+
+	alerts.Unsubscribe()
+	assert.Equal(t, []string{`KO`}, store.Missing([]string{`KO`}), `unwanted quotes are dropped`)
+	table.Set([]string{`IBM`})
+	assert.Len(t, table.Stocks(), 1)
+}
+
+func sorted(tickers []string) []string {
+	sort.Strings(tickers)
+	return tickers
+}
+
+func TestQuoteStoreFailures(t *testing.T) {
+	provider := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(200.00)}}}
+	store := NewQuoteStore(&withUnknown{provider})
+	subscription := store.Subscribe([]string{`AAPL`, `XYZZ`})
+
+	require.NoError(t, store.Refresh(context.Background(), store.Tickers()), `the good ones still get displayed`)
+	assert.Equal(t, []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.00)}}, subscription.Stocks())
+	assert.Equal(t, map[string]string{`XYZZ`: unknownSymbol}, subscription.Failures())
+
+	provider.stocks[`XYZZ`] = Stock{Ticker: `XYZZ`, LastTrade: numberOf(1.00)} // Ex. listed since.
+	require.NoError(t, store.Refresh(context.Background(), []string{`XYZZ`}))
+	assert.Len(t, subscription.Stocks(), 2)
+	assert.Empty(t, subscription.Failures())
+
+	delete(provider.stocks, `XYZZ`)
+	require.NoError(t, store.Refresh(context.Background(), store.Tickers()))
+	assert.Len(t, subscription.Stocks(), 1, `the failed ticker's quote is dropped`)
+	subscription.Set([]string{`AAPL`})
+	assert.Empty(t, subscription.Failures(), `unsubscribed`)
+	assert.Empty(t, store.failures)
+}
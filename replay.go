@@ -0,0 +1,195 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`path/filepath`
+	`sort`
+	`strings`
+	`sync`
+)
+
+// Replay plays back the snapshots recorded earlier instead of fetching the
+// market data and the stock quotes, ex. for demos and screenshots. The
+// snapshots are the JSON documents described in Snapshot, one per file, or
+// the web view updates that carry them. They are played back in the order
+// of the file names, one per quotes refresh, starting over after the last
+// one.
+type Replay struct {
+	files   []string   // Snapshot files, in the order they are played back.
+	mutex   sync.Mutex // Guards the playback state below.
+	next    int        // Index of the file to be played back next.
+	current *Snapshot  // Snapshot played back last, nil before the first one.
+}
+
+// NewReplay returns new Replay of the *.json snapshots in the given
+// directory.
+func NewReplay(dir string) (*Replay, error) {
+	files, err := filepath.Glob(filepath.Join(dir, `*.json`))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No snapshots (*.json) found in %s", dir)
+	}
+	sort.Strings(files)
+
+	return &Replay{files: files}, nil
+}
+
+// UseReplay makes the market data come from the replay rather than the
+// network.
+func (market *Market) UseReplay(replay *Replay) *Market {
+	market.replay = replay
+	return market
+}
+
+// UseReplay makes the stock quotes come from the replay rather than the
+// provider for the current session only.
+func (profile *Profile) UseReplay(replay *Replay) *Profile {
+	profile.replay = replay
+	return profile
+}
+
+// Fetch plays back the next snapshot and returns its quotes of the given
+// tickers. The tickers missing from the snapshot keep their previous values.
+func (replay *Replay) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	snapshot, err := replay.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]QuoteSnapshot)
+	for _, quote := range snapshot.Quotes {
+		recorded[strings.ToUpper(quote.Ticker)] = quote
+	}
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if quote, ok := recorded[strings.ToUpper(ticker)]; ok {
+			stock := quote.stock()
+			stock.Ticker = ticker
+			stocks = append(stocks, stock)
+		}
+	}
+
+	return stocks, nil
+}
+
+// Reads the next snapshot file and makes it the current one.
+//-----------------------------------------------------------------------------
+func (replay *Replay) advance() (*Snapshot, error) {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+
+	snapshot, err := readSnapshot(replay.files[replay.next])
+	if err != nil {
+		return nil, err
+	}
+	replay.next = (replay.next + 1) % len(replay.files)
+	replay.current = snapshot
+
+	return snapshot, nil
+}
+
+// Fills in the market data from the snapshot played back last, or the first
+// one if the quotes haven't been played back yet.
+//-----------------------------------------------------------------------------
+func (replay *Replay) market(market *Market) error {
+	replay.mutex.Lock()
+	snapshot, err := replay.current, error(nil)
+	if snapshot == nil {
+		snapshot, err = readSnapshot(replay.files[0])
+	}
+	replay.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if snapshot.Market == nil {
+		return errors.New(`No market data in the snapshot`)
+	}
+
+	indices := map[string]map[string]string{
+		`Dow`: market.Dow, `S&P 500`: market.Sp500, `NASDAQ`: market.Nasdaq,
+		`Tokyo`: market.Tokyo, `Hong Kong`: market.HongKong, `London`: market.London, `Frankfurt`: market.Frankfurt,
+		`10-year Yield`: market.Yield, `Oil`: market.Oil, `Yen`: market.Yen, `Euro`: market.Euro, `Gold`: market.Gold,
+	}
+	number := func(format string, value *float64) string {
+		if value == nil {
+			return `N/A`
+		}
+		return fmt.Sprintf(format, *value)
+	}
+	market.IsClosed = snapshot.Market.Closed
+	for _, index := range snapshot.Market.Indices {
+		values, ok := indices[index.Name]
+		if !ok {
+			continue
+		}
+		values[`latest`] = number(`%.2f`, index.Latest)
+		if index.Change == nil { // Yields, commodities, and currencies only report the change in percent.
+			values[`change`] = number(`%+.2f`, index.ChangePct)
+		} else {
+			values[`change`], values[`percent`] = number(`%+.2f`, index.Change), number(`%+.2f%%`, index.ChangePct)
+		}
+	}
+
+	return nil
+}
+
+// Reads the snapshot, or the web view update that carries one.
+//-----------------------------------------------------------------------------
+func readSnapshot(filename string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	document := struct {
+		Snapshot
+		Update *Snapshot `json:"snapshot"` // The web view update.
+	}{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %s", filename, err)
+	}
+	if document.Update != nil {
+		return document.Update, nil
+	}
+
+	return &document.Snapshot, nil
+}
+
+// Returns the stock quote as it would have been reported by the provider.
+//-----------------------------------------------------------------------------
+func (quote QuoteSnapshot) stock() Stock {
+	return Stock{
+		Ticker:     quote.Ticker,
+		QuoteType:  quote.Type,
+		Currency:   quote.Currency,
+		LastTrade:  orNA(quote.Last),
+		Change:     orNA(quote.Change),
+		ChangePct:  orNA(quote.ChangePct),
+		Open:       orNA(quote.Open),
+		Low:        orNA(quote.Low),
+		High:       orNA(quote.High),
+		Low52:      orNA(quote.Low52),
+		High52:     orNA(quote.High52),
+		Volume:     orNA(quote.Volume),
+		AvgVolume:  orNA(quote.AvgVolume),
+		PeRatio:    orNA(quote.PeRatio),
+		PeRatioX:   orNA(quote.PeRatio),
+		Dividend:   orNA(quote.Dividend),
+		Yield:      orNA(quote.Yield),
+		MarketCap:  orNA(quote.MarketCap),
+		MarketCapX: orNA(quote.MarketCap),
+		PrevClose:  orNA(quote.PrevClose),
+		Bid:        orNA(quote.Bid),
+		Ask:        orNA(quote.Ask),
+		Advancing:  quote.Change == nil || *quote.Change >= 0,
+	}
+}
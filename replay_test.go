@@ -0,0 +1,67 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	snapshots := map[string]string{
+		`2019-06-28T16-00.json`: `{ "schema": 1, "quotes": [ { "ticker": "AAPL", "last": 197.92, "change": -1.88, "changePct": -0.941 } ],
+			"market": { "closed": true, "indices": [ { "name": "Dow", "latest": 26599.96, "change": -85.1, "changePct": -0.32 }, { "name": "Gold", "latest": 1413.7, "change": null, "changePct": 0.45 } ] } }`,
+		`2019-06-28T16-05.json`: `{ "snapshot": { "schema": 1, "quotes": [ { "ticker": "AAPL", "last": 198.5, "change": -1.3, "changePct": -0.651 } ] }, "table": [] }`,
+	}
+	for name, snapshot := range snapshots {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(snapshot), 0644))
+	}
+
+	replay, err := NewReplay(dir)
+	require.NoError(t, err)
+
+	market := NewMarket().UseReplay(replay).Fetch()
+	ok, _ := market.Ok()
+	require.True(t, ok, `market data of the first snapshot before the quotes are played back`)
+	assert.True(t, market.IsClosed)
+	assert.Equal(t, map[string]string{`latest`: `26599.96`, `change`: `-85.10`, `percent`: `-0.32%`}, market.Dow)
+	assert.Equal(t, `+0.45`, market.Gold[`change`])
+
+	for _, last := range []string{`197.920`, `198.500`, `197.920`} {
+		stocks, err := replay.Fetch(context.Background(), []string{`aapl`, `IBM`})
+		require.NoError(t, err)
+		require.Len(t, stocks, 1, `tickers missing from the snapshot`)
+		assert.Equal(t, `aapl`, stocks[0].Ticker)
+		assert.Equal(t, last, stocks[0].LastTrade.String(), `played back in order, then over again`)
+		assert.False(t, stocks[0].Advancing)
+		assert.Equal(t, `N/A`, stocks[0].Volume.String())
+	}
+
+	_, err = NewReplay(t.TempDir())
+	assert.Error(t, err, `no snapshots`)
+}
+
+func TestReplayProfile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, `1.json`), []byte(`{ "quotes": [ { "ticker": "BTCUSDT", "last": 10850 } ] }`), 0644))
+	replay, err := NewReplay(dir)
+	require.NoError(t, err)
+
+	filename := filepath.Join(dir, `.moprc`)
+	profile := &Profile{filename: filename, Tickers: []string{`BTCUSDT`}, Holdings: map[string]Holding{`BTCUSDT`: {Shares: 1}}}
+	quotes := NewQuotes(NewMarket(), profile.UseReplay(replay)).Fetch()
+	ok, _ := quotes.Ok()
+	require.True(t, ok)
+	assert.Equal(t, `10850.000`, quotes.stocks[0].LastTrade.String(), `not routed to Binance`)
+	require.NoError(t, quotes.RecordValue(time.Now()))
+	assert.NoFileExists(t, filename+`.history`, `replayed values are not recorded`)
+}
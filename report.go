@@ -0,0 +1,122 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`fmt`
+	`html/template`
+	`strings`
+	`time`
+)
+
+// Size of the sparkline images in the report, in pixels.
+const (
+	sparklineWidth  = 100
+	sparklineHeight = 20
+)
+
+// Static HTML page of the report. The styles are inline so the page looks
+// the same when emailed.
+const reportMarkup = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Mop report as of {{.Time}}</title>
+</head>
+<body style="font-family: monospace">
+<h3>Stock quotes as of {{.Time}}</h3>
+<table style="border-collapse: collapse">
+<tr>{{range .Titles}}<th style="padding: 2px 8px; text-align: right; border-bottom: 1px solid gray">{{.}}</th>{{end}}<th style="padding: 2px 8px; border-bottom: 1px solid gray">Trend</th></tr>
+{{range .Rows}}<tr style="color: {{.Color}}">{{range .Cells}}<td style="padding: 2px 8px; text-align: right">{{.}}</td>{{end}}<td style="padding: 2px 8px">{{.Sparkline}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// Report renders the stock quotes the way they are currently displayed,
+// i.e. formatted, filtered, and sorted, into the static HTML page, ex. for
+// emailing or archiving the daily snapshots. The gains are green and the
+// losses are red, and each stock gets the sparkline of the prices seen
+// this session, or of the last month's closes if the fundamentals are
+// fetched.
+func (layout *Layout) Report(quotes *Quotes, now time.Time) string {
+	type row struct {
+		Color     string
+		Cells     []string
+		Sparkline template.HTML
+	}
+	vars := struct {
+		Time   string
+		Titles []string
+		Rows   []row
+	}{
+		Time: now.Format(`Jan 2, 2006 3:04pm MST`),
+		Rows: []row{},
+	}
+
+	table := layout.Table(quotes)
+	vars.Titles = table[0]
+	stocks, _ := layout.prettify(quotes)
+	for i, stock := range stocks {
+		color := `black`
+		if stock.Change.Known() && stock.Change.Value() > 0 {
+			color = `green`
+		} else if stock.Change.Known() && stock.Change.Value() < 0 {
+			color = `red`
+		}
+		vars.Rows = append(vars.Rows, row{
+			Color:     color,
+			Cells:     table[i+1],
+			Sparkline: sparkline(quotes.sparkline(stock.Ticker), color),
+		})
+	}
+
+	buffer := new(bytes.Buffer)
+	template.Must(template.New(`report`).Parse(reportMarkup)).Execute(buffer, vars)
+
+	return buffer.String()
+}
+
+// Returns the prices the stock's sparkline is drawn of: the ones seen this
+// session if there are a few, or else the cached daily closes.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) sparkline(ticker string) []float64 {
+	if prices := quotes.History(ticker); len(prices) > 1 || quotes.fundamentals == nil {
+		return prices
+	}
+
+	return quotes.fundamentals.closes(ticker)
+}
+
+// Returns the inline SVG image of the line through the given prices, scaled
+// to fit, or blank if there are not enough prices to draw the line.
+//-----------------------------------------------------------------------------
+func sparkline(prices []float64, color string) template.HTML {
+	if len(prices) < 2 {
+		return ``
+	}
+	low, high := prices[0], prices[0]
+	for _, price := range prices {
+		if price < low {
+			low = price
+		}
+		if price > high {
+			high = price
+		}
+	}
+
+	points := []string{}
+	for i, price := range prices {
+		y := float64(sparklineHeight-1) / 2 // Flat line through the middle.
+		if high > low {
+			y = float64(sparklineHeight-1) * (high - price) / (high - low)
+		}
+		points = append(points, fmt.Sprintf(`%.1f,%.1f`, float64(sparklineWidth*i)/float64(len(prices)-1), y+0.5))
+	}
+
+	return template.HTML(fmt.Sprintf(`<svg width="%d" height="%d"><polyline points="%s" fill="none" stroke="%s"/></svg>`,
+		sparklineWidth, sparklineHeight, strings.Join(points, ` `), color))
+}
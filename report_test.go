@@ -0,0 +1,39 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{HistorySize: 10}, stocks: []Stock{
+		{Ticker: `AAPL`, QuoteType: `EQUITY`, LastTrade: numberOf(207.48), Change: numberOf(3.1), ChangePct: numberOf(1.52)},
+		{Ticker: `<IBM>`, QuoteType: `EQUITY`, LastTrade: numberOf(138.2), Change: numberOf(-1.5), ChangePct: numberOf(-1.07)},
+	}}
+	quotes.sample(time.Now())
+	quotes.stocks[0].LastTrade = numberOf(208)
+	quotes.sample(time.Now())
+
+	report := NewLayout().Report(quotes, time.Date(2019, 6, 28, 16, 0, 0, 0, time.UTC))
+	assert.Contains(t, report, `Stock quotes as of Jun 28, 2019 4:00pm UTC`)
+	assert.Contains(t, report, `<th style="padding: 2px 8px; text-align: right; border-bottom: 1px solid gray">Ticker</th>`)
+	assert.Contains(t, report, `&lt;IBM&gt;`, `escaped`)
+	assert.Equal(t, 2, strings.Count(report, `<svg`), `unchanged price is the flat line`)
+	assert.Contains(t, NewLayout().Report(&Quotes{profile: &Profile{}, stocks: quotes.stocks}, time.Now()), `</td><td style="padding: 2px 8px"></td></tr>`, `no sparkline without the history`)
+	assert.Contains(t, report, `<tr style="color: green"><td style="padding: 2px 8px; text-align: right">AAPL</td>`)
+	assert.Contains(t, report, `<tr style="color: red">`)
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, `<svg width="100" height="20"><polyline points="0.0,19.5 50.0,0.5 100.0,10.0" fill="none" stroke="green"/></svg>`,
+		string(sparkline([]float64{1, 3, 2}, `green`)))
+	assert.Contains(t, string(sparkline([]float64{5, 5}, `black`)), `points="0.0,10.0 100.0,10.0"`, `flat`)
+	assert.Empty(t, sparkline([]float64{5}, `black`))
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`errors`
+	`fmt`
+	`io`
+	`math/rand`
+	`net`
+	`time`
+)
+
+// Retry policy defaults, and the longest wait between the attempts.
+const (
+	retryAttempts = 3
+	retryBackoff  = 500 * time.Millisecond
+	retryJitter   = 0.5
+	retryMaxWait  = 30 * time.Second
+)
+
+// RetrySettings is how the stock quotes and the market data requests that
+// fail on the way to the provider, ex. timed out or dropped connection, get
+// retried. The providers that respond with 429 or 5xx are held off by the
+// throttle instead.
+type RetrySettings struct {
+	Attempts int     // Number of attempts including the first one, 0 for 3, 1 to never retry.
+	Backoff  int     // Milliseconds to wait after the first failure, doubled after each one that follows, 0 for 500.
+	Jitter   float64 // Fraction of the wait that is random, ex. 0.5 for ±50%, 0 for 0.5, negative for none.
+}
+
+// retryPolicy makes the attempts as per the retry settings, and tells the
+// screen while it's waiting to retry.
+type retryPolicy struct {
+	attempts int                                                // Number of attempts including the first one.
+	backoff  time.Duration                                      // Wait after the first failure.
+	jitter   float64                                            // Fraction of the wait that is random.
+	notify   func(status string)                                // Reports the retry status, blank once done, nil to keep quiet.
+	random   func() float64                                     // Returns random number in [0, 1), replaced in tests.
+	sleep    func(ctx context.Context, wait time.Duration) bool // Waits unless the context gets cancelled, replaced in tests.
+}
+
+// Returns the retry policy with the defaults filled in for the settings
+// left blank, nil settings included.
+//-----------------------------------------------------------------------------
+func newRetryPolicy(settings *RetrySettings, notify func(string)) retryPolicy {
+	policy := retryPolicy{attempts: retryAttempts, backoff: retryBackoff, jitter: retryJitter, notify: notify, random: rand.Float64, sleep: sleep}
+	if settings != nil {
+		if settings.Attempts > 0 {
+			policy.attempts = settings.Attempts
+		}
+		if settings.Backoff > 0 {
+			policy.backoff = time.Duration(settings.Backoff) * time.Millisecond
+		}
+		if settings.Jitter < 0 {
+			policy.jitter = 0
+		} else if settings.Jitter > 0 {
+			policy.jitter = settings.Jitter
+		}
+	}
+
+	return policy
+}
+
+// Calls the function until it succeeds, fails with the error that is not
+// worth retrying, runs out of attempts, or the context gets cancelled. The
+// last error is returned.
+//-----------------------------------------------------------------------------
+func (policy retryPolicy) do(ctx context.Context, what string, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = call(ctx); err == nil || attempt >= policy.attempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+		policy.report(fmt.Sprintf("retrying %s… (%d/%d)", what, attempt+1, policy.attempts))
+		if !policy.sleep(ctx, policy.wait(attempt)) {
+			break
+		}
+	}
+	policy.report(``)
+
+	return err
+}
+
+// Returns how long to wait after the given failed attempt: the backoff that
+// doubles with every attempt, give or take the random jitter.
+//-----------------------------------------------------------------------------
+func (policy retryPolicy) wait(attempt int) time.Duration {
+	wait := retryMaxWait
+	if attempt < 16 {
+		wait = policy.backoff << uint(attempt-1)
+	}
+	if wait > retryMaxWait {
+		wait = retryMaxWait
+	}
+
+	return time.Duration(float64(wait) * (1 + policy.jitter*(2*policy.random()-1)))
+}
+
+//-----------------------------------------------------------------------------
+func (policy retryPolicy) report(status string) {
+	if policy.notify != nil {
+		policy.notify(status)
+	}
+}
+
+// Returns true if the request failed on the way to the provider, and could
+// succeed if made again. The throttled requests and the cancelled ones are
+// not worth retrying, and neither are the errors the provider responds with.
+//-----------------------------------------------------------------------------
+func retryable(err error) bool {
+	var throttled *throttled
+	if errors.As(err, &throttled) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var network net.Error
+
+	return errors.As(err, &network) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Waits for the given time unless the context gets cancelled first, and
+// returns false then.
+//-----------------------------------------------------------------------------
+func sleep(ctx context.Context, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the retry policy that records the statuses and the waits instead
+// of sleeping.
+func testRetryPolicy(settings *RetrySettings) (*retryPolicy, *[]string, *[]time.Duration) {
+	statuses, waits := []string{}, []time.Duration{}
+	policy := newRetryPolicy(settings, func(status string) { statuses = append(statuses, status) })
+	policy.random = func() float64 { return 0.5 } // No jitter.
+	policy.sleep = func(ctx context.Context, wait time.Duration) bool {
+		waits = append(waits, wait)
+		return ctx.Err() == nil
+	}
+	return &policy, &statuses, &waits
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy := newRetryPolicy(nil, nil)
+	assert.Equal(t, []interface{}{3, 500 * time.Millisecond, 0.5}, []interface{}{policy.attempts, policy.backoff, policy.jitter})
+
+	policy = newRetryPolicy(&RetrySettings{Attempts: 5, Backoff: 200, Jitter: -1}, nil)
+	assert.Equal(t, []interface{}{5, 200 * time.Millisecond, 0.0}, []interface{}{policy.attempts, policy.backoff, policy.jitter})
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	policy, statuses, waits := testRetryPolicy(&RetrySettings{Attempts: 4})
+	calls := 0
+	err := policy.do(context.Background(), `quotes`, func(ctx context.Context) error {
+		if calls++; calls < 3 {
+			return &url.Error{Op: `Get`, URL: `https://query1.finance.yahoo.com`, Err: io.ErrUnexpectedEOF}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *waits, `backoff doubles`)
+	assert.Equal(t, []string{`retrying quotes… (2/4)`, `retrying quotes… (3/4)`, ``}, *statuses)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	policy, _, waits := testRetryPolicy(nil)
+	calls, failure := 0, &net.OpError{Op: `dial`, Net: `tcp`, Err: errors.New(`connection refused`)}
+	err := policy.do(context.Background(), `market`, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+	assert.Equal(t, failure, err, `last error`)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, *waits, 2)
+}
+
+func TestRetryNotWorthIt(t *testing.T) {
+	for _, failure := range []error{
+		errors.New(`Invalid API key`),
+		&url.Error{Op: `Get`, URL: `https://finnhub.io`, Err: &throttled{errors.New(`Holding off finnhub requests`)}},
+		fmt.Errorf("Fetching: %w", context.Canceled),
+	} {
+		policy, statuses, _ := testRetryPolicy(nil)
+		calls := 0
+		err := policy.do(context.Background(), `quotes`, func(ctx context.Context) error {
+			calls++
+			return failure
+		})
+		assert.Equal(t, failure, err)
+		assert.Equal(t, 1, calls, failure.Error())
+		assert.Equal(t, []string{``}, *statuses)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	policy, _, _ := testRetryPolicy(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	policy.do(ctx, `quotes`, func(ctx context.Context) error {
+		calls++
+		cancel() // Ex. quitting while the request is under way.
+		return io.EOF
+	})
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryWait(t *testing.T) {
+	policy := newRetryPolicy(&RetrySettings{Backoff: 1000, Jitter: 0.25}, nil)
+	policy.random = func() float64 { return 0 }
+	assert.Equal(t, 750*time.Millisecond, policy.wait(1))
+	policy.random = func() float64 { return 0.999999 }
+	assert.InDelta(t, float64(2500*time.Millisecond), float64(policy.wait(2)), float64(time.Millisecond))
+	assert.InDelta(t, float64(retryMaxWait*5/4), float64(policy.wait(20)), float64(time.Millisecond), `capped`)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`io`
+	`io/ioutil`
+	`net/http`
+	`strings`
+	`sync`
+)
+
+// Maximum number of responses kept for revalidation, and the maximum size
+// of each of them.
+const (
+	maxRevalidated     = 64
+	maxRevalidatedSize = 4 << 20
+)
+
+// revalidator makes the GET requests conditional: it keeps the responses
+// with the ETag or Last-Modified header, sends them back in If-None-Match
+// and If-Modified-Since, and replays the kept response when the server
+// answers 304 Not Modified, so the unchanged data isn't downloaded again.
+type revalidator struct {
+	next      http.RoundTripper       // Transport that makes the requests.
+	mutex     sync.Mutex              // Guards the responses.
+	responses map[string]*revalidated // Responses by request URL.
+	order     []string                // Request URLs, oldest first.
+}
+
+// revalidated is the response kept for revalidation.
+type revalidated struct {
+	etag         string      // ETag header of the response.
+	lastModified string      // Last-Modified header of the response.
+	header       http.Header // All the response headers.
+	body         []byte      // Response body.
+}
+
+// The revalidator comes before the throttle, so the conditional requests
+// count towards the provider's budget too.
+var conditional = newRevalidator(outgoing)
+
+//-----------------------------------------------------------------------------
+func newRevalidator(next http.RoundTripper) *revalidator {
+	return &revalidator{next: next, responses: map[string]*revalidated{}}
+}
+
+// RoundTrip makes the request conditional if the response to the same URL
+// has been kept, and keeps the response for the next time.
+func (revalidator *revalidator) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != `GET` || request.Header.Get(`Range`) != `` {
+		return revalidator.next.RoundTrip(request)
+	}
+
+	key := request.URL.String()
+	revalidator.mutex.Lock()
+	kept := revalidator.responses[key]
+	revalidator.mutex.Unlock()
+	if kept != nil {
+		request = request.Clone(request.Context())
+		if kept.etag != `` {
+			request.Header.Set(`If-None-Match`, kept.etag)
+		}
+		if kept.lastModified != `` {
+			request.Header.Set(`If-Modified-Since`, kept.lastModified)
+		}
+	}
+
+	response, err := revalidator.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotModified && kept != nil {
+		response.Body.Close()
+		return kept.replay(request), nil
+	}
+	if response.StatusCode == http.StatusOK {
+		return revalidator.keep(key, response), nil
+	}
+
+	return response, nil
+}
+
+// Keeps the response if it could be revalidated, and returns the response
+// to read it from.
+//-----------------------------------------------------------------------------
+func (revalidator *revalidator) keep(key string, response *http.Response) *http.Response {
+	etag, lastModified := response.Header.Get(`ETag`), response.Header.Get(`Last-Modified`)
+	if etag == `` && lastModified == `` {
+		return response
+	}
+	if response.ContentLength > maxRevalidatedSize || strings.HasPrefix(response.Header.Get(`Content-Type`), `text/event-stream`) {
+		return response
+	}
+
+	original := response.Body
+	body, err := ioutil.ReadAll(io.LimitReader(original, maxRevalidatedSize+1))
+	if err != nil || len(body) > maxRevalidatedSize { // The rest is read as usual.
+		response.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return response
+	}
+	original.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	revalidator.mutex.Lock()
+	defer revalidator.mutex.Unlock()
+	if _, ok := revalidator.responses[key]; !ok {
+		revalidator.order = append(revalidator.order, key)
+	}
+	revalidator.responses[key] = &revalidated{etag: etag, lastModified: lastModified, header: response.Header.Clone(), body: body}
+	for len(revalidator.order) > maxRevalidated {
+		delete(revalidator.responses, revalidator.order[0])
+		revalidator.order = revalidator.order[1:]
+	}
+
+	return response
+}
+
+// Returns the kept response as if it has just been received.
+//-----------------------------------------------------------------------------
+func (kept *revalidated) replay(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        `200 OK`,
+		StatusCode:    http.StatusOK,
+		Proto:         `HTTP/1.1`,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        kept.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(kept.body)),
+		ContentLength: int64(len(kept.body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getString(t *testing.T, client *http.Client, url string) string {
+	response, err := client.Get(url)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	return string(body)
+}
+
+func TestRevalidatorWithETag(t *testing.T) {
+	downloads, version := 0, `v1`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`If-None-Match`) == `"`+version+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set(`ETag`, `"`+version+`"`)
+		w.Write([]byte(`quotes ` + version))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newRevalidator(http.DefaultTransport)}
+
+	assert.Equal(t, `quotes v1`, getString(t, client, server.URL))
+	assert.Equal(t, `quotes v1`, getString(t, client, server.URL))
+	assert.Equal(t, 1, downloads)
+
+	version = `v2`
+	assert.Equal(t, `quotes v2`, getString(t, client, server.URL))
+	assert.Equal(t, 2, downloads)
+}
+
+func TestRevalidatorWithLastModified(t *testing.T) {
+	const modified = `Tue, 05 Mar 2019 21:00:00 GMT`
+	conditional := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`If-Modified-Since`) == modified {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(`Last-Modified`, modified)
+		w.Write([]byte(`quotes`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newRevalidator(http.DefaultTransport)}
+
+	getString(t, client, server.URL)
+	assert.Equal(t, `quotes`, getString(t, client, server.URL))
+	assert.Equal(t, 1, conditional)
+}
+
+func TestRevalidatorSkipsResponsesWithoutValidators(t *testing.T) {
+	revalidator := newRevalidator(http.DefaultTransport)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(`If-None-Match`))
+		w.Write([]byte(`quotes`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: revalidator}
+
+	getString(t, client, server.URL)
+	getString(t, client, server.URL)
+	assert.Empty(t, revalidator.responses)
+}
+
+func TestRevalidatorEvictsOldest(t *testing.T) {
+	revalidator := newRevalidator(nil)
+	for i := 0; i <= maxRevalidated; i++ {
+		response := &http.Response{StatusCode: http.StatusOK, Header: http.Header{`Etag`: {`"x"`}}, Body: http.NoBody}
+		revalidator.keep(string(rune('A'+i)), response)
+	}
+	assert.Len(t, revalidator.responses, maxRevalidated)
+	assert.NotContains(t, revalidator.responses, `A`)
+}
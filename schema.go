@@ -0,0 +1,214 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`strconv`
+	`strings`
+	`time`
+)
+
+// SchemaVersion is the version of the machine-readable output produced by
+// the non-interactive modes, the API, and JSON exports. Within the same
+// version fields may be added but are never renamed, removed, or change
+// their type or meaning; anything else bumps the version.
+const SchemaVersion = 1
+
+// Snapshot is the machine-readable view of everything mop is tracking at
+// the given moment: the market overview, the stock quotes, and the
+// portfolio totals. Every JSON consumer gets the same document.
+type Snapshot struct {
+	Schema    int                `json:"schema"`              // Always SchemaVersion.
+	Time      time.Time          `json:"time"`                // When the snapshot was taken.
+	Market    *MarketSnapshot    `json:"market,omitempty"`    // Market overview, omitted when not fetched.
+	Quotes    []QuoteSnapshot    `json:"quotes"`              // Stock quotes in the order they are displayed.
+	Portfolio *PortfolioSnapshot `json:"portfolio,omitempty"` // Portfolio totals, omitted when there are no holdings.
+	Profile   *ProfileSnapshot   `json:"profile,omitempty"`   // How the quotes are displayed, omitted when the quotes are not tracked.
+}
+
+// ProfileSnapshot is how the profile has the stock quotes displayed, so the
+// consumers could show them the same way.
+type ProfileSnapshot struct {
+	Tickers    []string `json:"tickers"`             // Watchlist in the profile's order.
+	Filter     string   `json:"filter,omitempty"`    // Filter expression, omitted when none.
+	SortColumn string   `json:"sortColumn"`          // Title of the column the quotes are sorted by, ex. "Change%".
+	Ascending  bool     `json:"ascending"`           // True when the sort order is ascending.
+	Grouped    bool     `json:"grouped"`             // True when the advancing stocks are listed first.
+	Reference  string   `json:"reference,omitempty"` // Price the change is calculated from, omitted for previous close.
+	Account    string   `json:"account,omitempty"`   // Account the portfolio is shown for, omitted for all the accounts.
+}
+
+// MarketSnapshot is the market overview as displayed at the top of the
+// screen.
+type MarketSnapshot struct {
+	Closed  bool            `json:"closed"`  // True when U.S. markets are closed.
+	Indices []IndexSnapshot `json:"indices"` // Indices, yields, commodities, and currencies.
+}
+
+// IndexSnapshot is one of the market overview indicators.
+type IndexSnapshot struct {
+	Name      string   `json:"name"`      // Ex. "Dow", "10-year Yield", "Gold".
+	Latest    *float64 `json:"latest"`    // Latest value.
+	Change    *float64 `json:"change"`    // Change since previous close.
+	ChangePct *float64 `json:"changePct"` // Change since previous close, in percent.
+}
+
+// QuoteSnapshot is the stock quote. Numbers are null when the provider has
+// not reported them.
+type QuoteSnapshot struct {
+	Ticker    string   `json:"ticker"`
+	Type      string   `json:"type,omitempty"` // Ex. "EQUITY" or "ETF".
+	Currency  string   `json:"currency,omitempty"`
+	Last      *float64 `json:"last"`
+	Change    *float64 `json:"change"`
+	ChangePct *float64 `json:"changePct"`
+	Open      *float64 `json:"open"`
+	Low       *float64 `json:"low"`
+	High      *float64 `json:"high"`
+	Low52     *float64 `json:"low52"`
+	High52    *float64 `json:"high52"`
+	Volume    *float64 `json:"volume"`
+	AvgVolume *float64 `json:"avgVolume"`
+	PeRatio   *float64 `json:"peRatio"`
+	Dividend  *float64 `json:"dividend"`
+	Yield     *float64 `json:"yield"`
+	MarketCap *float64 `json:"marketCap"`
+	PrevClose *float64 `json:"prevClose"`
+	Bid       *float64 `json:"bid"`
+	Ask       *float64 `json:"ask"`
+	Shares    float64  `json:"shares,omitempty"`    // Number of shares held, if any.
+	CostBasis float64  `json:"costBasis,omitempty"` // Total amount paid for the shares, if any.
+}
+
+// PortfolioSnapshot is the portfolio totals.
+type PortfolioSnapshot struct {
+	Account   string   `json:"account,omitempty"`  // Account the totals are of, omitted for all the accounts together.
+	Value     float64  `json:"value"`              // Market value of all the holdings.
+	Cash      float64  `json:"cash,omitempty"`     // Cash balance of the accounts, if any.
+	Cost      float64  `json:"cost"`               // Total cost basis of all the holdings.
+	Realized  float64  `json:"realized,omitempty"` // Profit or loss realized by the sales in the ledger, if any.
+	DayChange float64  `json:"dayChange"`          // Change of the market value since previous close.
+	Largest   string   `json:"largest"`            // Ticker of the largest position.
+	MaxWeight float64  `json:"maxWeight"`          // Weight of the largest position, in percent.
+	TWR       *float64 `json:"twr,omitempty"`      // Time-weighted return over the value history, in percent.
+	IRR       *float64 `json:"irr,omitempty"`      // Money-weighted annual return of the ledger, in percent.
+}
+
+// NewSnapshot captures the latest market data and stock quotes. Either one
+// could be nil if it's not being tracked.
+func NewSnapshot(market *Market, quotes *Quotes, now time.Time) *Snapshot {
+	snapshot := &Snapshot{Schema: SchemaVersion, Time: now, Quotes: []QuoteSnapshot{}}
+
+	if market != nil && len(market.Dow) > 0 {
+		snapshot.Market = &MarketSnapshot{Closed: market.IsClosed, Indices: []IndexSnapshot{}}
+		for _, index := range []struct {
+			name   string
+			values map[string]string
+		}{
+			{`Dow`, market.Dow}, {`S&P 500`, market.Sp500}, {`NASDAQ`, market.Nasdaq},
+			{`Tokyo`, market.Tokyo}, {`Hong Kong`, market.HongKong}, {`London`, market.London}, {`Frankfurt`, market.Frankfurt},
+			{`10-year Yield`, market.Yield}, {`Oil`, market.Oil}, {`Yen`, market.Yen}, {`Euro`, market.Euro}, {`Gold`, market.Gold},
+		} {
+			if len(index.values) == 0 {
+				continue
+			}
+			change, percent := index.values[`change`], index.values[`percent`]
+			if percent == `` { // Yields, commodities, and currencies only report the change in percent.
+				change, percent = ``, change
+			}
+			snapshot.Market.Indices = append(snapshot.Market.Indices, IndexSnapshot{
+				Name:      index.name,
+				Latest:    decimal(index.values[`latest`]),
+				Change:    decimal(change),
+				ChangePct: decimal(percent),
+			})
+		}
+	}
+
+	if quotes != nil {
+		profile := quotes.profile
+		snapshot.Profile = &ProfileSnapshot{
+			Tickers:    append([]string{}, profile.Tickers...),
+			Filter:     profile.Filter,
+			SortColumn: NewLayout().titleOf(profile.SortColumn, profile),
+			Ascending:  profile.Ascending,
+			Grouped:    profile.Grouped,
+			Reference:  profile.Reference,
+			Account:    profile.Account,
+		}
+		for _, stock := range quotes.stocks {
+			shares, cost := quotes.profile.position(quotes.profile.Account, stock.Ticker)
+			snapshot.Quotes = append(snapshot.Quotes, QuoteSnapshot{
+				Ticker:    stock.Ticker,
+				Type:      stock.QuoteType,
+				Currency:  stock.Currency,
+				Last:      stock.LastTrade.optional(),
+				Change:    stock.Change.optional(),
+				ChangePct: stock.ChangePct.optional(),
+				Open:      stock.Open.optional(),
+				Low:       stock.Low.optional(),
+				High:      stock.High.optional(),
+				Low52:     stock.Low52.optional(),
+				High52:    stock.High52.optional(),
+				Volume:    stock.Volume.optional(),
+				AvgVolume: stock.AvgVolume.optional(),
+				PeRatio:   stock.PeRatio.or(stock.PeRatioX).optional(),
+				Dividend:  stock.Dividend.optional(),
+				Yield:     stock.Yield.optional(),
+				MarketCap: stock.MarketCap.or(stock.MarketCapX).optional(),
+				PrevClose: stock.PrevClose.optional(),
+				Bid:       stock.Bid.optional(),
+				Ask:       stock.Ask.optional(),
+				Shares:    shares,
+				CostBasis: cost,
+			})
+		}
+		if portfolio := NewPortfolio(quotes); portfolio.Value > 0 || portfolio.Cost > 0 || portfolio.Cash > 0 {
+			snapshot.Portfolio = &PortfolioSnapshot{
+				Account:   portfolio.Account,
+				Value:     portfolio.Value,
+				Cash:      portfolio.Cash,
+				Cost:      portfolio.Cost,
+				Realized:  portfolio.Realized,
+				DayChange: portfolio.DayChange,
+				Largest:   portfolio.Largest,
+				MaxWeight: portfolio.MaxWeight,
+				TWR:       portfolio.TWR,
+				IRR:       portfolio.IRR,
+			}
+		}
+	}
+
+	return snapshot
+}
+
+// Converts formatted number, ex. "1.2B", "+0.45%", or "24,512.38" back to
+// its value. Returns nil if the value is missing.
+//-----------------------------------------------------------------------------
+func decimal(str string) *float64 {
+	str = strings.Replace(strings.Trim(str, ` %$+`), `,`, ``, -1)
+	if str == `` {
+		return nil
+	}
+
+	multiplier := 1.0
+	switch str[len(str)-1] {
+	case 'T':
+		multiplier = 1.0e12
+	case 'B':
+		multiplier = 1.0e9
+	case 'M':
+		multiplier = 1.0e6
+	case 'K':
+		multiplier = 1.0e3
+	}
+	value, err := strconv.ParseFloat(strings.TrimRight(str, `TBMK`), 64)
+	if err != nil {
+		return nil
+	}
+	value *= multiplier
+
+	return &value
+}
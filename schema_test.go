@@ -0,0 +1,81 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimal(t *testing.T) {
+	for str, expected := range map[string]float64{
+		`201.500`:   201.5,
+		`-0.633`:    -0.633,
+		`+0.45%`:    0.45,
+		`24,512.38`: 24512.38,
+		`31.250M`:   31.25e6,
+		`1.2B`:      1.2e9,
+		`$2T`:       2e12,
+	} {
+		require.NotNil(t, decimal(str), str)
+		assert.InDelta(t, expected, *decimal(str), 1e-3, str)
+	}
+	assert.Nil(t, decimal(``))
+	assert.Nil(t, decimal(`N/A`))
+}
+
+func TestSnapshot(t *testing.T) {
+	market := NewMarket()
+	market.IsClosed = true
+	market.Dow = map[string]string{`change`: `-85.10`, `latest`: `26,599.96`, `percent`: `-0.32%`}
+	market.Gold = map[string]string{`latest`: `1,413.70`, `change`: `+0.61`}
+
+	profile := &Profile{Holdings: map[string]Holding{`AAPL`: {Shares: 10, CostBasis: 1500}}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200.000), Change: numberOf(2.000), PrevClose: numberOf(198.000), MarketCapX: numberOf(927e9)},
+		{Ticker: `KO`, LastTrade: numberOf(47.100)},
+	}}
+
+	now := time.Date(2019, 6, 28, 16, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(NewSnapshot(market, quotes, now))
+	require.NoError(t, err)
+
+	decoded := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, float64(SchemaVersion), decoded[`schema`])
+	assert.Equal(t, `2019-06-28T16:00:00Z`, decoded[`time`])
+
+	indices := decoded[`market`].(map[string]interface{})[`indices`].([]interface{})
+	require.Len(t, indices, 2)
+	assert.Equal(t, map[string]interface{}{`name`: `Dow`, `latest`: 26599.96, `change`: -85.1, `changePct`: -0.32}, indices[0])
+	assert.Equal(t, map[string]interface{}{`name`: `Gold`, `latest`: 1413.7, `change`: nil, `changePct`: 0.61}, indices[1])
+
+	stocks := decoded[`quotes`].([]interface{})
+	require.Len(t, stocks, 2)
+	aapl := stocks[0].(map[string]interface{})
+	assert.Equal(t, 200.0, aapl[`last`])
+	assert.Equal(t, 927e9, aapl[`marketCap`], `falls back to MarketCapX`)
+	assert.Nil(t, aapl[`open`])
+	assert.Equal(t, 10.0, aapl[`shares`])
+	assert.NotContains(t, stocks[1], `shares`)
+
+	portfolio := decoded[`portfolio`].(map[string]interface{})
+	assert.Equal(t, 2000.0, portfolio[`value`])
+	assert.Equal(t, `AAPL`, portfolio[`largest`])
+
+	profile.Tickers, profile.Filter, profile.SortColumn = []string{`KO`, `AAPL`}, `last > 100`, 3
+	data, _ = json.Marshal(NewSnapshot(nil, quotes, now).Profile)
+	assert.JSONEq(t, `{"tickers":["KO","AAPL"],"filter":"last > 100","sortColumn":"Change%","ascending":false,"grouped":false}`, string(data))
+
+	data, _ = json.Marshal(NewSnapshot(nil, &Quotes{profile: &Profile{}}, now))
+	assert.JSONEq(t, `{"schema":1,"time":"2019-06-28T16:00:00Z","quotes":[],
+	  "profile":{"tickers":[],"sortColumn":"Ticker","ascending":false,"grouped":false}}`, string(data))
+	data, _ = json.Marshal(NewSnapshot(market, nil, now))
+	assert.NotContains(t, string(data), `"profile"`)
+}
@@ -5,20 +5,37 @@
 package mop
 
 import (
+	`fmt`
 	`github.com/nsf/termbox-go`
 	`strings`
 	`time`
 )
 
+// Minimum terminal size needed to display anything meaningful. Narrower
+// terminals that are still larger than the minimum get stock quotes shown
+// as cards, which is why the minimum width is 40 rather than the 80 columns
+// the table takes.
+const (
+	minWidth  = 40
+	minHeight = 10
+)
+
 // Screen is thin wrapper aroung Termbox library to provide basic display
 // capabilities as requied by Mop.
 type Screen struct {
-	width    int        // Current number of columns.
-	height   int        // Current number of rows.
-	cleared  bool       // True after the screens gets cleared.
-	layout   *Layout    // Pointer to layout (gets created by screen).
-	markup   *Markup    // Pointer to markup processor (gets created by screen).
-	pausedAt *time.Time // Timestamp of the pause request or nil if none.
+	width    int                                               // Current number of columns.
+	height   int                                               // Current number of rows.
+	cleared  bool                                              // True after the screens gets cleared.
+	layout   *Layout                                           // Pointer to layout (gets created by screen).
+	markup   *Markup                                           // Pointer to markup processor (gets created by screen).
+	pausedAt *time.Time                                        // Timestamp of the pause request or nil if none.
+	rows     int                                               // Number of rows taken by the last stock quotes.
+	mode     string                                            // Current mode that determines footer key hints.
+	failed   map[string]bool                                   // Sources ("market", "quotes") the last fetch failed for.
+	lines    []string                                          // Stock quotes lines displayed last, so only the changed ones get redrawn.
+	notice   string                                            // Note displayed to the right of the footer hints, ex. about newer release.
+	retrying string                                            // Retry status displayed in place of the note while the failed fetch is retried.
+	setCell  func(x, y int, ch rune, fg, bg termbox.Attribute) // Puts the character on the screen, replaced in tests.
 }
 
 // Initializes Termbox, creates screen along with layout and markup, and
@@ -28,7 +45,7 @@ func NewScreen() *Screen {
 	if err := termbox.Init(); err != nil {
 		panic(err)
 	}
-	screen := &Screen{}
+	screen := &Screen{mode: NormalMode, failed: make(map[string]bool), setCell: termbox.SetCell}
 	screen.layout = NewLayout()
 	screen.markup = NewMarkup()
 
@@ -64,10 +81,39 @@ func (screen *Screen) Pause(pause bool) *Screen {
 	return screen
 }
 
+// Mode switches the screen to the given mode (ex. when the line editor
+// kicks in) and updates the footer hint bar accordingly.
+func (screen *Screen) Mode(mode string) *Screen {
+	screen.mode = mode
+	screen.drawFooter()
+
+	return screen
+}
+
+// Notify displays the note in the lower right corner, next to the footer
+// hints, or removes it if the note is blank.
+func (screen *Screen) Notify(notice string) *Screen {
+	screen.notice = notice
+	screen.drawFooter()
+
+	return screen
+}
+
+// Retrying displays the status of the failed fetch that is about to be
+// retried in place of the footer note, or brings the note back if the
+// status is blank.
+func (screen *Screen) Retrying(status string) *Screen {
+	screen.retrying = status
+	screen.drawFooter()
+
+	return screen
+}
+
 // Clear makes the entire screen blank using default background color.
 func (screen *Screen) Clear() *Screen {
 	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
 	screen.cleared = true
+	screen.lines = nil
 
 	return screen
 }
@@ -75,8 +121,9 @@ func (screen *Screen) Clear() *Screen {
 // ClearLine erases the contents of the line starting from (x,y) coordinate
 // till the end of the line.
 func (screen *Screen) ClearLine(x int, y int) *Screen {
+	screen.invalidate(y)
 	for i := x; i < screen.width; i++ {
-		termbox.SetCell(i, y, ' ', termbox.ColorDefault, termbox.ColorDefault)
+		screen.setCell(i, y, ' ', termbox.ColorDefault, termbox.ColorDefault)
 	}
 	termbox.Flush()
 
@@ -84,23 +131,44 @@ func (screen *Screen) ClearLine(x int, y int) *Screen {
 }
 
 // Draw accepts variable number of arguments and knows how to display the
-// market data, stock quotes, current time, and an arbitrary string.
+// market data, stock quotes, watchlist statistics, current time, and an
+// arbitrary string.
 func (screen *Screen) Draw(objects ...interface{}) *Screen {
+	if screen.width < minWidth || screen.height < minHeight {
+		return screen.drawTooSmall()
+	}
+
 	zonename, _ := time.Now().In(time.Local).Zone()
 	if screen.pausedAt != nil {
 		defer screen.DrawLine(0, 0, `<right><r>`+screen.pausedAt.Format(`3:04:05pm ` + zonename)+`</r></right>`)
 	}
+	defer screen.drawFooter()
 	for _, ptr := range objects {
 		switch ptr.(type) {
 		case *Market:
 			object := ptr.(*Market)
+			screen.drawStatus(`<white>fetching…</>`)
 			screen.draw(screen.layout.Market(object.Fetch()))
+			ok, _ := object.Ok()
+			screen.failed[`market`] = !ok
+			screen.drawStatus(``)
 		case *Quotes:
 			object := ptr.(*Quotes)
-			screen.draw(screen.layout.Quotes(object.Fetch()))
+			screen.drawStatus(`<white>fetching…</>`)
+			screen.drawQuotes(object.Fetch())
+			screen.drawAlerts(object.Alerts())
+			screen.drawHistogram(object)
+			ok, _ := object.Ok()
+			screen.failed[`quotes`] = !ok
+			screen.drawStatus(``)
+		case *Statistics:
+			screen.draw(screen.layout.Statistics(ptr.(*Statistics)))
+		case *PaneManager:
+			screen.draw(ptr.(*PaneManager).String())
 		case time.Time:
 			timestamp := ptr.(time.Time).Format(`3:04:05pm ` + zonename)
 			screen.DrawLine(0, 0, `<right>`+timestamp+`</right>`)
+			screen.drawStatus(``)
 		default:
 			screen.draw(ptr.(string))
 		}
@@ -113,6 +181,7 @@ func (screen *Screen) Draw(objects ...interface{}) *Screen {
 // elements, and displays it all starting at (x,y) location.
 func (screen *Screen) DrawLine(x int, y int, str string) {
 	start, column := 0, 0
+	screen.invalidate(y)
 
 	for _, token := range screen.markup.Tokenize(str) {
 		// First check if it's a tag. Tags are eaten up and not displayed.
@@ -128,12 +197,130 @@ func (screen *Screen) DrawLine(x int, y int, str string) {
 			} else {
 				start = screen.width - len(token) + i
 			}
-			termbox.SetCell(start, y, char, screen.markup.Foreground, screen.markup.Background)
+			screen.setCell(start, y, char, screen.markup.Foreground, screen.markup.Background)
 		}
 	}
 	termbox.Flush()
 }
 
+// DrawBox draws a frame with the title in the top border around the given
+// lines starting at (x,y) location. Visible length of each line must match
+// the inner width of the box. It returns top and bottom rows of the box.
+func (screen *Screen) DrawBox(x, y, width int, title string, lines []string) (int, int) {
+	title = `─ ` + title + ` `
+	screen.DrawLine(x, y, `┌`+title+strings.Repeat(`─`, width-len([]rune(title)))+`┐`)
+	for i, line := range lines {
+		screen.DrawLine(x, y+1+i, `│`+line+`│`)
+	}
+	screen.DrawLine(x, y+len(lines)+1, `└`+strings.Repeat(`─`, width)+`┘`)
+
+	return y, y + len(lines) + 1
+}
+
+// Displays fetch status to the left of the timestamp in the upper right
+// corner: the given string while the data is being fetched, or a warning
+// glyph if the last fetch of market data or stock quotes has failed.
+func (screen *Screen) drawStatus(status string) {
+	if status == `` && (screen.failed[`market`] || screen.failed[`quotes`]) {
+		status = `<yellow>⚠</>`
+	}
+	x := screen.width - 26 // Leave room for the timestamp.
+	screen.DrawLine(x, 0, `          `)
+	screen.DrawLine(x, 0, status)
+}
+
+// Displays the hint bar with the most relevant keyboard commands for the
+// current mode at the bottom of the screen.
+func (screen *Screen) drawFooter() {
+	if screen.width < minWidth || screen.height < minHeight {
+		return
+	}
+	notice, color := screen.notice, `white`
+	if screen.retrying != `` {
+		notice, color = screen.retrying, `yellow`
+	}
+	width := screen.width
+	if notice != `` {
+		width -= len([]rune(notice)) + 1
+	}
+	screen.ClearLine(0, screen.height-1)
+	screen.DrawLine(0, screen.height-1, Hints(screen.mode, width))
+	if notice != `` && width > 0 {
+		screen.DrawLine(0, screen.height-1, `<right><`+color+`>`+notice+`</></right>`)
+	}
+}
+
+// Replaces whatever is on the screen with the friendly placeholder instead
+// of displaying corrupt output when the terminal gets too small. The
+// placeholder is drawn even if the screen has just been cleared, ex. to
+// redraw everything, which would otherwise leave it blank.
+func (screen *Screen) drawTooSmall() *Screen {
+	if !screen.cleared {
+		screen.Clear()
+	}
+	screen.DrawLine(0, 0, fmt.Sprintf(`Terminal too small (need %dx%d)`, minWidth, minHeight))
+	screen.rows = 0
+
+	return screen
+}
+
+// Displays stock quotes (as cards if the table does not fit the screen)
+// and clears the rows left over from the previous and possibly longer list
+// (ex. after removing the tickers). Only the rows that have changed since
+// the stock quotes were displayed last get redrawn to reduce flicker.
+func (screen *Screen) drawQuotes(quotes *Quotes) {
+	var str string
+	if screen.width < screen.layout.Width(quotes.profile) {
+		str = screen.layout.Cards(quotes)
+	} else {
+		str = screen.layout.Quotes(quotes)
+	}
+	rows := strings.Count(str, "\n") + 1
+	screen.drawChanged(str)
+	for row := rows; row < screen.rows; row++ {
+		screen.ClearLine(0, row)
+	}
+	screen.rows = rows
+
+	if quotes.profile.OffscreenRefresh > 1 { // Keep track of the tickers that fit the screen.
+		fit := screen.height - screen.layout.HeaderRow(quotes.profile) - 3 // Header, alerts, and footer.
+		if screen.width < screen.layout.Width(quotes.profile) {
+			fit /= 3 // Each card is three lines.
+		}
+		stocks, _ := screen.layout.prettify(quotes)
+		quotes.visible = make(map[string]bool)
+		for i := 0; i < fit && i < len(stocks); i++ {
+			quotes.visible[strings.TrimSpace(stocks[i].Ticker)] = true
+		}
+	}
+}
+
+// Displays triggered alerts, if any, right above the footer unless they
+// would overlap the stock quotes.
+func (screen *Screen) drawAlerts(alerts []string) {
+	row := screen.height - 2
+	if screen.rows >= row {
+		return
+	}
+	screen.ClearLine(0, row)
+	if len(alerts) > 0 {
+		screen.DrawLine(0, row, `<r><red> ! </></r> <red>`+strings.Join(alerts, `; `)+`</>`)
+	}
+}
+
+// Displays the change distribution histogram (if enabled) at the bottom
+// of the screen unless it would overlap the stock quotes.
+func (screen *Screen) drawHistogram(quotes *Quotes) {
+	top := screen.height - 2 - histogramRows // Leave room for the alerts and the footer.
+	if !quotes.profile.Histogram || screen.rows >= top {
+		return
+	}
+	for row, line := range strings.Split(screen.layout.Histogram(quotes), "\n") {
+		screen.ClearLine(0, top+row)
+		screen.DrawLine(0, top+row, line)
+	}
+}
+
 // Underlying workhorse function that takes multiline string, splits it into
 // lines, and displays them row by row.
 func (screen *Screen) draw(str string) {
@@ -144,3 +331,25 @@ func (screen *Screen) draw(str string) {
 		screen.DrawLine(0, row, line)
 	}
 }
+
+// Same as draw() but skips the lines that are already on the screen.
+func (screen *Screen) drawChanged(str string) {
+	if !screen.cleared {
+		screen.Clear()
+	}
+	lines := strings.Split(str, "\n")
+	for row, line := range lines {
+		if row >= len(screen.lines) || screen.lines[row] != line {
+			screen.DrawLine(0, row, line)
+		}
+	}
+	screen.lines = lines
+}
+
+// Forgets what stock quotes line has been displayed in the given row once
+// something else gets drawn over it, so the line gets redrawn next time.
+func (screen *Screen) invalidate(row int) {
+	if row >= 0 && row < len(screen.lines) {
+		screen.lines[row] = ``
+	}
+}
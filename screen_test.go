@@ -0,0 +1,37 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the screen of the given size that keeps the characters drawn on
+// it by row.
+func recordingScreen(width, height int) (*Screen, map[int][]rune) {
+	cells := make(map[int][]rune)
+	screen := &Screen{width: width, height: height, mode: NormalMode, failed: make(map[string]bool), layout: NewLayout(), markup: NewMarkup()}
+	screen.setCell = func(x, y int, ch rune, fg, bg termbox.Attribute) {
+		for len(cells[y]) <= x {
+			cells[y] = append(cells[y], ' ')
+		}
+		cells[y][x] = ch
+	}
+	return screen, cells
+}
+
+func TestDrawTooSmall(t *testing.T) {
+	screen, cells := recordingScreen(30, 5)
+	screen.Draw()
+	assert.Equal(t, `Terminal too small (need 40x10)`, strings.TrimSpace(string(cells[0])))
+
+	delete(cells, 0)
+	screen.Clear().Draw() // Already cleared, ex. to redraw everything.
+	assert.Equal(t, `Terminal too small (need 40x10)`, strings.TrimSpace(string(cells[0])))
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`sort`
+	`strings`
+
+	`github.com/nsf/termbox-go`
+)
+
+// Selection implements bulk edit mode. When activated it displays the row
+// cursor that is moved with arrow keys, the Space key marks and unmarks the
+// rows, and the bulk actions (remove, export, compare, copy) get applied to
+// the marked rows, or to the row under the cursor if nothing is marked.
+type Selection struct {
+	screen  *Screen  // Pointer to Screen so we could redraw stock quotes.
+	quotes  *Quotes  // Pointer to Quotes to apply bulk actions to.
+	layout  *Layout  // Pointer to Layout to get the list of displayed stocks.
+	profile *Profile // Pointer to Profile where we keep the cursor and the marks.
+	dialog  *Dialog  // Confirmation or message dialog, if any.
+}
+
+// Returns new initialized Selection struct. As part of initialization it
+// puts the cursor on the row it was at when bulk edit mode was last used.
+func NewSelection(screen *Screen, quotes *Quotes) *Selection {
+	selection := &Selection{
+		screen:  screen,
+		quotes:  quotes,
+		layout:  screen.layout,
+		profile: quotes.profile,
+	}
+	selection.profile.marked = make(map[string]bool)
+	screen.Mode(SelectionMode)
+
+	return selection.moveTo(selection.profile.selectedRow)
+}
+
+// Handle takes over the keyboard events and dispatches them to appropriate
+// bulk edit handlers. It returns true when user presses Esc.
+func (selection *Selection) Handle(event termbox.Event) bool {
+	if selection.dialog != nil {
+		if done := selection.dialog.Handle(event); done {
+			selection.dialog = nil
+		}
+		return false
+	}
+
+	switch {
+	case event.Key == termbox.KeyEsc:
+		return selection.done()
+
+	case event.Key == termbox.KeyArrowUp || event.Ch == 'k':
+		selection.moveTo(selection.profile.selectedRow - 1)
+
+	case event.Key == termbox.KeyArrowDown || event.Ch == 'j':
+		selection.moveTo(selection.profile.selectedRow + 1)
+
+	case event.Key == termbox.KeySpace:
+		selection.toggle().moveTo(selection.profile.selectedRow + 1)
+
+	case event.Ch == 'a' || event.Ch == 'A':
+		selection.toggleAll()
+
+	case event.Ch == '-' || event.Key == termbox.KeyDelete:
+		selection.remove()
+
+	case event.Ch == 'e' || event.Ch == 'E':
+		selection.export()
+
+	case event.Ch == 'c' || event.Ch == 'C':
+		selection.compare()
+
+	case event.Ch == 'y' || event.Ch == 'Y':
+		selection.yank()
+
+	case event.Key == termbox.KeyEnter:
+		selection.drillDown()
+	}
+
+	return false
+}
+
+//-----------------------------------------------------------------------------
+func (selection *Selection) moveTo(row int) *Selection {
+	if tickers := selection.tickers(); row >= len(tickers) {
+		row = len(tickers) - 1
+	}
+	if row < 0 {
+		row = 0
+	}
+	selection.profile.selectedRow = row
+
+	return selection.redraw()
+}
+
+// Marks the row under the cursor, or unmarks it if it has been marked.
+func (selection *Selection) toggle() *Selection {
+	if tickers := selection.tickers(); selection.profile.selectedRow < len(tickers) {
+		ticker := tickers[selection.profile.selectedRow]
+		if selection.profile.marked[ticker] {
+			delete(selection.profile.marked, ticker)
+		} else {
+			selection.profile.marked[ticker] = true
+		}
+	}
+
+	return selection
+}
+
+// Marks all the rows, or unmarks them if all of them have been marked.
+func (selection *Selection) toggleAll() *Selection {
+	tickers := selection.tickers()
+	if len(selection.profile.marked) == len(tickers) {
+		selection.profile.marked = make(map[string]bool)
+	} else {
+		for _, ticker := range tickers {
+			selection.profile.marked[ticker] = true
+		}
+	}
+
+	return selection.redraw()
+}
+
+//-----------------------------------------------------------------------------
+func (selection *Selection) remove() *Selection {
+	tickers := selection.targets()
+	if len(tickers) == 0 {
+		return selection
+	}
+
+	question := `Remove ` + strings.Join(tickers, `, `) + `?`
+	selection.dialog = NewConfirmDialog(selection.screen, `Remove tickers`, question, func(ok bool) {
+		if ok {
+			selection.quotes.RemoveTickers(tickers)
+			selection.profile.marked = make(map[string]bool)
+		}
+		selection.screen.Draw(selection.quotes) // Also erases the dialog.
+		selection.moveTo(selection.profile.selectedRow)
+	})
+
+	return selection
+}
+
+//-----------------------------------------------------------------------------
+func (selection *Selection) export() *Selection {
+	tickers := make(map[string]bool)
+	for _, ticker := range selection.targets() {
+		tickers[ticker] = true
+	}
+
+	table := selection.layout.table(selection.quotes, tickers)
+	selection.dialog = NewExportDialog(selection.screen, `csv`, func(filename string) error {
+		return SaveCSV(filename, table)
+	}, func(message string) {
+		selection.redraw() // Erase the dialog.
+		selection.screen.Notify(message)
+	})
+
+	return selection
+}
+
+// Copies the summary of the marked stocks to the clipboard, ex. to paste it
+// into a chat.
+func (selection *Selection) yank() *Selection {
+	tickers := selection.targets()
+	if len(tickers) == 0 {
+		return selection
+	}
+
+	if err := copyToClipboard(selection.layout.Summary(selection.quotes, tickers)); err != nil {
+		selection.screen.Notify(err.Error())
+	} else {
+		selection.screen.Notify(fmt.Sprintf(`Copied %d stock(s) to clipboard`, len(tickers)))
+	}
+
+	return selection
+}
+
+// Shows marked stocks side by side in the dialog box. The box is likely to
+// cover the market data so the entire screen gets redrawn when it's closed.
+func (selection *Selection) compare() *Selection {
+	tickers := selection.targets()
+	if len(tickers) < 2 || len(tickers) > 5 {
+		selection.dialog = NewMessageDialog(selection.screen, `Compare`, `Mark 2 to 5 stocks to compare them.`, func(bool) {
+			selection.redraw() // Erase the dialog.
+		})
+		return selection
+	}
+
+	comparison := selection.layout.Compare(selection.quotes, tickers)
+	selection.dialog = NewMessageDialog(selection.screen, `Compare`, comparison, func(bool) {
+		selection.screen.Clear().Draw(selection.quotes.market)
+		selection.redraw()
+	})
+
+	return selection
+}
+
+// Lists the constituents of the index under the cursor as defined in the
+// profile, fetching the quotes of the ones that are not in the watchlist.
+func (selection *Selection) drillDown() *Selection {
+	tickers := selection.tickers()
+	if selection.profile.selectedRow >= len(tickers) {
+		return selection
+	}
+
+	index := tickers[selection.profile.selectedRow]
+	message := `No constituents of ` + index + ` are listed in the profile.`
+	if constituents := selection.profile.Constituents[index]; len(constituents) > 0 {
+		message = selection.layout.Constituents(selection.quotes.Subset(constituents))
+	}
+	selection.dialog = NewMessageDialog(selection.screen, index, message, func(bool) {
+		selection.screen.Clear().Draw(selection.quotes.market)
+		selection.redraw()
+	})
+
+	return selection
+}
+
+//-----------------------------------------------------------------------------
+func (selection *Selection) done() bool {
+	selection.profile.marked = nil
+	selection.redraw()
+	selection.screen.Mode(NormalMode)
+
+	return true
+}
+
+// Redraws stock quotes to reflect the cursor and the marks without fetching
+// the quotes again.
+func (selection *Selection) redraw() *Selection {
+	if screen := selection.screen; screen.width >= minWidth && screen.height >= minHeight {
+		screen.drawQuotes(selection.quotes)
+		termbox.Flush()
+	}
+
+	return selection
+}
+
+// Returns the tickers in the order they are displayed.
+func (selection *Selection) tickers() []string {
+	tickers := []string{}
+	stocks, _ := selection.layout.prettify(selection.quotes)
+	for _, stock := range stocks {
+		tickers = append(tickers, strings.TrimSpace(stock.Ticker))
+	}
+
+	return tickers
+}
+
+// Returns the tickers the bulk action applies to: the marked ones, or the
+// ticker under the cursor if none are marked.
+func (selection *Selection) targets() []string {
+	tickers := []string{}
+	for ticker := range selection.profile.marked {
+		tickers = append(tickers, ticker)
+	}
+	if len(tickers) == 0 {
+		if displayed := selection.tickers(); selection.profile.selectedRow < len(displayed) {
+			tickers = append(tickers, displayed[selection.profile.selectedRow])
+		}
+	}
+	sort.Strings(tickers)
+
+	return tickers
+}
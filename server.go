@@ -0,0 +1,366 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`crypto/tls`
+	_ `embed`
+	`encoding/json`
+	`net`
+	`net/http`
+	`strings`
+	`sync`
+	`time`
+)
+
+//go:embed web/index.html
+var indexHTML []byte
+
+// Server serves the web view of the market data and stock quotes that
+// mirrors the terminal, so mop could double as a home dashboard. The page
+// gets updated over the websocket every time the quotes are refreshed.
+// Besides the terminal's own profile the server could host the profiles of
+// other users, each with its own watchlist and alerts; all of them share
+// the quote store, and therefore the provider and its rate limit.
+type Server struct {
+	settings *ServerSettings        // Access tokens and TLS settings, nil for defaults.
+	layout   *Layout                // Formats the stock quotes table the same way the terminal does.
+	users    map[string]*Profile    // Profiles of the other users, by access token.
+	quotes   map[string]*Quotes     // Stock quotes of the other users, by access token.
+	mutex    sync.Mutex             // Guards the latest updates and the clients.
+	latest   map[string][]byte      // Latest update per user (blank for the terminal's profile), JSON.
+	clients  map[*websocket]*viewer // Connected web views and the users they belong to.
+	changes  chan *Change           // Watchlist changes requested by the clients, for the loop that owns the profile.
+}
+
+// viewer is the web view connected to the server: the user it belongs to,
+// and the updates waiting to be sent to it. Each web view gets the updates
+// sent by its own goroutine so the slow one doesn't hold up the rest.
+type viewer struct {
+	user    string      // User the web view belongs to, blank for the terminal's profile.
+	updates chan []byte // Updates waiting to be sent.
+}
+
+// Number of the updates that could be waiting to be sent to the web view
+// before it gets disconnected for falling behind.
+const viewerBacklog = 8
+
+// Change is the watchlist change requested through the server, either to
+// the terminal's profile or to the profile of the user the token belongs
+// to. The profiles belong to the loop that refreshes the quotes, so the
+// change is applied there, and the client waits for it.
+type Change struct {
+	Add     bool          // True to add the tickers, false to remove them.
+	Tickers []string      // Tickers to add or remove, ex. ["AAPL", "MSFT"].
+	User    string        // Access token of the user whose watchlist changes, blank for the terminal's profile.
+	server  *Server       // Server that hosts the profiles of the other users.
+	done    chan response // Number of the tickers added or removed, or the error.
+}
+
+// What the client gets back once the change has been applied.
+type response struct {
+	count int
+	err   error
+}
+
+// How long the client waits for the change to be applied.
+const changeTimeout = 10 * time.Second
+
+// How long the server waits for the request headers, and for the next
+// request on the kept-alive connection, before hanging up on the client.
+// There's no timeout for the whole request since the websocket stays open.
+const (
+	serverHeaderTimeout = 10 * time.Second
+	serverIdleTimeout   = 2 * time.Minute
+)
+
+// Update is the message the web view gets over the websocket: the snapshot
+// of the data along with the stock quotes table formatted as displayed.
+type Update struct {
+	Snapshot *Snapshot  `json:"snapshot"`
+	Table    [][]string `json:"table"`            // Column titles followed by the rows, as returned by Layout.Table().
+	Alerts   []string   `json:"alerts,omitempty"` // Triggered alerts.
+}
+
+// Returns new initialized Server struct with the given settings, if any.
+// The profiles of the other users are loaded (or created) right away.
+func NewServer(settings *ServerSettings) *Server {
+	server := &Server{
+		settings: settings,
+		layout:   NewLayout(),
+		users:    make(map[string]*Profile),
+		quotes:   make(map[string]*Quotes),
+		latest:   make(map[string][]byte),
+		clients:  make(map[*websocket]*viewer),
+		changes:  make(chan *Change),
+	}
+	if settings != nil {
+		for token, filename := range settings.Users {
+			server.users[token] = NewProfile(filename)
+		}
+	}
+
+	return server
+}
+
+// Listen starts serving the web view at the given address, ex. ":8080", in
+// the background. It's served over HTTPS if TLS certificate is set up.
+func (server *Server) Listen(address string) error {
+	var certificate tls.Certificate
+	secure := server.settings != nil && server.settings.Certificate != ``
+	if secure {
+		var err error
+		if certificate, err = tls.LoadX509KeyPair(server.settings.Certificate, server.settings.Key); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen(`tcp`, address)
+	if err != nil {
+		return err
+	}
+	if secure {
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{certificate}})
+	}
+	web := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: serverHeaderTimeout, IdleTimeout: serverIdleTimeout}
+	go web.Serve(listener)
+
+	return nil
+}
+
+// Handler returns HTTP handler that serves the web view page at the root,
+// the updates at /stream, and the latest quotes, market data, and profile
+// at /quotes, /market, and /profile, all for read-only tokens and up except
+// the page itself, which carries no data and passes the token given in its
+// URL on to the websocket. The
+// read-write tokens could also add and remove the tickers at /tickers, and
+// so could the other users, each on their own watchlist.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(`/`, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != `/` {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(`Content-Type`, `text/html; charset=utf-8`)
+		w.Write(indexHTML)
+	})
+	mux.HandleFunc(`/stream`, server.authorize(ScopeRead, server.stream))
+	mux.HandleFunc(`/quotes`, server.authorize(ScopeRead, server.section(func(snapshot *Snapshot) interface{} {
+		return snapshot.Quotes
+	})))
+	mux.HandleFunc(`/market`, server.authorize(ScopeRead, server.section(func(snapshot *Snapshot) interface{} {
+		return snapshot.Market
+	})))
+	mux.HandleFunc(`/profile`, server.authorize(ScopeRead, server.section(func(snapshot *Snapshot) interface{} {
+		return snapshot.Profile
+	})))
+	mux.HandleFunc(`/tickers`, server.authorize(ScopeWrite, server.tickers))
+
+	return mux
+}
+
+// Publish sends the latest market data and stock quotes to all the web
+// views. The quotes of the other users are refreshed from the same quote
+// store first.
+func (server *Server) Publish(market *Market, quotes *Quotes) *Server {
+	server.publish(``, market, quotes)
+	for token := range server.users {
+		server.publish(token, market, server.quotesOf(token, quotes).Fetch())
+	}
+
+	return server
+}
+
+// Returns the stock quotes of the user the token belongs to, shared with
+// the terminal's quotes, or the terminal's quotes for blank token.
+//-----------------------------------------------------------------------------
+func (server *Server) quotesOf(user string, quotes *Quotes) *Quotes {
+	if user == `` || server.users[user] == nil {
+		return quotes
+	}
+	if server.quotes[user] == nil {
+		server.quotes[user] = quotes.Share(server.users[user])
+	}
+
+	return server.quotes[user]
+}
+
+// Sends the update to the web views of the given user.
+//-----------------------------------------------------------------------------
+func (server *Server) publish(user string, market *Market, quotes *Quotes) {
+	update := Update{Snapshot: NewSnapshot(market, quotes, time.Now()), Table: server.layout.Table(quotes)}
+	if user != `` {
+		update.Alerts = quotes.Alerts() // The terminal's own alerts are displayed in the terminal.
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.latest[user] = data
+	for client, viewer := range server.clients {
+		if viewer.user != user {
+			continue
+		}
+		select {
+		case viewer.updates <- data:
+		default: // Fell behind, let it reconnect.
+			server.drop(client)
+		}
+	}
+}
+
+// Keeps the web view connected until it goes away. The web view gets the
+// latest update of the user the token belongs to as soon as it connects.
+//-----------------------------------------------------------------------------
+func (server *Server) stream(w http.ResponseWriter, r *http.Request) {
+	client, err := upgradeWebsocket(w, r)
+	if err != nil {
+		return
+	}
+
+	server.join(client, server.owner(r))
+	for { // Nothing is expected from the web view, just wait for it to disconnect.
+		if _, err := client.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	server.mutex.Lock()
+	server.drop(client)
+	server.mutex.Unlock()
+}
+
+// Registers the web view of the given user, and starts sending it the
+// updates, the latest one first.
+//-----------------------------------------------------------------------------
+func (server *Server) join(client *websocket, user string) {
+	viewer := &viewer{user: user, updates: make(chan []byte, viewerBacklog)}
+	server.mutex.Lock()
+	server.clients[client] = viewer
+	if latest := server.latest[user]; latest != nil {
+		viewer.updates <- latest
+	}
+	server.mutex.Unlock()
+
+	go func() {
+		for update := range viewer.updates {
+			if err := client.WriteText(update); err != nil {
+				client.Close() // Gets the web view dropped once the read fails.
+				return
+			}
+		}
+	}()
+}
+
+// Disconnects the web view, unless it's gone already. The caller is
+// expected to hold the mutex.
+//-----------------------------------------------------------------------------
+func (server *Server) drop(client *websocket) {
+	if viewer, ok := server.clients[client]; ok {
+		delete(server.clients, client)
+		close(viewer.updates)
+		client.Close()
+	}
+}
+
+// Changes returns the channel the watchlist changes requested by the
+// clients arrive at. Whoever refreshes the quotes is expected to apply them.
+func (server *Server) Changes() <-chan *Change {
+	return server.changes
+}
+
+// Apply adds or removes the tickers and lets the client know how it went.
+// The terminal's quotes are changed unless the change is for another user.
+func (change *Change) Apply(quotes *Quotes) error {
+	result := response{}
+	if change.server != nil {
+		quotes = change.server.quotesOf(change.User, quotes)
+	}
+	if change.Add {
+		result.count, result.err = quotes.AddTickers(change.Tickers)
+	} else {
+		result.count, result.err = quotes.RemoveTickers(change.Tickers)
+	}
+	change.done <- result
+
+	return result.err
+}
+
+// Adds the tickers given as comma-separated "tickers" parameter on POST
+// and removes them on DELETE, ex. "POST /tickers?tickers=AAPL,MSFT", then
+// responds with the number of the tickers added or removed. The other
+// users' tokens change their own watchlists.
+//-----------------------------------------------------------------------------
+func (server *Server) tickers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.Header().Set(`Allow`, `POST, DELETE`)
+		http.Error(w, `Method not allowed`, http.StatusMethodNotAllowed)
+		return
+	}
+	tickers := []string{}
+	for _, ticker := range strings.Split(strings.ToUpper(r.FormValue(`tickers`)), `,`) {
+		if ticker = strings.TrimSpace(ticker); ticker != `` {
+			tickers = append(tickers, ticker)
+		}
+	}
+	if len(tickers) == 0 {
+		http.Error(w, `No tickers given`, http.StatusBadRequest)
+		return
+	}
+
+	change := &Change{Add: r.Method == http.MethodPost, Tickers: tickers, User: server.owner(r), server: server, done: make(chan response, 1)}
+	select {
+	case server.changes <- change:
+	case <-time.After(changeTimeout):
+		http.Error(w, `The watchlist is busy, try again later`, http.StatusServiceUnavailable)
+		return
+	}
+	result := <-change.done
+	if result.err != nil {
+		http.Error(w, result.err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	key := `removed`
+	if change.Add {
+		key = `added`
+	}
+	w.Header().Set(`Content-Type`, `application/json`)
+	json.NewEncoder(w).Encode(map[string]int{key: result.count})
+}
+
+// Returns the handler that serves the given section of the latest snapshot
+// of the user the token belongs to as JSON, ex. the quotes, so the scripts
+// could read what mop is tracking without the websocket.
+//-----------------------------------------------------------------------------
+func (server *Server) section(pick func(snapshot *Snapshot) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		latest := server.latest[server.owner(r)]
+		server.mutex.Unlock()
+
+		update := Update{}
+		if latest == nil || json.Unmarshal(latest, &update) != nil || update.Snapshot == nil {
+			http.Error(w, `Nothing has been fetched yet`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false) // Filter expressions read better as is.
+		encoder.Encode(pick(update.Snapshot))
+	}
+}
+
+// Returns the user the request's token belongs to, or blank for the
+// terminal's profile.
+//-----------------------------------------------------------------------------
+func (server *Server) owner(r *http.Request) string {
+	return server.userOf(token(r))
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(nil)
+	web := httptest.NewServer(server.Handler())
+	defer web.Close()
+
+	response, err := http.Get(web.URL)
+	require.NoError(t, err)
+	page, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Contains(t, string(page), `/stream`)
+
+	profile := &Profile{Tickers: []string{`AAPL`}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.000), Change: numberOf(-1.500), ChangePct: numberOf(-0.744)}}}
+	server.Publish(NewMarket(), quotes)
+
+	client, err := dialWebsocket(strings.Replace(web.URL, `http://`, `ws://`, 1) + `/stream`)
+	require.NoError(t, err)
+	defer client.Close()
+
+	message, err := client.ReadMessage()
+	require.NoError(t, err)
+	update := Update{}
+	require.NoError(t, json.Unmarshal(message, &update))
+	assert.Equal(t, SchemaVersion, update.Snapshot.Schema)
+	require.Len(t, update.Table, 2, `the latest update is sent on connect`)
+	assert.Equal(t, []string{`AAPL`, `$200.00`, `-$1.50`}, update.Table[1][:3])
+
+	quotes.stocks[0].LastTrade = numberOf(201.000)
+	server.Publish(NewMarket(), quotes)
+	message, err = client.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(message, &update))
+	assert.Equal(t, `$201.00`, update.Table[1][1])
+}
+
+func TestServerRejectsPlainRequests(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewServer(nil).Handler().ServeHTTP(recorder, httptest.NewRequest(`GET`, `/stream`, nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	NewServer(nil).Handler().ServeHTTP(recorder, httptest.NewRequest(`GET`, `/nope`, nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServerStreamLimits(t *testing.T) {
+	recorder, request := httptest.NewRecorder(), httptest.NewRequest(`GET`, `/stream`, nil)
+	request.Header.Set(`Upgrade`, `websocket`)
+	request.Header.Set(`Sec-WebSocket-Key`, `dGhlIHNhbXBsZSBub25jZQ==`)
+	request.Header.Set(`Origin`, `https://evil.example`)
+	NewServer(nil).Handler().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code, `cross-origin connection`)
+
+	web := httptest.NewServer(NewServer(nil).Handler())
+	defer web.Close()
+	client, err := dialWebsocket(strings.Replace(web.URL, `http://`, `ws://`, 1) + `/stream`)
+	require.NoError(t, err)
+	defer client.Close()
+
+	header := []byte{0x80 | opText, 0x80 | 127, 0, 0, 1, 0, 0, 0, 0, 0, 1, 2, 3, 4} // 1TB masked frame.
+	_, err = client.conn.Write(header)
+	require.NoError(t, err)
+	_, opcode, payload, err := client.frame()
+	require.NoError(t, err)
+	assert.Equal(t, byte(opClose), opcode)
+	assert.Equal(t, []byte{0x03, 0xF1}, payload, `closed with 1009`)
+}
+
+func TestWebsocketClientLimit(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server, err := upgradeWebsocket(w, r)
+		require.NoError(t, err)
+		defer server.Close()
+		server.conn.Write([]byte{0x80 | opText, 127, 0xFF, 0, 0, 0, 0, 0, 0, 0}) // Way over any memory.
+		server.ReadMessage()
+	}))
+	defer web.Close()
+
+	client, err := dialWebsocket(strings.Replace(web.URL, `http://`, `ws://`, 1))
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.ReadMessage()
+	assert.EqualError(t, err, `Websocket message is over 4194304 bytes`)
+}
+
+func TestServerDropsStalledViewers(t *testing.T) {
+	server := NewServer(nil)
+	conn, peer := net.Pipe() // Nobody reads the peer end, so the writes stall.
+	defer peer.Close()
+	server.join(&websocket{conn: conn, reader: bufio.NewReader(conn), server: true}, ``)
+
+	quotes := &Quotes{profile: &Profile{Tickers: []string{`AAPL`}}, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.000)}}}
+	published := make(chan bool)
+	go func() {
+		for i := 0; i < viewerBacklog+2; i++ {
+			server.Publish(nil, quotes)
+		}
+		published <- true
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal(`publishing is held up by the stalled web view`)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	assert.Empty(t, server.clients, `the web view that fell behind is dropped`)
+}
+
+func TestServerUsers(t *testing.T) {
+	filename := t.TempDir() + `/alice.moprc`
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"Tickers":["KO"],"Alerts":["KO: last > 40"]}`), 0644))
+
+	server := NewServer(&ServerSettings{Tokens: map[string]string{`admin`: ScopeWrite}, Users: map[string]string{`alice`: filename}})
+	assert.True(t, server.allowed(`alice`, ScopeRead))
+	assert.True(t, server.allowed(`alice`, ScopeWrite), `on their own profile`)
+
+	web := httptest.NewServer(server.Handler())
+	defer web.Close()
+
+	provider := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(200.000)}, `KO`: {Ticker: `KO`, LastTrade: numberOf(47.100)}}}
+	quotes := &Quotes{market: NewMarket(), profile: &Profile{Tickers: []string{`AAPL`}}, store: NewQuoteStore(provider)}
+	quotes.subscription = quotes.store.Subscribe(quotes.profile.Tickers)
+	server.Publish(quotes.market, quotes.Fetch())
+
+	read := func(token string) Update {
+		client, err := dialWebsocket(strings.Replace(web.URL, `http://`, `ws://`, 1) + `/stream?token=` + token)
+		require.NoError(t, err)
+		defer client.Close()
+		message, err := client.ReadMessage()
+		require.NoError(t, err)
+		update := Update{}
+		require.NoError(t, json.Unmarshal(message, &update))
+		return update
+	}
+
+	admin := read(`admin`)
+	require.Len(t, admin.Table, 2)
+	assert.Equal(t, `AAPL`, admin.Table[1][0])
+	assert.Empty(t, admin.Alerts)
+
+	alice := read(`alice`)
+	require.Len(t, alice.Table, 2)
+	assert.Equal(t, `KO`, alice.Table[1][0], `each user gets own watchlist`)
+	assert.Equal(t, []string{`KO: last > 40`}, alice.Alerts)
+	assert.Equal(t, [][]string{{`AAPL`}, {`KO`}}, provider.requested, `quotes come from the shared store`)
+
+	go func() {
+		for change := range server.Changes() {
+			change.Apply(quotes)
+		}
+	}()
+	request, _ := http.NewRequest(`POST`, web.URL+`/tickers?tickers=PEP`, nil)
+	request.Header.Set(`Authorization`, `Bearer alice`)
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []string{`KO`, `PEP`}, server.users[`alice`].Tickers, `own watchlist changes`)
+	assert.Equal(t, []string{`AAPL`}, quotes.profile.Tickers, `terminal's watchlist stays`)
+}
+
+func TestServerEndpoints(t *testing.T) {
+	server := NewServer(nil)
+	get := func(path string) (int, string) {
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(`GET`, path, nil))
+		return recorder.Code, strings.TrimSpace(recorder.Body.String())
+	}
+	code, _ := get(`/quotes`)
+	assert.Equal(t, http.StatusServiceUnavailable, code, `nothing published yet`)
+
+	profile := &Profile{Tickers: []string{`AAPL`}, Filter: `last > 100`}
+	quotes := &Quotes{profile: profile, stocks: []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.000)}}}
+	server.Publish(nil, quotes)
+
+	code, body := get(`/quotes`)
+	assert.Equal(t, http.StatusOK, code)
+	decoded := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(body), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, 200.0, decoded[0][`last`])
+
+	_, body = get(`/market`)
+	assert.Equal(t, `null`, body, `no market data`)
+	_, body = get(`/profile`)
+	assert.Contains(t, body, `"filter":"last > 100"`)
+}
+
+func TestServerChanges(t *testing.T) {
+	server := NewServer(&ServerSettings{Tokens: map[string]string{`ro`: ScopeRead, `rw`: ScopeWrite}})
+	request := func(method, path, token string) (int, string) {
+		recorder, request := httptest.NewRecorder(), httptest.NewRequest(method, path, nil)
+		request.Header.Set(`Authorization`, `Bearer `+token)
+		server.Handler().ServeHTTP(recorder, request)
+		return recorder.Code, strings.TrimSpace(recorder.Body.String())
+	}
+	code, _ := request(`POST`, `/tickers?tickers=MSFT`, `ro`)
+	assert.Equal(t, http.StatusUnauthorized, code, `read-only token`)
+	code, _ = request(`GET`, `/tickers`, `rw`)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	code, _ = request(`POST`, `/tickers?tickers=,`, `rw`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `.moprc`), Tickers: []string{`AAPL`}}
+	quotes := &Quotes{profile: profile}
+	go func() {
+		for change := range server.Changes() {
+			change.Apply(quotes)
+		}
+	}()
+	code, body := request(`POST`, `/tickers?tickers=msft,+ibm`, `rw`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"added":2}`, body)
+	code, body = request(`DELETE`, `/tickers?tickers=AAPL,GOOG`, `rw`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"removed":1}`, body)
+	assert.ElementsMatch(t, []string{`IBM`, `MSFT`}, profile.Tickers)
+}
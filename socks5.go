@@ -0,0 +1,190 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`errors`
+	`fmt`
+	`io`
+	`net`
+	`net/url`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// SOCKS5 protocol constants, see RFC 1928 and RFC 1929.
+const (
+	socks5Version      = 0x05
+	socks5NoAuth       = 0x00
+	socks5PasswordAuth = 0x02
+	socks5NoMethods    = 0xFF
+	socks5Connect      = 0x01
+	socks5IPv4         = 0x01
+	socks5Domain       = 0x03
+	socks5IPv6         = 0x04
+)
+
+// Reasons the SOCKS5 proxy gives for not connecting, by reply code.
+var socks5Failures = map[byte]string{
+	0x01: `general failure`,
+	0x02: `connection not allowed`,
+	0x03: `network unreachable`,
+	0x04: `host unreachable`,
+	0x05: `connection refused`,
+	0x06: `TTL expired`,
+	0x07: `command not supported`,
+	0x08: `address type not supported`,
+}
+
+// UseSocks5 makes the requests go through the SOCKS5 proxy at the given
+// address, ex. "localhost:1080" or "user:password@localhost:1080", for the
+// current session only, ex. from the -socks5 command line flag.
+func (profile *Profile) UseSocks5(address string) *Profile {
+	profile.socks5 = address
+	return profile
+}
+
+// ProxySetting returns the proxy the requests go through: the SOCKS5 proxy
+// set on the command line or in the profile, or else the profile's Proxy.
+// Blank means the proxy from the environment, if any.
+func (profile *Profile) ProxySetting() string {
+	for _, address := range []string{profile.socks5, profile.Socks5} {
+		if address != `` {
+			return `socks5://` + strings.TrimPrefix(address, `socks5://`)
+		}
+	}
+	return profile.Proxy
+}
+
+// Connects to the host, ex. "stream.binance.com:9443", through the SOCKS5
+// proxy. The host name is resolved by the proxy, which keeps the lookups
+// private when going through Tor.
+//-----------------------------------------------------------------------------
+func dialSocks5(dialer *net.Dialer, via *url.URL, host string) (net.Conn, error) {
+	address := via.Host
+	if via.Port() == `` {
+		address += `:1080`
+	}
+	conn, err := dialer.Dial(`tcp`, address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(websocketTimeout))
+	if err := socks5Handshake(conn, via.User, host); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %s", address, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// Authenticates with the proxy, if needed, and asks it to connect to the
+// host.
+//-----------------------------------------------------------------------------
+func socks5Handshake(conn net.Conn, user *url.Userinfo, host string) error {
+	methods := []byte{socks5NoAuth}
+	if user != nil {
+		methods = append(methods, socks5PasswordAuth)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	switch {
+	case reply[0] != socks5Version:
+		return errors.New(`not a SOCKS5 proxy`)
+	case reply[1] == socks5PasswordAuth && user != nil:
+		if err := socks5Authenticate(conn, user); err != nil {
+			return err
+		}
+	case reply[1] == socks5NoMethods:
+		return errors.New(`user name and password required`)
+	case reply[1] != socks5NoAuth:
+		return fmt.Errorf("unsupported authentication method %d", reply[1])
+	}
+
+	hostname, portname, err := net.SplitHostPort(host)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portname, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portname)
+	}
+	request := []byte{socks5Version, socks5Connect, 0x00}
+	if ip := net.ParseIP(hostname); ip == nil {
+		if len(hostname) > 255 {
+			return fmt.Errorf("host name %q is too long", hostname)
+		}
+		request = append(append(request, socks5Domain, byte(len(hostname))), hostname...)
+	} else if ipv4 := ip.To4(); ipv4 != nil {
+		request = append(append(request, socks5IPv4), ipv4...)
+	} else {
+		request = append(append(request, socks5IPv6), ip.To16()...)
+	}
+	request = append(request, byte(port>>8), byte(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		if reason, ok := socks5Failures[header[1]]; ok {
+			return fmt.Errorf("unable to connect to %s: %s", host, reason)
+		}
+		return fmt.Errorf("unable to connect to %s: error %d", host, header[1])
+	}
+	length := 0
+	switch header[3] { // Skips the address the proxy has bound.
+	case socks5IPv4:
+		length = net.IPv4len
+	case socks5IPv6:
+		length = net.IPv6len
+	case socks5Domain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		length = int(size[0])
+	default:
+		return fmt.Errorf("unknown address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, length+2))
+
+	return err
+}
+
+// Sends the user name and password to the proxy.
+//-----------------------------------------------------------------------------
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	name := user.Username()
+	password, _ := user.Password()
+	if len(name) > 255 || len(password) > 255 {
+		return errors.New(`user name or password is too long`)
+	}
+	request := append([]byte{0x01, byte(len(name))}, name...)
+	request = append(append(request, byte(len(password))), password...)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New(`invalid user name or password`)
+	}
+
+	return nil
+}
@@ -8,138 +8,113 @@ import (
 	`sort`
 	`strconv`
 	`strings`
+	`time`
 )
 
-// Sorter gets called to sort stock quotes by one of the columns. The
-// setup is rather lengthy; there should probably be more concise way
-// that uses reflection and avoids hardcoding the column names.
+// Sorter gets called to sort stock quotes by one of the columns. Sorting is
+// stable and the stocks with equal values are always ordered by ticker, so
+// the rows don't jump around between the refreshes.
 type Sorter struct {
 	profile *Profile // Pointer to where we store sort column and order.
 }
 
-type sortable []Stock
+// Comparator compares two formatted values of the user-defined column, and
+// returns a negative number if the first one goes before the second one, a
+// positive number if it goes after, and zero if they are equal.
+type Comparator func(a, b string) int
 
-func (list sortable) Len() int      { return len(list) }
-func (list sortable) Swap(i, j int) { list[i], list[j] = list[j], list[i] }
-
-type byTickerAsc struct{ sortable }
-type byLastTradeAsc struct{ sortable }
-type byChangeAsc struct{ sortable }
-type byChangePctAsc struct{ sortable }
-type byOpenAsc struct{ sortable }
-type byLowAsc struct{ sortable }
-type byHighAsc struct{ sortable }
-type byLow52Asc struct{ sortable }
-type byHigh52Asc struct{ sortable }
-type byVolumeAsc struct{ sortable }
-type byAvgVolumeAsc struct{ sortable }
-type byPeRatioAsc struct{ sortable }
-type byDividendAsc struct{ sortable }
-type byYieldAsc struct{ sortable }
-type byMarketCapAsc struct{ sortable }
-
-type byTickerDesc struct{ sortable }
-type byLastTradeDesc struct{ sortable }
-type byChangeDesc struct{ sortable }
-type byChangePctDesc struct{ sortable }
-type byOpenDesc struct{ sortable }
-type byLowDesc struct{ sortable }
-type byHighDesc struct{ sortable }
-type byLow52Desc struct{ sortable }
-type byHigh52Desc struct{ sortable }
-type byVolumeDesc struct{ sortable }
-type byAvgVolumeDesc struct{ sortable }
-type byPeRatioDesc struct{ sortable }
-type byDividendDesc struct{ sortable }
-type byYieldDesc struct{ sortable }
-type byMarketCapDesc struct{ sortable }
-
-func (list byTickerAsc) Less(i, j int) bool {
-	return list.sortable[i].Ticker < list.sortable[j].Ticker
-}
-func (list byLastTradeAsc) Less(i, j int) bool {
-	return list.sortable[i].LastTrade < list.sortable[j].LastTrade
-}
-func (list byChangeAsc) Less(i, j int) bool {
-	return c(list.sortable[i].Change) < c(list.sortable[j].Change)
-}
-func (list byChangePctAsc) Less(i, j int) bool {
-	return c(list.sortable[i].ChangePct) < c(list.sortable[j].ChangePct)
-}
-func (list byOpenAsc) Less(i, j int) bool {
-	return list.sortable[i].Open < list.sortable[j].Open
-}
-func (list byLowAsc) Less(i, j int) bool { return list.sortable[i].Low < list.sortable[j].Low }
-func (list byHighAsc) Less(i, j int) bool {
-	return list.sortable[i].High < list.sortable[j].High
-}
-func (list byLow52Asc) Less(i, j int) bool {
-	return list.sortable[i].Low52 < list.sortable[j].Low52
-}
-func (list byHigh52Asc) Less(i, j int) bool {
-	return list.sortable[i].High52 < list.sortable[j].High52
-}
-func (list byVolumeAsc) Less(i, j int) bool {
-	return list.sortable[i].Volume < list.sortable[j].Volume
-}
-func (list byAvgVolumeAsc) Less(i, j int) bool {
-	return list.sortable[i].AvgVolume < list.sortable[j].AvgVolume
-}
-func (list byPeRatioAsc) Less(i, j int) bool {
-	return list.sortable[i].PeRatio < list.sortable[j].PeRatio
-}
-func (list byDividendAsc) Less(i, j int) bool {
-	return list.sortable[i].Dividend < list.sortable[j].Dividend
-}
-func (list byYieldAsc) Less(i, j int) bool {
-	return list.sortable[i].Yield < list.sortable[j].Yield
-}
-func (list byMarketCapAsc) Less(i, j int) bool {
-	return m(list.sortable[i].MarketCap) < m(list.sortable[j].MarketCap)
+// How to sort by each of the stock quotes columns, in the order of the
+// layout columns. The prices and the amounts compare as numbers.
+var sortColumns = []func(a, b Stock) int{
+	func(a, b Stock) int { return CompareStrings(a.Ticker, b.Ticker) },
+	func(a, b Stock) int { return a.LastTrade.compare(b.LastTrade) },
+	func(a, b Stock) int { return a.Change.compare(b.Change) },
+	func(a, b Stock) int { return a.ChangePct.compare(b.ChangePct) },
+	func(a, b Stock) int { return a.Open.compare(b.Open) },
+	func(a, b Stock) int { return a.Low.compare(b.Low) },
+	func(a, b Stock) int { return a.High.compare(b.High) },
+	func(a, b Stock) int { return a.Low52.compare(b.Low52) },
+	func(a, b Stock) int { return a.High52.compare(b.High52) },
+	func(a, b Stock) int { return a.Volume.compare(b.Volume) },
+	func(a, b Stock) int { return a.AvgVolume.compare(b.AvgVolume) },
+	func(a, b Stock) int { return a.PeRatio.compare(b.PeRatio) },
+	func(a, b Stock) int { return a.Dividend.compare(b.Dividend) },
+	func(a, b Stock) int { return a.Yield.compare(b.Yield) },
+	func(a, b Stock) int { return a.MarketCap.compare(b.MarketCap) },
+	func(a, b Stock) int { return a.PreOpen.compare(b.PreOpen) },
+	func(a, b Stock) int { return a.AfterHours.compare(b.AfterHours) },
+	func(a, b Stock) int { return a.PrePrice.compare(b.PrePrice) },
+	func(a, b Stock) int { return a.PreVolume.compare(b.PreVolume) },
+	func(a, b Stock) int { return a.PostPrice.compare(b.PostPrice) },
+	func(a, b Stock) int { return a.PostVolume.compare(b.PostVolume) },
+	func(a, b Stock) int { return CompareStrings(a.Exchange, b.Exchange) },
+	func(a, b Stock) int { return CompareStrings(a.Country, b.Country) },
+	func(a, b Stock) int { return a.ESG.compare(b.ESG) },
 }
 
-func (list byTickerDesc) Less(i, j int) bool {
-	return list.sortable[j].Ticker < list.sortable[i].Ticker
-}
-func (list byLastTradeDesc) Less(i, j int) bool {
-	return list.sortable[j].LastTrade < list.sortable[i].LastTrade
-}
-func (list byChangeDesc) Less(i, j int) bool {
-	return c(list.sortable[j].Change) < c(list.sortable[i].Change)
-}
-func (list byChangePctDesc) Less(i, j int) bool {
-	return c(list.sortable[j].ChangePct) < c(list.sortable[i].ChangePct)
-}
-func (list byOpenDesc) Less(i, j int) bool {
-	return list.sortable[j].Open < list.sortable[i].Open
-}
-func (list byLowDesc) Less(i, j int) bool { return list.sortable[j].Low < list.sortable[i].Low }
-func (list byHighDesc) Less(i, j int) bool {
-	return list.sortable[j].High < list.sortable[i].High
-}
-func (list byLow52Desc) Less(i, j int) bool {
-	return list.sortable[j].Low52 < list.sortable[i].Low52
-}
-func (list byHigh52Desc) Less(i, j int) bool {
-	return list.sortable[j].High52 < list.sortable[i].High52
-}
-func (list byVolumeDesc) Less(i, j int) bool {
-	return list.sortable[j].Volume < list.sortable[i].Volume
-}
-func (list byAvgVolumeDesc) Less(i, j int) bool {
-	return list.sortable[j].AvgVolume < list.sortable[i].AvgVolume
+// CompareStrings compares the strings regardless of case and surrounding
+// spaces, ex. tickers.
+func CompareStrings(a, b string) int {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if order := strings.Compare(strings.ToLower(a), strings.ToLower(b)); order != 0 {
+		return order
+	}
+	return strings.Compare(a, b)
 }
-func (list byPeRatioDesc) Less(i, j int) bool {
-	return list.sortable[j].PeRatio < list.sortable[i].PeRatio
+
+// CompareNumbers compares the prices and the amounts, ex. "$201.46",
+// "-1.25", "1,024", "27.3M", or "1.2T". Blank and N/A values are less
+// than any number.
+func CompareNumbers(a, b string) int {
+	x, xok := number(a)
+	y, yok := number(b)
+	switch {
+	case !xok || !yok:
+		return compareBools(xok, yok)
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
 }
-func (list byDividendDesc) Less(i, j int) bool {
-	return list.sortable[j].Dividend < list.sortable[i].Dividend
+
+// ComparePercents compares the percentages, ex. "+1.25%" or "-0.3%".
+func ComparePercents(a, b string) int {
+	return CompareNumbers(strings.Replace(a, `%`, ``, 1), strings.Replace(b, `%`, ``, 1))
 }
-func (list byYieldDesc) Less(i, j int) bool {
-	return list.sortable[j].Yield < list.sortable[i].Yield
+
+// CompareTimestamps compares the times given as seconds since epoch, ex.
+// the earnings dates, or as dates, ex. "2019-06-06". Blank and unknown
+// values are less than any time.
+func CompareTimestamps(a, b string) int {
+	x, xok := moment(a)
+	y, yok := moment(b)
+	switch {
+	case !xok || !yok:
+		return compareBools(xok, yok)
+	case x.Before(y):
+		return -1
+	case x.After(y):
+		return 1
+	}
+	return 0
 }
-func (list byMarketCapDesc) Less(i, j int) bool {
-	return m(list.sortable[j].MarketCap) < m(list.sortable[i].MarketCap)
+
+// CompareDurations compares the durations, ex. "2d 3h", "3h 25m", or
+// "1h30m". Blank and unknown values are less than any duration.
+func CompareDurations(a, b string) int {
+	x, xok := seconds(a)
+	y, yok := seconds(b)
+	switch {
+	case !xok || !yok:
+		return compareBools(xok, yok)
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
 }
 
 // Returns new Sorter struct.
@@ -149,70 +124,142 @@ func NewSorter(profile *Profile) *Sorter {
 	}
 }
 
-// SortByCurrentColumn builds a list of sort interface based on current sort
-// order, then calls sort.Sort to do the actual job.
+// SortByCurrentColumn sorts stock quotes by the column set in the profile
+// in the order set there, or by ticker if there is no such column.
 func (sorter *Sorter) SortByCurrentColumn(stocks []Stock) *Sorter {
-	var interfaces []sort.Interface
-
-	if sorter.profile.Ascending {
-		interfaces = []sort.Interface{
-			byTickerAsc{stocks},
-			byLastTradeAsc{stocks},
-			byChangeAsc{stocks},
-			byChangePctAsc{stocks},
-			byOpenAsc{stocks},
-			byLowAsc{stocks},
-			byHighAsc{stocks},
-			byLow52Asc{stocks},
-			byHigh52Asc{stocks},
-			byVolumeAsc{stocks},
-			byAvgVolumeAsc{stocks},
-			byPeRatioAsc{stocks},
-			byDividendAsc{stocks},
-			byYieldAsc{stocks},
-			byMarketCapAsc{stocks},
-		}
-	} else {
-		interfaces = []sort.Interface{
-			byTickerDesc{stocks},
-			byLastTradeDesc{stocks},
-			byChangeDesc{stocks},
-			byChangePctDesc{stocks},
-			byOpenDesc{stocks},
-			byLowDesc{stocks},
-			byHighDesc{stocks},
-			byLow52Desc{stocks},
-			byHigh52Desc{stocks},
-			byVolumeDesc{stocks},
-			byAvgVolumeDesc{stocks},
-			byPeRatioDesc{stocks},
-			byDividendDesc{stocks},
-			byYieldDesc{stocks},
-			byMarketCapDesc{stocks},
+	compare := sortColumns[0]
+	if index := sorter.profile.SortColumn; index >= 0 && index < len(sortColumns) {
+		compare = sortColumns[index]
+	}
+
+	return sorter.sort(stocks, compare)
+}
+
+// SortByComputedColumn sorts stock quotes by the values of the user-defined
+// column with the given index as per the column's type.
+func (sorter *Sorter) SortByComputedColumn(stocks []Stock, column int) *Sorter {
+	value := func(stock Stock) string {
+		if column < len(stock.Computed) {
+			return stock.Computed[column]
 		}
+		return ``
+	}
+	compare := computedColumn{}.compare
+	if column >= 0 && column < len(sorter.profile.computed) {
+		compare = sorter.profile.computed[column].compare
 	}
 
-	sort.Sort(interfaces[sorter.profile.SortColumn])
+	return sorter.sort(stocks, func(a, b Stock) int { return compare(value(a), value(b)) })
+}
+
+// Sorts the stocks as per the given comparison in the profile's order, and
+// then by ticker whatever the order is.
+//-----------------------------------------------------------------------------
+func (sorter *Sorter) sort(stocks []Stock, compare func(a, b Stock) int) *Sorter {
+	ascending := sorter.profile.Ascending
+	sort.SliceStable(stocks, func(i, j int) bool {
+		order := compare(stocks[i], stocks[j])
+		if !ascending {
+			order = -order
+		}
+		if order == 0 {
+			return CompareStrings(stocks[i].Ticker, stocks[j].Ticker) < 0
+		}
+		return order < 0
+	})
 
 	return sorter
 }
 
-// The same exact method is used to sort by $Change and Change%. In both cases
-// we sort by the value of Change% so that multiple $0.00s get sorted proferly.
-func c(str string) float32 {
-	c := "$"
-	for _, v := range currencies {
-		if strings.Contains(str,v) {
-			c = v
+// Returns -1 if only the second value is known, 1 if only the first one
+// is, and 0 if both or neither are.
+//-----------------------------------------------------------------------------
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	}
+	return 1
+}
+
+// Parses the number as it's displayed, with the currency symbol, thousands
+// separators, and the multiplier suffix if any.
+//-----------------------------------------------------------------------------
+func number(str string) (float64, bool) {
+	str = strings.NewReplacer(`$`, ``, `,`, ``, `+`, ``).Replace(strings.TrimSpace(str))
+	for _, symbol := range currencies {
+		str = strings.Replace(str, symbol, ``, 1)
+	}
+	if str == `` {
+		return 0, false
+	}
+
+	multiplier := 1.0
+	switch str[len(str)-1:] {
+	case `T`:
+		multiplier = 1000000000000.0
+	case `B`:
+		multiplier = 1000000000.0
+	case `M`:
+		multiplier = 1000000.0
+	case `K`:
+		multiplier = 1000.0
+	}
+	value, err := strconv.ParseFloat(strings.TrimRight(str, `TBMK`), 64)
+
+	return value * multiplier, err == nil
+}
+
+// Parses the duration, ex. "2d 3h" or "1h30m", into seconds.
+//-----------------------------------------------------------------------------
+func seconds(str string) (float64, bool) {
+	str = strings.Replace(strings.TrimSpace(str), ` `, ``, -1)
+	sign := 1.0
+	if strings.HasPrefix(str, `-`) {
+		sign, str = -1.0, str[1:]
+	}
+	if str == `` {
+		return 0, false
+	}
+	days := 0.0
+	if i := strings.Index(str, `d`); i > 0 {
+		count, err := strconv.ParseFloat(str[:i], 64)
+		if err != nil {
+			return 0, false
+		}
+		days, str = count, str[i+1:]
+	}
+	rest := time.Duration(0)
+	if str != `` {
+		parsed, err := time.ParseDuration(str)
+		if err != nil {
+			return 0, false
+		}
+		rest = parsed
+	}
+
+	return sign * (days*86400 + rest.Seconds()), true
+}
+
+// Parses the time given as seconds since epoch or as the date.
+//-----------------------------------------------------------------------------
+func moment(str string) (time.Time, bool) {
+	str = strings.TrimSpace(str)
+	if seconds, err := strconv.ParseInt(str, 10, 64); err == nil && seconds > 0 {
+		return time.Unix(seconds, 0), true
+	}
+	for _, layout := range []string{time.RFC3339, `2006-01-02 15:04`, `2006-01-02`} {
+		if parsed, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+			return parsed, true
 		}
 	}
-	trimmed := strings.Replace(strings.Trim(str, ` %`), c, ``, 1)
-	value, _ := strconv.ParseFloat(trimmed, 32)
-	return float32(value)
+	return time.Time{}, false
 }
 
-// When sorting by the market value we must first convert 42B etc. notations
-// to proper numeric values.
+// Converts the formatted value back to the number, ex. 42B notation of the
+// user-defined column's value.
 func m(str string) float32 {
 	if len(str) == 0 {
 		return 0
@@ -221,6 +268,8 @@ func m(str string) float32 {
 	multiplier := 1.0
 
 	switch str[len(str)-1 : len(str)] { // Check the last character.
+	case `T`:
+		multiplier = 1000000000000.0
 	case `B`:
 		multiplier = 1000000000.0
 	case `M`:
@@ -229,7 +278,7 @@ func m(str string) float32 {
 		multiplier = 1000.0
 	}
 
-	trimmed := strings.Trim(str, ` $BMK`) // Get rid of non-numeric characters.
+	trimmed := strings.Trim(str, ` $TBMK`) // Get rid of non-numeric characters.
 	value, _ := strconv.ParseFloat(trimmed, 32)
 
 	return float32(value * multiplier)
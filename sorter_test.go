@@ -0,0 +1,118 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tickersOf(stocks []Stock) []string {
+	tickers := []string{}
+	for _, stock := range stocks {
+		tickers = append(tickers, stock.Ticker)
+	}
+	return tickers
+}
+
+func TestComparators(t *testing.T) {
+	assert.Equal(t, 0, CompareStrings(` aapl`, `aapl `))
+	assert.True(t, CompareStrings(`aapl`, `IBM`) < 0)
+
+	assert.True(t, CompareNumbers(`$9.50`, `$10.25`) < 0, `not compared as strings`)
+	assert.True(t, CompareNumbers(`-$1.25`, `+$0.50`) < 0)
+	assert.True(t, CompareNumbers(`950.2B`, `1.2T`) < 0)
+	assert.True(t, CompareNumbers(`1,024`, `999`) > 0)
+	assert.True(t, CompareNumbers(`€12.30`, `¥100`) < 0)
+	assert.True(t, CompareNumbers(`N/A`, `-5`) < 0)
+	assert.True(t, CompareNumbers(`-`, `0.00`) < 0)
+	assert.Equal(t, 0, CompareNumbers(`N/A`, ``))
+
+	assert.True(t, ComparePercents(`-0.30%`, `+1.25%`) < 0)
+	assert.True(t, ComparePercents(`10.00%`, `9.00%`) > 0)
+
+	assert.True(t, CompareTimestamps(`1559851200`, `1560000000`) < 0)
+	assert.True(t, CompareTimestamps(`2019-06-06`, `2019-06-05`) > 0)
+	assert.True(t, CompareTimestamps(``, `2019-06-05`) < 0)
+
+	assert.True(t, CompareDurations(`5h 10m`, `1d`) < 0)
+	assert.True(t, CompareDurations(`1h30m`, `90m`) == 0)
+	assert.True(t, CompareDurations(`-`, `0s`) < 0)
+}
+
+func TestSortTieBreaksByTicker(t *testing.T) {
+	profile := &Profile{SortColumn: 3}
+	stocks := []Stock{
+		{Ticker: `KO`, ChangePct: numberOf(1.00)},
+		{Ticker: `IBM`, ChangePct: numberOf(1.00)},
+		{Ticker: `V`, ChangePct: numberOf(-2.00)},
+		{Ticker: `AAPL`, ChangePct: numberOf(1.00)},
+	}
+
+	NewSorter(profile).SortByCurrentColumn(stocks)
+	assert.Equal(t, []string{`AAPL`, `IBM`, `KO`, `V`}, tickersOf(stocks))
+
+	profile.Ascending = true
+	NewSorter(profile).SortByCurrentColumn(stocks)
+	assert.Equal(t, []string{`V`, `AAPL`, `IBM`, `KO`}, tickersOf(stocks), `ties are by ticker either way`)
+}
+
+func TestSortByNumbers(t *testing.T) {
+	profile := &Profile{SortColumn: 1}
+	stocks := []Stock{
+		{Ticker: `C`, LastTrade: numberOf(9.50)},
+		{Ticker: `GOOG`, LastTrade: numberOf(1120.40)},
+		{Ticker: `BTCUSDT`, LastTrade: Number{}},
+		{Ticker: `IBM`, LastTrade: numberOf(138.20)},
+	}
+
+	NewSorter(profile).SortByCurrentColumn(stocks)
+	assert.Equal(t, []string{`GOOG`, `IBM`, `C`, `BTCUSDT`}, tickersOf(stocks))
+}
+
+func TestSortByPreMarketColumns(t *testing.T) {
+	profile := &Profile{SortColumn: 15, Ascending: true}
+	stocks := []Stock{{Ticker: `AAPL`, PreOpen: numberOf(0.50)}, {Ticker: `IBM`, PreOpen: numberOf(-0.25)}}
+
+	NewSorter(profile).SortByCurrentColumn(stocks)
+	assert.Equal(t, []string{`IBM`, `AAPL`}, tickersOf(stocks))
+
+	profile.SortColumn = 99
+	NewSorter(profile).SortByCurrentColumn(stocks)
+	assert.Equal(t, []string{`AAPL`, `IBM`}, tickersOf(stocks), `unknown column sorts by ticker`)
+}
+
+func TestSortByComputedColumn(t *testing.T) {
+	profile := &Profile{Ascending: true}
+	stocks := []Stock{
+		{Ticker: `KO`, Computed: []string{`12.5`, `yes`}},
+		{Ticker: `AAPL`, Computed: []string{`3.25`, `no`}},
+		{Ticker: `IBM`, Computed: []string{`12.5`, `yes`}},
+	}
+
+	NewSorter(profile).SortByComputedColumn(stocks, 0)
+	assert.Equal(t, []string{`AAPL`, `IBM`, `KO`}, tickersOf(stocks))
+
+	NewSorter(profile).SortByComputedColumn(stocks, 1)
+	assert.Equal(t, []string{`AAPL`, `IBM`, `KO`}, tickersOf(stocks))
+}
+
+func TestSortByTypedComputedColumn(t *testing.T) {
+	profile := &Profile{Ascending: true, Columns: []string{}}
+	require.NoError(t, profile.SetColumns([]string{`held:duration = 0`, `note:string = ''`}))
+	stocks := []Stock{
+		{Ticker: `KO`, Computed: []string{`2d 3h`, `10`}},
+		{Ticker: `AAPL`, Computed: []string{`5h 10m`, `9`}},
+		{Ticker: `IBM`, Computed: []string{`1d`, `x`}},
+	}
+
+	NewSorter(profile).SortByComputedColumn(stocks, 0)
+	assert.Equal(t, []string{`AAPL`, `IBM`, `KO`}, tickersOf(stocks))
+
+	NewSorter(profile).SortByComputedColumn(stocks, 1)
+	assert.Equal(t, []string{`KO`, `AAPL`, `IBM`}, tickersOf(stocks), `strings compare as such`)
+}
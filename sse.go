@@ -0,0 +1,219 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bufio`
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`net/http`
+	`strings`
+	`sync`
+)
+
+// sse receives stock quotes from the endpoint that emits them as server-sent
+// events, ex. the company's own market data gateway. Each event carries one
+// quote or an array of quotes as JSON (see sseQuote). The quotes are kept
+// as they arrive, and Fetch returns the latest ones.
+type sse struct {
+	url       string        // Event stream URL.
+	token     string        // Sent as the bearer token, if set.
+	client    *http.Client  // HTTP client without timeout since the stream never ends.
+	mutex     sync.Mutex    // Guards the connection state.
+	connected bool          // True while the event stream is open.
+	latest    *latestQuotes // Latest quote per ticker.
+}
+
+// latestQuotes keeps the latest quote of each ticker as the quotes arrive,
+// ex. over the event stream.
+type latestQuotes struct {
+	mutex   sync.Mutex       // Guards the quotes.
+	stocks  map[string]Stock // Latest quote per ticker.
+	tickers []string         // Tickers in the order they first arrived.
+}
+
+// sseQuote is the quote event, ex.
+//
+//   data: { "ticker": "AAPL", "last": 201.46, "prevClose": 199.65, "volume": 27316739 }
+//
+// Only the ticker and the last price are required. The change is
+// calculated from the previous close unless given, and whatever else is
+// missing is reported as N/A.
+type sseQuote struct {
+	Ticker    string   `json:"ticker"`
+	Last      *float64 `json:"last"`
+	Change    *float64 `json:"change"`
+	ChangePct *float64 `json:"changePct"`
+	Open      *float64 `json:"open"`
+	High      *float64 `json:"high"`
+	Low       *float64 `json:"low"`
+	Volume    *float64 `json:"volume"`
+	PrevClose *float64 `json:"prevClose"`
+	Bid       *float64 `json:"bid"`
+	Ask       *float64 `json:"ask"`
+}
+
+// Returns new server-sent events provider for the given event stream URL
+// and the optional bearer token.
+func newSSE(url, token string) *sse {
+	return &sse{url: url, token: token, client: newClient(0), latest: newLatestQuotes()}
+}
+
+// Fetch returns the latest quotes received for the given tickers. The event
+// stream gets opened on first use and reopened if it has been closed; the
+// quotes received so far are returned in the meantime along with the error.
+func (sse *sse) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if sse.url == `` {
+		return nil, errors.New(`Event stream URL is not set in the profile`)
+	}
+
+	sse.mutex.Lock()
+	var err error
+	if !sse.connected {
+		if response, e := sse.connect(); e != nil {
+			err = e
+		} else {
+			sse.connected = true
+			go sse.listen(response)
+		}
+	}
+	sse.mutex.Unlock()
+
+	return sse.latest.pick(tickers), err
+}
+
+//-----------------------------------------------------------------------------
+func (sse *sse) connect() (*http.Response, error) {
+	request, err := http.NewRequest(`GET`, sse.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set(`Accept`, `text/event-stream`)
+	if sse.token != `` {
+		request.Header.Set(`Authorization`, `Bearer `+sse.token)
+	}
+
+	response, err := sse.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("Event stream responded with %s", response.Status)
+	}
+
+	return response, nil
+}
+
+// Reads the events until the stream is closed. The event's data lines are
+// joined together, and the events other than the default "message" or
+// "quote" are ignored, same as the comments and the unparsable data.
+//-----------------------------------------------------------------------------
+func (sse *sse) listen(response *http.Response) {
+	defer response.Body.Close()
+
+	event, data := ``, []string{}
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == ``: // Dispatch the event.
+			if len(data) > 0 && (event == `` || event == `message` || event == `quote`) {
+				sse.latest.receive([]byte(strings.Join(data, "\n")))
+			}
+			event, data = ``, data[:0]
+		case strings.HasPrefix(line, `:`): // Comment, ex. keep-alive.
+		default:
+			field, value := line, ``
+			if colon := strings.Index(line, `:`); colon >= 0 {
+				field, value = line[:colon], strings.TrimPrefix(line[colon+1:], ` `)
+			}
+			switch field {
+			case `event`:
+				event = value
+			case `data`:
+				data = append(data, value)
+			}
+		}
+	}
+
+	sse.mutex.Lock()
+	sse.connected = false // Reopened on next fetch.
+	sse.mutex.Unlock()
+}
+
+//-----------------------------------------------------------------------------
+func newLatestQuotes() *latestQuotes {
+	return &latestQuotes{stocks: make(map[string]Stock)}
+}
+
+// Keeps the quote, or quotes, carried by the JSON data. The quotes without
+// the ticker or the last price are ignored, same as unparsable data.
+//-----------------------------------------------------------------------------
+func (latest *latestQuotes) receive(data []byte) {
+	quotes := []sseQuote{}
+	if json.Unmarshal(data, &quotes) != nil {
+		quote := sseQuote{}
+		if json.Unmarshal(data, &quote) != nil {
+			return
+		}
+		quotes = append(quotes, quote)
+	}
+
+	latest.mutex.Lock()
+	defer latest.mutex.Unlock()
+	for _, quote := range quotes {
+		if quote.Ticker != `` && quote.Last != nil {
+			ticker := strings.ToUpper(quote.Ticker)
+			if _, ok := latest.stocks[ticker]; !ok {
+				latest.tickers = append(latest.tickers, ticker)
+			}
+			latest.stocks[ticker] = quote.stock()
+		}
+	}
+}
+
+// Returns the latest quotes received for the given tickers.
+//-----------------------------------------------------------------------------
+func (latest *latestQuotes) pick(tickers []string) []Stock {
+	latest.mutex.Lock()
+	defer latest.mutex.Unlock()
+
+	stocks := []Stock{}
+	for _, ticker := range tickers {
+		if stock, ok := latest.stocks[strings.ToUpper(ticker)]; ok {
+			stock.Ticker = ticker
+			stocks = append(stocks, stock)
+		}
+	}
+	return stocks
+}
+
+//-----------------------------------------------------------------------------
+func (quote sseQuote) stock() Stock {
+	stock := Stock{
+		Ticker:    strings.ToUpper(quote.Ticker),
+		LastTrade: orNA(quote.Last),
+		Open:      orNA(quote.Open),
+		Low:       orNA(quote.Low),
+		High:      orNA(quote.High),
+		Volume:    orNA(quote.Volume),
+		PrevClose: orNA(quote.PrevClose),
+		Bid:       orNA(quote.Bid),
+		Ask:       orNA(quote.Ask),
+	}
+	withChange(&stock, quote.Last, quote.PrevClose)
+	if quote.Change != nil {
+		stock.Change, stock.Advancing = numberOf(*quote.Change), *quote.Change >= 0
+	}
+	if quote.ChangePct != nil {
+		stock.ChangePct = numberOf(*quote.ChangePct)
+	}
+
+	return stock
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEQuote(t *testing.T) {
+	last, previous, change := 201.46, 199.65, 2.0
+	stock := sseQuote{Ticker: `aapl`, Last: &last, PrevClose: &previous}.stock()
+	assert.Equal(t, `AAPL`, stock.Ticker)
+	assert.Equal(t, `1.810`, stock.Change.String())
+	assert.Equal(t, `0.907`, stock.ChangePct.String())
+	assert.Equal(t, `N/A`, stock.Volume.String())
+
+	stock = sseQuote{Ticker: `AAPL`, Last: &last, Change: &change}.stock()
+	assert.Equal(t, `2.000`, stock.Change.String(), `change given by the endpoint`)
+	assert.Equal(t, `N/A`, stock.ChangePct.String())
+}
+
+func TestSSEFetch(t *testing.T) {
+	authorization, release := make(chan string, 1), make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != `/` {
+			http.NotFound(w, r)
+			return
+		}
+		authorization <- r.Header.Get(`Authorization`)
+		w.Header().Set(`Content-Type`, `text/event-stream`)
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: quote\ndata: {\"ticker\": \"AAPL\", \"last\": 201.46,\ndata:  \"prevClose\": 199.65}\n\n")
+		fmt.Fprint(w, "event: status\ndata: {\"ticker\": \"IBM\", \"last\": 1}\n\n")
+		fmt.Fprint(w, "data: [{\"ticker\": \"IBM\", \"last\": 139.2}, {\"ticker\": \"KO\"}]\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	provider := newSSE(server.URL, `secret`)
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `IBM`, `KO`})
+	require.NoError(t, err)
+	assert.Empty(t, stocks, `nothing received yet`)
+	assert.Equal(t, `Bearer secret`, <-authorization)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		stocks, err = provider.Fetch(context.Background(), []string{`AAPL`, `IBM`, `KO`})
+		return len(stocks) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, `201.460`, stocks[0].LastTrade.String())
+	assert.Equal(t, `1.810`, stocks[0].Change.String())
+	assert.Equal(t, `139.200`, stocks[1].LastTrade.String(), `only quote events count`)
+
+	_, err = newSSE(``, ``).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+	_, err = newSSE(server.URL+`/nope`, ``).Fetch(context.Background(), []string{`AAPL`})
+	assert.Error(t, err)
+}
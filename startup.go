@@ -0,0 +1,99 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`strings`
+)
+
+// Filter variables that go by different names than the columns they are
+// displayed in, so the startup script could sort by either.
+var sortAliases = map[string]string{
+	`last`:          `LastTrade`,
+	`changePercent`: `ChangePct`,
+	`pe`:            `PeRatio`,
+	`mktCap`:        `MarketCap`,
+}
+
+// RunStartup executes the commands of the profile's startup script in
+// order, so mop opens in the preferred state no matter how it was left, ex.
+//
+//   "Startup": [ "list tech", "filter changePercent > 0", "sort changePercent desc" ]
+//
+// The commands are "list" followed by the watchlist name (none for all the
+// tickers), "account" followed by the account name (none for all the
+// accounts), "filter" followed by the expression (none to clear it), "sort"
+// followed by the column and optional "asc" or "desc", and "set" followed
+// by the setting and its value, same as `mop config set`. The command that
+// fails doesn't stop the rest, and the first error is returned.
+func (profile *Profile) RunStartup() (err error) {
+	for _, command := range profile.Startup {
+		if e := profile.run(command); e != nil && err == nil {
+			err = fmt.Errorf("Startup command `%s`: %s", command, e)
+		}
+	}
+
+	return
+}
+
+// Executes the command of the startup script.
+//-----------------------------------------------------------------------------
+func (profile *Profile) run(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	verb, args := strings.ToLower(fields[0]), fields[1:]
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), fields[0]))
+
+	switch verb {
+	case `list`:
+		return profile.SelectList(rest)
+	case `account`:
+		return profile.SelectAccount(rest)
+	case `filter`:
+		return profile.SetFilter(rest)
+	case `sort`:
+		if len(args) == 0 || len(args) > 2 {
+			return fmt.Errorf("Expected the column and optional asc or desc")
+		}
+		ascending := true
+		if len(args) == 2 {
+			switch strings.ToLower(args[1]) {
+			case `asc`:
+			case `desc`:
+				ascending = false
+			default:
+				return fmt.Errorf("Expected asc or desc, got `%s`", args[1])
+			}
+		}
+		return profile.SortBy(args[0], ascending)
+	case `set`:
+		if len(args) < 2 {
+			return fmt.Errorf("Expected the setting and its value")
+		}
+		return profile.Set(args[0], strings.TrimSpace(strings.TrimPrefix(rest, args[0])))
+	}
+
+	return fmt.Errorf("Unknown command `%s`", fields[0])
+}
+
+// SortBy sorts the stock quotes by the column with the given name, title,
+// or filter variable, ex. "ChangePct", "Change%", or "changePercent", and
+// saves the profile.
+func (profile *Profile) SortBy(column string, ascending bool) error {
+	if alias, ok := sortAliases[column]; ok {
+		column = alias
+	}
+	for i, candidate := range NewLayout().columnsFor(profile) {
+		if (candidate.name != `` && strings.EqualFold(candidate.name, column)) || strings.EqualFold(candidate.title, column) || strings.EqualFold(candidate.brief, column) {
+			profile.SortColumn, profile.Ascending = i, ascending
+			return profile.Save()
+		}
+	}
+
+	return fmt.Errorf("Unknown column `%s`", column)
+}
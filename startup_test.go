@@ -0,0 +1,57 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStartup(t *testing.T) {
+	profile := &Profile{
+		filename:   filepath.Join(t.TempDir(), `.moprc`),
+		Tickers:    []string{`AAPL`, `BTCUSDT`, `IBM`, `MSFT`},
+		Watchlists: map[string]Watchlist{`tech`: {Tickers: []string{`AAPL`, `IBM`, `MSFT`}}},
+		Startup:    []string{`list tech`, `filter changePercent > -2`, `sort changePercent desc`, `set QuotesRefresh 30`},
+	}
+	require.NoError(t, profile.RunStartup())
+	assert.Equal(t, `tech`, profile.List)
+	assert.Equal(t, `changePercent > -2`, profile.Filter)
+	assert.Equal(t, 3, profile.SortColumn)
+	assert.False(t, profile.Ascending)
+	assert.Equal(t, 30, profile.QuotesRefresh)
+
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: numberOf(200), ChangePct: numberOf(1)},
+		{Ticker: `BTCUSDT`, LastTrade: numberOf(9000), ChangePct: numberOf(5)},
+		{Ticker: `IBM`, LastTrade: numberOf(140), ChangePct: numberOf(-3)},
+		{Ticker: `MSFT`, LastTrade: numberOf(130), ChangePct: numberOf(2)},
+	}}
+	stocks, err := NewLayout().prettify(quotes)
+	require.NoError(t, err)
+	tickers := []string{}
+	for _, stock := range stocks {
+		tickers = append(tickers, strings.TrimSpace(stock.Ticker))
+	}
+	assert.Equal(t, []string{`MSFT`, `AAPL`}, tickers, `listed, filtered, and sorted`)
+
+	require.NoError(t, profile.SortBy(`Change%`, true))
+	assert.Equal(t, 3, profile.SortColumn, `by the title`)
+	require.NoError(t, profile.SortBy(`lastTrade`, true))
+	assert.Equal(t, 1, profile.SortColumn, `by the name`)
+
+	profile.Startup = []string{`list crypto`, `sort Bogus`, `sort Ticker up`, `fly away`, `list`}
+	err = profile.RunStartup()
+	require.Error(t, err)
+	assert.Equal(t, "Startup command `list crypto`: No \"crypto\" watchlist in the profile", err.Error(), `the first error`)
+	assert.Equal(t, ``, profile.List, `the rest are executed`)
+	for _, command := range profile.Startup[1:4] {
+		assert.Error(t, profile.run(command), command)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/json`
+	`io/ioutil`
+)
+
+// state is the runtime-only UI state that is not part of the settings but
+// is kept between the sessions so restarting mop returns exactly where the
+// user left off. It gets saved next to the profile, ex. ~/.moprc.state.
+type state struct {
+	Paused      bool // True when screen updates are paused.
+	SelectedRow int  // Row the cursor was at when bulk edit mode was last used.
+}
+
+// RestoreState loads the UI state saved by the previous session, if any,
+// and returns true if screen updates were paused.
+func (profile *Profile) RestoreState() (paused bool) {
+	saved := state{}
+	if data, err := ioutil.ReadFile(profile.stateFile()); err == nil {
+		json.Unmarshal(data, &saved)
+	}
+	profile.selectedRow = saved.SelectedRow
+
+	return saved.Paused
+}
+
+// SaveState saves the UI state when mop exits.
+func (profile *Profile) SaveState(paused bool) error {
+	data, err := json.Marshal(state{Paused: paused, SelectedRow: profile.selectedRow})
+	if err != nil {
+		return err
+	}
+
+	return writeAtomically(profile.stateFile(), data)
+}
+
+//-----------------------------------------------------------------------------
+func (profile *Profile) stateFile() string {
+	return profile.filename + `.state`
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `mop`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	profile := &Profile{filename: filepath.Join(dir, `.moprc`)}
+	assert.False(t, profile.RestoreState(), `no state saved yet`)
+
+	profile.selectedRow = 3
+	require.NoError(t, profile.SaveState(true))
+
+	restored := &Profile{filename: profile.filename}
+	assert.True(t, restored.RestoreState())
+	assert.Equal(t, 3, restored.selectedRow)
+}
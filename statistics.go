@@ -0,0 +1,80 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`math`
+	`sort`
+	`strings`
+)
+
+// Statistics summarizes the watchlist based on the current stock quotes.
+type Statistics struct {
+	Count          int        // Number of stocks in the watchlist.
+	Advancing      int        // Number of stocks with positive change.
+	Declining      int        // Number of stocks with negative change.
+	Unchanged      int        // Number of stocks with zero change.
+	AveragePE      float64    // Average P/E ratio of the stocks that have one.
+	MedianChange   float64    // Median change, in percent.
+	TotalMarketCap float64    // Sum of market caps.
+	BiggestMover   string     // Ticker of the stock with the largest absolute change percent.
+	BiggestMove    float64    // Change percent of the biggest mover.
+	Portfolio      *Portfolio // Portfolio value and returns, nil without holdings.
+}
+
+// NewStatistics calculates the watchlist statistics from the stock quotes
+// fetched last.
+func NewStatistics(quotes *Quotes) *Statistics {
+	stats := &Statistics{Count: len(quotes.stocks)}
+	changes, earnings := []float64{}, 0
+
+	for _, stock := range quotes.stocks {
+		values := variables(stock, quotes.profile)
+		change := values[`changePercent`].(float64)
+		switch {
+		case values[`change`].(float64) > 0:
+			stats.Advancing++
+		case values[`change`].(float64) < 0:
+			stats.Declining++
+		default:
+			stats.Unchanged++
+		}
+		if pe := values[`pe`].(float64); pe > 0 {
+			stats.AveragePE += pe
+			earnings++
+		}
+		if stats.BiggestMover == `` || math.Abs(change) > math.Abs(stats.BiggestMove) {
+			stats.BiggestMover, stats.BiggestMove = strings.TrimSpace(stock.Ticker), change
+		}
+		stats.TotalMarketCap += values[`mktCap`].(float64)
+		changes = append(changes, change)
+	}
+
+	if earnings > 0 {
+		stats.AveragePE /= float64(earnings)
+	}
+	if quotes.profile.hasHoldings() {
+		stats.Portfolio = NewPortfolio(quotes)
+	}
+	if len(changes) > 0 {
+		sort.Float64s(changes)
+		if middle := len(changes) / 2; len(changes)%2 == 1 {
+			stats.MedianChange = changes[middle]
+		} else {
+			stats.MedianChange = (changes[middle-1] + changes[middle]) / 2
+		}
+	}
+
+	return stats
+}
+
+// Breadth returns the share of advancing stocks among the stocks that
+// have changed, in percent.
+func (stats *Statistics) Breadth() float64 {
+	if changed := stats.Advancing + stats.Declining; changed > 0 {
+		return float64(stats.Advancing) * 100 / float64(changed)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatistics(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{}, stocks: []Stock{
+		{Ticker: `AAPL`, Change: numberOf(2.50), ChangePct: numberOf(1.20), PeRatio: numberOf(20.00), MarketCap: numberOf(1.5e12)},
+		{Ticker: `IBM`, Change: numberOf(-1.00), ChangePct: numberOf(-0.80), PeRatio: unavailable, MarketCap: numberOf(120e9)},
+		{Ticker: `KO`, Change: numberOf(0.00), ChangePct: numberOf(0.00), PeRatio: numberOf(30.00), MarketCap: numberOf(200e9)},
+		{Ticker: `TSLA`, Change: numberOf(-12.00), ChangePct: numberOf(-4.10), PeRatio: unavailable, MarketCap: numberOf(80e9)},
+	}}
+
+	stats := NewStatistics(quotes)
+	assert.Equal(t, 4, stats.Count)
+	assert.Equal(t, 1, stats.Advancing)
+	assert.Equal(t, 2, stats.Declining)
+	assert.Equal(t, 1, stats.Unchanged)
+	assert.InDelta(t, 33.33, stats.Breadth(), 0.01)
+	assert.InDelta(t, 25.0, stats.AveragePE, 0.01)
+	assert.InDelta(t, -0.4, stats.MedianChange, 0.01)
+	assert.InDelta(t, 1.9e12, stats.TotalMarketCap, 1e9)
+	assert.Equal(t, `TSLA`, stats.BiggestMover)
+	assert.InDelta(t, -4.1, stats.BiggestMove, 0.01)
+}
+
+func TestDistribution(t *testing.T) {
+	quotes := &Quotes{profile: &Profile{}, stocks: []Stock{
+		{ChangePct: numberOf(-4.10)}, {ChangePct: numberOf(-0.80)}, {ChangePct: numberOf(0.00)}, {ChangePct: numberOf(0.45)}, {ChangePct: numberOf(1.20)}, {ChangePct: numberOf(3.00)},
+	}}
+
+	assert.Equal(t, []int{1, 0, 0, 1, 2, 1, 0, 1}, distribution(quotes))
+	assert.Equal(t, `<-3`, binLabel(0))
+	assert.Equal(t, `+0`, binLabel(4))
+	assert.Equal(t, `>3`, binLabel(7))
+}
+
+func TestGeographicAllocation(t *testing.T) {
+	profile := &Profile{Holdings: map[string]Holding{`AAPL`: {Shares: 10}, `SAP`: {Shares: 10}, `TSM`: {Shares: 20}, `XYZ`: {Shares: 10}}}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		{Ticker: `AAPL`, Country: `United States`, LastTrade: numberOf(200)},
+		{Ticker: `SAP`, Country: `Germany`, LastTrade: numberOf(100)},
+		{Ticker: `TSM`, Country: `Taiwan`, LastTrade: numberOf(50)},
+		{Ticker: `XYZ`, LastTrade: numberOf(100)},
+		{Ticker: `IBM`, Country: `United States`, LastTrade: numberOf(140)},
+	}}
+
+	portfolio := NewPortfolio(quotes)
+	assert.Equal(t, []Weight{{`United States`, 40}, {`Germany`, 20}, {`Taiwan`, 20}, {`Unknown`, 20}}, portfolio.Countries)
+
+	stats := NewLayout().Statistics(NewStatistics(quotes))
+	assert.Contains(t, stats, "<u>Geographic allocation</u>\n\n  United States      40.0%\n  Germany            20.0%\n")
+}
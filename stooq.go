@@ -0,0 +1,127 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bytes`
+	`context`
+	`encoding/csv`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/url`
+	`strconv`
+	`strings`
+)
+
+// Symbol, date, time, open, high, low, close, volume, and previous close.
+const stooqURL = `https://stooq.com/q/l/?s=%s&f=sd2t2ohlcvp&e=csv`
+
+// stooq fetches basic stock quotes (prices, change, and volume) from Stooq,
+// which requires no API key. It's used as a fallback when Yahoo is not
+// available.
+type stooq struct {
+	url string // Quotes URL, with placeholder for the symbols.
+}
+
+// Returns new Stooq provider.
+func newStooq() *stooq {
+	return &stooq{url: stooqURL}
+}
+
+// Fetch requests the quotes in batches of 50 tickers.
+func (stooq *stooq) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	stocks := []Stock{}
+	for _, batch := range batches(tickers, maxTickersPerRequest) {
+		symbols, keys := make(map[string]string), []string{} // Stooq symbol => ticker.
+		for _, ticker := range batch {
+			symbols[stooqSymbol(ticker)] = ticker
+			keys = append(keys, stooqSymbol(ticker))
+		}
+
+		response, err := get(ctx, fmt.Sprintf(stooq.url, url.QueryEscape(strings.Join(keys, `,`))))
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Stooq responded with %s", response.Status)
+		}
+
+		parsed, err := parseStooq(body, symbols)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, parsed...)
+	}
+
+	return stocks, unknownTickers(tickers, stocks)
+}
+
+// Stooq lists U.S. stocks with the .US suffix, ex. AAPL => aapl.us, while
+// the indices and foreign stocks keep their symbols, ex. ^DJI or VOD.UK.
+//-----------------------------------------------------------------------------
+func stooqSymbol(ticker string) string {
+	if strings.ContainsAny(ticker, `.^=`) {
+		return strings.ToLower(ticker)
+	}
+	return strings.ToLower(ticker) + `.us`
+}
+
+// Parses Stooq CSV quotes, ex.
+//
+//   AAPL.US,2019-06-28,22:00:07,198.68,199.495,197.05,197.92,31110642,199.8
+//
+// Missing values come as N/D, and unknown symbols have N/D for everything
+// but the symbol.
+//-----------------------------------------------------------------------------
+func parseStooq(body []byte, symbols map[string]string) ([]Stock, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	stocks := []Stock{}
+	for _, record := range records {
+		if len(record) < 9 {
+			continue
+		}
+		ticker, ok := symbols[strings.ToLower(record[0])]
+		if !ok || record[6] == `N/D` { // Header or unknown symbol.
+			continue
+		}
+		number := func(str string) Number {
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return unavailable
+			}
+			return numberOf(value)
+		}
+		stock := Stock{
+			Ticker:    ticker,
+			LastTrade: number(record[6]),
+			Open:      number(record[3]),
+			Low:       number(record[5]),
+			High:      number(record[4]),
+			Volume:    number(record[7]),
+			PrevClose: number(record[8]),
+		}
+		last, _ := strconv.ParseFloat(record[6], 64)
+		var previous *float64
+		if value, err := strconv.ParseFloat(record[8], 64); err == nil {
+			previous = &value
+		}
+		withChange(&stock, &last, previous)
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stooqQuotes = `Symbol,Date,Time,Open,High,Low,Close,Volume,Prev
+AAPL.US,2019-06-28,22:00:07,198.68,199.495,197.05,197.92,31110642,199.8
+^DJI,2019-06-28,22:00:07,26539.4,26620.5,26482.4,26599.96,N/D,26526.58
+NOPE.US,N/D,N/D,N/D,N/D,N/D,N/D,N/D,N/D
+`
+
+func TestParseStooq(t *testing.T) {
+	stocks, err := parseStooq([]byte(stooqQuotes), map[string]string{`aapl.us`: `AAPL`, `^dji`: `^DJI`, `nope.us`: `NOPE`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 2)
+
+	aapl := stocks[0]
+	assert.Equal(t, `AAPL`, aapl.Ticker)
+	assert.Equal(t, `197.920`, aapl.LastTrade.String())
+	assert.Equal(t, `-1.880`, aapl.Change.String())
+	assert.Equal(t, `-0.941`, aapl.ChangePct.String())
+	assert.Equal(t, `31.111M`, aapl.Volume.String())
+	assert.Equal(t, `N/A`, aapl.PeRatio.String())
+	assert.False(t, aapl.Advancing)
+
+	assert.Equal(t, `^DJI`, stocks[1].Ticker)
+	assert.Equal(t, `N/A`, stocks[1].Volume.String())
+	assert.True(t, stocks[1].Advancing)
+
+	assert.Equal(t, `aapl.us`, stooqSymbol(`AAPL`))
+	assert.Equal(t, `^dji`, stooqSymbol(`^DJI`))
+	assert.Equal(t, `vod.uk`, stooqSymbol(`VOD.UK`))
+}
+
+func TestStooqFetch(t *testing.T) {
+	var symbols string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbols = r.URL.Query().Get(`s`)
+		fmt.Fprint(w, stooqQuotes)
+	}))
+	defer server.Close()
+
+	provider := newStooq()
+	provider.url = server.URL + `?s=%s`
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `^DJI`})
+	require.NoError(t, err)
+	assert.Len(t, stocks, 2)
+	assert.Equal(t, `aapl.us,^dji`, symbols)
+}
+
+func TestFallback(t *testing.T) {
+	primary := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(200.000)}}}
+	secondary := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(199.000)}}}
+	provider := &fallback{primary: primary, secondary: secondary}
+
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, `200.000`, stocks[0].LastTrade.String())
+	assert.Empty(t, secondary.requested)
+
+	primary.err = errors.New(`Yahoo is down`)
+	stocks, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, `199.000`, stocks[0].LastTrade.String())
+
+	secondary.err = errors.New(`Stooq is down too`)
+	_, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	assert.EqualError(t, err, `Yahoo is down`)
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`net`
+	`net/http`
+	`strconv`
+	`strings`
+	`sync`
+	`time`
+)
+
+// How long to hold off the requests after the first 429 or 5xx response;
+// the wait doubles with every failure that follows, up to the maximum.
+const (
+	backoffBase = 10 * time.Second
+	backoffMax  = 10 * time.Minute
+)
+
+// Hosts of the services mop talks to, by provider name. The hosts of the
+// same provider share its budget.
+var providerHosts = map[string][]string{
+	`alphavantage`: {`www.alphavantage.co`},
+	`binance`:      {`api.binance.com`},
+	`cnn`:          {`money.cnn.com`},
+	`finnhub`:      {`finnhub.io`},
+	`github`:       {`api.github.com`},
+	`iex`:          {`cloud.iexapis.com`},
+	`polygon`:      {`api.polygon.io`},
+	`stooq`:        {`stooq.com`},
+	`tiingo`:       {`api.tiingo.com`},
+	`yahoo`:        {`fc.yahoo.com`, `query1.finance.yahoo.com`, `query2.finance.yahoo.com`},
+}
+
+// Maximum number of requests per minute by provider unless the profile
+// says otherwise. The providers that are not listed aren't limited.
+var defaultBudgets = map[string]int{
+	`alphavantage`: 5,
+	`binance`:      600,
+	`cnn`:          30,
+	`finnhub`:      60,
+	`iex`:          100,
+	`polygon`:      5,
+	`stooq`:        60,
+	`tiingo`:       50,
+	`yahoo`:        120,
+}
+
+// throttle is the transport all the outgoing requests go through. It keeps
+// the requests to each provider within the budget, and holds them off with
+// exponential backoff after the provider responds with 429 Too Many
+// Requests or 5xx, so large watchlists don't get the user's IP banned. The
+// requests that would exceed the budget, or are made while holding off,
+// fail right away without reaching the network: the fetches are made by
+// the loop that handles the keys, which must not wait for the budget to
+// refill.
+type throttle struct {
+	next    http.RoundTripper        // Transport that makes the requests.
+	mutex   sync.Mutex               // Guards the state below.
+	budgets map[string]int           // Budgets set in the profile, by provider or host name.
+	states  map[string]*backoffState // Requests made and failures, by provider or host name.
+	now     func() time.Time         // Returns current time, replaced in tests.
+}
+
+// backoffState keeps track of the requests made to the provider.
+type backoffState struct {
+	limiter  rateLimiter // Requests made within the last minute.
+	failures int         // Number of 429 or 5xx responses in a row.
+	until    time.Time   // Requests are held off until then.
+}
+
+// throttled is the error of the request the throttle has not let through.
+type throttled struct {
+	error
+}
+
+// The throttle wraps the transport of the client all the providers use.
+var outgoing = newThrottle(transport)
+
+//-----------------------------------------------------------------------------
+func newThrottle(next http.RoundTripper) *throttle {
+	return &throttle{next: next, budgets: map[string]int{}, states: map[string]*backoffState{}, now: time.Now}
+}
+
+// RoundTrip makes the request unless it's over the provider's budget or the
+// provider is being held off.
+func (throttle *throttle) RoundTrip(request *http.Request) (*http.Response, error) {
+	name := throttle.nameOf(request.URL.Hostname())
+	if name == `` {
+		return throttle.next.RoundTrip(request)
+	}
+	if err := throttle.admit(name); err != nil {
+		return nil, err
+	}
+
+	response, err := throttle.next.RoundTrip(request)
+	if err == nil {
+		throttle.record(name, response)
+	}
+
+	return response, err
+}
+
+// Sets the budgets, requests per minute by provider or host name, that take
+// precedence over the defaults, ex. {"yahoo": 60}.
+//-----------------------------------------------------------------------------
+func (throttle *throttle) setBudgets(budgets map[string]int) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	throttle.budgets = budgets
+}
+
+// Returns the name of the provider the host belongs to, the host itself if
+// it's unknown, or blank for the local hosts that are never throttled.
+//-----------------------------------------------------------------------------
+func (throttle *throttle) nameOf(host string) string {
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); host == `localhost` || (ip != nil && ip.IsLoopback()) {
+		return ``
+	}
+	for name, hosts := range providerHosts {
+		for _, known := range hosts {
+			if host == known {
+				return name
+			}
+		}
+	}
+
+	return host
+}
+
+// Returns an error if the request to the provider must not be made right
+// now, or records it otherwise. The error over the budget says when the
+// budget refills.
+//-----------------------------------------------------------------------------
+func (throttle *throttle) admit(name string) error {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	state := throttle.state(name)
+	if now := throttle.now(); now.Before(state.until) {
+		return &throttled{fmt.Errorf("Holding off %s requests for %s after too many failures", name, state.until.Sub(now).Round(time.Second))}
+	}
+	if wait := state.limiter.refill(); state.limiter.limit > 0 && wait > 0 {
+		return &throttled{fmt.Errorf("Reached %d requests per minute budget for %s, next request in %s", state.limiter.limit, name, wait.Round(time.Second))}
+	}
+	state.limiter.record()
+
+	return nil
+}
+
+// Backs off after 429 or 5xx response, or resets the backoff otherwise.
+//-----------------------------------------------------------------------------
+func (throttle *throttle) record(name string, response *http.Response) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	state := throttle.state(name)
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode < 500 {
+		state.failures = 0
+		return
+	}
+
+	wait := backoffMax
+	if state.failures < 16 {
+		wait = backoffBase << uint(state.failures)
+	}
+	if retry, ok := retryAfter(response.Header.Get(`Retry-After`), throttle.now()); ok && retry > wait {
+		wait = retry
+	}
+	if wait > backoffMax {
+		wait = backoffMax
+	}
+	state.failures++
+	state.until = throttle.now().Add(wait)
+}
+
+// Returns the state of the provider, with its budget as per the profile or
+// the defaults. Must be called with the mutex held.
+//-----------------------------------------------------------------------------
+func (throttle *throttle) state(name string) *backoffState {
+	state, ok := throttle.states[name]
+	if !ok {
+		state = &backoffState{limiter: newRateLimiter(0)}
+		state.limiter.now = func() time.Time { return throttle.now() }
+		throttle.states[name] = state
+	}
+	state.limiter.limit = defaultBudgets[name]
+	if budget, ok := throttle.budgets[name]; ok {
+		state.limiter.limit = budget
+	}
+
+	return state
+}
+
+// Parses Retry-After header: the number of seconds to wait, or the time to
+// wait until.
+//-----------------------------------------------------------------------------
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header = strings.TrimSpace(header); header == `` {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return at.Sub(now), true
+	}
+
+	return 0, false
+}
+
+// Returns the request budgets set in the profile: the provider's RateLimit
+// and the Budgets by provider or host name.
+//-----------------------------------------------------------------------------
+func (profile *Profile) budgets() map[string]int {
+	budgets := map[string]int{}
+	if profile.RateLimit > 0 {
+		name := profile.Provider
+		if profile.source != `` {
+			name = profile.source
+		}
+		if name == `` {
+			name = `yahoo`
+		}
+		budgets[name] = profile.RateLimit
+	}
+	for name, budget := range profile.Budgets {
+		budgets[name] = budget
+	}
+
+	return budgets
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripper responds with the given status, and counts the requests.
+type roundTripper struct {
+	status   int
+	header   http.Header
+	requests int
+}
+
+func (fake *roundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	fake.requests++
+	recorder := httptest.NewRecorder()
+	for name, values := range fake.header {
+		recorder.Header()[name] = values
+	}
+	recorder.WriteHeader(fake.status)
+	return recorder.Result(), nil
+}
+
+func testThrottle(status int) (*throttle, *roundTripper, *time.Time) {
+	now, fake := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC), &roundTripper{status: status}
+	throttle := newThrottle(fake)
+	throttle.now = func() time.Time { return now }
+	return throttle, fake, &now
+}
+
+func TestThrottleBudget(t *testing.T) {
+	throttle, fake, now := testThrottle(http.StatusOK)
+	throttle.setBudgets(map[string]int{`yahoo`: 2})
+	client := &http.Client{Transport: throttle}
+
+	for _, url := range []string{`https://query1.finance.yahoo.com/v7`, `https://query2.finance.yahoo.com/v10`} {
+		_, err := client.Get(url)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, fake.requests, `hosts share the provider's budget`)
+
+	_, err := client.Get(`https://stooq.com/q/l/`)
+	assert.NoError(t, err, `other providers have their own budgets`)
+
+	*now = now.Add(20 * time.Second)
+	_, err = client.Get(`https://query1.finance.yahoo.com/v7`)
+	assert.ErrorContains(t, err, `Reached 2 requests per minute budget for yahoo, next request in 40s`, `fails without waiting`)
+	assert.Equal(t, 3, fake.requests)
+
+	*now = now.Add(40 * time.Second)
+	_, err = client.Get(`https://query1.finance.yahoo.com/v7`)
+	assert.NoError(t, err, `the budget is refilled now`)
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	throttle, fake, now := testThrottle(http.StatusTooManyRequests)
+	client := &http.Client{Transport: throttle}
+
+	response, err := client.Get(`https://finnhub.io/api/v1/quote`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+
+	_, err = client.Get(`https://finnhub.io/api/v1/quote`)
+	assert.ErrorContains(t, err, `Holding off finnhub requests for 10s`)
+	assert.Equal(t, 1, fake.requests)
+
+	*now = now.Add(backoffBase)
+	fake.status = http.StatusBadGateway
+	_, err = client.Get(`https://finnhub.io/api/v1/quote`)
+	require.NoError(t, err)
+	_, err = client.Get(`https://finnhub.io/api/v1/quote`)
+	assert.ErrorContains(t, err, `for 20s`, `the wait doubles`)
+
+	*now = now.Add(2 * backoffBase)
+	fake.status = http.StatusOK
+	_, err = client.Get(`https://finnhub.io/api/v1/quote`)
+	require.NoError(t, err)
+	fake.status = http.StatusServiceUnavailable
+	client.Get(`https://finnhub.io/api/v1/quote`)
+	_, err = client.Get(`https://finnhub.io/api/v1/quote`)
+	assert.ErrorContains(t, err, `for 10s`, `success resets the backoff`)
+}
+
+func TestThrottleRetryAfter(t *testing.T) {
+	throttle, fake, _ := testThrottle(http.StatusServiceUnavailable)
+	fake.header = http.Header{`Retry-After`: {`120`}}
+	client := &http.Client{Transport: throttle}
+
+	client.Get(`https://api.polygon.io/v2/snapshot`)
+	_, err := client.Get(`https://api.polygon.io/v2/snapshot`)
+	assert.ErrorContains(t, err, `for 2m0s`)
+
+	fake.header = http.Header{`Retry-After`: {`3600`}}
+	client.Get(`https://api.tiingo.com/iex`)
+	_, err = client.Get(`https://api.tiingo.com/iex`)
+	assert.ErrorContains(t, err, `for 10m0s`, `never longer than the maximum`)
+}
+
+func TestThrottleSkipsLocalHosts(t *testing.T) {
+	throttle, fake, _ := testThrottle(http.StatusInternalServerError)
+	client := &http.Client{Transport: throttle}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(`http://127.0.0.1:8080/events`)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, fake.requests)
+}
+
+func TestProfileBudgets(t *testing.T) {
+	assert.Empty(t, (&Profile{}).budgets())
+	assert.Equal(t, map[string]int{`yahoo`: 30}, (&Profile{RateLimit: 30}).budgets())
+
+	profile := &Profile{Provider: `alphavantage`, RateLimit: 75, Budgets: map[string]int{`yahoo`: 60, `alphavantage`: 30}}
+	assert.Equal(t, map[string]int{`yahoo`: 60, `alphavantage`: 30}, profile.budgets())
+	assert.Equal(t, map[string]int{`yahoo`: 60, `alphavantage`: 30, `finnhub`: 75}, profile.UseSource(`finnhub`).budgets())
+}
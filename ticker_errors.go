@@ -0,0 +1,91 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`errors`
+	`sort`
+	`strings`
+)
+
+// What the providers say about the ticker they know nothing about, ex.
+// delisted or mistyped.
+const unknownSymbol = `unknown symbol`
+
+// tickerErrors is the error the provider returns along with the quotes of
+// the tickers it has fetched when some of the tickers have failed. Unlike
+// the other errors it doesn't fail the whole fetch: the failed tickers get
+// flagged on the screen while the rest of the quotes are displayed.
+type tickerErrors map[string]string // Why the ticker has failed, by ticker in upper case.
+
+// Error lists the failed tickers, ex. "Unable to fetch XYZ: unknown symbol".
+func (failed tickerErrors) Error() string {
+	tickers := []string{}
+	for ticker := range failed {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+	for i, ticker := range tickers {
+		tickers[i] = ticker + `: ` + failed[ticker]
+	}
+
+	return `Unable to fetch ` + strings.Join(tickers, `, `)
+}
+
+// Returns the ticker errors the error is, or wraps, nil if it's not one.
+//-----------------------------------------------------------------------------
+func tickerErrorsOf(err error) tickerErrors {
+	var failed tickerErrors
+	if errors.As(err, &failed) {
+		return failed
+	}
+	return nil
+}
+
+// Returns the ticker errors of the tickers the provider has not returned
+// the quotes for, nil if it has returned them all. Only the providers that
+// return all the quotes they know about, ex. not limited by the rate
+// limit, can tell the ticker is unknown this way.
+//-----------------------------------------------------------------------------
+func unknownTickers(tickers []string, stocks []Stock) error {
+	fetched := map[string]bool{}
+	for _, stock := range stocks {
+		fetched[strings.ToUpper(stock.Ticker)] = true
+	}
+	failed := tickerErrors{}
+	for _, ticker := range unique(tickers) {
+		if !fetched[ticker] {
+			failed[ticker] = unknownSymbol
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return failed
+}
+
+// Combines the errors of the providers that fetch different tickers: the
+// ticker errors are merged, and the other errors take precedence over them
+// since they fail the whole fetch, the first one over the second.
+//-----------------------------------------------------------------------------
+func joinErrors(err, other error) error {
+	switch failed, more := tickerErrorsOf(err), tickerErrorsOf(other); {
+	case err == nil:
+		return other
+	case other == nil || failed == nil:
+		return err
+	case more == nil:
+		return other
+	default:
+		merged := tickerErrors{}
+		for _, errs := range []tickerErrors{failed, more} {
+			for ticker, message := range errs {
+				merged[ticker] = message
+			}
+		}
+		return merged
+	}
+}
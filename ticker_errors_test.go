@@ -0,0 +1,68 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withUnknown reports the tickers the provider has not returned as the
+// unknown ones, the way Yahoo does.
+type withUnknown struct {
+	Provider
+}
+
+func (provider *withUnknown) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	stocks, err := provider.Provider.Fetch(ctx, tickers)
+	if err != nil {
+		return nil, err
+	}
+	return stocks, unknownTickers(tickers, stocks)
+}
+
+func TestUnknownTickers(t *testing.T) {
+	stocks := []Stock{{Ticker: `AAPL`}, {Ticker: `ibm`}}
+	assert.NoError(t, unknownTickers([]string{`aapl`, `IBM`}, stocks))
+
+	err := unknownTickers([]string{`AAPL`, `XYZZ`, `IBM`, `QQQQ`}, stocks)
+	assert.Equal(t, tickerErrors{`XYZZ`: unknownSymbol, `QQQQ`: unknownSymbol}, tickerErrorsOf(err))
+	assert.EqualError(t, err, `Unable to fetch QQQQ: unknown symbol, XYZZ: unknown symbol`)
+}
+
+func TestTickerErrorsOf(t *testing.T) {
+	failed := tickerErrors{`XYZZ`: unknownSymbol}
+	assert.Equal(t, failed, tickerErrorsOf(fmt.Errorf("Yahoo: %w", failed)))
+	assert.Nil(t, tickerErrorsOf(errors.New(`Yahoo is down`)))
+	assert.Nil(t, tickerErrorsOf(nil))
+}
+
+func TestJoinErrors(t *testing.T) {
+	down, limited := errors.New(`Yahoo is down`), errors.New(`Reached the budget`)
+	xyzz, qqqq := tickerErrors{`XYZZ`: unknownSymbol}, tickerErrors{`QQQQ`: `delisted`}
+
+	assert.NoError(t, joinErrors(nil, nil))
+	assert.Equal(t, down, joinErrors(nil, down))
+	assert.Equal(t, down, joinErrors(down, nil))
+	assert.Equal(t, down, joinErrors(down, limited), `first one`)
+	assert.Equal(t, down, joinErrors(xyzz, down), `whole fetch failure`)
+	assert.Equal(t, down, joinErrors(down, xyzz), `whole fetch failure`)
+	assert.Equal(t, tickerErrors{`XYZZ`: unknownSymbol, `QQQQ`: `delisted`}, joinErrors(xyzz, qqqq))
+}
+
+func TestFallbackKeepsUnknownTickers(t *testing.T) {
+	primary := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(200.00)}}}
+	secondary := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`, LastTrade: numberOf(199.00)}}}
+	provider := &fallback{primary: &withUnknown{primary}, secondary: secondary}
+
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `XYZZ`})
+	assert.Equal(t, tickerErrors{`XYZZ`: unknownSymbol}, tickerErrorsOf(err))
+	assert.Equal(t, []Stock{{Ticker: `AAPL`, LastTrade: numberOf(200.00)}}, stocks)
+	assert.Empty(t, secondary.requested, `no fallback for unknown tickers`)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`fmt`
+	`regexp`
+	`strings`
+)
+
+// Exchange prefixes the tickers often come with when copied from the charting
+// sites, ex. "NASDAQ:AAPL", that the quotes providers don't expect.
+var exchangePrefixes = map[string]bool{
+	`NASDAQ`: true, `NYSE`: true, `NYSEARCA`: true, `NYSEAMERICAN`: true,
+	`AMEX`: true, `ARCA`: true, `BATS`: true, `CBOE`: true, `OTC`: true,
+}
+
+// Share class of the ticker, ex. "BRK.B", "BRK/B", or "BRK-B". Only the
+// classes A through C are matched so the exchange suffixes like "VOD.L" are
+// left alone.
+var shareClass = regexp.MustCompile(`^([A-Z]+)[./-]([A-C])$`)
+
+// NormalizeTicker returns the ticker trimmed and uppercased, without the
+// exchange prefix, and with the share class written the way Yahoo does,
+// ex. "BRK-B", if the profile asks for that. The alias is replaced with
+// the ticker it stands for.
+func (profile *Profile) NormalizeTicker(ticker string) string {
+	ticker = strings.TrimSpace(ticker)
+	for aliased, label := range profile.Aliases {
+		if label != `` && strings.EqualFold(label, ticker) {
+			return strings.ToUpper(strings.TrimSpace(aliased))
+		}
+	}
+
+	ticker = strings.ToUpper(ticker)
+	if colon := strings.Index(ticker, `:`); colon > 0 && exchangePrefixes[ticker[:colon]] {
+		ticker = strings.TrimSpace(ticker[colon+1:])
+	}
+	if profile.HyphenateClasses {
+		ticker = shareClass.ReplaceAllString(ticker, `$1-$2`)
+	}
+
+	return ticker
+}
+
+// Label returns the alias the ticker is displayed as, or the ticker itself
+// if it has none.
+func (profile *Profile) Label(ticker string) string {
+	for aliased, label := range profile.Aliases {
+		if tickerKey(aliased) == tickerKey(ticker) && label != `` {
+			return label
+		}
+	}
+
+	return strings.TrimSpace(ticker)
+}
+
+// CheckTickers normalizes the tickers being added, and splits them into the
+// ones that are not on the list yet, and the notes about the rest, ex. "AAPL
+// is already on the list", or "BRK.B is already on the list as BRK-B". The
+// blank tickers and the ones repeated within the same request are dropped.
+func (profile *Profile) CheckTickers(tickers []string) (fresh, notes []string) {
+	existing := make(map[string]string)
+	for _, ticker := range profile.Tickers {
+		existing[tickerKey(ticker)] = strings.TrimSpace(ticker)
+	}
+	for _, name := range profile.watchlistNames() {
+		for _, ticker := range profile.Watchlists[name].Tickers {
+			if _, ok := existing[tickerKey(ticker)]; !ok {
+				existing[tickerKey(ticker)] = strings.TrimSpace(ticker)
+			}
+		}
+	}
+
+	requested := make(map[string]bool)
+	for _, ticker := range tickers {
+		ticker = profile.NormalizeTicker(ticker)
+		key := tickerKey(ticker)
+		if ticker == `` || requested[key] {
+			continue
+		}
+		requested[key] = true
+
+		listed, found := existing[key]
+		switch {
+		case !found:
+			fresh = append(fresh, ticker)
+		case listed != ticker:
+			notes = append(notes, fmt.Sprintf(`%s is already on the list as %s`, ticker, listed))
+		case profile.watchlistOf(listed) != ``:
+			notes = append(notes, fmt.Sprintf(`%s is already on the %q watchlist`, ticker, profile.watchlistOf(listed)))
+		default:
+			notes = append(notes, fmt.Sprintf(`%s is already on the list`, ticker))
+		}
+	}
+
+	return
+}
+
+// Returns the form the tickers are compared in to find the duplicates, so
+// that "brk.b " and "BRK-B" are the same ticker.
+//-----------------------------------------------------------------------------
+func tickerKey(ticker string) string {
+	return shareClass.ReplaceAllString(strings.ToUpper(strings.TrimSpace(ticker)), `$1-$2`)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTicker(t *testing.T) {
+	profile := &Profile{}
+	assert.Equal(t, "AAPL", profile.NormalizeTicker(" aapl "))
+	assert.Equal(t, "AAPL", profile.NormalizeTicker("NASDAQ:AAPL"))
+	assert.Equal(t, "SPY", profile.NormalizeTicker("nysearca: spy"))
+	assert.Equal(t, "BINANCE:BTCUSDT", profile.NormalizeTicker("binance:btcusdt"))
+	assert.Equal(t, "BRK.B", profile.NormalizeTicker("brk.b"))
+
+	profile.HyphenateClasses = true
+	assert.Equal(t, "BRK-B", profile.NormalizeTicker("brk.b"))
+	assert.Equal(t, "BF-A", profile.NormalizeTicker("NYSE:BF/A"))
+	assert.Equal(t, "VOD.L", profile.NormalizeTicker("VOD.L"))
+}
+
+func TestAliases(t *testing.T) {
+	profile := &Profile{Tickers: []string{"AAPL", "BRK-B"}, Aliases: map[string]string{"BRK-B": "Berkshire"}}
+	assert.Equal(t, "Berkshire", profile.Label("BRK-B "))
+	assert.Equal(t, "AAPL", profile.Label("AAPL"))
+	assert.Equal(t, "BRK-B", profile.NormalizeTicker("BERKSHIRE"))
+
+	_, notes := profile.CheckTickers([]string{"berkshire"})
+	assert.Equal(t, []string{"BRK-B is already on the list"}, notes)
+
+	profile.dryRun = true
+	removed, err := profile.RemoveTickers([]string{"BERKSHIRE"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []string{"AAPL"}, profile.Tickers)
+}
+
+func TestCheckTickers(t *testing.T) {
+	profile := &Profile{
+		Tickers:    []string{"AAPL", "BRK-B", "BTCUSDT"},
+		Watchlists: map[string]Watchlist{"crypto": {Tickers: []string{"BTCUSDT", "ETHUSDT"}}},
+	}
+
+	fresh, notes := profile.CheckTickers([]string{" msft", "NASDAQ:AAPL", "brk.b", "btcusdt", "ethusdt", "MSFT", ""})
+	assert.Equal(t, []string{"MSFT"}, fresh)
+	assert.Equal(t, []string{
+		`AAPL is already on the list`,
+		`BRK.B is already on the list as BRK-B`,
+		`BTCUSDT is already on the "crypto" watchlist`,
+		`ETHUSDT is already on the "crypto" watchlist`,
+	}, notes)
+}
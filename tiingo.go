@@ -0,0 +1,193 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/url`
+	`strings`
+	`time`
+)
+
+const tiingoIEXURL = `https://api.tiingo.com/iex/?tickers=%s&token=%s`
+const tiingoEODURL = `https://api.tiingo.com/tiingo/daily/%s/prices?startDate=%s&token=%s`
+
+// tiingo fetches stock quotes using Tiingo API: real time prices of the
+// stocks traded on IEX, and end-of-day prices of the rest (ex. mutual
+// funds). Tiingo supplies prices and volume only, and the fields it doesn't
+// supply are reported as N/A.
+type tiingo struct {
+	iexURL string           // IEX prices URL, with placeholders for the tickers and the API token.
+	eodURL string           // End-of-day prices URL, with placeholders for the ticker, start date, and the API token.
+	token  string           // Tiingo API token.
+	now    func() time.Time // Returns current time, replaced in tests.
+}
+
+// Returns new Tiingo provider for the given API token.
+func newTiingo(token string) *tiingo {
+	return &tiingo{iexURL: tiingoIEXURL, eodURL: tiingoEODURL, token: token, now: time.Now}
+}
+
+// Fetch requests IEX prices in batches of 50 tickers, and end-of-day prices
+// of the tickers IEX doesn't know about one by one.
+func (tiingo *tiingo) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	if tiingo.token == `` {
+		return nil, errors.New(`Tiingo API token is not set in the profile`)
+	}
+
+	fetched := make(map[string]Stock)
+	for _, batch := range batches(tickers, maxTickersPerRequest) {
+		body, err := tiingo.get(ctx, fmt.Sprintf(tiingo.iexURL, url.QueryEscape(strings.Join(batch, `,`)), url.QueryEscape(tiingo.token)))
+		if err != nil {
+			return nil, err
+		}
+		stocks, err := parseTiingoIEX(body)
+		if err != nil {
+			return nil, err
+		}
+		for _, stock := range stocks {
+			fetched[stock.Ticker] = stock
+		}
+	}
+
+	stocks := []Stock{}
+	weekAgo := tiingo.now().AddDate(0, 0, -7).Format(`2006-01-02`)
+	for _, ticker := range tickers {
+		stock, ok := fetched[strings.ToUpper(ticker)]
+		if !ok {
+			body, err := tiingo.get(ctx, fmt.Sprintf(tiingo.eodURL, url.PathEscape(ticker), weekAgo, url.QueryEscape(tiingo.token)))
+			if err != nil {
+				continue // Unknown ticker.
+			}
+			if stock, err = parseTiingoEOD(ticker, body); err != nil {
+				continue
+			}
+		}
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, unknownTickers(tickers, stocks)
+}
+
+//-----------------------------------------------------------------------------
+func (tiingo *tiingo) get(ctx context.Context, url string) ([]byte, error) {
+	response, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK { // Errors come as { "detail": "Invalid token." }.
+		failure := struct{ Detail string }{}
+		json.Unmarshal(body, &failure)
+		if failure.Detail == `` {
+			failure.Detail = strings.TrimSpace(string(body))
+		}
+		return nil, fmt.Errorf("Tiingo responded with %d: %s", response.StatusCode, failure.Detail)
+	}
+
+	return body, nil
+}
+
+// Parses Tiingo IEX prices, ex.
+//
+//   [ { "ticker": "AAPL", "last": 201.46, "tngoLast": 201.46, "prevClose": 199.65, "open": 200,
+//       "high": 202, "low": 199.6, "volume": 27316739, "bidPrice": 201.45, "askPrice": 201.47 } ]
+//
+// Any of the prices could be null, ex. bid and ask outside of market hours.
+//-----------------------------------------------------------------------------
+func parseTiingoIEX(body []byte) ([]Stock, error) {
+	prices := []struct {
+		Ticker    string
+		Last      *float64
+		TngoLast  *float64
+		PrevClose *float64
+		Open      *float64
+		High      *float64
+		Low       *float64
+		Volume    *float64
+		BidPrice  *float64
+		AskPrice  *float64
+	}{}
+	if err := json.Unmarshal(body, &prices); err != nil {
+		return nil, err
+	}
+
+	stocks := []Stock{}
+	for _, price := range prices {
+		last := price.Last
+		if last == nil {
+			last = price.TngoLast // Tiingo's own last price, ex. before the open.
+		}
+		stock := Stock{
+			Ticker:    strings.ToUpper(price.Ticker),
+			LastTrade: orNA(last),
+			Open:      orNA(price.Open),
+			Low:       orNA(price.Low),
+			High:      orNA(price.High),
+			Volume:    orNA(price.Volume),
+			PrevClose: orNA(price.PrevClose),
+			Bid:       orNA(price.BidPrice),
+			Ask:       orNA(price.AskPrice),
+		}
+		withChange(&stock, last, price.PrevClose)
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, nil
+}
+
+// Parses Tiingo end-of-day prices since the given date, ex.
+//
+//   [ { "date": "2019-06-27T00:00:00.000Z", "close": 199.65, "high": 201.57, "low": 199.57, "open": 200.29, "volume": 20899717 }, ... ]
+//
+// The last two days give the latest close and the change from the close
+// before it.
+//-----------------------------------------------------------------------------
+func parseTiingoEOD(ticker string, body []byte) (Stock, error) {
+	days := []struct {
+		Close  *float64
+		Open   *float64
+		High   *float64
+		Low    *float64
+		Volume *float64
+	}{}
+	if err := json.Unmarshal(body, &days); err != nil {
+		return Stock{}, err
+	}
+	if len(days) == 0 {
+		return Stock{}, errors.New(`No end-of-day prices for ` + ticker)
+	}
+
+	latest := days[len(days)-1]
+	stock := Stock{
+		Ticker:    strings.ToUpper(ticker),
+		LastTrade: orNA(latest.Close),
+		Open:      orNA(latest.Open),
+		Low:       orNA(latest.Low),
+		High:      orNA(latest.High),
+		Volume:    orNA(latest.Volume),
+		PrevClose: unavailable,
+		Bid:       unavailable,
+		Ask:       unavailable,
+	}
+	var previous *float64
+	if len(days) > 1 {
+		previous = days[len(days)-2].Close
+		stock.PrevClose = orNA(previous)
+	}
+	withChange(&stock, latest.Close, previous)
+
+	return stock, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTiingo(t *testing.T) {
+	stocks, err := parseTiingoIEX([]byte(`[
+	  {"ticker":"aapl","last":null,"tngoLast":201.46,"prevClose":199.65,"open":200,"high":202,"low":199.6,
+	   "volume":27316739,"bidPrice":null,"askPrice":null}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, stocks, 1)
+
+	aapl := stocks[0]
+	assert.Equal(t, `AAPL`, aapl.Ticker)
+	assert.Equal(t, `201.460`, aapl.LastTrade.String())
+	assert.Equal(t, `1.810`, aapl.Change.String())
+	assert.Equal(t, `0.907`, aapl.ChangePct.String())
+	assert.Equal(t, `N/A`, aapl.Bid.String())
+	assert.Equal(t, `N/A`, aapl.MarketCap.String())
+	assert.Equal(t, `N/A`, aapl.AvgVolume.String())
+	assert.True(t, aapl.Advancing)
+
+	fund, err := parseTiingoEOD(`vfiax`, []byte(`[
+	  {"date":"2019-06-26T00:00:00.000Z","close":270.12,"open":270.12,"high":270.12,"low":270.12,"volume":0},
+	  {"date":"2019-06-27T00:00:00.000Z","close":268.5,"open":268.5,"high":268.5,"low":268.5,"volume":0}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, `VFIAX`, fund.Ticker)
+	assert.Equal(t, `268.500`, fund.LastTrade.String())
+	assert.Equal(t, `270.120`, fund.PrevClose.String())
+	assert.Equal(t, `-1.620`, fund.Change.String())
+	assert.False(t, fund.Advancing)
+
+	_, err = parseTiingoEOD(`NOPE`, []byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestTiingoFetch(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch {
+		case r.URL.Query().Get(`token`) != `secret`:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"detail":"Invalid token."}`)
+		case r.URL.Path == `/iex/`:
+			fmt.Fprint(w, `[{"ticker":"aapl","last":201.46,"prevClose":199.65}]`)
+		case strings.HasPrefix(r.URL.Path, `/daily/VFIAX`):
+			assert.Equal(t, `2019-06-21`, r.URL.Query().Get(`startDate`))
+			fmt.Fprint(w, `[{"close":268.5}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTiingo(`secret`)
+	provider.iexURL = server.URL + `/iex/?tickers=%s&token=%s`
+	provider.eodURL = server.URL + `/daily/%s/prices?startDate=%s&token=%s`
+	provider.now = func() time.Time { return time.Date(2019, 6, 28, 10, 0, 0, 0, time.UTC) }
+
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`, `VFIAX`, `NOPE`})
+	assert.Equal(t, tickerErrors{`NOPE`: unknownSymbol}, tickerErrorsOf(err))
+	require.Len(t, stocks, 2)
+	assert.Equal(t, `AAPL`, stocks[0].Ticker)
+	assert.Equal(t, `VFIAX`, stocks[1].Ticker)
+	assert.Equal(t, `N/A`, stocks[1].Change.String(), `no previous close to compare with`)
+	assert.Equal(t, []string{`/iex/`, `/daily/VFIAX/prices`, `/daily/NOPE/prices`}, requested)
+
+	provider.token = `wrong`
+	_, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	assert.EqualError(t, err, `Tiingo responded with 401: Invalid token.`)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/json`
+	`io/ioutil`
+	`sync`
+	`time`
+)
+
+// ttlCache keeps the data that changes slowly, ex. the company's sector,
+// along with the time it was fetched, so it's only fetched again once it
+// expires. The cache is saved to the disk and therefore survives restarts.
+type ttlCache struct {
+	filename string                // File the cache is saved to.
+	mutex    sync.Mutex            // Guards the entries.
+	entries  map[string]cacheEntry // Cached values by key.
+	now      func() time.Time      // Returns current time, replaced in tests.
+}
+
+// cacheEntry is the cached value along with the time it was fetched.
+type cacheEntry struct {
+	Fetched time.Time       // Time the value was fetched.
+	Value   json.RawMessage // Value itself, in JSON.
+}
+
+// Returns the cache saved in the given file, or the empty one if the file
+// doesn't exist yet or can't be read.
+//-----------------------------------------------------------------------------
+func openCache(filename string) *ttlCache {
+	cache := &ttlCache{filename: filename, entries: make(map[string]cacheEntry), now: time.Now}
+	if data, err := ioutil.ReadFile(filename); err == nil {
+		json.Unmarshal(data, &cache.entries)
+	}
+
+	return cache
+}
+
+// Decodes the cached value into the given one, and returns true if it has
+// been fetched within the given time to live.
+//-----------------------------------------------------------------------------
+func (cache *ttlCache) get(key string, ttl time.Duration, value interface{}) (fresh bool) {
+	cache.mutex.Lock()
+	entry, ok := cache.entries[key]
+	cache.mutex.Unlock()
+	if !ok || json.Unmarshal(entry.Value, value) != nil {
+		return false
+	}
+
+	return cache.now().Sub(entry.Fetched) < ttl
+}
+
+// Returns true if the value for the key has been fetched within the given
+// time to live.
+//-----------------------------------------------------------------------------
+func (cache *ttlCache) fresh(key string, ttl time.Duration) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry, ok := cache.entries[key]
+
+	return ok && cache.now().Sub(entry.Fetched) < ttl
+}
+
+// Caches the value fetched just now.
+//-----------------------------------------------------------------------------
+func (cache *ttlCache) put(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	cache.mutex.Lock()
+	cache.entries[key] = cacheEntry{Fetched: cache.now(), Value: data}
+	cache.mutex.Unlock()
+
+	return nil
+}
+
+// Saves the cache to the disk.
+//-----------------------------------------------------------------------------
+func (cache *ttlCache) save() error {
+	cache.mutex.Lock()
+	data, err := json.Marshal(cache.entries)
+	cache.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return writeAtomically(cache.filename, data)
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLCacheExpires(t *testing.T) {
+	now := time.Date(2019, 6, 3, 9, 0, 0, 0, time.UTC)
+	cache := openCache(filepath.Join(t.TempDir(), `cache`))
+	cache.now = func() time.Time { return now }
+
+	value := ``
+	assert.False(t, cache.get(`sector:AAPL`, time.Hour, &value))
+	assert.False(t, cache.fresh(`sector:AAPL`, time.Hour))
+
+	require.NoError(t, cache.put(`sector:AAPL`, `Technology`))
+	assert.True(t, cache.get(`sector:AAPL`, time.Hour, &value))
+	assert.Equal(t, `Technology`, value)
+
+	now = now.Add(2 * time.Hour)
+	value = ``
+	assert.False(t, cache.get(`sector:AAPL`, time.Hour, &value))
+	assert.Equal(t, `Technology`, value, `expired value is still decoded`)
+	assert.True(t, cache.fresh(`sector:AAPL`, 24*time.Hour))
+}
+
+func TestTTLCacheSurvivesRestart(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `cache`)
+	cache := openCache(filename)
+	require.NoError(t, cache.put(`rating:IBM`, `hold`))
+	require.NoError(t, cache.save())
+
+	value := ``
+	assert.True(t, openCache(filename).get(`rating:IBM`, time.Hour, &value))
+	assert.Equal(t, `hold`, value)
+}
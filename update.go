@@ -0,0 +1,358 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bufio`
+	`bytes`
+	`crypto/sha256`
+	`encoding/hex`
+	`encoding/json`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`os`
+	`runtime`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// Version is the version of the running mop, set at build time.
+var Version = `0.2.0`
+
+const latestReleaseURL = `https://api.github.com/repos/mop-tracker/mop/releases/latest`
+const releasesURL = `https://api.github.com/repos/mop-tracker/mop/releases`
+
+// Name of the release asset that lists SHA-256 checksums of the binaries.
+const checksumsAsset = `checksums.txt`
+
+// Release describes mop release published on GitHub.
+type Release struct {
+	Version    string         `json:"tag_name"`   // Ex. "v0.3.0".
+	Prerelease bool           `json:"prerelease"` // True for release candidates.
+	Draft      bool           `json:"draft"`      // True for unpublished releases.
+	Assets     []ReleaseAsset `json:"assets"`     // Binaries and checksums.
+}
+
+// ReleaseAsset is the file attached to the release.
+type ReleaseAsset struct {
+	Name string `json:"name"`                 // Ex. "mop-0.3.0-linux-64".
+	URL  string `json:"browser_download_url"` // Where to download the file from.
+}
+
+// Updater checks if a newer mop has been released, and replaces the running
+// binary with it.
+type Updater struct {
+	url         string // GitHub API URL of the latest stable release.
+	releasesURL string // GitHub API URL of all the releases, pre-releases included.
+	prerelease  bool   // True when pre-releases are considered too.
+	version     string // Version of the running mop.
+	goos        string // Operating system the binary is built for.
+	goarch      string // Architecture the binary is built for.
+}
+
+// UpdateSettings opt in to checking for newer mop releases on startup.
+type UpdateSettings struct {
+	Check   bool   // True to check for newer release once a day on startup.
+	Channel string // Release channel: "prerelease" to get release candidates too, or blank for stable releases.
+	Checked string // Date of the last check, ex. "2019-06-28".
+	Latest  string // Newer version found by the last check, blank if mop was up to date.
+}
+
+// Returns new Updater for the running mop.
+func NewUpdater() *Updater {
+	return &Updater{
+		url:         latestReleaseURL,
+		releasesURL: releasesURL,
+		version:     Version,
+		goos:        runtime.GOOS,
+		goarch:      runtime.GOARCH,
+	}
+}
+
+// Channel selects the release channel: "prerelease" to consider release
+// candidates too, anything else for stable releases only.
+func (updater *Updater) Channel(channel string) *Updater {
+	updater.prerelease = channel == `prerelease`
+
+	return updater
+}
+
+// Check returns the latest release if it's newer than the running version,
+// or nil if mop is up to date.
+func (updater *Updater) Check() (*Release, error) {
+	release, err := updater.latest()
+	if err != nil || release == nil || !newer(release.Version, updater.version) {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// Install downloads the release binary for the current platform, verifies
+// its checksum, and replaces the given executable with it.
+func (updater *Updater) Install(release *Release, executable string) error {
+	name := updater.asset(release.Version)
+	binary, checksums := ``, ``
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case name:
+			binary = asset.URL
+		case checksumsAsset:
+			checksums = asset.URL
+		}
+	}
+	if binary == `` {
+		return fmt.Errorf("Release %s has no binary for %s/%s", release.Version, updater.goos, updater.goarch)
+	}
+	if checksums == `` {
+		return fmt.Errorf("Release %s has no checksums, not installing unverified binary", release.Version)
+	}
+
+	list, err := download(checksums)
+	if err != nil {
+		return err
+	}
+	data, err := download(binary)
+	if err != nil {
+		return err
+	}
+	if err := verify(data, name, list); err != nil {
+		return err
+	}
+
+	return replaceExecutable(executable, data)
+}
+
+// Managed returns the package manager command that should be used to update
+// mop instead, if mop has been installed by Homebrew or Scoop.
+func Managed(executable string) string {
+	path := strings.Replace(executable, `\`, `/`, -1)
+	switch {
+	case strings.Contains(path, `/Cellar/`) || strings.Contains(path, `/homebrew/`):
+		return `brew upgrade mop`
+	case strings.Contains(strings.ToLower(path), `/scoop/`):
+		return `scoop update mop`
+	}
+	return ``
+}
+
+// Updater returns the updater for the release channel set in the profile.
+func (profile *Profile) Updater() *Updater {
+	updater := NewUpdater()
+	if profile.Updates != nil {
+		updater.Channel(profile.Updates.Channel)
+	}
+
+	return updater
+}
+
+// UpdateCheckDue returns true if the user has opted in to update checks and
+// there was no check today yet.
+func (profile *Profile) UpdateCheckDue(now time.Time) bool {
+	return profile.Updates != nil && profile.Updates.Check && profile.Updates.Checked != now.Format(`2006-01-02`)
+}
+
+// CheckedForUpdate records the outcome of today's update check: the newer
+// release found, or nil if mop is up to date.
+func (profile *Profile) CheckedForUpdate(release *Release, now time.Time) error {
+	if profile.Updates == nil {
+		return nil
+	}
+	profile.Updates.Checked, profile.Updates.Latest = now.Format(`2006-01-02`), ``
+	if release != nil {
+		profile.Updates.Latest = release.Version
+	}
+
+	return profile.Save()
+}
+
+// UpdateNotice returns the note about the newer release found by the last
+// update check, or blank if there is none or mop has been updated since.
+func (profile *Profile) UpdateNotice() string {
+	if profile.Updates == nil || !profile.Updates.Check || !newer(profile.Updates.Latest, Version) {
+		return ``
+	}
+
+	return fmt.Sprintf(`mop %s is out, run mop update`, profile.Updates.Latest)
+}
+
+// Returns the latest release in the selected channel. The stable releases
+// are looked up directly, while the pre-releases are picked from the list
+// of recent releases.
+//-----------------------------------------------------------------------------
+func (updater *Updater) latest() (*Release, error) {
+	if !updater.prerelease {
+		body, err := download(updater.url)
+		if err != nil {
+			return nil, err
+		}
+		release := &Release{}
+		if err := json.Unmarshal(body, release); err != nil {
+			return nil, err
+		}
+		return release, nil
+	}
+
+	body, err := download(updater.releasesURL)
+	if err != nil {
+		return nil, err
+	}
+	releases := []*Release{}
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, err
+	}
+	var latest *Release
+	for _, release := range releases {
+		if !release.Draft && (latest == nil || newer(release.Version, latest.Version)) {
+			latest = release
+		}
+	}
+
+	return latest, nil
+}
+
+// Returns the name of the release binary for the current platform, same as
+// built by `make buildall`, ex. mop-0.3.0-osx-64.
+//-----------------------------------------------------------------------------
+func (updater *Updater) asset(version string) string {
+	goos, bits, suffix := updater.goos, `64`, ``
+	if goos == `darwin` {
+		goos = `osx`
+	}
+	if updater.goarch == `386` {
+		bits = `32`
+	}
+	if updater.goos == `windows` {
+		suffix = `.exe`
+	}
+	return fmt.Sprintf(`mop-%s-%s-%s%s`, strings.TrimPrefix(version, `v`), goos, bits, suffix)
+}
+
+// Checks the SHA-256 checksum of the binary against the checksums list as
+// produced by sha256sum, i.e. "<checksum>  <filename>" lines.
+//-----------------------------------------------------------------------------
+func verify(data []byte, name string, list []byte) error {
+	sum := sha256.Sum256(data)
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], `*`) == name {
+			if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+				return fmt.Errorf("Checksum mismatch for %s", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("No checksum for %s", name)
+}
+
+// Writes new binary next to the running one and moves it in place. Windows
+// doesn't allow replacing the running binary, but it could be renamed.
+//-----------------------------------------------------------------------------
+func replaceExecutable(executable string, data []byte) error {
+	temporary := executable + `.new`
+	if err := ioutil.WriteFile(temporary, data, 0755); err != nil {
+		return err
+	}
+	if runtime.GOOS == `windows` {
+		os.Remove(executable + `.old`)
+		if err := os.Rename(executable, executable+`.old`); err != nil {
+			os.Remove(temporary)
+			return err
+		}
+	}
+	if err := os.Rename(temporary, executable); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	return nil
+}
+
+// Returns true if the version is newer than the other one, ex. v0.10.0 is
+// newer than 0.9.1. Pre-releases are ordered the semver way: the release is
+// newer than its pre-releases, ex. 0.3.0 than 0.3.0-rc1, and the pre-release
+// identifiers are compared numerically or lexically, ex. 0.3.0-rc.2 is newer
+// than 0.3.0-rc.1, and 0.3.0-rc2 than 0.3.0-rc1. The build metadata is
+// ignored.
+//-----------------------------------------------------------------------------
+func newer(version, other string) bool {
+	split := func(version string) (string, string) {
+		version = strings.SplitN(strings.TrimPrefix(version, `v`), `+`, 2)[0]
+		if parts := strings.SplitN(version, `-`, 2); len(parts) == 2 {
+			return parts[0], parts[1]
+		}
+		return version, ``
+	}
+
+	release, prerelease := split(version)
+	otherRelease, otherPrerelease := split(other)
+	if order := compareIdentifiers(strings.Split(release, `.`), strings.Split(otherRelease, `.`), true); order != 0 {
+		return order > 0
+	}
+	switch {
+	case prerelease == otherPrerelease:
+		return false
+	case prerelease == ``: // The release is newer than any of its pre-releases.
+		return true
+	case otherPrerelease == ``:
+		return false
+	}
+
+	return compareIdentifiers(strings.Split(prerelease, `.`), strings.Split(otherPrerelease, `.`), false) > 0
+}
+
+// Compares the dot-separated version identifiers one by one, and returns
+// a positive number if the first ones are greater, negative if they are
+// less, or 0 if they are the same. The numeric identifiers are compared
+// numerically and rank below the alphanumeric ones, which are compared
+// lexically. The release numbers that are missing or aren't numbers count
+// as 0, while the pre-release with more identifiers is greater.
+//-----------------------------------------------------------------------------
+func compareIdentifiers(a, b []string, release bool) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if !release && (i >= len(a) || i >= len(b)) {
+			return len(a) - len(b)
+		}
+		x, y := `0`, `0`
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case release || xerr == nil && yerr == nil:
+			if xn != yn {
+				return xn - yn
+			}
+		case xerr == nil:
+			return -1
+		case yerr == nil:
+			return 1
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+//-----------------------------------------------------------------------------
+func download(url string) ([]byte, error) {
+	response, err := newClient(downloadTimeout).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(`Unable to download ` + url + `: ` + response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewer(t *testing.T) {
+	assert.True(t, newer(`v0.3.0`, `0.2.0`))
+	assert.True(t, newer(`v0.10.0`, `0.9.1`))
+	assert.True(t, newer(`1.0`, `0.9.9`))
+	assert.False(t, newer(`v0.2.0`, `0.2.0`))
+	assert.False(t, newer(`v0.2.0-rc1`, `0.2.0`))
+	assert.False(t, newer(`0.1.9`, `0.2.0`))
+
+	assert.True(t, newer(`v0.3.0`, `0.3.0-rc1`), `release over its pre-release`)
+	assert.True(t, newer(`v0.3.0-rc2`, `0.3.0-rc1`))
+	assert.False(t, newer(`v0.3.0-rc1`, `0.3.0-rc2`))
+	assert.False(t, newer(`v0.3.0-rc1`, `v0.3.0-rc1`))
+	assert.True(t, newer(`0.3.0-rc.10`, `0.3.0-rc.9`), `numeric identifiers`)
+	assert.True(t, newer(`0.3.0-rc.1`, `0.3.0-rc`), `more identifiers`)
+	assert.True(t, newer(`0.3.0-rc.1`, `0.3.0-1`), `alphanumeric over numeric`)
+	assert.True(t, newer(`0.3.0-rc1`, `0.2.9`))
+	assert.False(t, newer(`0.3.0+build.5`, `0.3.0`), `build metadata`)
+}
+
+func TestUpdater(t *testing.T) {
+	binary := []byte(`new mop`)
+	sum := sha256.Sum256(binary)
+	checksums := fmt.Sprintf("%x  mop-0.3.0-linux-64\n%x  mop-0.3.0-osx-64\n", sum, sha256.Sum256([]byte(`other`)))
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/latest`:
+			fmt.Fprintf(w, `{"tag_name":"v0.3.0","assets":[
+			  {"name":"mop-0.3.0-linux-64","browser_download_url":"%[1]s/linux"},
+			  {"name":"mop-0.3.0-osx-64","browser_download_url":"%[1]s/osx"},
+			  {"name":"checksums.txt","browser_download_url":"%[1]s/checksums"}]}`, server.URL)
+		case `/linux`, `/osx`:
+			w.Write(binary)
+		case `/checksums`:
+			fmt.Fprint(w, checksums)
+		}
+	}))
+	defer server.Close()
+
+	updater := &Updater{url: server.URL + `/latest`, version: `0.2.0`, goos: `linux`, goarch: `amd64`}
+	release, err := updater.Check()
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Equal(t, `v0.3.0`, release.Version)
+
+	executable := filepath.Join(t.TempDir(), `mop`)
+	require.NoError(t, ioutil.WriteFile(executable, []byte(`old mop`), 0755))
+	require.NoError(t, updater.Install(release, executable))
+	data, _ := ioutil.ReadFile(executable)
+	assert.Equal(t, binary, data)
+
+	updater.goos = `darwin`
+	assert.EqualError(t, updater.Install(release, executable), `Checksum mismatch for mop-0.3.0-osx-64`)
+	updater.goos = `freebsd`
+	assert.Error(t, updater.Install(release, executable), `no binary`)
+
+	updater.version = `0.3.0`
+	release, err = updater.Check()
+	require.NoError(t, err)
+	assert.Nil(t, release, `up to date`)
+}
+
+func TestManaged(t *testing.T) {
+	assert.Equal(t, `brew upgrade mop`, Managed(`/usr/local/Cellar/mop/0.2.0/bin/mop`))
+	assert.Equal(t, `scoop update mop`, Managed(`C:\Users\me\scoop\apps\mop\current\mop.exe`))
+	assert.Equal(t, ``, Managed(`/home/me/go/bin/mop`))
+}
+
+func TestPrereleaseChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/latest`:
+			fmt.Fprint(w, `{"tag_name":"v0.2.0"}`)
+		case `/releases`:
+			fmt.Fprint(w, `[{"tag_name":"v0.4.0","draft":true},{"tag_name":"v0.3.0-rc1","prerelease":true},{"tag_name":"v0.2.0"}]`)
+		}
+	}))
+	defer server.Close()
+
+	updater := &Updater{url: server.URL + `/latest`, releasesURL: server.URL + `/releases`, version: `0.2.0`}
+	release, err := updater.Check()
+	require.NoError(t, err)
+	assert.Nil(t, release, `stable channel is up to date`)
+
+	release, err = updater.Channel(`prerelease`).Check()
+	require.NoError(t, err)
+	require.NotNil(t, release)
+	assert.Equal(t, `v0.3.0-rc1`, release.Version, `drafts are skipped`)
+}
+
+func TestUpdateCheck(t *testing.T) {
+	now := time.Date(2019, 6, 28, 10, 0, 0, 0, time.UTC)
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `.moprc`)}
+	assert.False(t, profile.UpdateCheckDue(now), `not opted in`)
+	assert.Equal(t, ``, profile.UpdateNotice())
+
+	profile.Updates = &UpdateSettings{Check: true, Channel: `prerelease`}
+	assert.True(t, profile.UpdateCheckDue(now))
+	assert.True(t, profile.Updater().prerelease)
+
+	require.NoError(t, profile.CheckedForUpdate(&Release{Version: `v9.0.0`}, now))
+	assert.False(t, profile.UpdateCheckDue(now.Add(time.Hour)), `once a day`)
+	assert.True(t, profile.UpdateCheckDue(now.AddDate(0, 0, 1)))
+	assert.Equal(t, `mop v9.0.0 is out, run mop update`, profile.UpdateNotice())
+
+	require.NoError(t, profile.CheckedForUpdate(nil, now))
+	assert.Equal(t, ``, profile.UpdateNotice(), `up to date`)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`encoding/json`
+	`math`
+)
+
+const historyURL = `https://query2.finance.yahoo.com/v8/finance/chart/%s?range=3mo&interval=1d`
+
+// Number of trading days the historical volatility and the average true
+// range are calculated over, and the number of trading days in a year.
+const (
+	volatilityDays = 20
+	atrDays        = 14
+	tradingDays    = 252
+)
+
+// Number of the latest daily closing prices kept for the sparklines, about
+// a month of trading days.
+const sparklineDays = 22
+
+// Parses Yahoo chart of the daily prices into the ticker's historical
+// volatility and the average true range. The days with no trades are
+// skipped.
+//-----------------------------------------------------------------------------
+func parseHistory(body []byte) (fundamentals, error) {
+	chart := struct {
+		Chart struct {
+			Result []struct {
+				Indicators struct {
+					Quote []struct {
+						High  []*float64 `json:"high"`
+						Low   []*float64 `json:"low"`
+						Close []*float64 `json:"close"`
+					} `json:"quote"`
+				} `json:"indicators"`
+			} `json:"result"`
+		} `json:"chart"`
+	}{}
+	if err := json.Unmarshal(body, &chart); err != nil {
+		return fundamentals{}, err
+	}
+
+	highs, lows, closes := []float64{}, []float64{}, []float64{}
+	for _, result := range chart.Chart.Result {
+		for _, quote := range result.Indicators.Quote {
+			for i := range quote.Close {
+				if i < len(quote.High) && i < len(quote.Low) && quote.High[i] != nil && quote.Low[i] != nil && quote.Close[i] != nil {
+					highs, lows, closes = append(highs, *quote.High[i]), append(lows, *quote.Low[i]), append(closes, *quote.Close[i])
+				}
+			}
+		}
+	}
+
+	var recent []float64
+	if len(closes) > sparklineDays {
+		recent = closes[len(closes)-sparklineDays:]
+	} else if len(closes) > 0 {
+		recent = closes
+	}
+
+	return fundamentals{
+		Volatility: historicalVolatility(closes, volatilityDays),
+		ATR:        averageTrueRange(highs, lows, closes, atrDays),
+		Closes:     recent,
+	}, nil
+}
+
+// Returns the annualized standard deviation of the daily log returns over
+// the given number of the latest days, in percent, or 0 if there are not
+// enough closing prices.
+//-----------------------------------------------------------------------------
+func historicalVolatility(closes []float64, days int) float64 {
+	if len(closes) < days+1 {
+		return 0
+	}
+	closes = closes[len(closes)-days-1:]
+
+	returns, mean := make([]float64, days), 0.0
+	for i := range returns {
+		if closes[i] <= 0 || closes[i+1] <= 0 {
+			return 0
+		}
+		returns[i] = math.Log(closes[i+1] / closes[i])
+		mean += returns[i] / float64(days)
+	}
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean) / float64(days-1)
+	}
+
+	return math.Sqrt(variance*tradingDays) * 100
+}
+
+// Returns Wilder's average true range over the given number of days, or 0
+// if there are not enough prices. The first average is the simple one, and
+// each day that follows is smoothed in.
+//-----------------------------------------------------------------------------
+func averageTrueRange(highs, lows, closes []float64, days int) float64 {
+	if len(closes) < days+1 {
+		return 0
+	}
+
+	atr := 0.0
+	for i := 1; i < len(closes); i++ {
+		trueRange := math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+		if i <= days {
+			atr += trueRange / float64(days)
+		} else {
+			atr = (atr*float64(days-1) + trueRange) / float64(days)
+		}
+	}
+
+	return atr
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoricalVolatility(t *testing.T) {
+	closes := []float64{}
+	for i := 0; i <= volatilityDays; i++ {
+		closes = append(closes, 100+10*float64(i%2))
+	}
+	expected := math.Sqrt(float64(volatilityDays)/float64(volatilityDays-1)*tradingDays) * math.Log(1.1) * 100
+	assert.InDelta(t, expected, historicalVolatility(closes, volatilityDays), 1e-9)
+	assert.InDelta(t, expected, historicalVolatility(append([]float64{1, 2, 3}, closes...), volatilityDays), 1e-9, `latest days only`)
+
+	assert.Zero(t, historicalVolatility(closes[1:], volatilityDays), `not enough days`)
+	assert.InDelta(t, 0, historicalVolatility([]float64{100, 101, 102.01, 103.0301}, 3), 1e-9, `steady growth`)
+}
+
+func TestAverageTrueRange(t *testing.T) {
+	highs, lows, closes := []float64{}, []float64{}, []float64{}
+	for i := 0; i <= atrDays; i++ {
+		highs, lows, closes = append(highs, 101), append(lows, 99), append(closes, 100)
+	}
+	assert.InDelta(t, 2, averageTrueRange(highs, lows, closes, atrDays), 1e-9)
+
+	// The gap up counts from the previous close, and is smoothed in.
+	highs, lows, closes = append(highs, 110), append(lows, 106), append(closes, 108)
+	assert.InDelta(t, (2*float64(atrDays-1)+10)/float64(atrDays), averageTrueRange(highs, lows, closes, atrDays), 1e-9)
+
+	assert.Zero(t, averageTrueRange(highs[:atrDays], lows[:atrDays], closes[:atrDays], atrDays), `not enough days`)
+}
+
+func TestParseHistory(t *testing.T) {
+	series := func(price string) string { // The day with no trades first.
+		return `null` + strings.Repeat(`,`+price, atrDays+1)
+	}
+	body := `{"chart":{"result":[{"indicators":{"quote":[{"high":[` + series(`101`) + `],"low":[` + series(`99`) + `],"close":[` + series(`100`) + `]}]}}],"error":null}}`
+	parsed, err := parseHistory([]byte(body))
+	require.NoError(t, err)
+	assert.InDelta(t, 2, parsed.ATR, 1e-9)
+	assert.Zero(t, parsed.Volatility, `not enough days`)
+	assert.Len(t, parsed.Closes, atrDays+1, `days with no trades skipped`)
+
+	parsed, err = parseHistory([]byte(`{"chart":{"result":null,"error":{"code":"Not Found"}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, fundamentals{}, parsed)
+
+	_, err = parseHistory([]byte(`<html>`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`fmt`
+	`sort`
+	`strings`
+	`time`
+)
+
+// Watchlist is the group of the tickers that are refreshed at their own
+// cadence and fetched from their own provider, ex. crypto pairs refreshed
+// every 2 seconds from Binance, and long-term holdings every 5 minutes from
+// Yahoo. The tickers are listed in the profile's Tickers too.
+type Watchlist struct {
+	Tickers  []string // Tickers of the watchlist, ex. ["BTCUSDT", "ETHUSDT"].
+	Refresh  int      // Seconds between the refreshes, 0 for the profile's QuotesRefresh.
+	Provider string   // Stock quotes provider, same names as the profile's, blank for the profile's one.
+	APIKey   string   // API key of the provider, blank for the profile's APIKey.
+}
+
+// Picker item that shows all the tickers rather than one of the watchlists.
+const allTickers = `All tickers`
+
+// How early the refresh could be made, so the ticks that come a bit early
+// don't skip the watchlist until the next tick.
+const scheduleSlack = 500 * time.Millisecond
+
+// scheduler picks the tickers due for the refresh: each watchlist gets
+// refreshed at its own interval, and the tickers that are not on any of the
+// watchlists at the profile's QuotesRefresh interval.
+type scheduler struct {
+	next map[string]time.Time // Time of the next refresh by watchlist name, blank name for the rest.
+	now  func() time.Time     // Returns current time, replaced in tests.
+}
+
+//-----------------------------------------------------------------------------
+func newScheduler() *scheduler {
+	return &scheduler{next: map[string]time.Time{}, now: time.Now}
+}
+
+// Returns the tickers of the watchlists that are due for the refresh, and
+// schedules their next refresh.
+//-----------------------------------------------------------------------------
+func (scheduler *scheduler) due(profile *Profile, tickers []string) []string {
+	if len(profile.Watchlists) == 0 {
+		return tickers
+	}
+
+	now, due, picked := scheduler.now(), map[string]bool{}, []string{}
+	for _, ticker := range tickers {
+		name := profile.watchlistOf(ticker)
+		if _, ok := due[name]; !ok {
+			due[name] = !now.Add(scheduleSlack).Before(scheduler.next[name])
+		}
+		if due[name] {
+			picked = append(picked, ticker)
+		}
+	}
+	for name, ok := range due {
+		if ok {
+			scheduler.next[name] = now.Add(profile.refreshOf(name))
+		}
+	}
+
+	return picked
+}
+
+// RefreshInterval returns how often the stock quotes should be checked for
+// the refresh: often enough to refresh each watchlist at its own interval.
+func (profile *Profile) RefreshInterval() time.Duration {
+	interval := profile.QuotesRefresh
+	for _, watchlist := range profile.Watchlists {
+		if watchlist.Refresh > 0 {
+			interval = gcd(interval, watchlist.Refresh)
+		}
+	}
+	if interval <= 0 {
+		interval = 1
+	}
+
+	return time.Duration(interval) * time.Second
+}
+
+// SelectList chooses the watchlist the stock quotes are shown for, blank
+// for all the tickers, and saves the profile.
+func (profile *Profile) SelectList(name string) error {
+	if _, ok := profile.Watchlists[name]; name != `` && !ok {
+		return fmt.Errorf("No %q watchlist in the profile", name)
+	}
+	profile.List = name
+
+	return profile.Save()
+}
+
+// NewWatchlistPicker displays the picker to choose the watchlist the stock
+// quotes are shown for.
+func NewWatchlistPicker(screen *Screen, quotes *Quotes) *Picker {
+	profile := quotes.profile
+	return NewPicker(screen, `Watchlist`, append([]string{allTickers}, profile.watchlistNames()...), func(name string, ok bool) {
+		if ok {
+			if name == allTickers {
+				name = ``
+			}
+			profile.SelectList(name)
+		}
+		screen.Draw(quotes)
+	})
+}
+
+// Returns true if the ticker is shown, i.e. either no watchlist is chosen
+// or the ticker is on the chosen one.
+//-----------------------------------------------------------------------------
+func (profile *Profile) listed(ticker string) bool {
+	if profile.List == `` {
+		return true
+	}
+	for _, listed := range profile.Watchlists[profile.List].Tickers {
+		if strings.EqualFold(strings.TrimSpace(listed), strings.TrimSpace(ticker)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the name of the watchlist the ticker is on, blank if it's not on
+// any of them. The first watchlist in alphabetical order wins if the ticker
+// is on several.
+//-----------------------------------------------------------------------------
+func (profile *Profile) watchlistOf(ticker string) string {
+	for _, name := range profile.watchlistNames() {
+		for _, listed := range profile.Watchlists[name].Tickers {
+			if strings.EqualFold(strings.TrimSpace(listed), strings.TrimSpace(ticker)) {
+				return name
+			}
+		}
+	}
+
+	return ``
+}
+
+// Returns the refresh interval of the watchlist with the given name, or the
+// profile's QuotesRefresh.
+//-----------------------------------------------------------------------------
+func (profile *Profile) refreshOf(name string) time.Duration {
+	seconds := profile.QuotesRefresh
+	if watchlist, ok := profile.Watchlists[name]; ok && watchlist.Refresh > 0 {
+		seconds = watchlist.Refresh
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+//-----------------------------------------------------------------------------
+func (profile *Profile) watchlistNames() []string {
+	names := []string{}
+	for name := range profile.Watchlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// byWatchlist routes the tickers of the watchlists with their own provider
+// to that provider, and the rest of the tickers to the profile's one.
+type byWatchlist struct {
+	profile   *Profile            // Profile with the watchlists.
+	providers map[string]Provider // Providers by watchlist name.
+	rest      Provider            // Provider of the tickers of the other watchlists.
+}
+
+// Returns the profile's provider along with the providers of the
+// watchlists, if any of them has its own.
+//-----------------------------------------------------------------------------
+func (profile *Profile) watchlistProviders(rest Provider) Provider {
+	providers := map[string]Provider{}
+	for name, watchlist := range profile.Watchlists {
+		if watchlist.Provider != `` {
+			key := watchlist.APIKey
+			if key == `` {
+				key = profile.APIKey
+			}
+			providers[name] = NewProvider(watchlist.Provider, key, profile.Endpoint, 0)
+		}
+	}
+	if len(providers) == 0 {
+		return rest
+	}
+
+	return &byWatchlist{profile: profile, providers: providers, rest: rest}
+}
+
+// Fetch requests the quotes of each watchlist from its provider. The
+// errors don't stop the other providers; the first one is returned along
+// with whatever quotes the rest of the providers have returned, or the
+// unknown tickers of all the providers.
+func (routed *byWatchlist) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	grouped, order := map[string][]string{}, []string{}
+	for _, ticker := range tickers {
+		name := routed.profile.watchlistOf(ticker)
+		if _, ok := routed.providers[name]; !ok {
+			name = ``
+		}
+		if _, ok := grouped[name]; !ok {
+			order = append(order, name)
+		}
+		grouped[name] = append(grouped[name], ticker)
+	}
+
+	var fetched []Stock
+	var err error
+	for _, name := range order {
+		provider, ok := routed.providers[name]
+		if !ok {
+			provider = routed.rest
+		}
+		stocks, e := provider.Fetch(ctx, grouped[name])
+		fetched = append(fetched, stocks...)
+		err = joinErrors(err, e)
+	}
+
+	return fetched, err
+}
+
+// Returns the greatest common divisor of the positive numbers.
+//-----------------------------------------------------------------------------
+func gcd(a, b int) int {
+	if a <= 0 {
+		return b
+	}
+	for b > 0 {
+		a, b = b, a%b
+	}
+	return a
+}
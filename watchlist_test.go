@@ -0,0 +1,88 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func watchlistProfile() *Profile {
+	return &Profile{
+		QuotesRefresh: 6,
+		Tickers:       []string{`AAPL`, `BTCUSDT`, `ETHUSDT`, `VTI`},
+		Watchlists: map[string]Watchlist{
+			`crypto`:    {Tickers: []string{`BTCUSDT`, `ethusdt`}, Refresh: 2, Provider: `binance`},
+			`long-term`: {Tickers: []string{`VTI`}, Refresh: 300},
+		},
+	}
+}
+
+func TestSchedulerDue(t *testing.T) {
+	profile := watchlistProfile()
+	scheduler := newScheduler()
+	now := time.Date(2019, 3, 5, 11, 0, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+
+	assert.Equal(t, profile.Tickers, scheduler.due(profile, profile.Tickers))
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, []string{`BTCUSDT`, `ETHUSDT`}, scheduler.due(profile, profile.Tickers))
+
+	now = now.Add(4*time.Second - scheduleSlack/2) // Slightly early tick.
+	assert.Equal(t, []string{`AAPL`, `BTCUSDT`, `ETHUSDT`}, scheduler.due(profile, profile.Tickers))
+
+	now = now.Add(300 * time.Second)
+	assert.Equal(t, profile.Tickers, scheduler.due(profile, profile.Tickers))
+}
+
+func TestSchedulerWithoutWatchlists(t *testing.T) {
+	profile := &Profile{QuotesRefresh: 5, Tickers: []string{`AAPL`, `IBM`}}
+	scheduler := newScheduler()
+	assert.Equal(t, profile.Tickers, scheduler.due(profile, profile.Tickers))
+	assert.Equal(t, profile.Tickers, scheduler.due(profile, profile.Tickers))
+}
+
+func TestRefreshInterval(t *testing.T) {
+	assert.Equal(t, 2*time.Second, watchlistProfile().RefreshInterval())
+	assert.Equal(t, 5*time.Second, (&Profile{QuotesRefresh: 5}).RefreshInterval())
+	assert.Equal(t, time.Second, (&Profile{}).RefreshInterval())
+
+	profile := &Profile{QuotesRefresh: 5, Watchlists: map[string]Watchlist{`slow`: {Refresh: 300}, `fast`: {Refresh: 2}}}
+	assert.Equal(t, time.Second, profile.RefreshInterval())
+}
+
+func TestMarketInterval(t *testing.T) {
+	assert.Equal(t, 30*time.Second, (&Profile{MarketRefresh: 30}).MarketInterval())
+	assert.Equal(t, 12*time.Second, (&Profile{}).MarketInterval())
+	assert.Equal(t, 12*time.Second, (&Profile{MarketRefresh: -1}).MarketInterval())
+}
+
+func TestByWatchlistRoutesTickers(t *testing.T) {
+	crypto := &fakeProvider{err: errors.New(`down`)}
+	rest := &fakeProvider{stocks: map[string]Stock{`AAPL`: {Ticker: `AAPL`}, `VTI`: {Ticker: `VTI`}}}
+	routed := &byWatchlist{profile: watchlistProfile(), providers: map[string]Provider{`crypto`: crypto}, rest: rest}
+
+	stocks, err := routed.Fetch(context.Background(), []string{`AAPL`, `BTCUSDT`, `VTI`, `ETHUSDT`})
+	assert.EqualError(t, err, `down`)
+	assert.Equal(t, []Stock{{Ticker: `AAPL`}, {Ticker: `VTI`}}, stocks)
+	assert.Equal(t, [][]string{{`BTCUSDT`, `ETHUSDT`}}, crypto.requested)
+	assert.Equal(t, [][]string{{`AAPL`, `VTI`}}, rest.requested)
+}
+
+func TestWatchlistProviders(t *testing.T) {
+	profile := watchlistProfile()
+	routed, ok := profile.provider().(*byWatchlist)
+	assert.True(t, ok)
+	assert.IsType(t, &binance{}, routed.providers[`crypto`])
+	assert.NotContains(t, routed.providers, `long-term`)
+
+	profile.Watchlists = map[string]Watchlist{`slow`: {Tickers: []string{`VTI`}, Refresh: 300}}
+	assert.IsType(t, &withBinance{}, profile.provider())
+}
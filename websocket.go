@@ -0,0 +1,332 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`bufio`
+	`crypto/rand`
+	`crypto/sha1`
+	`crypto/tls`
+	`encoding/base64`
+	`encoding/binary`
+	`errors`
+	`fmt`
+	`io`
+	`net`
+	`net/http`
+	`net/url`
+	`strings`
+	`sync`
+	`time`
+)
+
+// Magic string the server appends to the key to accept the handshake.
+const websocketGUID = `258EAFA5-E914-47DA-95CA-C5AB0DC85B11`
+
+// How long to wait for the connection to be established.
+const websocketTimeout = 10 * time.Second
+
+// How long the server waits for the frame to be sent before giving up on
+// the web view.
+const websocketWriteTimeout = 10 * time.Second
+
+// Largest message the server accepts from the web views, which aren't
+// expected to send anything bigger than a ping, and the largest message
+// the client accepts from the providers' servers.
+const (
+	websocketLimit       = 64 << 10
+	websocketClientLimit = 4 << 20
+)
+
+// Close status sent when the message is over the limit.
+const statusTooBig = 1009
+
+// Websocket frame opcodes.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// websocket is a bare-bones websocket connection that is just enough to
+// stream updates: it sends text messages and reads text messages while
+// answering pings. Extensions and subprotocols are not supported.
+type websocket struct {
+	conn   net.Conn      // Underlying connection.
+	reader *bufio.Reader // Buffered reader for the incoming frames.
+	mutex  sync.Mutex    // Serializes writes: pongs are sent while reading.
+	server bool          // True on the server side of the connection, which doesn't mask the frames.
+}
+
+// Connects to the websocket server at the given ws:// or wss:// URL.
+func dialWebsocket(rawurl string) (*websocket, error) {
+	target, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: websocketTimeout}
+	host := target.Host
+	switch target.Scheme {
+	case `ws`:
+		if target.Port() == `` {
+			host += `:80`
+		}
+		conn, err = dialProxied(dialer, &url.URL{Scheme: `http`, Host: target.Host}, host)
+	case `wss`:
+		if target.Port() == `` {
+			host += `:443`
+		}
+		if conn, err = dialProxied(dialer, &url.URL{Scheme: `https`, Host: target.Host}, host); err == nil {
+			secure := tls.Client(conn, &tls.Config{ServerName: target.Hostname()})
+			conn.SetDeadline(time.Now().Add(websocketTimeout))
+			if err = secure.Handshake(); err != nil {
+				conn.Close()
+			}
+			conn.SetDeadline(time.Time{})
+			conn = secure
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported websocket scheme %q", target.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &websocket{conn: conn, reader: bufio.NewReader(conn)}
+	if err := ws.handshake(target); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// Accepts websocket connection request made to the HTTP server. Browsers
+// send the Origin header, and the connections the pages of the other sites
+// make are refused, so they can't read the data with the token they got
+// hold of.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocket, error) {
+	key := r.Header.Get(`Sec-WebSocket-Key`)
+	if !strings.EqualFold(r.Header.Get(`Upgrade`), `websocket`) || key == `` {
+		http.Error(w, `Websocket connection expected`, http.StatusBadRequest)
+		return nil, errors.New(`Not a websocket request`)
+	}
+	if origin := r.Header.Get(`Origin`); origin != `` {
+		if parsed, err := url.Parse(origin); err != nil || !strings.EqualFold(parsed.Host, r.Host) {
+			http.Error(w, `Cross-origin websocket connection`, http.StatusForbidden)
+			return nil, fmt.Errorf("Websocket connection from %q is refused", origin)
+		}
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, `Websocket is not supported`, http.StatusInternalServerError)
+		return nil, errors.New(`Websocket is not supported`)
+	}
+
+	conn, buffer, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(buffer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(digest[:]))
+	if err := buffer.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocket{conn: conn, reader: buffer.Reader, server: true}, nil
+}
+
+// WriteText sends the text message to the other side.
+func (ws *websocket) WriteText(data []byte) error {
+	return ws.write(opText, data)
+}
+
+// ReadMessage returns the next text or binary message sent by the other
+// side. Pings are answered transparently; io.EOF is returned once the other
+// side closes the connection. The messages over the limit get the
+// connection closed.
+func (ws *websocket) ReadMessage() ([]byte, error) {
+	message := []byte{}
+	for {
+		final, opcode, payload, err := ws.frame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := ws.write(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			ws.write(opClose, nil)
+			return nil, io.EOF
+		}
+		if uint64(len(message)+len(payload)) > ws.limit() {
+			return nil, ws.tooBig()
+		}
+		if message = append(message, payload...); final {
+			return message, nil
+		}
+	}
+}
+
+// Close closes the connection.
+func (ws *websocket) Close() error {
+	return ws.conn.Close()
+}
+
+// Sends the opening handshake and validates the server response.
+//-----------------------------------------------------------------------------
+func (ws *websocket) handshake(target *url.URL) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request, err := http.NewRequest(`GET`, `http://`+target.Host+target.RequestURI(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set(`Upgrade`, `websocket`)
+	request.Header.Set(`Connection`, `Upgrade`)
+	request.Header.Set(`Sec-WebSocket-Key`, key)
+	request.Header.Set(`Sec-WebSocket-Version`, `13`)
+	if err := request.Write(ws.conn); err != nil {
+		return err
+	}
+
+	response, err := http.ReadResponse(ws.reader, request)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("Websocket handshake failed: %s", response.Status)
+	}
+	digest := sha1.Sum([]byte(key + websocketGUID))
+	if response.Header.Get(`Sec-WebSocket-Accept`) != base64.StdEncoding.EncodeToString(digest[:]) {
+		return errors.New(`Websocket handshake failed: invalid accept key`)
+	}
+
+	return nil
+}
+
+// Reads one frame, unmasking the payload if needed.
+//-----------------------------------------------------------------------------
+func (ws *websocket) frame() (final bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(ws.reader, header); err != nil {
+		return
+	}
+	final, opcode = header[0]&0x80 != 0, header[0]&0x0F
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err = io.ReadFull(ws.reader, extended); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err = io.ReadFull(ws.reader, extended); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	if length > ws.limit() {
+		err = ws.tooBig()
+		return
+	}
+
+	mask := []byte{}
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(ws.reader, mask); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err == nil && len(mask) > 0 {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return
+}
+
+// Tells the other side its message is over the limit, and closes the
+// connection.
+//-----------------------------------------------------------------------------
+func (ws *websocket) tooBig() error {
+	ws.write(opClose, []byte{statusTooBig >> 8, statusTooBig & 0xFF})
+	ws.conn.Close()
+
+	return fmt.Errorf("Websocket message is over %d bytes", ws.limit())
+}
+
+// Returns the largest message this side of the connection accepts.
+//-----------------------------------------------------------------------------
+func (ws *websocket) limit() uint64 {
+	if ws.server {
+		return websocketLimit
+	}
+	return websocketClientLimit
+}
+
+// Writes one frame. Client frames must be masked, server frames must not.
+//-----------------------------------------------------------------------------
+func (ws *websocket) write(opcode byte, payload []byte) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	masked := byte(0x80)
+	if ws.server {
+		masked = 0
+	}
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, masked|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, masked|126, byte(length>>8), byte(length))
+	default:
+		extended := make([]byte, 8)
+		binary.BigEndian.PutUint64(extended, uint64(length))
+		frame = append(append(frame, masked|127), extended...)
+	}
+
+	if ws.server {
+		frame = append(frame, payload...)
+	} else {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	}
+
+	if ws.server {
+		ws.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
@@ -6,13 +6,14 @@ package mop
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"math"
 	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // const quotesURL = `http://download.finance.yahoo.com/d/quotes.csv?s=%s&f=sl1c1p2oghjkva2r2rdyj3j1`
@@ -21,92 +22,256 @@ const quotesURLv7QueryParts = `&range=1d&interval=5m&indicators=close&includeTim
 
 const noDataIndicator = `N/A`
 
+// Maximum number of tickers fetched in one request; longer lists are
+// fetched in batches.
+const maxTickersPerRequest = 50
+
+// Maximum number of batches fetched at the same time.
+const maxParallelRequests = 4
+
 // Stock stores quote information for the particular stock ticker. The data
 // for all the fields except 'Advancing' is fetched using Yahoo market API.
+// The prices and the amounts are kept as numbers, and get formatted only
+// when they are displayed. It is not meant to be encoded as is: the machine
+// readable output has its own schema.
 type Stock struct {
-	Ticker     string `json:"symbol"`                      // Stock ticker.
-	LastTrade  string `json:"regularMarketPrice"`          // l1: last trade.
-	Change     string `json:"regularMarketChange"`         // c6: change real time.
-	ChangePct  string `json:"regularMarketChangePercent"`  // k2: percent change real time.
-	Open       string `json:"regularMarketOpen"`           // o: market open price.
-	Low        string `json:"regularMarketDayLow"`         // g: day's low.
-	High       string `json:"regularMarketDayHigh"`        // h: day's high.
-	Low52      string `json:"fiftyTwoWeekLow"`             // j: 52-weeks low.
-	High52     string `json:"fiftyTwoWeekHigh"`            // k: 52-weeks high.
-	Volume     string `json:"regularMarketVolume"`         // v: volume.
-	AvgVolume  string `json:"averageDailyVolume10Day"`     // a2: average volume.
-	PeRatio    string `json:"trailingPE"`                  // r2: P/E ration real time.
-	PeRatioX   string `json:"trailingPE"`                  // r: P/E ration (fallback when real time is N/A).
-	Dividend   string `json:"trailingAnnualDividendRate"`  // d: dividend.
-	Yield      string `json:"trailingAnnualDividendYield"` // y: dividend yield.
-	MarketCap  string `json:"marketCap"`                   // j3: market cap real time.
-	MarketCapX string `json:"marketCap"`                   // j1: market cap (fallback when real time is N/A).
-	Currency   string `json:"currency"`                    // String code for currency of stock.
-	Advancing  bool   // True when change is >= $0.
-	PreOpen    string `json:"preMarketChangePercent,omitempty"`
-	AfterHours string `json:"postMarketChangePercent,omitempty"`
+	Ticker        string   // Stock ticker.
+	LastTrade     Number   // l1: last trade.
+	Change        Number   // c6: change real time.
+	ChangePct     Number   // k2: percent change real time.
+	Open          Number   // o: market open price.
+	Low           Number   // g: day's low.
+	High          Number   // h: day's high.
+	Low52         Number   // j: 52-weeks low.
+	High52        Number   // k: 52-weeks high.
+	Volume        Number   // v: volume.
+	AvgVolume     Number   // a2: average volume.
+	PeRatio       Number   // r2: P/E ration real time.
+	PeRatioX      Number   // r: P/E ration (fallback when real time is N/A).
+	Dividend      Number   // d: dividend.
+	Yield         Number   // y: dividend yield.
+	MarketCap     Number   // j3: market cap real time.
+	MarketCapX    Number   // j1: market cap (fallback when real time is N/A).
+	Currency      string   // String code for currency of stock.
+	Advancing     bool     // True when change is >= $0.
+	PreOpen       Number   // Pre-market change, in percent.
+	AfterHours    Number   // After hours change, in percent.
+	PrevClose     Number   // Previous close price.
+	Computed      []string // Values of user-defined columns.
+	NewHigh       bool     // True when the last trade is the highest price seen by mop this session.
+	NewLow        bool     // True when the last trade is the lowest price seen by mop this session.
+	Changed       bool     // True when the quote has changed since the previous fetch.
+	QuoteType     string   // Type of the security, ex. EQUITY or ETF.
+	ExpenseRatio  Number   // Fund expense ratio, in percent.
+	FundYield     Number   // Fund yield (TTM), in percent.
+	NetAssets     Number   // Fund assets under management.
+	PrePrice      Number   // Pre-market price.
+	PreVolume     Number   // Pre-market volume.
+	PostPrice     Number   // After hours price.
+	PostVolume    Number   // After hours volume.
+	MarketState   string   // Trading session the quote is from, ex. PRE, REGULAR, or POST, blank if unknown.
+	Exchange      string   // Exchange the stock is listed on, ex. "NasdaqGS".
+	Earnings      int64    // Time of the upcoming earnings report, seconds since epoch, 0 if unknown.
+	Bid           Number   // Best bid price.
+	Ask           Number   // Best ask price.
+	Sector        string   // Company's sector, if fundamentals are fetched.
+	Industry      string   // Company's industry, if fundamentals are fetched.
+	Country       string   // Company's country, if fundamentals are fetched.
+	Rating        string   // Analysts' consensus, ex. "buy", if fundamentals are fetched.
+	TargetPrice   Number   // Analysts' mean target price, if fundamentals are fetched.
+	Volatility    Number   // 20-day historical volatility, in percent, if fundamentals are fetched.
+	ATR           Number   // 14-day average true range, if fundamentals are fetched.
+	SharesOut     Number   // Shares outstanding, if fundamentals are fetched.
+	FloatShares   Number   // Shares available for trading, if fundamentals are fetched.
+	ESG           Number   // Total ESG risk score, the lower the better, if the profile asks for it.
+	ESGEnv        Number   // Environment risk score.
+	ESGSocial     Number   // Social risk score.
+	ESGGov        Number   // Governance risk score.
+	SessionChange Number   // Change since the first price mop has seen this session, in percent.
+	Trend         string   // Direction of the trend as of the last few refreshes, ex. "↗↗→".
+	TrendSlope    Number   // Latest slope of the trend, in percent of the price per refresh.
+	Error         string   // Why the ticker has failed, ex. unknown symbol, for the placeholder row.
+}
+
+// yahoo is the default stock quotes provider.
+type yahoo struct {
+	url     string        // Quotes URL, with placeholder for the tickers.
+	session *yahooSession // Cookie and crumb attached to every request.
+}
+
+// Returns new Yahoo provider.
+func newYahoo() *yahoo {
+	return &yahoo{url: quotesURLv7 + quotesURLv7QueryParts, session: newYahooSession()}
 }
 
 // Quotes stores relevant pointers as well as the array of stock quotes for
 // the tickers we are tracking.
 type Quotes struct {
-	market  *Market  // Pointer to Market.
-	profile *Profile // Pointer to Profile.
-	stocks  []Stock  // Array of stock quote data.
-	errors  string   // Error string if any.
+	market       *Market               // Pointer to Market.
+	profile      *Profile              // Pointer to Profile.
+	store        *QuoteStore           // Store that fetches and keeps the latest quotes.
+	subscription *Subscription         // Subscription to the tickers we are tracking.
+	stocks       []Stock               // Array of stock quote data.
+	errors       string                // Error string if any.
+	watermarks   map[string][2]float64 // Lowest and highest prices seen this session per ticker.
+	digestError  string                // Error sending the digest, if any.
+	exportError  string                // Error logging or exporting the quotes fetched last, if any.
+	visible      map[string]bool       // Tickers displayed on screen last.
+	refreshes    int                   // Number of times the quotes have been refreshed.
+	fundamentals *fundamentalsCache    // Cached fundamentals, nil until they are first needed.
+	schedule     *scheduler            // Picks the watchlists due for the refresh, nil until the first fetch.
+	inflight     inflight              // Fetch under way, if any.
+	retrying     func(status string)   // Reports the retry status, nil to keep quiet.
+	failures     map[string]string     // Why the tickers that have failed the last fetch have failed, by ticker.
+	samples      map[string]*quoteRing // Recent prices of each stock seen this session, by ticker.
 }
 
-// Sets the initial values and returns new Quotes struct.
+// Sets the initial values and returns new Quotes struct. The quotes are
+// fetched by the quote store that uses the provider set in the profile.
 func NewQuotes(market *Market, profile *Profile) *Quotes {
+	store := NewQuoteStore(profile.provider())
 	return &Quotes{
-		market:  market,
-		profile: profile,
-		errors:  ``,
+		market:       market,
+		profile:      profile,
+		store:        store,
+		subscription: store.Subscribe(profile.Tickers),
+		errors:       ``,
+		watermarks:   make(map[string][2]float64),
 	}
 }
 
-// Fetch the latest stock quotes and parse raw fetched data into array of
-// []Stock structs.
-func (quotes *Quotes) Fetch() (self *Quotes) {
-	self = quotes // <-- This ensures we return correct quotes after recover() from panic().
-	if quotes.isReady() {
-		defer func() {
-			if err := recover(); err != nil {
-				quotes.errors = fmt.Sprintf("\n\n\n\nError fetching stock quotes...\n%s", err)
-			} else {
-				quotes.errors = ""
-			}
-		}()
+// Share returns new Quotes for another profile that fetch the stock quotes
+// through the same quote store, and therefore share the provider and its
+// rate limit.
+func (quotes *Quotes) Share(profile *Profile) *Quotes {
+	return &Quotes{
+		market:       quotes.market,
+		profile:      profile,
+		store:        quotes.store,
+		subscription: quotes.store.Subscribe(profile.Tickers),
+		watermarks:   make(map[string][2]float64),
+	}
+}
 
-		url := fmt.Sprintf(quotesURLv7, strings.Join(quotes.profile.Tickers, `,`))
-		response, err := http.Get(url + quotesURLv7QueryParts)
+// Fetch gets the latest stock quotes from Yahoo market API. Long lists of
+// tickers are fetched in batches, several batches at a time, so even the
+// watchlists of hundreds of tickers get refreshed within one cycle. Yahoo
+// leaves out the tickers it doesn't know, and these are reported as the
+// ticker errors.
+func (yahoo *yahoo) Fetch(ctx context.Context, tickers []string) ([]Stock, error) {
+	stocks, err := fetchConcurrently(ctx, batches(tickers, maxTickersPerRequest), maxParallelRequests, func(ctx context.Context, batch []string) ([]Stock, error) {
+		body, err := yahoo.session.Get(ctx, fmt.Sprintf(yahoo.url, strings.Join(batch, `,`)))
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
+		return parseYahoo(body)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		defer response.Body.Close()
-		body, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			panic(err)
-		}
+	return stocks, unknownTickers(tickers, stocks)
+}
 
-		quotes.parse2(body)
+// Fetch applies the profile settings (tickers and refresh priorities) to
+// refresh the latest stock quotes in the quote store, and takes the ones
+// we are subscribed to. The quote store and the providers know nothing
+// about the profile.
+func (quotes *Quotes) Fetch() *Quotes {
+	return quotes.FetchContext(context.Background())
+}
+
+// FetchContext is Fetch that gets abandoned as soon as the context is
+// cancelled or Cancel is called, ex. on quit. The quotes fetched before are
+// kept then, and no error is reported. The requests that fail on the way
+// to the provider are retried as per the profile's retry settings.
+func (quotes *Quotes) FetchContext(ctx context.Context) *Quotes {
+	if feed := quotes.profile.feed; feed != nil { // The watchlist grows as the tickers arrive.
+		quotes.profile.Tickers = feed.watchlist(quotes.profile.Tickers)
 	}
+	if quotes.isReady() {
+		ctx = quotes.inflight.begin(ctx)
+		defer quotes.inflight.done()
 
+		quotes.errors = ``
+		if err := quotes.refresh(ctx); err != nil {
+			quotes.errors = fmt.Sprintf("\n\n\n\nError fetching stock quotes...\n%s", err)
+		}
+	}
+
+	return quotes
+}
+
+// OnRetry sets the function that gets called with the status, ex.
+// "retrying quotes… (2/3)", while the failed fetch is about to be retried,
+// and with the blank status once it's done.
+func (quotes *Quotes) OnRetry(notify func(status string)) *Quotes {
+	quotes.retrying = notify
 	return quotes
 }
 
+// Refreshes the stock quotes due for the refresh, and takes the ones we are
+// subscribed to.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) refresh(ctx context.Context) error {
+	profile, previous := quotes.profile, quotes.stocks
+	if err := SetProxy(profile.ProxySetting()); err != nil {
+		return err
+	}
+	outgoing.setBudgets(profile.budgets())
+	quotes.refreshes++
+	tickers := profile.Tickers
+	if previous != nil && quotes.visible != nil {
+		tickers = prioritize(profile.Tickers, quotes.visible, profile.Pinned, profile.OffscreenRefresh, quotes.refreshes)
+	}
+	if quotes.schedule == nil {
+		quotes.schedule = newScheduler()
+	}
+	tickers = quotes.schedule.due(profile, tickers)
+	quotes.subscription.Set(profile.Tickers)
+	err := newRetryPolicy(profile.Retry, quotes.retrying).do(ctx, `quotes`, func(ctx context.Context) error {
+		return quotes.store.Refresh(ctx, tickers)
+	})
+	if ctx.Err() != nil { // Abandoned, keeps the quotes fetched before.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	quotes.stocks, quotes.failures = quotes.subscription.Stocks(), quotes.subscription.Failures()
+	quotes.enrich()
+	quotes.compare(previous)
+	quotes.watermark()
+	quotes.sample(time.Now())
+	quotes.export(ctx, time.Now(), tickers)
+
+	return nil
+}
+
+// Cancel abandons the fetch under way, if any, ex. on quit or pause.
+func (quotes *Quotes) Cancel() {
+	quotes.inflight.done()
+}
+
 // Ok returns two values: 1) boolean indicating whether the error has occured,
 // and 2) the error text itself.
 func (quotes *Quotes) Ok() (bool, string) {
 	return quotes.errors == ``, quotes.errors
 }
 
+// Failures returns why the tickers that have failed the last fetch, ex.
+// delisted or mistyped, have failed, by ticker. The rest of the stock
+// quotes are fetched regardless.
+func (quotes *Quotes) Failures() map[string]string {
+	return quotes.failures
+}
+
 // AddTickers saves the list of tickers and refreshes the stock data if new
 // tickers have been added. The function gets called from the line editor
 // when user adds new stock tickers.
 func (quotes *Quotes) AddTickers(tickers []string) (added int, err error) {
 	if added, err = quotes.profile.AddTickers(tickers); err == nil && added > 0 {
+		quotes.Cancel()     // The fetch under way is for the old tickers.
 		quotes.stocks = nil // Force fetch.
 	}
 	return
@@ -117,20 +282,116 @@ func (quotes *Quotes) AddTickers(tickers []string) (added int, err error) {
 // when user removes existing stock tickers.
 func (quotes *Quotes) RemoveTickers(tickers []string) (removed int, err error) {
 	if removed, err = quotes.profile.RemoveTickers(tickers); err == nil && removed > 0 {
+		quotes.Cancel()     // The fetch under way is for the old tickers.
 		quotes.stocks = nil // Force fetch.
 	}
 	return
 }
 
+// watermark updates the lowest and highest prices mop has seen since it was
+// started, and flags the stocks that have made new session high or low. The
+// very first quote of the stock sets the watermarks without flagging it.
+func (quotes *Quotes) watermark() *Quotes {
+	if quotes.watermarks == nil {
+		quotes.watermarks = make(map[string][2]float64)
+	}
+	for i, stock := range quotes.stocks {
+		last := stock.LastTrade.Value()
+		if last <= 0 {
+			continue
+		}
+		marks, seen := quotes.watermarks[stock.Ticker]
+		if !seen {
+			marks = [2]float64{last, last}
+		}
+		quotes.stocks[i].NewLow, quotes.stocks[i].NewHigh = last < marks[0], last > marks[1]
+		marks[0], marks[1] = math.Min(marks[0], last), math.Max(marks[1], last)
+		quotes.watermarks[stock.Ticker] = marks
+	}
+
+	return quotes
+}
+
+// Subset returns stock quotes for the given tickers (ex. index constituents)
+// sharing the quote store. Only the tickers the store has no quotes for get
+// fetched.
+func (quotes *Quotes) Subset(tickers []string) *Quotes {
+	subset := &Quotes{market: quotes.market, profile: quotes.profile, store: quotes.store}
+	subscription := quotes.store.Subscribe(tickers)
+	defer subscription.Unsubscribe()
+
+	if err := quotes.store.Refresh(context.Background(), quotes.store.Missing(tickers)); err != nil {
+		subset.errors = fmt.Sprintf("\n\n\n\nError fetching stock quotes...\n%s", err)
+	}
+	subset.stocks = subscription.Stocks()
+
+	return subset
+}
+
+// compare flags the stock quotes that have changed since the previous fetch.
+// Nothing is flagged on the very first fetch.
+func (quotes *Quotes) compare(previous []Stock) *Quotes {
+	before := make(map[string]Stock)
+	for _, stock := range previous {
+		before[stock.Ticker] = stock
+	}
+	for i, stock := range quotes.stocks {
+		old, seen := before[stock.Ticker]
+		stock.NewHigh, stock.NewLow, stock.Changed = old.NewHigh, old.NewLow, old.Changed                 // Ignore the flags.
+		stock.SessionChange, stock.Trend, stock.TrendSlope = old.SessionChange, old.Trend, old.TrendSlope // Sampled after the fetch.
+		quotes.stocks[i].Changed = seen && !reflect.DeepEqual(old, stock)
+	}
+
+	return quotes
+}
+
+// Appends the quotes of the given tickers that have just been fetched to
+// the quote log, and writes them to the line protocol endpoint, if the
+// profile asks for it. The recorded and piped quotes are left out, and the
+// failure is reported along with the alerts rather than in place of the
+// quotes.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) export(ctx context.Context, now time.Time, tickers []string) {
+	profile := quotes.profile
+	if profile.replay != nil || profile.feed != nil {
+		return
+	}
+
+	quotes.exportError = ``
+	if err := quotes.record(now, tickers); err != nil {
+		quotes.exportError = fmt.Sprintf("Quote log failed: %s", err)
+	} else if err := quotes.writeLines(ctx, now, tickers); err != nil && ctx.Err() == nil {
+		quotes.exportError = fmt.Sprintf("Line protocol export failed: %s", err)
+	}
+}
+
+// Fills in the fundamentals if the profile asks for them, and gets the
+// expired ones refreshed. The recorded and piped quotes are left as is.
+//-----------------------------------------------------------------------------
+func (quotes *Quotes) enrich() {
+	profile := quotes.profile
+	if !profile.Fundamentals || profile.replay != nil || profile.feed != nil {
+		return
+	}
+	if quotes.fundamentals == nil {
+		quotes.fundamentals = newFundamentalsCache(profile.filename + `.cache`)
+	}
+	quotes.fundamentals.optional = map[string]bool{`esg`: profile.ESG == `yahoo`}
+	quotes.fundamentals.refresh(profile.Tickers)
+	quotes.fundamentals.apply(quotes.stocks)
+}
+
 // isReady returns true if we haven't fetched the quotes yet *or* the stock
-// market is still open and we might want to grab the latest quotes. In both
-// cases we make sure the list of requested tickers is not empty.
+// market is still open (or it's pre-market watch time) and we might want to
+// grab the latest quotes. In both cases we make sure the list of requested
+// tickers is not empty.
 func (quotes *Quotes) isReady() bool {
-	return (quotes.stocks == nil || !quotes.market.IsClosed) && len(quotes.profile.Tickers) > 0
+	return (quotes.stocks == nil || !quotes.market.IsClosed || quotes.profile.preMarket) && len(quotes.profile.Tickers) > 0
 }
 
-// this will parse the json objects
-func (quotes *Quotes) parse2(body []byte) (*Quotes, error) {
+// parseYahoo parses the JSON response of Yahoo market API into the array
+// of []Stock structs.
+func parseYahoo(body []byte) ([]Stock, error) {
 	// response -> quoteResponse -> result|error (array) -> map[string]interface{}
 	// Stocks has non-int things
 	// d := map[string]map[string][]Stock{}
@@ -143,96 +404,157 @@ func (quotes *Quotes) parse2(body []byte) (*Quotes, error) {
 	}
 	results := d["quoteResponse"]["result"]
 
-	quotes.stocks = make([]Stock, len(results))
+	stocks := make([]Stock, len(results))
 	for i, raw := range results {
-		result := map[string]string{}
-		for k, v := range raw {
-			switch v.(type) {
-			case string:
-				result[k] = v.(string)
-			case float64:
-				result[k] = float2Str(v.(float64))
-			default:
-				result[k] = fmt.Sprintf("%v", v)
+		number := func(key string) Number {
+			if value, ok := raw[key].(float64); ok {
+				return numberOf(value)
 			}
-
+			return Number{}
 		}
-		quotes.stocks[i].Ticker = result["symbol"]
-		quotes.stocks[i].LastTrade = result["regularMarketPrice"]
-		quotes.stocks[i].Change = result["regularMarketChange"]
-		quotes.stocks[i].ChangePct = result["regularMarketChangePercent"]
-		quotes.stocks[i].Open = result["regularMarketOpen"]
-		quotes.stocks[i].Low = result["regularMarketDayLow"]
-		quotes.stocks[i].High = result["regularMarketDayHigh"]
-		quotes.stocks[i].Low52 = result["fiftyTwoWeekLow"]
-		quotes.stocks[i].High52 = result["fiftyTwoWeekHigh"]
-		quotes.stocks[i].Volume = result["regularMarketVolume"]
-		quotes.stocks[i].AvgVolume = result["averageDailyVolume10Day"]
-		quotes.stocks[i].PeRatio = result["trailingPE"]
-		// TODO calculate rt
-		quotes.stocks[i].PeRatioX = result["trailingPE"]
-		quotes.stocks[i].Dividend = result["trailingAnnualDividendRate"]
-		quotes.stocks[i].Yield = result["trailingAnnualDividendYield"]
-		quotes.stocks[i].MarketCap = result["marketCap"]
-		// TODO calculate rt?
-		quotes.stocks[i].MarketCapX = result["marketCap"]
-		quotes.stocks[i].Currency = result["currency"]
-		quotes.stocks[i].PreOpen = result["preMarketChangePercent"]
-		quotes.stocks[i].AfterHours = result["postMarketChangePercent"]
-		/*
-			fmt.Println(i)
-			fmt.Println("-------------------")
-			for k, v := range result {
-				fmt.Println(k, v)
+		text := func(key string) string {
+			if value, ok := raw[key]; ok {
+				return fmt.Sprintf("%v", value)
 			}
-			fmt.Println("-------------------")
-		*/
-		adv, err := strconv.ParseFloat(quotes.stocks[i].Change, 64)
-		if err == nil {
-			quotes.stocks[i].Advancing = adv >= 0.0
+			return ``
 		}
+		stocks[i].Ticker = text("symbol")
+		stocks[i].LastTrade = number("regularMarketPrice")
+		stocks[i].Change = number("regularMarketChange")
+		stocks[i].ChangePct = number("regularMarketChangePercent")
+		stocks[i].Open = number("regularMarketOpen")
+		stocks[i].Low = number("regularMarketDayLow")
+		stocks[i].High = number("regularMarketDayHigh")
+		stocks[i].Low52 = number("fiftyTwoWeekLow")
+		stocks[i].High52 = number("fiftyTwoWeekHigh")
+		stocks[i].Volume = number("regularMarketVolume")
+		stocks[i].AvgVolume = number("averageDailyVolume10Day")
+		stocks[i].PeRatio = number("trailingPE")
+		// TODO calculate rt
+		stocks[i].PeRatioX = number("trailingPE")
+		stocks[i].Dividend = number("trailingAnnualDividendRate")
+		stocks[i].Yield = percentage(raw["trailingAnnualDividendYield"])
+		stocks[i].MarketCap = number("marketCap")
+		// TODO calculate rt?
+		stocks[i].MarketCapX = number("marketCap")
+		stocks[i].Currency = text("currency")
+		stocks[i].PreOpen = number("preMarketChangePercent")
+		stocks[i].AfterHours = number("postMarketChangePercent")
+		stocks[i].PrevClose = number("regularMarketPreviousClose")
+		stocks[i].QuoteType = text("quoteType")
+		stocks[i].ExpenseRatio = number("netExpenseRatio")
+		stocks[i].FundYield = percentage(raw["yield"])
+		stocks[i].NetAssets = number("netAssets")
+		stocks[i].PrePrice = number("preMarketPrice")
+		stocks[i].PreVolume = number("preMarketVolume")
+		stocks[i].PostPrice = number("postMarketPrice")
+		stocks[i].PostVolume = number("postMarketVolume")
+		stocks[i].MarketState = text("marketState")
+		stocks[i].Exchange = text("fullExchangeName")
+		stocks[i].Earnings = timestamp(raw["earningsTimestamp"])
+		stocks[i].Bid = number("bid")
+		stocks[i].Ask = number("ask")
+		stocks[i].Advancing = stocks[i].Change.Known() && stocks[i].Change.Value() >= 0.0
 	}
-	return quotes, nil
+	return stocks, nil
 }
 
-// Use reflection to parse and assign the quotes data fetched using the Yahoo
-// market API.
-func (quotes *Quotes) parse(body []byte) *Quotes {
-	lines := bytes.Split(body, []byte{'\n'})
-	quotes.stocks = make([]Stock, len(lines))
-	//
-	// Get the total number of fields in the Stock struct. Skip the last
-	// Advanicing field which is not fetched.
-	//
-	fieldsCount := reflect.ValueOf(quotes.stocks[0]).NumField() - 1
-	//
-	// Split each line into columns, then iterate over the Stock struct
-	// fields to assign column values.
-	//
-	for i, line := range lines {
-		columns := bytes.Split(bytes.TrimSpace(line), []byte{','})
-		for j := 0; j < fieldsCount; j++ {
-			// ex. quotes.stocks[i].Ticker = string(columns[0])
-			reflect.ValueOf(&quotes.stocks[i]).Elem().Field(j).SetString(string(columns[j]))
+// Returns the list of tickers to refresh this time given how many times the
+// quotes have been refreshed. Unless every is greater than one all the
+// tickers get refreshed every time. Otherwise the tickers displayed on
+// screen and the pinned ones get refreshed every time while the rest only
+// once every so many refreshes.
+//-----------------------------------------------------------------------------
+func prioritize(tickers []string, visible map[string]bool, pinned []string, every, refresh int) []string {
+	if every <= 1 || refresh%every == 0 {
+		return tickers
+	}
+
+	always := make(map[string]bool)
+	for _, ticker := range pinned {
+		always[ticker] = true
+	}
+	due := []string{}
+	for _, ticker := range tickers {
+		if visible[ticker] || always[ticker] {
+			due = append(due, ticker)
 		}
-		//
-		// Try realtime value and revert to the last known if the
-		// realtime is not available.
-		//
-		if quotes.stocks[i].PeRatio == `N/A` && quotes.stocks[i].PeRatioX != `N/A` {
-			quotes.stocks[i].PeRatio = quotes.stocks[i].PeRatioX
+	}
+
+	return due
+}
+
+// Splits the list of tickers into batches of up to the given size.
+//-----------------------------------------------------------------------------
+func batches(tickers []string, size int) [][]string {
+	batched := [][]string{}
+	for len(tickers) > size {
+		batched, tickers = append(batched, tickers[:size]), tickers[size:]
+	}
+	if len(tickers) > 0 {
+		batched = append(batched, tickers)
+	}
+
+	return batched
+}
+
+// Fetches the batches of tickers concurrently, up to the given number of
+// batches at a time, and returns the quotes in the order of the batches.
+// The first error abandons the batches still under way, and is returned
+// instead of the quotes.
+//-----------------------------------------------------------------------------
+func fetchConcurrently(ctx context.Context, batches [][]string, parallel int, fetch func(context.Context, []string) ([]Stock, error)) ([]Stock, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wait sync.WaitGroup
+	var failure sync.Once
+	var err error
+	fetched := make([][]Stock, len(batches))
+	slots := make(chan bool, parallel)
+	for i, batch := range batches {
+		slots <- true
+		if ctx.Err() != nil {
+			break
 		}
-		if quotes.stocks[i].MarketCap == `N/A` && quotes.stocks[i].MarketCapX != `N/A` {
-			quotes.stocks[i].MarketCap = quotes.stocks[i].MarketCapX
+		wait.Add(1)
+		go func(i int, batch []string) {
+			defer func() { <-slots; wait.Done() }()
+			stocks, e := fetch(ctx, batch)
+			if e != nil {
+				failure.Do(func() { err = e; cancel() })
+			}
+			fetched[i] = stocks
+		}(i, batch)
+	}
+	wait.Wait()
+	if err == nil {
+		err = ctx.Err() // The parent context has been cancelled.
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stocks := []Stock{}
+	for _, batch := range fetched {
+		stocks = append(stocks, batch...)
+	}
+
+	return stocks, nil
+}
+
+// Returns the list of tickers in upper case without duplicates.
+//-----------------------------------------------------------------------------
+func unique(tickers []string) []string {
+	seen, list := make(map[string]bool), []string{}
+	for _, ticker := range tickers {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != `` && !seen[ticker] {
+			seen[ticker] = true
+			list = append(list, ticker)
 		}
-		//
-		// Stock is advancing if the change is not negative (i.e. $0.00
-		// is also "advancing").
-		//
-		quotes.stocks[i].Advancing = (quotes.stocks[i].Change[0:1] != `-`)
 	}
 
-	return quotes
+	return list
 }
 
 //-----------------------------------------------------------------------------
@@ -240,6 +562,14 @@ func sanitize(body []byte) []byte {
 	return bytes.Replace(bytes.TrimSpace(body), []byte{'"'}, []byte{}, -1)
 }
 
+// Yahoo reports yields as fractions, i.e. 0.0185 => 1.850.
+func percentage(v interface{}) Number {
+	if fraction, ok := v.(float64); ok {
+		return numberOf(fraction * 100)
+	}
+	return Number{}
+}
+
 func float2Str(v float64) string {
 	unit := ""
 	switch {
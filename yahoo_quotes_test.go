@@ -5,34 +5,144 @@
 package mop
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestQuotes(t *testing.T) {
-	market := NewMarket()
-	profile := NewProfile()
-
-	profile.Tickers = []string{"GOOG", "BA"}
-
-	quotes := NewQuotes(market, profile)
-	require.NotNil(t, quotes)
-
 	data, err := ioutil.ReadFile("./yahoo_quotes_sample.json")
 	require.Nil(t, err)
 	require.NotNil(t, data)
 
-	require.True(t, quotes.isReady())
-	//quotes.Fetch(data)
-	_, err = quotes.parse2(data)
+	stocks, err := parseYahoo(data)
 	assert.NoError(t, err)
 
-	require.Equal(t, 2, len(quotes.stocks))
-	assert.Equal(t, "BA", quotes.stocks[0].Ticker)
-	assert.Equal(t, "331.76", quotes.stocks[0].LastTrade)
-	assert.Equal(t, "GOOG", quotes.stocks[1].Ticker)
-	assert.Equal(t, "1214.38", quotes.stocks[1].LastTrade)
+	require.Equal(t, 2, len(stocks))
+	assert.Equal(t, "BA", stocks[0].Ticker)
+	assert.Equal(t, "331.760", stocks[0].LastTrade.String())
+	assert.Equal(t, "NYSE", stocks[0].Exchange)
+	assert.Equal(t, "PREPRE", stocks[0].MarketState)
+	assert.Equal(t, "GOOG", stocks[1].Ticker)
+	assert.Equal(t, "1214.380", stocks[1].LastTrade.String())
+
+	quotes := NewQuotes(NewMarket(), &Profile{Tickers: []string{"GOOG", "BA"}})
+	require.NotNil(t, quotes)
+	require.True(t, quotes.isReady())
+}
+
+func TestWatermarks(t *testing.T) {
+	quotes := NewQuotes(NewMarket(), &Profile{})
+
+	quotes.stocks = []Stock{{Ticker: "GOOG", LastTrade: numberOf(1214.38)}}
+	quotes.watermark()
+	assert.False(t, quotes.stocks[0].NewHigh)
+	assert.False(t, quotes.stocks[0].NewLow)
+
+	quotes.stocks = []Stock{{Ticker: "GOOG", LastTrade: numberOf(1220.00)}}
+	quotes.watermark()
+	assert.True(t, quotes.stocks[0].NewHigh)
+	assert.False(t, quotes.stocks[0].NewLow)
+
+	quotes.stocks = []Stock{{Ticker: "GOOG", LastTrade: numberOf(1216.00)}}
+	quotes.watermark()
+	assert.False(t, quotes.stocks[0].NewHigh)
+	assert.False(t, quotes.stocks[0].NewLow)
+
+	quotes.stocks = []Stock{{Ticker: "GOOG", LastTrade: numberOf(1210.00)}}
+	quotes.watermark()
+	assert.False(t, quotes.stocks[0].NewHigh)
+	assert.True(t, quotes.stocks[0].NewLow)
+}
+
+func TestRefreshTiers(t *testing.T) {
+	tickers, visible := []string{"AAPL", "BA", "GOOG", "IBM"}, map[string]bool{"AAPL": true}
+	assert.Equal(t, []string{"AAPL", "IBM"}, prioritize(tickers, visible, []string{"IBM"}, 3, 2))
+	assert.Equal(t, tickers, prioritize(tickers, visible, []string{"IBM"}, 3, 3), "off-screen tickers are refreshed every 3rd time")
+	assert.Equal(t, tickers, prioritize(tickers, visible, nil, 0, 2))
+
+	assert.Equal(t, [][]string{{"AAPL", "BA"}, {"GOOG", "IBM"}}, batches(tickers, 2))
+	assert.Equal(t, [][]string{{"AAPL", "BA", "GOOG"}, {"IBM"}}, batches(tickers, 3))
+}
+
+func TestChanged(t *testing.T) {
+	quotes := NewQuotes(NewMarket(), &Profile{})
+	previous := []Stock{{Ticker: "BA", LastTrade: numberOf(331.76)}, {Ticker: "GOOG", LastTrade: numberOf(1214.38), NewHigh: true}}
+
+	quotes.stocks = []Stock{{Ticker: "BA", LastTrade: numberOf(331.76)}, {Ticker: "GOOG", LastTrade: numberOf(1216.00)}, {Ticker: "IBM"}}
+	quotes.compare(previous)
+	assert.False(t, quotes.stocks[0].Changed)
+	assert.True(t, quotes.stocks[1].Changed)
+	assert.False(t, quotes.stocks[2].Changed, "first quote is not a change")
+
+	previous, quotes.stocks = quotes.stocks, []Stock{{Ticker: "GOOG", LastTrade: numberOf(1216.00)}}
+	quotes.compare(previous)
+	assert.False(t, quotes.stocks[0].Changed)
+}
+
+func TestSubset(t *testing.T) {
+	assert.Equal(t, []string{"AAPL", "BRK-B"}, unique([]string{"AAPL", "brk-b", " aapl", "BRK-B", ""}))
+
+	provider := &fakeProvider{stocks: map[string]Stock{
+		"AAPL": {Ticker: "AAPL", LastTrade: numberOf(200.00)},
+		"MSFT": {Ticker: "MSFT", LastTrade: numberOf(140.00)},
+		"UNH":  {Ticker: "UNH", LastTrade: numberOf(250.00)},
+	}}
+	quotes := NewQuotes(NewMarket(), &Profile{Tickers: []string{"AAPL", "MSFT"}})
+	quotes.store = NewQuoteStore(provider)
+	quotes.subscription = quotes.store.Subscribe(quotes.profile.Tickers)
+	quotes.Fetch()
+	require.Len(t, quotes.stocks, 2)
+
+	subset := quotes.Subset([]string{"msft", "UNH", "AAPL", "MSFT"})
+	require.Len(t, subset.stocks, 3)
+	assert.Equal(t, "MSFT", subset.stocks[0].Ticker)
+	assert.Equal(t, "250.000", subset.stocks[1].LastTrade.String())
+	assert.Equal(t, [][]string{{"AAPL", "MSFT"}, {"UNH"}}, provider.requested, "only missing tickers are fetched")
+	assert.Equal(t, []string{"AAPL", "MSFT"}, quotes.store.Tickers(), "drill-down unsubscribes")
+}
+
+func TestFetchConcurrently(t *testing.T) {
+	var running, most int32
+	fetch := func(ctx context.Context, batch []string) ([]Stock, error) {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&most)
+			if now <= seen || atomic.CompareAndSwapInt32(&most, seen, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return []Stock{{Ticker: batch[0]}}, nil
+	}
+
+	tickers := []string{}
+	for i := 0; i < 250; i++ {
+		tickers = append(tickers, string(rune('A'+i/26))+string(rune('A'+i%26)))
+	}
+	stocks, err := fetchConcurrently(context.Background(), batches(tickers, maxTickersPerRequest), 2, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, []Stock{{Ticker: `AA`}, {Ticker: `BY`}, {Ticker: `DW`}, {Ticker: `FU`}, {Ticker: `HS`}}, stocks)
+	assert.Equal(t, int32(2), most)
+}
+
+func TestFetchConcurrentlyFails(t *testing.T) {
+	fetch := func(ctx context.Context, batch []string) ([]Stock, error) {
+		if batch[0] == `B` {
+			return nil, errors.New(`rejected`)
+		}
+		<-ctx.Done() // The rest of the batches get abandoned.
+		return nil, ctx.Err()
+	}
+
+	stocks, err := fetchConcurrently(context.Background(), [][]string{{`A`}, {`B`}, {`C`}}, 3, fetch)
+	assert.EqualError(t, err, `rejected`)
+	assert.Nil(t, stocks)
 }
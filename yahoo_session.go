@@ -0,0 +1,135 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	`context`
+	`errors`
+	`fmt`
+	`io/ioutil`
+	`net/http`
+	`net/http/cookiejar`
+	`net/url`
+	`strings`
+	`sync`
+)
+
+const yahooCookieURL = `https://fc.yahoo.com`
+const yahooCrumbURL = `https://query2.finance.yahoo.com/v1/test/getcrumb`
+
+// Yahoo turns away the requests that don't look like they come from the
+// browser.
+const yahooUserAgent = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15`
+
+// yahooSession holds the cookie and the matching crumb Yahoo market API
+// requires with every request. The cookie is set by any Yahoo page, and
+// the crumb is requested with the cookie. Both get refreshed when Yahoo
+// stops accepting them. The session could be used by several goroutines
+// at once.
+type yahooSession struct {
+	cookieURL string       // Page that sets the cookie.
+	crumbURL  string       // Returns the crumb for the cookie.
+	client    *http.Client // Keeps the cookie in its jar.
+	mutex     sync.Mutex   // Guards the crumb, held while it's being refreshed.
+	crumb     string       // Current crumb, blank until the first request.
+}
+
+// Returns new Yahoo session; the cookie and crumb get fetched on first use.
+func newYahooSession() *yahooSession {
+	client := newClient(requestTimeout)
+	client.Jar, _ = cookiejar.New(nil)
+	return &yahooSession{cookieURL: yahooCookieURL, crumbURL: yahooCrumbURL, client: client}
+}
+
+// Get requests the given URL with the cookie and crumb attached, and
+// returns the response body. If Yahoo rejects them the session is
+// refreshed and the request is made once again.
+func (session *yahooSession) Get(ctx context.Context, address string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		crumb, err := session.current(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		status, body, err := session.get(ctx, address+`&crumb=`+url.QueryEscape(crumb))
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusUnauthorized || status == http.StatusForbidden {
+			session.expire(crumb) // The cookie or crumb has expired.
+			if attempt == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Yahoo rejected the session: %d %s", status, http.StatusText(status))
+		}
+
+		return body, nil
+	}
+}
+
+// Returns the current crumb, and gets the new one first if there is none.
+//-----------------------------------------------------------------------------
+func (session *yahooSession) current(ctx context.Context) (string, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.crumb == `` {
+		if err := session.refresh(ctx); err != nil {
+			return ``, err
+		}
+	}
+
+	return session.crumb, nil
+}
+
+// Forgets the crumb Yahoo has rejected unless another request has already
+// replaced it.
+//-----------------------------------------------------------------------------
+func (session *yahooSession) expire(crumb string) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.crumb == crumb {
+		session.crumb = ``
+	}
+}
+
+// Gets the cookie and then the crumb that goes with it. Must be called with
+// the mutex held.
+//-----------------------------------------------------------------------------
+func (session *yahooSession) refresh(ctx context.Context) error {
+	if _, _, err := session.get(ctx, session.cookieURL); err != nil { // The page itself could be 404, only the cookie matters.
+		return err
+	}
+
+	status, body, err := session.get(ctx, session.crumbURL)
+	if err != nil {
+		return err
+	}
+	crumb := strings.TrimSpace(string(body))
+	if status != http.StatusOK || crumb == `` || strings.ContainsAny(crumb, `<{ `) {
+		return errors.New(`Unable to get Yahoo crumb`)
+	}
+	session.crumb = crumb
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+func (session *yahooSession) get(ctx context.Context, address string) (int, []byte, error) {
+	request, err := http.NewRequestWithContext(ctx, `GET`, address, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	request.Header.Set(`User-Agent`, yahooUserAgent)
+
+	response, err := session.client.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	return response.StatusCode, body, err
+}
+
@@ -0,0 +1,79 @@
+// Copyright (c) 2013-2019 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYahooSession(t *testing.T) {
+	crumb, requested := `abc`, []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		cookie, _ := r.Cookie(`A3`)
+		switch r.URL.Path {
+		case `/cookie`:
+			http.SetCookie(w, &http.Cookie{Name: `A3`, Value: `session`, Path: `/`})
+			http.NotFound(w, r)
+		case `/crumb`:
+			if cookie == nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, crumb)
+		case `/quote`:
+			if cookie == nil || r.URL.Query().Get(`crumb`) != crumb {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"quoteResponse":{"result":[{"symbol":"AAPL","regularMarketPrice":200}]}}`)
+		}
+	}))
+	defer server.Close()
+
+	provider := newYahoo()
+	provider.url = server.URL + `/quote?symbols=%s`
+	provider.session.cookieURL, provider.session.crumbURL = server.URL+`/cookie`, server.URL+`/crumb`
+
+	stocks, err := provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	require.Len(t, stocks, 1)
+	assert.Equal(t, `200.000`, stocks[0].LastTrade.String())
+	assert.Equal(t, []string{`/cookie`, `/crumb`, `/quote`}, requested)
+
+	requested = nil
+	_, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`/quote`}, requested, `the session is reused`)
+
+	requested, crumb = nil, `def`
+	_, err = provider.Fetch(context.Background(), []string{`AAPL`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`/quote`, `/cookie`, `/crumb`, `/quote`}, requested, `expired crumb gets refreshed`)
+	assert.Equal(t, `def`, provider.session.crumb)
+}
+
+func TestYahooSessionRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == `/crumb` {
+			fmt.Fprint(w, `abc`)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	session := newYahooSession()
+	session.cookieURL, session.crumbURL = server.URL+`/cookie`, server.URL+`/crumb`
+	_, err := session.Get(context.Background(), server.URL+`/quote?symbols=AAPL`)
+	assert.EqualError(t, err, `Yahoo rejected the session: 401 Unauthorized`)
+}